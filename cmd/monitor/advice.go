@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"danich/pkg/advisor"
+	"danich/pkg/monitor"
+)
+
+// runAdvice muestra las sugerencias registradas con la explicación de cada una
+func runAdvice(args []string) error {
+	fs := flag.NewFlagSet("sugerencias", flag.ExitOnError)
+	from := fs.String("desde", "", "Desde (2006-01-02 15:04)")
+	to := fs.String("hasta", "", "Hasta (2006-01-02 15:04)")
+	last := fs.Int("ultimas", 5, "Cantidad de sugerencias más recientes (0 = todas)")
+	accion := fs.String("accion", "", "Solo esta acción (mover, mantener, prevenir)")
+	asJSON := fs.Bool("json", false, "Salida en JSON")
+	fs.Parse(args)
+
+	fromTime, err := monitor.ParseTimeArg(*from)
+	if err != nil {
+		return err
+	}
+	toTime, err := monitor.ParseTimeArg(*to)
+	if err != nil {
+		return err
+	}
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+
+	store, err := monitor.OpenStore(config, monitor.NewPersistence(config, nil))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	records, err := store.Advice(fromTime, toTime)
+	if err != nil {
+		return err
+	}
+
+	if *accion != "" {
+		filtered := records[:0]
+		for _, record := range records {
+			if record.Advice.Accion == *accion {
+				filtered = append(filtered, record)
+			}
+		}
+		records = filtered
+	}
+	if *last > 0 && len(records) > *last {
+		records = records[len(records)-*last:]
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+
+	if len(records) == 0 {
+		fmt.Println("Sin sugerencias en el período")
+		return nil
+	}
+	for _, record := range records {
+		printAdvice(record)
+	}
+	return nil
+}
+
+// printAdvice muestra una sugerencia y su traza
+func printAdvice(record monitor.AdviceRecord) {
+	advice := record.Advice
+	fmt.Printf("\n%s  #%d  %s\n", record.DateTime.Format("2006-01-02 15:04:05"), record.CheckCount, strings.ToUpper(advice.Summary()))
+	fmt.Printf("  📋 %s\n", advice.Razon)
+
+	trace := advice.Trace
+	if trace == nil {
+		fmt.Println("  (sin traza: sugerencia registrada antes de guardar explicaciones)")
+		return
+	}
+
+	var thresholds []string
+	if trace.Thresholds.MinDifference > 0 {
+		thresholds = append(thresholds, fmt.Sprintf("diferencia > %.0f%%", trace.Thresholds.MinDifference))
+	}
+	if trace.Thresholds.Forecast > 0 {
+		thresholds = append(thresholds, fmt.Sprintf("pronóstico ≥ %.0f%%", trace.Thresholds.Forecast))
+	}
+	fmt.Printf("  Umbrales: %s\n", strings.Join(thresholds, ", "))
+
+	if len(trace.Imbalances) > 0 {
+		fmt.Println("  Desbalances (prioridad = dif × (1 + carga/100) × (1 + relativo)):")
+		for _, imb := range trace.Imbalances {
+			mark := " "
+			if imb.Selected {
+				mark = "→"
+			}
+			fmt.Printf("   %s %-8s S1 %5.1f%%  S2 %5.1f%%  dif %5.1f  carga %5.1f  relativo %.2f  prioridad %6.1f\n",
+				mark, imb.SKU, imb.Sorter1Pct, imb.Sorter2Pct, imb.Difference, imb.TotalLoad, imb.RelativeImbalance, imb.Priority)
+		}
+	}
+
+	if len(trace.Rejected) > 0 {
+		fmt.Println("  Descartados:")
+		for _, move := range trace.Rejected {
+			fmt.Printf("     %-8s %s\n", move.SKU, move.Reason)
+		}
+	}
+
+	printLLMTrace(trace)
+}
+
+// printLLMTrace indica si el LLM cambió la recomendación de las reglas
+func printLLMTrace(trace *advisor.AdviceTrace) {
+	llm := trace.LLM
+	if llm == nil {
+		fmt.Println("  LLM: no consultado")
+		return
+	}
+	switch {
+	case !llm.Used:
+		fmt.Printf("  LLM %s: sin respuesta (%s), se usó la regla\n", llm.Model, llm.Error)
+	case llm.Overridden():
+		fmt.Printf("  LLM %s: cambió %s (regla: %s)\n", llm.Model, strings.Join(llm.Changed, ", "), trace.Rule.Summary())
+	case len(llm.Changed) > 0:
+		fmt.Printf("  LLM %s: confirmó la regla y amplió la razón (%s)\n", llm.Model, llm.Format)
+	default:
+		fmt.Printf("  LLM %s: confirmó la regla\n", llm.Model)
+	}
+	if llm.Response != "" {
+		fmt.Printf("  Respuesta: %s\n", llm.Response)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"danich/pkg/advisor"
+	"danich/pkg/monitor"
+)
+
+// runAsk responde una pregunta sobre la planta con el LLM configurado, que
+// consulta los datos guardados a través de las herramientas del asistente
+func runAsk(args []string) error {
+	fs := flag.NewFlagSet("ask", flag.ExitOnError)
+	model := fs.String("modelo", "", "Modelo de Ollama (por defecto advisor.ollama_model y sus respaldos)")
+	timeout := fs.Duration("timeout", 0, "Tiempo máximo por respuesta del modelo (por defecto advisor.timeout)")
+	detail := fs.Bool("detalle", false, "Mostrar las herramientas consultadas y sus resultados")
+	asJSON := fs.Bool("json", false, "Salida en JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Uso: monitor ask [flags] \"pregunta\"")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	question := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if question == "" {
+		fs.Usage()
+		return fmt.Errorf("falta la pregunta")
+	}
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+	if *model != "" {
+		config.Advisor.OllamaModel = *model
+		config.Advisor.FallbackModels = nil
+	}
+	if *timeout > 0 {
+		config.Advisor.Timeout = *timeout
+	}
+	config.Advisor.WarmUp = false // La primera pregunta ya carga el modelo
+
+	persistence := monitor.NewPersistence(config, nil)
+	store, err := monitor.OpenStore(config, persistence)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	adv := advisor.NewAdvisor(config.Advisor, nil)
+	if health := adv.Prepare(ctx); !health.Healthy() {
+		if health.Error != "" {
+			return fmt.Errorf("Ollama no disponible: %s", health.Error)
+		}
+		return fmt.Errorf("no hay un modelo de Ollama configurado (advisor.ollama_model)")
+	}
+
+	answer, err := monitor.NewAssistant(config, store, persistence, adv).Ask(ctx, question, nil)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(answer)
+	}
+
+	if *detail {
+		for _, step := range answer.Steps {
+			args, _ := json.Marshal(step.Args)
+			fmt.Printf("🔧 %s %s\n", step.Name, args)
+			if step.Error != "" {
+				fmt.Printf("   ❌ %s\n", step.Error)
+			} else {
+				fmt.Printf("   %s\n", step.Result)
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("💬 %s\n", answer.Text)
+	if len(answer.Steps) > 0 {
+		names := make([]string, len(answer.Steps))
+		for i, step := range answer.Steps {
+			names[i] = step.Name
+		}
+		fmt.Printf("\n📎 %s · datos: %s · %.1fs\n", answer.Model, strings.Join(names, ", "), float64(answer.DurationMs)/1000)
+	} else {
+		fmt.Printf("\n📎 %s · sin consultar datos · %.1fs\n", answer.Model, float64(answer.DurationMs)/1000)
+	}
+	return nil
+}
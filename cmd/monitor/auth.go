@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"danich/pkg/monitor"
+
+	"github.com/mattn/go-isatty"
+)
+
+// runAuth despacha los subcomandos de acceso a la API
+func runAuth(args []string) error {
+	usage := fmt.Errorf("uso: auth clave|usuario|auditoria [opciones]")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "clave":
+		return runAuthKey(args[1:])
+	case "usuario":
+		return runAuthUser(args[1:])
+	case "auditoria":
+		return runAuthAudit(args[1:])
+	default:
+		return usage
+	}
+}
+
+// runAuthKey genera una clave de API y la entrada de config.yaml con su hash
+func runAuthKey(args []string) error {
+	fs := flag.NewFlagSet("auth clave", flag.ExitOnError)
+	name := fs.String("nombre", "", "Nombre de la integración (p. ej. grafana)")
+	role := fs.String("rol", monitor.RoleViewer, "Rol: lector, supervisor o admin")
+	fs.Parse(args)
+
+	if *name == "" {
+		return fmt.Errorf("falta -nombre")
+	}
+	if !validRole(*role) {
+		return fmt.Errorf("rol inválido %q (lector, supervisor o admin)", *role)
+	}
+
+	key, hash, err := monitor.NewAPIKeySecret()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔑 Clave: %s\n", key)
+	fmt.Println("   Se muestra solo esta vez; enviarla en Authorization: Bearer <clave> o X-API-Key.")
+	fmt.Println("\nAgregar en config.yaml, en api.claves:")
+	fmt.Printf("    - nombre: %s\n      rol: %s\n      sha256: %s\n", *name, *role, hash)
+	return nil
+}
+
+// runAuthUser pide una contraseña y muestra la entrada de config.yaml con su
+// hash bcrypt. La contraseña se lee de stdin para que no quede en el
+// historial del shell.
+func runAuthUser(args []string) error {
+	fs := flag.NewFlagSet("auth usuario", flag.ExitOnError)
+	username := fs.String("usuario", "", "Nombre de usuario")
+	role := fs.String("rol", monitor.RoleViewer, "Rol: lector, supervisor o admin")
+	fs.Parse(args)
+
+	if *username == "" || strings.Contains(*username, ":") {
+		return fmt.Errorf("usuario inválido %q", *username)
+	}
+	if !validRole(*role) {
+		return fmt.Errorf("rol inválido %q (lector, supervisor o admin)", *role)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	interactive := isatty.IsTerminal(os.Stdin.Fd())
+	password, err := readLine(reader, interactive, "Contraseña: ")
+	if err != nil {
+		return err
+	}
+	if interactive {
+		confirm, err := readLine(reader, interactive, "Repetir contraseña: ")
+		if err != nil {
+			return err
+		}
+		if confirm != password {
+			return fmt.Errorf("las contraseñas no coinciden")
+		}
+	}
+
+	hash, err := monitor.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Agregar en config.yaml, en api.usuarios:")
+	fmt.Printf("    - usuario: %s\n      rol: %s\n      bcrypt: %q\n", *username, *role, hash)
+	return nil
+}
+
+// runAuthAudit muestra la auditoría de acciones privilegiadas
+func runAuthAudit(args []string) error {
+	fs := flag.NewFlagSet("auth auditoria", flag.ExitOnError)
+	hours := fs.Float64("horas", 24, "Horas hacia atrás (0 = todo)")
+	username := fs.String("usuario", "", "Solo las acciones de este usuario o clave")
+	action := fs.String("accion", "", "Solo esta acción (p. ej. config.recargar)")
+	asJSON := fs.Bool("json", false, "Salida en JSON")
+	fs.Parse(args)
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+
+	query := monitor.AuditQuery{Usuario: *username, Accion: *action}
+	if *hours > 0 {
+		query.From = time.Now().Add(-time.Duration(*hours * float64(time.Hour)))
+	}
+	entries, err := monitor.NewPersistence(config, nil).LoadAudit(query)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("Sin acciones registradas en el período")
+		return nil
+	}
+	for _, entry := range entries {
+		icon := "✓"
+		switch entry.Resultado {
+		case monitor.AuditDenied:
+			icon = "⛔"
+		case monitor.AuditError:
+			icon = "❌"
+		}
+		fmt.Printf("%s %s  %-12s %-10s %-22s %s", icon, entry.DateTime.Format("2006-01-02 15:04:05"),
+			entry.Usuario, entry.Rol, entry.Accion, entry.Remoto)
+		if entry.Detalle != "" {
+			fmt.Printf("  %s", entry.Detalle)
+		}
+		if entry.Error != "" {
+			fmt.Printf("  (%s)", entry.Error)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// validRole indica si role es uno de los roles de la API
+func validRole(role string) bool {
+	return role == monitor.RoleViewer || role == monitor.RoleSupervisor || role == monitor.RoleAdmin
+}
+
+// readLine lee una línea de stdin, con un prompt en stderr si es una terminal
+func readLine(reader *bufio.Reader, interactive bool, prompt string) (string, error) {
+	if interactive {
+		fmt.Fprint(os.Stderr, prompt)
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("no se pudo leer la contraseña: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"danich/pkg/monitor"
+)
+
+// runCompact repara la carpeta de datos y aplica la retención configurada
+func runCompact(args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	fs.Parse(args)
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+
+	result, err := monitor.CompactFolder(config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ %d snapshots únicos en %d archivos diarios\n", result.Snapshots, result.DailyFiles)
+	fmt.Printf("✓ Cambios: %d → %d (duplicados exactos eliminados)\n", result.ChangesBefore, result.ChangesAfter)
+
+	retention := result.RetentionApply
+	if len(retention.ArchivedDays) > 0 {
+		fmt.Printf("✓ Archivados %d snapshots de %d día(s): %s\n",
+			retention.ArchivedSnapshots, len(retention.ArchivedDays), strings.Join(retention.ArchivedDays, ", "))
+	}
+	if retention.RollupRecords > 0 {
+		fmt.Printf("✓ %d agregados generados para los días archivados\n", retention.RollupRecords)
+	}
+	for _, path := range retention.DeletedFiles {
+		fmt.Printf("🗑️  Eliminado por presupuesto de disco: %s\n", path)
+	}
+	if retention.OverBudget {
+		fmt.Printf("⚠️  La carpeta sigue excediendo el presupuesto de %d MB\n", config.DiskBudgetMB)
+	}
+
+	fmt.Printf("\n✓ Carpeta de datos: %.1f MB → %.1f MB\n",
+		float64(retention.BytesBefore)/(1024*1024), float64(retention.BytesAfter)/(1024*1024))
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"danich/pkg/monitor"
+)
+
+// runConfig muestra la configuración efectiva con el origen de cada valor
+func runConfig(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	options := monitor.RegisterConfigFlags(fs)
+	fs.Parse(args)
+
+	config, err := loadConfig(*options)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLAVE\tVALOR\tORIGEN\tVARIABLE")
+	for _, v := range config.Effective() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Key, v.Value, v.Origin, v.EnvVar)
+	}
+	return w.Flush()
+}
+
+// loadConfig carga la configuración de un subcomando y muestra sus avisos en
+// stderr, para no mezclarlos con la salida
+func loadConfig(options monitor.ConfigOptions) (*monitor.SystemConfig, error) {
+	config, err := monitor.LoadConfigWith(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range config.Notes {
+		fmt.Fprintf(os.Stderr, "⚠️  %s\n", note)
+	}
+	return config, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"danich/pkg/monitor"
+)
+
+// runExport despacha los subcomandos de exportación
+func runExport(args []string) error {
+	if len(args) == 0 || args[0] != "features" {
+		return fmt.Errorf("uso: export features [opciones]")
+	}
+	return runExportFeatures(args[1:])
+}
+
+// runExportFeatures exporta la matriz de features para entrenamiento
+func runExportFeatures(args []string) error {
+	defaults := monitor.DefaultFeatureOptions()
+
+	fs := flag.NewFlagSet("export features", flag.ExitOnError)
+	from := fs.String("desde", "", "Desde (2006-01-02 15:04)")
+	to := fs.String("hasta", "", "Hasta (2006-01-02 15:04)")
+	format := fs.String("formato", "ambos", "Formato: csv, parquet o ambos")
+	output := fs.String("salida", "", "Ruta de salida sin extensión (por defecto <carpeta de datos>/features)")
+	lags := fs.Int("lags", defaults.Lags, "Porcentajes rezagados por SKU")
+	window := fs.Int("ventana", defaults.Window, "Muestras para media y pendiente móviles")
+	horizon := fs.Int("horizonte", int(defaults.Horizon.Minutes()), "Minutos del horizonte de las etiquetas")
+	fs.Parse(args)
+
+	if *format != "csv" && *format != "parquet" && *format != "ambos" {
+		return fmt.Errorf("formato inválido: %q", *format)
+	}
+
+	fromTime, err := monitor.ParseTimeArg(*from)
+	if err != nil {
+		return err
+	}
+	toTime, err := monitor.ParseTimeArg(*to)
+	if err != nil {
+		return err
+	}
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		*output = filepath.Join(config.DatasetFolder, "features")
+	}
+
+	store, err := monitor.OpenStore(config, monitor.NewPersistence(config, nil))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	opts := monitor.FeatureOptions{Lags: *lags, Window: *window, Horizon: time.Duration(*horizon) * time.Minute}
+
+	snapshots, err := store.Snapshots(fromTime, toTime)
+	if err != nil {
+		return err
+	}
+
+	// Los cambios posteriores al rango también cuentan para las etiquetas
+	changesTo := toTime
+	if !changesTo.IsZero() {
+		changesTo = changesTo.Add(opts.Horizon)
+	}
+	changes, err := store.Changes(time.Time{}, changesTo)
+	if err != nil {
+		return err
+	}
+
+	matrix := monitor.BuildFeatureMatrix(snapshots, changes, opts)
+	fmt.Printf("✓ Matriz de %d filas × %d columnas (%d SKUs)\n", len(matrix.Rows), len(matrix.Columns), len(matrix.SKUs))
+
+	if *format == "csv" || *format == "ambos" {
+		if err := matrix.WriteCSV(*output + ".csv"); err != nil {
+			return err
+		}
+		fmt.Printf("✓ CSV guardado en %s.csv\n", *output)
+	}
+	if *format == "parquet" || *format == "ambos" {
+		if err := matrix.WriteParquet(*output + ".parquet"); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Parquet guardado en %s.parquet\n", *output)
+	}
+
+	if err := matrix.WriteSchema(*output + "_schema.json"); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Esquema de columnas en %s_schema.json\n", *output)
+	return nil
+}
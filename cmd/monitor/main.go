@@ -1,18 +1,70 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"danich/pkg/monitor"
 )
 
+// commands contiene los subcomandos disponibles además del monitoreo
+var commands = map[string]func(args []string) error{
+	"rollups":       runRollups,
+	"reporte-turno": runShiftReport,
+	"migrar":        runMigrate,
+	"historial":     runHistory,
+	"export":        runExport,
+	"compact":       runCompact,
+	"config":        runConfig,
+	"throughput":    runThroughput,
+	"whatif":        runWhatIf,
+	"sugerencias":   runAdvice,
+	"prompt":        runPrompt,
+	"ask":           runAsk,
+	"auth":          runAuth,
+}
+
 func main() {
-	m, err := monitor.New()
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		command, exists := commands[os.Args[1]]
+		if !exists {
+			fmt.Fprintf(os.Stderr, "Comando desconocido: %s\n", os.Args[1])
+			os.Exit(2)
+		}
+		if err := command(os.Args[2:]); err != nil {
+			log.Fatalf("Error ejecutando %s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	// Sin subcomando: monitoreo, con flags para cualquier valor de configuración
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	options := monitor.RegisterConfigFlags(fs)
+	fs.Parse(os.Args[1:])
+
+	m, err := monitor.New(*options)
 	if err != nil {
 		log.Fatalf("Error inicializando monitor: %v", err)
 	}
 
-	if err := m.Run(); err != nil {
+	// Ctrl+C o SIGTERM detienen el loop y cierran todo en orden; una segunda
+	// señal termina el proceso de inmediato
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := m.Run(ctx); err != nil {
 		log.Fatalf("Error ejecutando monitor: %v", err)
 	}
+	if ctx.Err() != nil {
+		os.Exit(130)
+	}
 }
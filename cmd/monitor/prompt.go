@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"danich/pkg/advisor"
+	"danich/pkg/monitor"
+)
+
+// runPrompt muestra el prompt que recibiría el LLM para un snapshot, sin consultarlo
+func runPrompt(args []string) error {
+	fs := flag.NewFlagSet("prompt", flag.ExitOnError)
+	at := fs.String("fecha", "", "Snapshot a usar: el último hasta esta fecha (por defecto el más reciente)")
+	language := fs.String("idioma", "", "Idioma de las plantillas (por defecto advisor.idioma)")
+	dir := fs.String("plantillas", "", "Carpeta con las plantillas de la planta (por defecto advisor.plantillas)")
+	asJSON := fs.Bool("json", false, "Salida en JSON")
+	fs.Parse(args)
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+	if *language != "" {
+		config.Advisor.Language = *language
+	}
+	if *dir != "" {
+		config.Advisor.PromptDir = *dir
+	}
+
+	// Se cargan antes para informar errores de las plantillas en vez de usar las incluidas
+	if _, err := advisor.LoadPromptTemplates(config.Advisor.Language, config.Advisor.PromptDir); err != nil {
+		return err
+	}
+
+	store, err := monitor.OpenStore(config, monitor.NewPersistence(config, nil))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	snapshot, err := snapshotAt(store, *at)
+	if err != nil {
+		return err
+	}
+
+	from := snapshot.DateTime.Add(-2 * time.Hour)
+	changes, err := store.Changes(from, snapshot.DateTime)
+	if err != nil {
+		return err
+	}
+	records, err := store.Advice(from, snapshot.DateTime)
+	if err != nil {
+		return err
+	}
+
+	state := monitor.AdvisorState(snapshot, changes, records)
+	prompt, err := advisor.NewAdvisor(config.Advisor, nil).RenderPrompt(state)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(prompt)
+	}
+
+	fmt.Printf("── SISTEMA (%s, snapshot %s) ──\n", config.Advisor.Language, snapshot.Timestamp)
+	fmt.Println(prompt.System)
+	fmt.Println("\n── PROMPT ──")
+	fmt.Println(prompt.User)
+	return nil
+}
+
+// snapshotAt retorna el último snapshot hasta la fecha indicada, o el más
+// reciente si está vacía
+func snapshotAt(store monitor.Store, value string) (monitor.DataSnapshot, error) {
+	at, err := monitor.ParseTimeArg(value)
+	if err != nil {
+		return monitor.DataSnapshot{}, err
+	}
+	if at.IsZero() {
+		return monitor.LatestSnapshot(store)
+	}
+
+	snapshots, err := store.Snapshots(at.Add(-24*time.Hour), at)
+	if err != nil {
+		return monitor.DataSnapshot{}, err
+	}
+	if len(snapshots) == 0 {
+		return monitor.DataSnapshot{}, fmt.Errorf("no hay snapshots en las 24 horas anteriores a %s", at.Format("2006-01-02 15:04"))
+	}
+	return snapshots[len(snapshots)-1], nil
+}
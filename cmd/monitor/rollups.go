@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"danich/pkg/monitor"
+)
+
+// runRollups consulta los agregados de porcentajes persistidos
+func runRollups(args []string) error {
+	fs := flag.NewFlagSet("rollups", flag.ExitOnError)
+	window := fs.String("ventana", "", "Ventana: 5m, 1h o turno")
+	sorter := fs.Int("sorter", 0, "Sorter (0 = todos)")
+	kind := fs.String("tipo", "", "Tipo de clave: sku o calibre")
+	key := fs.String("clave", "", "Prefijo de SKU o calibre")
+	from := fs.String("desde", "", "Desde (2006-01-02 15:04)")
+	to := fs.String("hasta", "", "Hasta (2006-01-02 15:04)")
+	asJSON := fs.Bool("json", false, "Salida en JSON")
+	fs.Parse(args)
+
+	if err := monitor.ValidateWindow(*window); err != nil {
+		return err
+	}
+
+	query := monitor.RollupQuery{Window: *window, SorterID: *sorter, Kind: *kind, Key: *key}
+	var err error
+	if query.From, err = monitor.ParseTimeArg(*from); err != nil {
+		return err
+	}
+	if query.To, err = monitor.ParseTimeArg(*to); err != nil {
+		return err
+	}
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+
+	records, err := monitor.NewPersistence(config, nil).LoadRollups(query)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INICIO\tVENTANA\tTURNO\tSORTER\tCLAVE\tN\tPROM\tMIN\tMAX\tP50\tP90\tP95\tSOBRE UMBRAL")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%v\n",
+			r.Start.Format("2006-01-02 15:04"), r.Window, r.Shift, r.SorterID, r.Key, r.Samples,
+			r.Mean, r.Min, r.Max, r.P50, r.P90, r.P95,
+			(time.Duration(r.SecondsAbove) * time.Second).Round(time.Second))
+	}
+	return w.Flush()
+}
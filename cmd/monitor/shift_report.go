@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"danich/pkg/advisor"
+	"danich/pkg/monitor"
+)
+
+// runShiftReport genera a pedido el reporte de un turno ya registrado
+func runShiftReport(args []string) error {
+	fs := flag.NewFlagSet("reporte-turno", flag.ExitOnError)
+	day := fs.String("dia", "", "Día productivo (2006-01-02)")
+	shift := fs.String("turno", "", "Nombre del turno")
+	fs.Parse(args)
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+
+	window, ok := config.ShiftCalendar().Window(*day, *shift)
+	if !ok {
+		return fmt.Errorf("turno %q del día %q no encontrado en la configuración", *shift, *day)
+	}
+
+	persistence := monitor.NewPersistence(config, nil)
+	store, err := monitor.OpenStore(config, persistence)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	reporter := monitor.NewShiftReporter(config, store, advisor.NewAdvisor(config.Advisor, nil))
+	report, err := reporter.Build(window)
+	if err != nil {
+		return err
+	}
+
+	path, err := persistence.SaveReport(report)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(monitor.FormatShiftReport(report))
+	fmt.Printf("\n✓ Reporte guardado en %s\n", path)
+	return nil
+}
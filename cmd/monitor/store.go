@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"danich/pkg/monitor"
+)
+
+// runMigrate importa los archivos JSON existentes a la base SQLite
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrar", flag.ExitOnError)
+	path := fs.String("db", "", "Archivo SQLite de destino (por defecto almacenamiento.ruta)")
+	fs.Parse(args)
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+	if *path == "" {
+		*path = config.SQLitePath
+	}
+
+	source := monitor.NewJSONStore(monitor.NewPersistence(config, nil))
+	target, err := monitor.OpenSQLiteStore(*path)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	snapshots, err := source.Snapshots(time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		if err := target.SaveSnapshot(snapshot); err != nil {
+			return fmt.Errorf("error migrando snapshot %s: %w", snapshot.Timestamp, err)
+		}
+	}
+	fmt.Printf("✓ %d snapshots migrados\n", len(snapshots))
+
+	changes, err := source.Changes(time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if err := target.SaveChange(change); err != nil {
+			return fmt.Errorf("error migrando cambio %s: %w", change.Timestamp, err)
+		}
+	}
+	fmt.Printf("✓ %d cambios migrados\n", len(changes))
+
+	records, err := source.Advice(time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := target.SaveAdvice(record); err != nil {
+			return fmt.Errorf("error migrando advice: %w", err)
+		}
+	}
+	fmt.Printf("✓ %d sugerencias migradas\n", len(records))
+
+	fmt.Printf("\n✓ Migración completa en %s\n", *path)
+	fmt.Println("  Para usarla configura almacenamiento.tipo: sqlite en config.yaml")
+	return nil
+}
+
+// runHistory consulta el histórico de porcentajes de los gráficos
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("historial", flag.ExitOnError)
+	sku := fs.String("sku", "", "Prefijo de SKU")
+	sorter := fs.Int("sorter", 0, "Sorter (0 = todos)")
+	min := fs.Float64("min", 0, "Porcentaje mínimo")
+	from := fs.String("desde", "", "Desde (2006-01-02 15:04)")
+	to := fs.String("hasta", "", "Hasta (2006-01-02 15:04)")
+	asJSON := fs.Bool("json", false, "Salida en JSON")
+	fs.Parse(args)
+
+	query := monitor.PercentageQuery{SKU: *sku, SorterID: *sorter, MinPercentage: *min}
+	var err error
+	if query.From, err = monitor.ParseTimeArg(*from); err != nil {
+		return err
+	}
+	if query.To, err = monitor.ParseTimeArg(*to); err != nil {
+		return err
+	}
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+
+	store, err := monitor.OpenStore(config, monitor.NewPersistence(config, nil))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	points, err := store.PercentageHistory(query)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(points)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FECHA\tSORTER\tSKU\tPORCENTAJE")
+	for _, p := range points {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%.1f\n", p.DateTime.Format("2006-01-02 15:04:05"), p.SorterID, p.SKU, p.Percentage)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"danich/pkg/monitor"
+)
+
+// runThroughput muestra o registra el throughput manual de un sorter. El
+// monitor lo toma desde el próximo ciclo y reemplaza al de la configuración.
+func runThroughput(args []string) error {
+	fs := flag.NewFlagSet("throughput", flag.ExitOnError)
+	sorter := fs.Int("sorter", 0, "Sorter a actualizar (sin -sorter solo se muestran los valores)")
+	perMinute := fs.Float64("por-minuto", 0, "Entrada del sorter por minuto (0 = volver al valor de la configuración)")
+	fs.Parse(args)
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+	persistence := monitor.NewPersistence(config, nil)
+
+	if *sorter != 0 {
+		if *sorter < 1 || (config.PackingSorters > 0 && *sorter > config.PackingSorters) {
+			return fmt.Errorf("sorter inválido %d", *sorter)
+		}
+		if *perMinute < 0 {
+			return fmt.Errorf("-por-minuto debe ser >= 0")
+		}
+		entry := monitor.ThroughputEntry{SorterID: *sorter, PerMinute: *perMinute, UpdatedAt: time.Now()}
+		if err := persistence.SaveThroughput(entry); err != nil {
+			return err
+		}
+		if *perMinute > 0 {
+			fmt.Printf("✓ Throughput del sorter %d: %.1f %s/min\n", *sorter, *perMinute, config.CapacityUnit)
+		} else {
+			fmt.Printf("✓ Throughput manual del sorter %d eliminado\n", *sorter)
+		}
+	}
+
+	manual, err := persistence.LoadThroughput()
+	if err != nil {
+		return err
+	}
+
+	sorterIDs := make(map[int]bool)
+	for sorterID := range config.SorterThroughput {
+		sorterIDs[sorterID] = true
+	}
+	for sorterID := range manual {
+		sorterIDs[sorterID] = true
+	}
+	ids := make([]int, 0, len(sorterIDs))
+	for sorterID := range sorterIDs {
+		ids = append(ids, sorterID)
+	}
+	sort.Ints(ids)
+
+	if len(ids) == 0 {
+		fmt.Println("Sin throughput configurado ni ingresado manualmente")
+		return nil
+	}
+
+	fmt.Printf("\n📦 Throughput por sorter (%s/min):\n", config.CapacityUnit)
+	for _, sorterID := range ids {
+		if entry, ok := manual[sorterID]; ok {
+			fmt.Printf("  • Sorter %d: %.1f (manual, %s)\n", sorterID, entry.PerMinute, entry.UpdatedAt.Format("2006-01-02 15:04"))
+			continue
+		}
+		fmt.Printf("  • Sorter %d: %.1f (config)\n", sorterID, config.SorterThroughput[sorterID])
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"danich/pkg/monitor"
+)
+
+// editFlag acumula ediciones de un mismo tipo pasadas con un flag repetible
+type editFlag struct {
+	accion string
+	edits  *[]monitor.WhatIfEdit
+}
+
+func (f editFlag) String() string { return "" }
+
+// Set interpreta "sorter:SKU:salida" para agregar/quitar y
+// "SKU:de:a[:salida,salida]" para mover
+func (f editFlag) Set(value string) error {
+	parts := strings.Split(value, ":")
+	atoi := func(s string) (int, error) {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return 0, fmt.Errorf("número inválido %q en %q", s, value)
+		}
+		return n, nil
+	}
+
+	edit := monitor.WhatIfEdit{Accion: f.accion}
+	var err error
+	switch f.accion {
+	case monitor.WhatIfMoveSKU:
+		if len(parts) < 3 || len(parts) > 4 {
+			return fmt.Errorf("se esperaba SKU:de:a[:salidas], no %q", value)
+		}
+		edit.SKU = parts[0]
+		if edit.SorterID, err = atoi(parts[1]); err != nil {
+			return err
+		}
+		if edit.ASorter, err = atoi(parts[2]); err != nil {
+			return err
+		}
+		if len(parts) == 4 && parts[3] != "" {
+			for _, s := range strings.Split(parts[3], ",") {
+				salida, err := atoi(s)
+				if err != nil {
+					return err
+				}
+				edit.Salidas = append(edit.Salidas, salida)
+			}
+		}
+	default:
+		if len(parts) != 3 {
+			return fmt.Errorf("se esperaba sorter:SKU:salida, no %q", value)
+		}
+		if edit.SorterID, err = atoi(parts[0]); err != nil {
+			return err
+		}
+		edit.SKU = parts[1]
+		if edit.Salida, err = atoi(parts[2]); err != nil {
+			return err
+		}
+	}
+
+	*f.edits = append(*f.edits, edit)
+	return nil
+}
+
+// runWhatIf simula ediciones de assignments sobre el último snapshot guardado
+// y muestra la comparación antes/después sin modificar ningún dato
+func runWhatIf(args []string) error {
+	var edits []monitor.WhatIfEdit
+	fs := flag.NewFlagSet("whatif", flag.ExitOnError)
+	fs.Var(editFlag{monitor.WhatIfAddSalida, &edits}, "agregar", "Agregar salida: sorter:SKU:salida (repetible)")
+	fs.Var(editFlag{monitor.WhatIfRemoveSalida, &edits}, "quitar", "Quitar salida: sorter:SKU:salida (repetible)")
+	fs.Var(editFlag{monitor.WhatIfMoveSKU, &edits}, "mover", "Mover SKU: SKU:de:a[:salida,salida] (repetible)")
+	file := fs.String("archivo", "", "Archivo JSON con una lista de ediciones")
+	asJSON := fs.Bool("json", false, "Salida en JSON")
+	fs.Parse(args)
+
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		var fromFile []monitor.WhatIfEdit
+		if err := json.Unmarshal(data, &fromFile); err != nil {
+			return fmt.Errorf("error leyendo %s: %w", *file, err)
+		}
+		edits = append(fromFile, edits...)
+	}
+
+	config, err := loadConfig(monitor.ConfigOptions{})
+	if err != nil {
+		return err
+	}
+	persistence := monitor.NewPersistence(config, nil)
+
+	store, err := monitor.OpenStore(config, persistence)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	snapshot, err := monitor.LatestSnapshot(store)
+	if err != nil {
+		return err
+	}
+	manual, err := persistence.LoadThroughput()
+	if err != nil {
+		return err
+	}
+
+	result, err := monitor.NewWhatIfSimulator(config).Simulate(snapshot, manual, edits)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	fmt.Printf("🧪 Simulación sobre el snapshot %s\n", result.Timestamp)
+	for _, edit := range result.Edits {
+		fmt.Printf("  • %s\n", edit)
+	}
+
+	fmt.Println("\nCarga por salida:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SORTER\tSALIDA\tANTES %\tDESPUÉS %\tANTES /MIN\tDESPUÉS /MIN\t")
+	for _, change := range result.Changes {
+		overload := ""
+		if change.OverloadAfter {
+			overload = "⚠️ sobrecarga"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%s\n",
+			change.SorterID, change.Salida, change.Before, change.After, change.LoadBefore, change.LoadAfter, overload)
+	}
+	w.Flush()
+
+	fmt.Println("\nDesbalances entre sorters:")
+	printImbalances("Antes", result.Before)
+	printImbalances("Después", result.After)
+	return nil
+}
+
+// printImbalances muestra los desbalances de un escenario
+func printImbalances(label string, state monitor.WhatIfState) {
+	if len(state.Imbalances) == 0 {
+		fmt.Printf("  %s: sin desbalances\n", label)
+		return
+	}
+	fmt.Printf("  %s: %d desbalance(s), prioridad máxima %.1f\n", label, len(state.Imbalances), state.MaxPriority)
+	for _, imb := range state.Imbalances {
+		fmt.Printf("    %s: %.1f%% diferencia (S1:%.1f%% vs S2:%.1f%%) prioridad %.1f\n",
+			imb.SKU, imb.Difference, imb.Sorter1Pct, imb.Sorter2Pct, imb.Priority)
+	}
+}
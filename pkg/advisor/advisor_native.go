@@ -1,12 +1,16 @@
- package advisor
+package advisor
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"math"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,6 +19,12 @@ type AdvisorConfig struct {
 	OllamaURL   string
 	OllamaModel string
 	Timeout     time.Duration
+	Language    string // Idioma de los prompts (es, en)
+	PromptDir   string // Carpeta con las plantillas de la planta (vacía = incluidas)
+
+	FallbackModels []string // Modelos a usar en orden si OllamaModel no está disponible
+	PullMissing    bool     // Descargar al iniciar los modelos que no estén instalados
+	WarmUp         bool     // Cargar el modelo en memoria antes de la primera sugerencia
 }
 
 // SorterData datos de un sorter específico
@@ -26,6 +36,13 @@ type SorterData struct {
 type SKUInfo struct {
 	Percentage float64 `json:"percentage"`
 	Lines      []int   `json:"lines"`
+	Volume     float64 `json:"volume,omitempty"`   // Carga absoluta por minuto (0 = sin modelo de capacidad)
+	Capacity   float64 `json:"capacity,omitempty"` // Capacidad de sus líneas por minuto
+}
+
+// Overloaded indica si el SKU recibe más de lo que sus líneas pueden procesar
+func (info SKUInfo) Overloaded() bool {
+	return info.Capacity > 0 && info.Volume > info.Capacity
 }
 
 // SystemState estado completo del sistema
@@ -33,6 +50,9 @@ type SystemState struct {
 	Timestamp time.Time  `json:"timestamp"`
 	Sorter1   SorterData `json:"sorter_1"`
 	Sorter2   SorterData `json:"sorter_2"`
+	Unit      string     `json:"unit,omitempty"` // Unidad de Volume y Capacity (cajas/min, kg/min)
+
+	History []HistoryEntry `json:"history,omitempty"` // Cambios y sugerencias recientes para el prompt
 }
 
 // Advice recomendación del advisor
@@ -43,64 +63,119 @@ type Advice struct {
 	ASorter   int    `json:"a_sorter,omitempty"`
 	Razon     string `json:"razon"`
 	Timestamp string `json:"timestamp"`
+
+	Trace *AdviceTrace `json:"trace,omitempty"` // Explicación de la recomendación
 }
 
 // Imbalance representa un desbalance detectado
 type Imbalance struct {
-	SKU        string
-	Sorter1Pct float64
-	Sorter2Pct float64
-	Difference float64
-	Priority   float64
+	SKU        string  `json:"sku"`
+	Sorter1Pct float64 `json:"sorter1_pct"`
+	Sorter2Pct float64 `json:"sorter2_pct"`
+	Difference float64 `json:"difference"`
+	Priority   float64 `json:"priority"`
 }
 
 // Advisor implementa la lógica de asesoramiento
 type Advisor struct {
-	config AdvisorConfig
-	client *http.Client
+	config  AdvisorConfig
+	client  *http.Client
+	slow    *http.Client // Descargas y precalentado, que tardan más que una consulta
+	logger  *slog.Logger
+	prompts *PromptTemplates
+
+	mu     sync.RWMutex
+	model  string // Modelo de Ollama en uso, elegido por Prepare y los chequeos
+	health OllamaHealth
 }
 
-// NewAdvisor crea una nueva instancia del advisor
-func NewAdvisor(config AdvisorConfig) *Advisor {
+// NewAdvisor crea una nueva instancia del advisor. Si logger es nil se usa el
+// logger por defecto. Si las plantillas de la planta no cargan se usan las
+// incluidas en español.
+func NewAdvisor(config AdvisorConfig, logger *slog.Logger) *Advisor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	prompts, err := LoadPromptTemplates(config.Language, config.PromptDir)
+	if err != nil {
+		logger.Warn("plantillas de prompt inválidas, se usan las incluidas", "idioma", config.Language, "error", err)
+		prompts, _ = LoadPromptTemplates(LanguageES, "")
+	}
+
 	return &Advisor{
 		config: config,
 		client: &http.Client{
 			Timeout: config.Timeout,
 		},
+		slow: &http.Client{
+			Timeout: pullTimeout,
+		},
+		logger:  logger,
+		prompts: prompts,
+		model:   config.OllamaModel,
 	}
 }
 
 // GetAdvice genera una recomendación basada en el estado actual
 func (a *Advisor) GetAdvice(state SystemState) (*Advice, error) {
-	fmt.Println("🔍 Analizando estado del sistema...")
+	a.logger.Debug("analizando estado del sistema",
+		"skus_sorter1", len(state.Sorter1.SKUs), "skus_sorter2", len(state.Sorter2.SKUs))
 
 	// Detectar desbalances críticos
 	imbalances := a.detectImbalances(state)
+	advice := ruleAdvice(state, imbalances)
+	if advice.Accion == "mantener" {
+		return advice, nil
+	}
+
+	worst := imbalances[0]
+	a.logger.Info("desbalance crítico detectado",
+		"sku", worst.SKU, "diferencia", worst.Difference,
+		"pct_sorter1", worst.Sorter1Pct, "pct_sorter2", worst.Sorter2Pct)
+	trace := advice.Trace
+
+	// Intentar enriquecer con Ollama si está disponible
+	if model := a.Model(); model != "" {
+		trace.LLM = &LLMTrace{Model: model}
+		enhancedAdvice, err := a.enhanceWithOllama(state, imbalances, advice, trace.LLM)
+		if err != nil {
+			a.logger.Warn("Ollama no disponible", "modelo", model, "error", err)
+			trace.LLM.Error = err.Error()
+			return advice, nil
+		}
+		traceLLM(trace.LLM, trace.Rule, *enhancedAdvice)
+		enhancedAdvice.Trace = trace
+		return enhancedAdvice, nil
+	}
+
+	return advice, nil
+}
+
+// ruleAdvice genera la recomendación de las reglas, con su traza: mover el SKU
+// del desbalance más prioritario o mantener si no hay desbalances críticos
+func ruleAdvice(state SystemState, imbalances []Imbalance) *Advice {
+	trace := explainImbalances(state, imbalances)
 
 	if len(imbalances) == 0 {
-		return &Advice{
+		razon := fmt.Sprintf("Sistema balanceado - todas las diferencias <%.0f%%", imbalanceThreshold)
+		if overloads := describeOverloads(state); overloads != "" {
+			razon += ". Sobrecarga: " + overloads
+		}
+		advice := &Advice{
 			Accion:    "mantener",
-			Razon:     "Sistema balanceado - todas las diferencias <8%",
+			Razon:     razon,
 			Timestamp: time.Now().Format(time.RFC3339),
-		}, nil
+		}
+		trace.Rule = *advice
+		advice.Trace = trace
+		return advice
 	}
 
-	// Tomar el desbalance más crítico
+	// Tomar el desbalance más crítico y determinar la dirección del movimiento
 	worst := imbalances[0]
-	fmt.Printf("📊 Desbalance crítico detectado: %s (%.1f%% diferencia)\n",
-		worst.SKU, worst.Difference)
-
-	// Determinar dirección del movimiento
-	var deSorter, aSorter int
-	if worst.Sorter1Pct > worst.Sorter2Pct {
-		deSorter = 1
-		aSorter = 2
-	} else {
-		deSorter = 2
-		aSorter = 1
-	}
+	deSorter, aSorter := moveDirection(worst)
 
-	// Crear advice básico
 	advice := &Advice{
 		Accion:   "mover",
 		SKU:      worst.SKU,
@@ -111,18 +186,60 @@ func (a *Advisor) GetAdvice(state SystemState) (*Advice, error) {
 			worst.Sorter1Pct, aSorter, worst.Sorter2Pct),
 		Timestamp: time.Now().Format(time.RFC3339),
 	}
+	if load := describeLoad(state, deSorter, worst.SKU); load != "" {
+		advice.Razon += ". " + load
+	}
+	trace.Rule = *advice
+	advice.Trace = trace
+	return advice
+}
 
-	// Intentar enriquecer con Ollama si está disponible
-	if a.config.OllamaModel != "" {
-		enhancedAdvice, err := a.enhanceWithOllama(state, advice)
-		if err != nil {
-			fmt.Printf("⚠️ Ollama no disponible: %v\n", err)
-			return advice, nil
+// LoadForecast es el pronóstico de un SKU que se acerca al umbral de carga
+type LoadForecast struct {
+	SorterID  int
+	SKU       string
+	Current   float64 // Porcentaje actual
+	Predicted float64 // Porcentaje pronosticado al horizonte
+	Lower     float64
+	Upper     float64
+	Minutes   float64 // Minutos estimados hasta superar el umbral
+}
+
+// PreventiveAdvice genera una sugerencia preventiva para el SKU que superará
+// antes el umbral. Retorna nil si no hay pronósticos.
+func (a *Advisor) PreventiveAdvice(forecasts []LoadForecast, threshold float64) *Advice {
+	if len(forecasts) == 0 {
+		return nil
+	}
+
+	first := forecasts[0]
+	for _, forecast := range forecasts[1:] {
+		if forecast.Minutes < first.Minutes {
+			first = forecast
 		}
-		return enhancedAdvice, nil
 	}
 
-	return advice, nil
+	a.logger.Info("carga pronosticada sobre el umbral",
+		"sku", first.SKU, "sorter", first.SorterID, "minutos", first.Minutes, "pronostico", first.Predicted)
+
+	advice := &Advice{
+		Accion:   "prevenir",
+		SKU:      first.SKU,
+		DeSorter: first.SorterID,
+		Razon: fmt.Sprintf("%s superará %.0f%% en S%d en ~%.0f min (actual %.1f%%, pronóstico %.1f%% entre %.1f%% y %.1f%%)",
+			first.SKU, threshold, first.SorterID, math.Ceil(first.Minutes), first.Current,
+			first.Predicted, first.Lower, first.Upper),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	trace := explainForecasts(forecasts, first, threshold)
+	trace.Rule = *advice
+	advice.Trace = trace
+	return advice
+}
+
+// Imbalances retorna los desbalances críticos entre sorters ordenados por prioridad
+func (a *Advisor) Imbalances(state SystemState) []Imbalance {
+	return a.detectImbalances(state)
 }
 
 // detectImbalances encuentra desbalances críticos entre sorters
@@ -154,7 +271,7 @@ func (a *Advisor) detectImbalances(state SystemState) []Imbalance {
 		difference := math.Abs(s1Pct - s2Pct)
 
 		// Solo considerar desbalances significativos
-		if difference > 8.0 {
+		if difference > imbalanceThreshold {
 			priority := calculatePriority(s1Pct, s2Pct, difference)
 
 			imbalances = append(imbalances, Imbalance{
@@ -179,6 +296,44 @@ func (a *Advisor) detectImbalances(state SystemState) []Imbalance {
 	return imbalances
 }
 
+// sorterData retorna los datos de un sorter del estado
+func (s SystemState) sorterData(sorterID int) SorterData {
+	if sorterID == 2 {
+		return s.Sorter2
+	}
+	return s.Sorter1
+}
+
+// describeLoad expresa la carga de un SKU en un sorter en unidades reales
+// (p. ej. "S1: 22.4 cajas/min en 2 líneas, capacidad 20.0 (112%)")
+func describeLoad(state SystemState, sorterID int, sku string) string {
+	info, ok := state.sorterData(sorterID).SKUs[sku]
+	if !ok || state.Unit == "" || info.Capacity <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("S%d: %.1f %s en %d líneas, capacidad %.1f (%.0f%%)",
+		sorterID, info.Volume, state.Unit, len(info.Lines), info.Capacity, info.Volume/info.Capacity*100)
+}
+
+// describeOverloads lista los SKUs cuya carga supera la capacidad de sus líneas
+func describeOverloads(state SystemState) string {
+	var parts []string
+	for _, sorterID := range []int{1, 2} {
+		skus := state.sorterData(sorterID).SKUs
+		names := make([]string, 0, len(skus))
+		for sku, info := range skus {
+			if info.Overloaded() {
+				names = append(names, sku)
+			}
+		}
+		sort.Strings(names)
+		for _, sku := range names {
+			parts = append(parts, sku+" "+describeLoad(state, sorterID, sku))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
 // calculatePriority calcula la prioridad de un desbalance
 func calculatePriority(s1Pct, s2Pct, difference float64) float64 {
 	// Factores que aumentan la prioridad:
@@ -186,77 +341,55 @@ func calculatePriority(s1Pct, s2Pct, difference float64) float64 {
 	// 2. Mayor carga total (s1 + s2)
 	// 3. Mayor desproporción relativa
 
-	totalLoad := s1Pct + s2Pct
-	relativeImbalance := difference / (totalLoad + 1) // +1 para evitar división por 0
+	totalLoad, relativeImbalance := priorityComponents(s1Pct, s2Pct, difference)
 
 	return difference * (1 + totalLoad/100) * (1 + relativeImbalance)
 }
 
-// enhanceWithOllama mejora el advice usando Ollama
-func (a *Advisor) enhanceWithOllama(state SystemState, basicAdvice *Advice) (*Advice, error) {
-	prompt := a.buildPrompt(state, basicAdvice)
+// enhanceWithOllama mejora el advice usando Ollama y registra la respuesta en llm
+func (a *Advisor) enhanceWithOllama(state SystemState, imbalances []Imbalance, basicAdvice *Advice, llm *LLMTrace) (*Advice, error) {
+	prompt, err := a.prompts.Render(NewPromptData(state, imbalances, *basicAdvice))
+	if err != nil {
+		return nil, err
+	}
 
-	ollamaResponse, err := a.queryOllama(prompt)
+	ollamaResponse, err := a.queryOllama(llm.Model, prompt)
 	if err != nil {
 		return nil, err
 	}
+	llm.Used = true
+	llm.Response = truncate(ollamaResponse, llmResponseLimit)
 
 	// Intentar parsear la respuesta JSON de Ollama
 	var enhancedAdvice Advice
 	err = json.Unmarshal([]byte(ollamaResponse), &enhancedAdvice)
 	if err != nil {
 		// Si Ollama no devolvió JSON válido, usar advice básico pero con explicación mejorada
-		basicAdvice.Razon = fmt.Sprintf("%s. Análisis: %s", basicAdvice.Razon, ollamaResponse)
-		return basicAdvice, nil
+		llm.Format = "texto"
+		enriched := *basicAdvice
+		enriched.Razon = fmt.Sprintf("%s. Análisis: %s", basicAdvice.Razon, ollamaResponse)
+		return &enriched, nil
 	}
 
+	llm.Format = "json"
+
 	// Asegurar timestamp
 	enhancedAdvice.Timestamp = time.Now().Format(time.RFC3339)
 	return &enhancedAdvice, nil
 }
 
-// buildPrompt construye el prompt para Ollama
-func (a *Advisor) buildPrompt(state SystemState, advice *Advice) string {
-	var prompt bytes.Buffer
-
-	prompt.WriteString("ESTADO ACTUAL DEL SISTEMA:\n\n")
-
-	prompt.WriteString("Sorter 1:\n")
-	for sku, info := range state.Sorter1.SKUs {
-		if info.Percentage > 0 {
-			prompt.WriteString(fmt.Sprintf("  %s: %.1f%% (líneas %v)\n",
-				sku, info.Percentage, info.Lines))
-		}
-	}
-
-	prompt.WriteString("\nSorter 2:\n")
-	for sku, info := range state.Sorter2.SKUs {
-		if info.Percentage > 0 {
-			prompt.WriteString(fmt.Sprintf("  %s: %.1f%% (líneas %v)\n",
-				sku, info.Percentage, info.Lines))
-		}
-	}
-
-	prompt.WriteString("\nDESBALANCES DETECTADOS:\n")
+// RenderPrompt renderiza el prompt que se enviaría al LLM para un estado, sin
+// consultarlo. La sugerencia base es la de las reglas.
+func (a *Advisor) RenderPrompt(state SystemState) (Prompt, error) {
 	imbalances := a.detectImbalances(state)
-	for _, imb := range imbalances {
-		if len(imbalances) <= 3 { // Solo mostrar los top 3
-			prompt.WriteString(fmt.Sprintf("  %s: %.1f%% diferencia (S1:%.1f%% vs S2:%.1f%%)\n",
-				imb.SKU, imb.Difference, imb.Sorter1Pct, imb.Sorter2Pct))
-		}
-	}
-
-	prompt.WriteString(fmt.Sprintf("\nSUGERENCIA INICIAL: %s\n", advice.Razon))
-	prompt.WriteString("\nPor favor confirma o mejora esta sugerencia siguiendo las reglas de balanceo.")
-
-	return prompt.String()
+	return a.prompts.Render(NewPromptData(state, imbalances, *ruleAdvice(state, imbalances)))
 }
 
 // queryOllama envía una consulta a Ollama
-func (a *Advisor) queryOllama(prompt string) (string, error) {
+func (a *Advisor) queryOllama(model string, prompt Prompt) (string, error) {
 	reqBody := map[string]interface{}{
-		"model":       a.config.OllamaModel,
-		"prompt":      prompt,
+		"model":       model,
+		"prompt":      prompt.User,
 		"stream":      false,
 		"temperature": 0.1,
 		"options": map[string]interface{}{
@@ -265,6 +398,10 @@ func (a *Advisor) queryOllama(prompt string) (string, error) {
 		},
 	}
 
+	if prompt.System != "" {
+		reqBody["system"] = prompt.System
+	}
+
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
 		return "", err
@@ -311,5 +448,7 @@ func DefaultConfig() AdvisorConfig {
 		OllamaURL:   "http://localhost:11434",
 		OllamaModel: "danich-advisor", // Modelo fine-tuneado
 		Timeout:     15 * time.Second,
+		Language:    LanguageES,
+		WarmUp:      true,
 	}
 }
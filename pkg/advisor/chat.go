@@ -0,0 +1,299 @@
+package advisor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// askMaxRounds es la cantidad máxima de respuestas del modelo por pregunta;
+// cada ronda puede pedir varias herramientas
+const askMaxRounds = 6
+
+// toolResultLimit es el largo máximo del resultado de una herramienta que se
+// envía al modelo
+const toolResultLimit = 8000
+
+// ChatMessage es un mensaje de /api/chat
+type ChatMessage struct {
+	Role      string     `json:"role"` // system | user | assistant | tool
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"` // Herramienta que produjo el mensaje (role tool)
+}
+
+// ToolCall es una llamada a herramienta pedida por el modelo
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction es el nombre y los argumentos de una llamada a herramienta
+type ToolCallFunction struct {
+	Name      string   `json:"name"`
+	Arguments ToolArgs `json:"arguments"`
+}
+
+// Tool es la definición de una herramienta en el formato de /api/chat
+type Tool struct {
+	Type     string       `json:"type"` // "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describe una herramienta y sus parámetros (JSON Schema)
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  ToolParameters `json:"parameters"`
+}
+
+// ToolParameters son los parámetros de una herramienta
+type ToolParameters struct {
+	Type       string                  `json:"type"` // "object"
+	Properties map[string]ToolProperty `json:"properties"`
+	Required   []string                `json:"required,omitempty"`
+}
+
+// ToolProperty es un parámetro de una herramienta
+type ToolProperty struct {
+	Type        string                  `json:"type"`
+	Description string                  `json:"description,omitempty"`
+	Enum        []string                `json:"enum,omitempty"`
+	Items       *ToolProperty           `json:"items,omitempty"`
+	Properties  map[string]ToolProperty `json:"properties,omitempty"`
+}
+
+// ToolArgs son los argumentos de una llamada a herramienta
+type ToolArgs map[string]any
+
+// String retorna un argumento de texto ("" si falta)
+func (args ToolArgs) String(key string) string {
+	switch value := args[key].(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Float retorna un argumento numérico. Los modelos a veces envían números como
+// texto, así que también se aceptan.
+func (args ToolArgs) Float(key string, fallback float64) float64 {
+	switch value := args[key].(type) {
+	case float64:
+		return value
+	case string:
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// Int retorna un argumento entero
+func (args ToolArgs) Int(key string, fallback int) int {
+	return int(args.Float(key, float64(fallback)))
+}
+
+// ChatTool es una herramienta con su implementación
+type ChatTool struct {
+	Tool
+	Run func(ctx context.Context, args ToolArgs) (any, error)
+}
+
+// NewChatTool arma una herramienta a partir de su nombre, descripción y parámetros
+func NewChatTool(name, description string, properties map[string]ToolProperty, required []string,
+	run func(ctx context.Context, args ToolArgs) (any, error)) ChatTool {
+	if properties == nil {
+		properties = map[string]ToolProperty{}
+	}
+	return ChatTool{
+		Tool: Tool{
+			Type: "function",
+			Function: ToolFunction{
+				Name:        name,
+				Description: description,
+				Parameters:  ToolParameters{Type: "object", Properties: properties, Required: required},
+			},
+		},
+		Run: run,
+	}
+}
+
+// ChatBackend envía una conversación a un LLM con herramientas y retorna su respuesta
+type ChatBackend interface {
+	Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (ChatMessage, error)
+	Model() string
+}
+
+// Answer es la respuesta a una pregunta, con las herramientas usadas para obtenerla
+type Answer struct {
+	Question   string     `json:"question"`
+	Text       string     `json:"answer"`
+	Model      string     `json:"model"`
+	Steps      []ToolStep `json:"tools,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+}
+
+// ToolStep es una llamada a herramienta hecha durante una pregunta
+type ToolStep struct {
+	Name   string   `json:"name"`
+	Args   ToolArgs `json:"args,omitempty"`
+	Result string   `json:"result,omitempty"` // JSON enviado al modelo
+	Error  string   `json:"error,omitempty"`
+}
+
+// Ask responde una pregunta sobre la planta. El modelo decide qué herramientas
+// consultar; sus resultados se le devuelven hasta que responde en texto.
+func (a *Advisor) Ask(ctx context.Context, question string, tools []ChatTool, backend ChatBackend) (*Answer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, fmt.Errorf("la pregunta está vacía")
+	}
+
+	system, err := a.prompts.RenderAsk(AskPromptData{Now: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	answer := &Answer{Question: question, Model: backend.Model()}
+	messages := []ChatMessage{
+		{Role: "system", Content: system},
+		{Role: "user", Content: question},
+	}
+
+	definitions := make([]Tool, len(tools))
+	byName := make(map[string]ChatTool, len(tools))
+	for i, tool := range tools {
+		definitions[i] = tool.Tool
+		byName[tool.Function.Name] = tool
+	}
+
+	for round := 0; round < askMaxRounds; round++ {
+		reply, err := backend.Chat(ctx, messages, definitions)
+		if err != nil {
+			return nil, err
+		}
+		reply.Role = "assistant"
+		messages = append(messages, reply)
+
+		if len(reply.ToolCalls) == 0 {
+			answer.Text = strings.TrimSpace(reply.Content)
+			answer.DurationMs = time.Since(start).Milliseconds()
+			return answer, nil
+		}
+
+		for _, call := range reply.ToolCalls {
+			step := runTool(ctx, byName, call.Function)
+			a.logger.Debug("herramienta del asistente", "nombre", step.Name, "args", step.Args, "error", step.Error)
+			answer.Steps = append(answer.Steps, step)
+
+			content := step.Result
+			if step.Error != "" {
+				content = `{"error": ` + strconv.Quote(step.Error) + `}`
+			}
+			messages = append(messages, ChatMessage{Role: "tool", ToolName: step.Name, Content: content})
+		}
+	}
+
+	return nil, fmt.Errorf("el modelo no respondió después de %d rondas de herramientas", askMaxRounds)
+}
+
+// runTool ejecuta una llamada a herramienta y serializa su resultado
+func runTool(ctx context.Context, tools map[string]ChatTool, call ToolCallFunction) ToolStep {
+	step := ToolStep{Name: call.Name, Args: call.Arguments}
+	tool, ok := tools[call.Name]
+	if !ok {
+		step.Error = fmt.Sprintf("herramienta desconocida %q", call.Name)
+		return step
+	}
+
+	args := call.Arguments
+	if args == nil {
+		args = ToolArgs{}
+	}
+	result, err := tool.Run(ctx, args)
+	if err != nil {
+		step.Error = err.Error()
+		return step
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		step.Error = err.Error()
+		return step
+	}
+	step.Result = truncate(string(data), toolResultLimit)
+	return step
+}
+
+// ollamaChat es el backend de chat de Ollama (/api/chat con herramientas)
+type ollamaChat struct {
+	advisor *Advisor
+	model   string
+}
+
+// ChatBackend retorna el backend de Ollama con el modelo en uso
+func (a *Advisor) ChatBackend() (ChatBackend, error) {
+	model := a.Model()
+	if model == "" {
+		return nil, fmt.Errorf("no hay un modelo de Ollama disponible (advisor.ollama_model)")
+	}
+	return &ollamaChat{advisor: a, model: model}, nil
+}
+
+// Model retorna el modelo que responde
+func (c *ollamaChat) Model() string {
+	return c.model
+}
+
+// Chat envía la conversación a /api/chat
+func (c *ollamaChat) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (ChatMessage, error) {
+	body := map[string]interface{}{
+		"model":    c.model,
+		"messages": messages,
+		"tools":    tools,
+		"stream":   false,
+		"options": map[string]interface{}{
+			"temperature": 0.1,
+		},
+	}
+
+	var response struct {
+		Message ChatMessage `json:"message"`
+	}
+	if err := c.advisor.ollamaRequest(ctx, c.advisor.client, http.MethodPost, "/api/chat", body, &response); err != nil {
+		return ChatMessage{}, err
+	}
+	return response.Message, nil
+}
+
+// ScriptedChat es un backend con respuestas fijas, en orden, para probar las
+// herramientas y el flujo de preguntas sin un LLM. Registra las
+// conversaciones recibidas.
+type ScriptedChat struct {
+	Replies  []ChatMessage
+	Received [][]ChatMessage
+}
+
+// Model retorna el nombre del backend
+func (c *ScriptedChat) Model() string {
+	return "guion"
+}
+
+// Chat retorna la siguiente respuesta del guion
+func (c *ScriptedChat) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (ChatMessage, error) {
+	c.Received = append(c.Received, append([]ChatMessage(nil), messages...))
+	if len(c.Replies) == 0 {
+		return ChatMessage{}, fmt.Errorf("el guion no tiene más respuestas")
+	}
+	reply := c.Replies[0]
+	c.Replies = c.Replies[1:]
+	return reply, nil
+}
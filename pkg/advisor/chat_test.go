@@ -0,0 +1,138 @@
+package advisor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func testAdvisor(t *testing.T, config AdvisorConfig) *Advisor {
+	t.Helper()
+	return NewAdvisor(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func toolCall(name string, args ToolArgs) ChatMessage {
+	return ChatMessage{Role: "assistant", ToolCalls: []ToolCall{{Function: ToolCallFunction{Name: name, Arguments: args}}}}
+}
+
+// echoTools retorna una herramienta que repite su argumento y una que exige un
+// parámetro
+func echoTools() []ChatTool {
+	return []ChatTool{
+		NewChatTool("eco", "Repite el texto", map[string]ToolProperty{"texto": {Type: "string"}}, nil,
+			func(ctx context.Context, args ToolArgs) (any, error) {
+				return map[string]string{"eco": args.String("texto")}, nil
+			}),
+		NewChatTool("doble", "Duplica un número", map[string]ToolProperty{"n": {Type: "number"}}, []string{"n"},
+			func(ctx context.Context, args ToolArgs) (any, error) {
+				if _, ok := args["n"]; !ok {
+					return nil, fmt.Errorf("falta el parámetro n")
+				}
+				return args.Float("n", 0) * 2, nil
+			}),
+	}
+}
+
+func TestAskMultipleRounds(t *testing.T) {
+	a := testAdvisor(t, DefaultConfig())
+	backend := &ScriptedChat{Replies: []ChatMessage{
+		toolCall("eco", ToolArgs{"texto": "hola"}),
+		toolCall("doble", ToolArgs{"n": "21"}),
+		{Content: "  La respuesta es 42  "},
+	}}
+
+	answer, err := a.Ask(context.Background(), "¿cuánto es?", echoTools(), backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Text != "La respuesta es 42" {
+		t.Errorf("Text = %q", answer.Text)
+	}
+	if answer.Model != "guion" {
+		t.Errorf("Model = %q", answer.Model)
+	}
+	if len(answer.Steps) != 2 || answer.Steps[0].Result != `{"eco":"hola"}` || answer.Steps[1].Result != "42" {
+		t.Fatalf("Steps = %+v", answer.Steps)
+	}
+	if len(backend.Received) != 3 {
+		t.Fatalf("rondas = %d, se esperaban 3", len(backend.Received))
+	}
+
+	// Cada ronda recibe la conversación completa, con los resultados anteriores
+	last := backend.Received[2]
+	if last[0].Role != "system" || last[1].Role != "user" || last[1].Content != "¿cuánto es?" {
+		t.Errorf("inicio de la conversación: %+v", last[:2])
+	}
+	var tools []ChatMessage
+	for _, message := range last {
+		if message.Role == "tool" {
+			tools = append(tools, message)
+		}
+	}
+	if len(tools) != 2 || tools[0].ToolName != "eco" || tools[1].Content != "42" {
+		t.Errorf("mensajes de herramientas: %+v", tools)
+	}
+}
+
+func TestAskToolErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		call    ChatMessage
+		wantErr string
+	}{
+		{"herramienta desconocida", toolCall("borrar_todo", nil), `herramienta desconocida "borrar_todo"`},
+		{"argumento faltante", toolCall("doble", nil), "falta el parámetro n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := testAdvisor(t, DefaultConfig())
+			backend := &ScriptedChat{Replies: []ChatMessage{tt.call, {Content: "no pude"}}}
+
+			answer, err := a.Ask(context.Background(), "pregunta", echoTools(), backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(answer.Steps) != 1 || answer.Steps[0].Error != tt.wantErr {
+				t.Fatalf("Steps = %+v", answer.Steps)
+			}
+
+			// El error vuelve al modelo como resultado de la herramienta
+			second := backend.Received[1]
+			result := second[len(second)-1]
+			if result.Role != "tool" || !strings.HasPrefix(result.Content, `{"error": `) {
+				t.Errorf("resultado enviado al modelo: %+v", result)
+			}
+		})
+	}
+}
+
+func TestAskMaxRounds(t *testing.T) {
+	a := testAdvisor(t, DefaultConfig())
+	backend := &ScriptedChat{}
+	for i := 0; i < askMaxRounds+2; i++ {
+		backend.Replies = append(backend.Replies, toolCall("eco", ToolArgs{"texto": "otra vez"}))
+	}
+
+	_, err := a.Ask(context.Background(), "pregunta", echoTools(), backend)
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("%d rondas", askMaxRounds)) {
+		t.Fatalf("error = %v", err)
+	}
+	if len(backend.Received) != askMaxRounds {
+		t.Errorf("rondas = %d, se esperaban %d", len(backend.Received), askMaxRounds)
+	}
+}
+
+func TestAskEmptyQuestion(t *testing.T) {
+	a := testAdvisor(t, DefaultConfig())
+	backend := &ScriptedChat{}
+	if _, err := a.Ask(context.Background(), "   ", echoTools(), backend); err == nil {
+		t.Fatal("se esperaba un error")
+	}
+	if len(backend.Received) != 0 {
+		t.Error("no debería consultar al modelo")
+	}
+}
@@ -0,0 +1,308 @@
+package advisor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tiempos máximos de las operaciones lentas de Ollama, que no usan el
+// timeout de las consultas. pullTimeout es además el timeout del cliente slow.
+const (
+	pullTimeout   = 30 * time.Minute
+	warmUpTimeout = 2 * time.Minute
+)
+
+// OllamaHealth es el resultado del último chequeo de Ollama
+type OllamaHealth struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Reachable bool      `json:"reachable"`
+	Model     string    `json:"model,omitempty"`   // Modelo en uso ("" = solo reglas)
+	Fallback  bool      `json:"fallback"`          // El modelo en uso no es advisor.ollama_model
+	Details   string    `json:"details,omitempty"` // Familia, tamaño y cuantización según /api/show
+	Missing   []string  `json:"missing,omitempty"` // Modelos configurados que no están instalados
+	Pulled    []string  `json:"pulled,omitempty"`  // Modelos descargados en este chequeo
+	LatencyMs int64     `json:"latency_ms"`        // Duración de /api/tags
+	WarmUpMs  int64     `json:"warm_up_ms,omitempty"`
+	Failures  int       `json:"failures"` // Chequeos fallidos consecutivos
+	Error     string    `json:"error,omitempty"`
+}
+
+// Healthy indica si Ollama responde y hay un modelo en uso
+func (h OllamaHealth) Healthy() bool {
+	return h.Reachable && h.Model != ""
+}
+
+// ollamaModelInfo es un modelo instalado según /api/tags
+type ollamaModelInfo struct {
+	Name string `json:"name"`
+}
+
+// ollamaShowResponse es la parte de /api/show que se muestra en el chequeo
+type ollamaShowResponse struct {
+	Details struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// Model retorna el modelo de Ollama en uso ("" = solo reglas)
+func (a *Advisor) Model() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.model
+}
+
+// Health retorna el resultado del último chequeo de Ollama
+func (a *Advisor) Health() OllamaHealth {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.health
+}
+
+// LLMEnabled indica si hay modelos de Ollama configurados
+func (a *Advisor) LLMEnabled() bool {
+	return len(a.candidateModels()) > 0
+}
+
+// Prepare verifica al iniciar los modelos configurados en orden (principal y
+// respaldos), descarga los que faltan si advisor.descargar_modelos está
+// activo, elige el primero disponible y lo precalienta
+func (a *Advisor) Prepare(ctx context.Context) OllamaHealth {
+	return a.refresh(ctx, a.config.PullMissing)
+}
+
+// RunHealthProbes repite el chequeo cada interval hasta que ctx termine. Si el
+// modelo en uso desaparece se pasa al siguiente disponible, y si Ollama vuelve
+// se retoma el modelo.
+func (a *Advisor) RunHealthProbes(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.refresh(ctx, false)
+		}
+	}
+}
+
+// refresh consulta los modelos instalados, elige el modelo en uso y guarda el
+// resultado del chequeo
+func (a *Advisor) refresh(ctx context.Context, pull bool) OllamaHealth {
+	candidates := a.candidateModels()
+	if len(candidates) == 0 {
+		return OllamaHealth{}
+	}
+
+	previous := a.Health()
+	health := OllamaHealth{CheckedAt: time.Now()}
+
+	start := time.Now()
+	installed, err := a.listModels(ctx)
+	health.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		health.Error = err.Error()
+		health.Failures = previous.Failures + 1
+		a.setModel("", health)
+		if previous.Failures == 0 {
+			a.logger.Warn("Ollama no responde, se usan solo las reglas", "url", a.config.OllamaURL, "error", err)
+		}
+		return health
+	}
+	health.Reachable = true
+
+	var problems []string
+	for i, candidate := range candidates {
+		name, ok := findModel(installed, candidate)
+		if !ok && pull {
+			a.logger.Info("descargando modelo de Ollama", "modelo", candidate)
+			if err := a.pullModel(ctx, candidate); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", candidate, err))
+			} else {
+				name, ok = candidate, true
+				health.Pulled = append(health.Pulled, candidate)
+			}
+		}
+		if !ok {
+			health.Missing = append(health.Missing, candidate)
+			continue
+		}
+
+		details, err := a.showModel(ctx, name)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+
+		health.Model = name
+		health.Fallback = i > 0
+		health.Details = details
+		break
+	}
+
+	if health.Model == "" {
+		health.Failures = previous.Failures + 1
+		if len(health.Missing) > 0 {
+			problems = append(problems, "no instalados: "+strings.Join(health.Missing, ", "))
+		}
+		health.Error = "ningún modelo disponible (" + strings.Join(problems, "; ") + ")"
+		a.setModel("", health)
+		if previous.Model != "" || previous.Failures == 0 {
+			a.logger.Warn("sin modelos de Ollama disponibles, se usan solo las reglas", "error", health.Error)
+		}
+		return health
+	}
+
+	// Precalentar solo al cambiar de modelo: la primera sugerencia no espera la carga
+	if health.Model != previous.Model {
+		if a.config.WarmUp {
+			start := time.Now()
+			if err := a.warmUp(ctx, health.Model); err != nil {
+				a.logger.Warn("error precalentando el modelo", "modelo", health.Model, "error", err)
+			}
+			health.WarmUpMs = time.Since(start).Milliseconds()
+		}
+		a.logger.Info("modelo de Ollama en uso", "modelo", health.Model, "respaldo", health.Fallback,
+			"detalles", health.Details, "precalentado_ms", health.WarmUpMs)
+	} else {
+		health.WarmUpMs = previous.WarmUpMs
+	}
+
+	a.setModel(health.Model, health)
+	return health
+}
+
+// setModel actualiza el modelo en uso y el último chequeo
+func (a *Advisor) setModel(model string, health OllamaHealth) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.model = model
+	a.health = health
+}
+
+// candidateModels retorna el modelo principal y los de respaldo, en orden y sin repetir
+func (a *Advisor) candidateModels() []string {
+	var models []string
+	seen := make(map[string]bool)
+	for _, model := range append([]string{a.config.OllamaModel}, a.config.FallbackModels...) {
+		model = strings.TrimSpace(model)
+		if model != "" && !seen[model] {
+			seen[model] = true
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// findModel busca un modelo entre los instalados. Un nombre sin tag equivale a ":latest".
+func findModel(installed []ollamaModelInfo, model string) (string, bool) {
+	for _, info := range installed {
+		if info.Name == model || (!strings.Contains(model, ":") && info.Name == model+":latest") {
+			return info.Name, true
+		}
+	}
+	return "", false
+}
+
+// listModels consulta los modelos instalados (/api/tags)
+func (a *Advisor) listModels(ctx context.Context) ([]ollamaModelInfo, error) {
+	var response struct {
+		Models []ollamaModelInfo `json:"models"`
+	}
+	if err := a.ollamaRequest(ctx, a.client, http.MethodGet, "/api/tags", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Models, nil
+}
+
+// showModel verifica que un modelo se pueda cargar y resume sus detalles (/api/show)
+func (a *Advisor) showModel(ctx context.Context, model string) (string, error) {
+	var response ollamaShowResponse
+	if err := a.ollamaRequest(ctx, a.client, http.MethodPost, "/api/show", map[string]string{"model": model}, &response); err != nil {
+		return "", err
+	}
+
+	var parts []string
+	for _, part := range []string{response.Details.Family, response.Details.ParameterSize, response.Details.QuantizationLevel} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// pullModel descarga un modelo (/api/pull)
+func (a *Advisor) pullModel(ctx context.Context, model string) error {
+	ctx, cancel := context.WithTimeout(ctx, pullTimeout)
+	defer cancel()
+
+	var response struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	body := map[string]interface{}{"model": model, "stream": false}
+	if err := a.ollamaRequest(ctx, a.slow, http.MethodPost, "/api/pull", body, &response); err != nil {
+		return err
+	}
+	if response.Error != "" {
+		return fmt.Errorf("%s", response.Error)
+	}
+	return nil
+}
+
+// warmUp carga el modelo en memoria con una generación vacía
+func (a *Advisor) warmUp(ctx context.Context, model string) error {
+	ctx, cancel := context.WithTimeout(ctx, warmUpTimeout)
+	defer cancel()
+
+	body := map[string]interface{}{"model": model, "prompt": "", "stream": false}
+	return a.ollamaRequest(ctx, a.slow, http.MethodPost, "/api/generate", body, nil)
+}
+
+// ollamaRequest envía una consulta JSON a Ollama y decodifica la respuesta en out (si no es nil)
+func (a *Advisor) ollamaRequest(ctx context.Context, client *http.Client, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.config.OllamaURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if text := strings.TrimSpace(string(message)); text != "" {
+			return fmt.Errorf("%s respondió %d: %s", path, resp.StatusCode, text)
+		}
+		return fmt.Errorf("%s respondió %d", path, resp.StatusCode)
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,263 @@
+package advisor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOllama responde /api/tags, /api/show, /api/pull y /api/generate con
+// los modelos instalados y registra los pedidos recibidos
+type fakeOllama struct {
+	mu        sync.Mutex
+	down      bool
+	installed []string
+	broken    map[string]bool // Modelos que /api/show rechaza
+	pulls     []string
+	warmUps   []string
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.down {
+		http.Error(w, "sin GPU", http.StatusServiceUnavailable)
+		return
+	}
+
+	var body struct {
+		Model string `json:"model"`
+	}
+	if r.Method == http.MethodPost {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	switch r.URL.Path {
+	case "/api/tags":
+		models := []ollamaModelInfo{}
+		for _, name := range f.installed {
+			models = append(models, ollamaModelInfo{Name: name})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"models": models})
+	case "/api/show":
+		if f.broken[body.Model] {
+			http.Error(w, "modelo dañado", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"details": map[string]string{"family": "llama", "parameter_size": "8B"}})
+	case "/api/pull":
+		f.pulls = append(f.pulls, body.Model)
+		f.installed = append(f.installed, body.Model)
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	case "/api/generate":
+		f.warmUps = append(f.warmUps, body.Model)
+		json.NewEncoder(w).Encode(map[string]any{"done": true})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeOllama) set(change func(f *fakeOllama)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change(f)
+}
+
+func (f *fakeOllama) requests() (pulls, warmUps []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.pulls...), append([]string(nil), f.warmUps...)
+}
+
+func newFakeOllama(t *testing.T, installed ...string) (*fakeOllama, AdvisorConfig) {
+	t.Helper()
+	fake := &fakeOllama{installed: installed, broken: map[string]bool{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	config := DefaultConfig()
+	config.OllamaURL = server.URL
+	config.OllamaModel = "principal"
+	config.FallbackModels = []string{"respaldo", "ultimo"}
+	return fake, config
+}
+
+func TestPrepareFallbackOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		installed    []string
+		broken       []string
+		wantModel    string
+		wantFallback bool
+		wantMissing  []string
+	}{
+		{"principal instalado", []string{"ultimo:latest", "principal:latest"}, nil, "principal:latest", false, nil},
+		{"primer respaldo", []string{"ultimo:latest", "respaldo:latest"}, nil, "respaldo:latest", true, []string{"principal"}},
+		{"último respaldo", []string{"ultimo:latest"}, nil, "ultimo:latest", true, []string{"principal", "respaldo"}},
+		{"respaldo dañado", []string{"respaldo:latest", "ultimo:latest"}, []string{"respaldo:latest"}, "ultimo:latest", true, []string{"principal"}},
+		{"ninguno", []string{"otro:latest"}, nil, "", false, []string{"principal", "respaldo", "ultimo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, config := newFakeOllama(t, tt.installed...)
+			for _, name := range tt.broken {
+				fake.broken[name] = true
+			}
+			a := testAdvisor(t, config)
+
+			health := a.Prepare(context.Background())
+			if health.Model != tt.wantModel || health.Fallback != tt.wantFallback {
+				t.Errorf("modelo = %q (respaldo %v), se esperaba %q (respaldo %v)", health.Model, health.Fallback, tt.wantModel, tt.wantFallback)
+			}
+			if !reflect.DeepEqual(health.Missing, tt.wantMissing) {
+				t.Errorf("faltantes = %v, se esperaba %v", health.Missing, tt.wantMissing)
+			}
+			if a.Model() != tt.wantModel {
+				t.Errorf("Model() = %q", a.Model())
+			}
+			if health.Healthy() != (tt.wantModel != "") {
+				t.Errorf("Healthy() = %v", health.Healthy())
+			}
+		})
+	}
+}
+
+func TestPreparePullsOnlyWhenEnabled(t *testing.T) {
+	for _, pull := range []bool{false, true} {
+		fake, config := newFakeOllama(t, "ultimo:latest")
+		config.PullMissing = pull
+		a := testAdvisor(t, config)
+
+		health := a.Prepare(context.Background())
+		pulls, _ := fake.requests()
+
+		if !pull {
+			if len(pulls) != 0 || health.Model != "ultimo:latest" {
+				t.Errorf("sin descarga: pulls = %v, modelo = %q", pulls, health.Model)
+			}
+			continue
+		}
+		// Se descarga el principal y ya no hace falta el respaldo
+		if !reflect.DeepEqual(pulls, []string{"principal"}) || !reflect.DeepEqual(health.Pulled, []string{"principal"}) {
+			t.Errorf("pulls = %v, Pulled = %v", pulls, health.Pulled)
+		}
+		if health.Model != "principal" || health.Fallback {
+			t.Errorf("modelo = %q (respaldo %v)", health.Model, health.Fallback)
+		}
+	}
+
+	// Los chequeos periódicos nunca descargan
+	fake, config := newFakeOllama(t, "ultimo:latest")
+	config.PullMissing = true
+	a := testAdvisor(t, config)
+	a.refresh(context.Background(), false)
+	if pulls, _ := fake.requests(); len(pulls) != 0 {
+		t.Errorf("el chequeo descargó %v", pulls)
+	}
+}
+
+func TestWarmUpOnModelChange(t *testing.T) {
+	fake, config := newFakeOllama(t, "respaldo:latest")
+	a := testAdvisor(t, config)
+
+	a.Prepare(context.Background())
+	a.refresh(context.Background(), false) // Mismo modelo: no se precalienta de nuevo
+	if _, warmUps := fake.requests(); !reflect.DeepEqual(warmUps, []string{"respaldo:latest"}) {
+		t.Fatalf("precalentados = %v", warmUps)
+	}
+
+	fake.set(func(f *fakeOllama) { f.installed = append(f.installed, "principal:latest") })
+	health := a.refresh(context.Background(), false)
+	if health.Model != "principal:latest" {
+		t.Fatalf("modelo = %q", health.Model)
+	}
+	if _, warmUps := fake.requests(); !reflect.DeepEqual(warmUps, []string{"respaldo:latest", "principal:latest"}) {
+		t.Errorf("precalentados = %v", warmUps)
+	}
+
+	// Sin precalentado configurado
+	fake, config = newFakeOllama(t, "principal:latest")
+	config.WarmUp = false
+	testAdvisor(t, config).Prepare(context.Background())
+	if _, warmUps := fake.requests(); len(warmUps) != 0 {
+		t.Errorf("precalentó %v con advisor.precalentar desactivado", warmUps)
+	}
+}
+
+func TestHealthDownAndUp(t *testing.T) {
+	fake, config := newFakeOllama(t, "principal:latest")
+	a := testAdvisor(t, config)
+	if health := a.Prepare(context.Background()); !health.Healthy() {
+		t.Fatalf("inicio: %+v", health)
+	}
+
+	fake.set(func(f *fakeOllama) { f.down = true })
+	for want := 1; want <= 2; want++ {
+		health := a.refresh(context.Background(), false)
+		if health.Reachable || health.Model != "" || health.Failures != want || health.Error == "" {
+			t.Fatalf("caído (%d): %+v", want, health)
+		}
+	}
+	if a.Model() != "" {
+		t.Errorf("Model() = %q con Ollama caído", a.Model())
+	}
+
+	fake.set(func(f *fakeOllama) { f.down = false })
+	health := a.refresh(context.Background(), false)
+	if !health.Healthy() || health.Failures != 0 || a.Model() != "principal:latest" {
+		t.Errorf("recuperado: %+v", health)
+	}
+	if _, warmUps := fake.requests(); len(warmUps) != 2 {
+		t.Errorf("precalentados = %v, se esperaba precalentar al volver", warmUps)
+	}
+}
+
+func TestRunHealthProbes(t *testing.T) {
+	fake, config := newFakeOllama(t, "principal:latest")
+	a := testAdvisor(t, config)
+	a.Prepare(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.RunHealthProbes(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+
+	waitFor := func(what string, ok func(OllamaHealth) bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !ok(a.Health()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: %+v", what, a.Health())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	fake.set(func(f *fakeOllama) { f.down = true })
+	waitFor("caída", func(h OllamaHealth) bool { return !h.Reachable })
+	fake.set(func(f *fakeOllama) { f.down = false })
+	waitFor("recuperación", func(h OllamaHealth) bool { return h.Healthy() })
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunHealthProbes no terminó al cancelar el contexto")
+	}
+}
+
+func TestSlowRequestsUseBoundedClient(t *testing.T) {
+	a := testAdvisor(t, DefaultConfig())
+	if a.slow == nil || a.slow == http.DefaultClient || a.slow.Timeout <= 0 {
+		t.Fatalf("cliente de descargas sin timeout: %+v", a.slow)
+	}
+}
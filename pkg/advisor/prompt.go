@@ -0,0 +1,192 @@
+package advisor
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Idiomas de los prompts incluidos en el binario
+const (
+	LanguageES = "es"
+	LanguageEN = "en"
+)
+
+// Tipos de entrada del historial del prompt
+const (
+	HistoryChange = "cambio"
+	HistoryAdvice = "sugerencia"
+)
+
+// promptMaxImbalances es la cantidad de desbalances que se muestran al LLM
+const promptMaxImbalances = 3
+
+//go:embed prompts/*.tmpl
+var defaultPrompts embed.FS
+
+// HistoryEntry es un cambio o una sugerencia reciente que se muestra al LLM
+type HistoryEntry struct {
+	Time        string `json:"time"` // Hora para mostrar (15:04)
+	Kind        string `json:"kind"` // HistoryChange | HistoryAdvice
+	Description string `json:"description"`
+}
+
+// PromptData son los datos que reciben las plantillas del prompt
+type PromptData struct {
+	Timestamp  time.Time      // Momento del snapshot analizado
+	Unit       string         // Unidad de Volume y Capacity (cajas/min, kg/min); vacía sin modelo de capacidad
+	Sorters    []PromptSorter // Sorters en orden
+	Imbalances []Imbalance    // Desbalances más prioritarios (máximo 3)
+	Threshold  float64        // Diferencia mínima de un desbalance, en puntos porcentuales
+	Advice     Advice         // Sugerencia de las reglas que el LLM debe confirmar o mejorar
+	History    []HistoryEntry // Cambios y sugerencias recientes, del más antiguo al más nuevo
+}
+
+// PromptSorter es la carga de un sorter en el prompt
+type PromptSorter struct {
+	ID   int
+	SKUs []PromptSKU // SKUs con carga, de mayor a menor porcentaje
+}
+
+// PromptSKU es un SKU de un sorter en el prompt
+type PromptSKU struct {
+	SKU        string
+	Percentage float64
+	Lines      []int
+	Volume     float64 // 0 sin modelo de capacidad
+	Capacity   float64 // 0 sin modelo de capacidad
+	Overloaded bool
+}
+
+// Prompt es el prompt renderizado
+type Prompt struct {
+	System string `json:"system"`
+	User   string `json:"prompt"`
+}
+
+// AskPromptData son los datos del prompt de sistema de las preguntas ("ask")
+type AskPromptData struct {
+	Now time.Time
+}
+
+// PromptTemplates son las plantillas de un idioma, con las redefiniciones de la planta
+type PromptTemplates struct {
+	language string
+	tmpl     *template.Template
+}
+
+// LoadPromptTemplates carga las plantillas de un idioma. Si dir no está vacío
+// y contiene <idioma>.tmpl, sus bloques {{define}} reemplazan a los incluidos.
+func LoadPromptTemplates(language, dir string) (*PromptTemplates, error) {
+	name := language + ".tmpl"
+	base, err := fs.ReadFile(defaultPrompts, "prompts/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("idioma de prompt no soportado %q (disponibles: %s, %s)", language, LanguageES, LanguageEN)
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(base))
+	if err != nil {
+		return nil, fmt.Errorf("error en la plantilla incluida %s: %w", name, err)
+	}
+
+	if dir != "" {
+		path := filepath.Join(dir, name)
+		override, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// Sin redefiniciones para este idioma
+		case err != nil:
+			return nil, err
+		default:
+			if tmpl, err = tmpl.Parse(string(override)); err != nil {
+				return nil, fmt.Errorf("error en la plantilla %s: %w", path, err)
+			}
+		}
+	}
+
+	for _, block := range []string{"system", "prompt", "ask"} {
+		if tmpl.Lookup(block) == nil {
+			return nil, fmt.Errorf("la plantilla %s no define %q", name, block)
+		}
+	}
+	return &PromptTemplates{language: language, tmpl: tmpl}, nil
+}
+
+// Language retorna el idioma de las plantillas
+func (pt *PromptTemplates) Language() string {
+	return pt.language
+}
+
+// Render ejecuta las plantillas del prompt de sistema y del prompt de usuario
+func (pt *PromptTemplates) Render(data PromptData) (Prompt, error) {
+	var system, user bytes.Buffer
+	if err := pt.tmpl.ExecuteTemplate(&system, "system", data); err != nil {
+		return Prompt{}, fmt.Errorf("error renderizando el prompt de sistema: %w", err)
+	}
+	if err := pt.tmpl.ExecuteTemplate(&user, "prompt", data); err != nil {
+		return Prompt{}, fmt.Errorf("error renderizando el prompt: %w", err)
+	}
+	return Prompt{
+		System: strings.TrimSpace(system.String()),
+		User:   strings.TrimSpace(user.String()),
+	}, nil
+}
+
+// RenderAsk ejecuta la plantilla del prompt de sistema de las preguntas
+func (pt *PromptTemplates) RenderAsk(data AskPromptData) (string, error) {
+	var system bytes.Buffer
+	if err := pt.tmpl.ExecuteTemplate(&system, "ask", data); err != nil {
+		return "", fmt.Errorf("error renderizando el prompt de preguntas: %w", err)
+	}
+	return strings.TrimSpace(system.String()), nil
+}
+
+// NewPromptData arma los datos del prompt para un estado y la sugerencia de
+// las reglas
+func NewPromptData(state SystemState, imbalances []Imbalance, advice Advice) PromptData {
+	data := PromptData{
+		Timestamp: state.Timestamp,
+		Unit:      state.Unit,
+		Threshold: imbalanceThreshold,
+		Advice:    advice,
+		History:   state.History,
+	}
+
+	for _, sorterID := range []int{1, 2} {
+		sorter := PromptSorter{ID: sorterID}
+		for sku, info := range state.sorterData(sorterID).SKUs {
+			if info.Percentage <= 0 {
+				continue
+			}
+			sorter.SKUs = append(sorter.SKUs, PromptSKU{
+				SKU:        sku,
+				Percentage: info.Percentage,
+				Lines:      info.Lines,
+				Volume:     info.Volume,
+				Capacity:   info.Capacity,
+				Overloaded: info.Overloaded(),
+			})
+		}
+		sort.Slice(sorter.SKUs, func(i, j int) bool {
+			if sorter.SKUs[i].Percentage != sorter.SKUs[j].Percentage {
+				return sorter.SKUs[i].Percentage > sorter.SKUs[j].Percentage
+			}
+			return sorter.SKUs[i].SKU < sorter.SKUs[j].SKU
+		})
+		data.Sorters = append(data.Sorters, sorter)
+	}
+
+	if len(imbalances) > promptMaxImbalances {
+		imbalances = imbalances[:promptMaxImbalances]
+	}
+	data.Imbalances = imbalances
+	return data
+}
@@ -0,0 +1,218 @@
+package advisor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// imbalanceThreshold es la diferencia mínima (en puntos porcentuales) para
+// considerar crítico el desbalance de un SKU entre sorters
+const imbalanceThreshold = 8.0
+
+// llmResponseLimit es el largo máximo de la respuesta del LLM guardada en la traza
+const llmResponseLimit = 500
+
+// AdviceTrace explica cómo se llegó a una recomendación: qué se evaluó, con
+// qué umbrales, qué se descartó y si el LLM cambió el resultado de las reglas
+type AdviceTrace struct {
+	Thresholds TraceThresholds  `json:"thresholds"`
+	Imbalances []TraceImbalance `json:"imbalances,omitempty"`
+	Rejected   []RejectedMove   `json:"rejected,omitempty"`
+	Rule       Advice           `json:"rule"` // Resultado de las reglas, antes del LLM
+	LLM        *LLMTrace        `json:"llm,omitempty"`
+}
+
+// TraceThresholds son los umbrales aplicados
+type TraceThresholds struct {
+	MinDifference float64 `json:"min_difference,omitempty"` // Diferencia mínima entre sorters
+	Forecast      float64 `json:"forecast,omitempty"`       // Umbral de los avisos preventivos
+}
+
+// TraceImbalance es un desbalance evaluado con los componentes de su prioridad
+type TraceImbalance struct {
+	Imbalance
+	TotalLoad         float64 `json:"total_load"`         // S1 + S2
+	RelativeImbalance float64 `json:"relative_imbalance"` // Diferencia / (carga total + 1)
+	Selected          bool    `json:"selected"`
+}
+
+// RejectedMove es un movimiento candidato que no se recomendó
+type RejectedMove struct {
+	SKU      string `json:"sku"`
+	DeSorter int    `json:"de_sorter,omitempty"`
+	ASorter  int    `json:"a_sorter,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// LLMTrace registra la consulta al LLM y su efecto sobre la recomendación
+type LLMTrace struct {
+	Model    string   `json:"model"`
+	Used     bool     `json:"used"`              // El LLM respondió
+	Format   string   `json:"format,omitempty"`  // "json" (reemplaza) o "texto" (se agrega a la razón)
+	Changed  []string `json:"changed,omitempty"` // Campos que cambió respecto a las reglas
+	Error    string   `json:"error,omitempty"`
+	Response string   `json:"response,omitempty"` // Respuesta recortada
+}
+
+// Overridden indica si el LLM cambió la acción o el movimiento de las reglas
+func (t *LLMTrace) Overridden() bool {
+	if t == nil {
+		return false
+	}
+	for _, field := range t.Changed {
+		if field != "razon" {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary resume la acción de una recomendación (p. ej. "mover 3J S1→S2")
+func (a Advice) Summary() string {
+	if a.Accion == "mover" {
+		return fmt.Sprintf("mover %s S%d→S%d", a.SKU, a.DeSorter, a.ASorter)
+	}
+	if a.SKU != "" {
+		return a.Accion + " " + a.SKU
+	}
+	return a.Accion
+}
+
+// priorityComponents retorna los factores de la prioridad de un desbalance
+func priorityComponents(s1Pct, s2Pct, difference float64) (totalLoad, relativeImbalance float64) {
+	totalLoad = s1Pct + s2Pct
+	relativeImbalance = difference / (totalLoad + 1) // +1 para evitar división por 0
+	return totalLoad, relativeImbalance
+}
+
+// moveDirection retorna el sorter de origen y de destino de un desbalance
+func moveDirection(imbalance Imbalance) (deSorter, aSorter int) {
+	if imbalance.Sorter1Pct > imbalance.Sorter2Pct {
+		return 1, 2
+	}
+	return 2, 1
+}
+
+// explainImbalances construye la traza de la evaluación por reglas: los
+// desbalances críticos (el primero es el elegido) y los SKUs descartados
+func explainImbalances(state SystemState, imbalances []Imbalance) *AdviceTrace {
+	trace := &AdviceTrace{Thresholds: TraceThresholds{MinDifference: imbalanceThreshold}}
+
+	critical := make(map[string]bool)
+	for i, imbalance := range imbalances {
+		critical[imbalance.SKU] = true
+		totalLoad, relative := priorityComponents(imbalance.Sorter1Pct, imbalance.Sorter2Pct, imbalance.Difference)
+		trace.Imbalances = append(trace.Imbalances, TraceImbalance{
+			Imbalance:         imbalance,
+			TotalLoad:         totalLoad,
+			RelativeImbalance: relative,
+			Selected:          i == 0,
+		})
+		if i == 0 {
+			continue
+		}
+
+		deSorter, aSorter := moveDirection(imbalance)
+		trace.Rejected = append(trace.Rejected, RejectedMove{
+			SKU:      imbalance.SKU,
+			DeSorter: deSorter,
+			ASorter:  aSorter,
+			Reason: fmt.Sprintf("prioridad %.1f menor que %s (%.1f)",
+				imbalance.Priority, imbalances[0].SKU, imbalances[0].Priority),
+		})
+	}
+
+	// SKUs bajo el umbral, de mayor a menor diferencia
+	type candidate struct {
+		move       RejectedMove
+		difference float64
+	}
+	var below []candidate
+	for _, sku := range stateSKUs(state) {
+		if critical[sku] {
+			continue
+		}
+		s1Pct := state.Sorter1.SKUs[sku].Percentage
+		s2Pct := state.Sorter2.SKUs[sku].Percentage
+		difference := math.Abs(s1Pct - s2Pct)
+		deSorter, aSorter := moveDirection(Imbalance{Sorter1Pct: s1Pct, Sorter2Pct: s2Pct})
+		below = append(below, candidate{
+			move: RejectedMove{
+				SKU:      sku,
+				DeSorter: deSorter,
+				ASorter:  aSorter,
+				Reason:   fmt.Sprintf("diferencia %.1f%% no supera %.0f%%", difference, imbalanceThreshold),
+			},
+			difference: difference,
+		})
+	}
+	sort.SliceStable(below, func(i, j int) bool { return below[i].difference > below[j].difference })
+	for _, c := range below {
+		trace.Rejected = append(trace.Rejected, c.move)
+	}
+
+	return trace
+}
+
+// explainForecasts construye la traza de un aviso preventivo: el pronóstico
+// elegido y los que cruzan el umbral más tarde
+func explainForecasts(forecasts []LoadForecast, first LoadForecast, threshold float64) *AdviceTrace {
+	trace := &AdviceTrace{Thresholds: TraceThresholds{Forecast: threshold}}
+	for _, forecast := range forecasts {
+		if forecast == first {
+			continue
+		}
+		trace.Rejected = append(trace.Rejected, RejectedMove{
+			SKU:      forecast.SKU,
+			DeSorter: forecast.SorterID,
+			Reason: fmt.Sprintf("cruza el umbral más tarde (~%.0f min, %s en ~%.0f min)",
+				math.Ceil(forecast.Minutes), first.SKU, math.Ceil(first.Minutes)),
+		})
+	}
+	return trace
+}
+
+// traceLLM compara la recomendación de las reglas con la del LLM
+func traceLLM(trace *LLMTrace, rule, result Advice) {
+	fields := []struct {
+		name    string
+		changed bool
+	}{
+		{"accion", rule.Accion != result.Accion},
+		{"sku", rule.SKU != result.SKU},
+		{"de_sorter", rule.DeSorter != result.DeSorter},
+		{"a_sorter", rule.ASorter != result.ASorter},
+		{"razon", rule.Razon != result.Razon},
+	}
+	for _, field := range fields {
+		if field.changed {
+			trace.Changed = append(trace.Changed, field.name)
+		}
+	}
+}
+
+// truncate recorta un texto a limit runas
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
+
+// stateSKUs retorna los SKUs de ambos sorters ordenados
+func stateSKUs(state SystemState) []string {
+	seen := make(map[string]bool)
+	var skus []string
+	for _, sorter := range []SorterData{state.Sorter1, state.Sorter2} {
+		for sku := range sorter.SKUs {
+			if !seen[sku] {
+				seen[sku] = true
+				skus = append(skus, sku)
+			}
+		}
+	}
+	sort.Strings(skus)
+	return skus
+}
@@ -0,0 +1,178 @@
+package advisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// traceState tiene dos desbalances críticos (3J y 4J) y dos SKUs bajo el umbral
+func traceState() SystemState {
+	return SystemState{
+		Sorter1: SorterData{SKUs: map[string]SKUInfo{
+			"3J": {Percentage: 40}, "4J": {Percentage: 20}, "XL": {Percentage: 5},
+		}},
+		Sorter2: SorterData{SKUs: map[string]SKUInfo{
+			"3J": {Percentage: 10}, "4J": {Percentage: 5}, "XL": {Percentage: 2}, "SJ": {Percentage: 6},
+		}},
+	}
+}
+
+func TestRuleAdviceTrace(t *testing.T) {
+	a := testAdvisor(t, AdvisorConfig{})
+	advice, err := a.GetAdvice(traceState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advice.Summary() != "mover 3J S1→S2" {
+		t.Fatalf("recomendación = %s", advice.Summary())
+	}
+
+	trace := advice.Trace
+	if trace == nil {
+		t.Fatal("sin traza")
+	}
+	if trace.Thresholds.MinDifference != imbalanceThreshold {
+		t.Errorf("umbral = %v", trace.Thresholds.MinDifference)
+	}
+	if len(trace.Imbalances) != 2 || !trace.Imbalances[0].Selected || trace.Imbalances[1].Selected {
+		t.Errorf("desbalances = %+v, esperaba 3J elegido y 4J descartado", trace.Imbalances)
+	}
+	if trace.Rule.Summary() != advice.Summary() || trace.LLM != nil {
+		t.Errorf("reglas = %s, LLM = %+v", trace.Rule.Summary(), trace.LLM)
+	}
+
+	// El descartado por prioridad primero y luego los que no superan el
+	// umbral, de mayor a menor diferencia
+	want := []RejectedMove{
+		{SKU: "4J", DeSorter: 1, ASorter: 2, Reason: fmt.Sprintf("prioridad %.1f menor que 3J (%.1f)",
+			calculatePriority(20, 5, 15), calculatePriority(40, 10, 30))},
+		{SKU: "SJ", DeSorter: 2, ASorter: 1, Reason: "diferencia 6.0% no supera 8%"},
+		{SKU: "XL", DeSorter: 1, ASorter: 2, Reason: "diferencia 3.0% no supera 8%"},
+	}
+	if !reflect.DeepEqual(trace.Rejected, want) {
+		t.Errorf("descartados:\n got  %+v\n want %+v", trace.Rejected, want)
+	}
+}
+
+func TestBalancedAdviceTrace(t *testing.T) {
+	state := SystemState{
+		Sorter1: SorterData{SKUs: map[string]SKUInfo{"3J": {Percentage: 50}}},
+		Sorter2: SorterData{SKUs: map[string]SKUInfo{"3J": {Percentage: 45}}},
+	}
+	advice, err := testAdvisor(t, AdvisorConfig{}).GetAdvice(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advice.Accion != "mantener" || len(advice.Trace.Imbalances) != 0 {
+		t.Fatalf("recomendación = %s, desbalances = %+v", advice.Summary(), advice.Trace.Imbalances)
+	}
+	want := []RejectedMove{{SKU: "3J", DeSorter: 1, ASorter: 2, Reason: "diferencia 5.0% no supera 8%"}}
+	if !reflect.DeepEqual(advice.Trace.Rejected, want) {
+		t.Errorf("descartados = %+v", advice.Trace.Rejected)
+	}
+}
+
+func TestLLMAdviceTrace(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		response       string
+		wantSummary    string
+		wantFormat     string
+		wantChanged    []string
+		wantOverridden bool
+		wantError      bool
+	}{
+		{
+			name:        "JSON con la misma acción",
+			status:      http.StatusOK,
+			response:    `{"accion":"mover","sku":"3J","de_sorter":1,"a_sorter":2,"razon":"3J satura S1"}`,
+			wantSummary: "mover 3J S1→S2",
+			wantFormat:  "json",
+			wantChanged: []string{"razon"},
+		},
+		{
+			name:           "JSON que cambia el movimiento",
+			status:         http.StatusOK,
+			response:       `{"accion":"mover","sku":"4J","de_sorter":1,"a_sorter":2,"razon":"4J es más fácil de mover"}`,
+			wantSummary:    "mover 4J S1→S2",
+			wantFormat:     "json",
+			wantChanged:    []string{"sku", "razon"},
+			wantOverridden: true,
+		},
+		{
+			name:           "JSON que mantiene",
+			status:         http.StatusOK,
+			response:       `{"accion":"mantener","razon":"el turno termina en 10 minutos"}`,
+			wantSummary:    "mantener",
+			wantFormat:     "json",
+			wantChanged:    []string{"accion", "sku", "de_sorter", "a_sorter", "razon"},
+			wantOverridden: true,
+		},
+		{
+			name:        "texto libre se agrega a la razón",
+			status:      http.StatusOK,
+			response:    "Conviene mover 3J cuanto antes",
+			wantSummary: "mover 3J S1→S2",
+			wantFormat:  "texto",
+			wantChanged: []string{"razon"},
+		},
+		{
+			name:        "Ollama con error",
+			status:      http.StatusInternalServerError,
+			wantSummary: "mover 3J S1→S2",
+			wantError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != http.StatusOK {
+					http.Error(w, "sin GPU", tt.status)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]any{"response": tt.response, "done": true})
+			}))
+			defer server.Close()
+
+			config := DefaultConfig()
+			config.OllamaURL = server.URL
+			config.OllamaModel = "principal"
+			advice, err := testAdvisor(t, config).GetAdvice(traceState())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if advice.Summary() != tt.wantSummary {
+				t.Errorf("recomendación = %s, esperaba %s", advice.Summary(), tt.wantSummary)
+			}
+			trace := advice.Trace
+			if trace == nil || trace.LLM == nil {
+				t.Fatalf("traza sin LLM: %+v", trace)
+			}
+			if trace.Rule.Summary() != "mover 3J S1→S2" || len(trace.Rejected) != 3 {
+				t.Errorf("la traza perdió el resultado de las reglas: %s, %d descartados",
+					trace.Rule.Summary(), len(trace.Rejected))
+			}
+
+			llm := trace.LLM
+			if llm.Model != "principal" || llm.Used == tt.wantError || (llm.Error != "") != tt.wantError {
+				t.Errorf("LLM = %+v", llm)
+			}
+			if llm.Format != tt.wantFormat || !reflect.DeepEqual(llm.Changed, tt.wantChanged) {
+				t.Errorf("formato = %q, cambios = %v, esperaba %q, %v", llm.Format, llm.Changed, tt.wantFormat, tt.wantChanged)
+			}
+			if llm.Overridden() != tt.wantOverridden {
+				t.Errorf("Overridden = %v, esperaba %v", llm.Overridden(), tt.wantOverridden)
+			}
+			if !tt.wantError && llm.Response != tt.response {
+				t.Errorf("respuesta guardada = %q", llm.Response)
+			}
+		})
+	}
+}
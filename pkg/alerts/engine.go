@@ -0,0 +1,173 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Estados de una alerta
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Severidades
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Condition es una condición de alerta que se cumple en la evaluación actual
+type Condition struct {
+	Key      string            // Identifica la condición (regla + sorter + SKU...)
+	Rule     string            // Tipo de regla que la generó
+	Severity string            // warning o critical
+	Message  string            // Descripción legible
+	For      time.Duration     // Tiempo que debe mantenerse antes de disparar
+	Labels   map[string]string // Datos adicionales (sorter, sku, valor...)
+}
+
+// Alert representa una alerta disparada o resuelta
+type Alert struct {
+	Key        string            `json:"key"`
+	Rule       string            `json:"rule"`
+	Severity   string            `json:"severity"`
+	State      string            `json:"state"`
+	Message    string            `json:"message"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	StartedAt  time.Time         `json:"started_at"` // Primera vez que se cumplió la condición
+	FiredAt    time.Time         `json:"fired_at"`
+	ResolvedAt time.Time         `json:"resolved_at,omitempty"`
+}
+
+// Notifier envía alertas a un destino externo
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Engine evalúa condiciones y maneja deduplicación, cooldown y resolución
+type Engine struct {
+	cooldown      time.Duration
+	notifyTimeout time.Duration
+	notifiers     []Notifier
+
+	mu           sync.Mutex
+	pending      map[string]time.Time // Condiciones activas que aún no disparan
+	firing       map[string]*Alert
+	lastResolved map[string]time.Time
+}
+
+// NewEngine crea un nuevo motor de alertas
+func NewEngine(cooldown time.Duration, notifiers ...Notifier) *Engine {
+	return &Engine{
+		cooldown:      cooldown,
+		notifyTimeout: 15 * time.Second,
+		notifiers:     notifiers,
+		pending:       make(map[string]time.Time),
+		firing:        make(map[string]*Alert),
+		lastResolved:  make(map[string]time.Time),
+	}
+}
+
+// Evaluate procesa las condiciones activas en el instante now.
+// Retorna las alertas que dispararon o se resolvieron en esta evaluación
+// y los errores de notificación.
+func (e *Engine) Evaluate(now time.Time, active []Condition) ([]Alert, []error) {
+	e.mu.Lock()
+	var events []Alert
+
+	seen := make(map[string]bool, len(active))
+	for _, condition := range active {
+		seen[condition.Key] = true
+
+		if alert, isFiring := e.firing[condition.Key]; isFiring {
+			// Deduplicación: solo se actualiza el mensaje
+			alert.Message = condition.Message
+			alert.Labels = condition.Labels
+			continue
+		}
+
+		since, isPending := e.pending[condition.Key]
+		if !isPending {
+			since = now
+			e.pending[condition.Key] = now
+		}
+
+		if now.Sub(since) < condition.For {
+			continue
+		}
+		if resolvedAt, ok := e.lastResolved[condition.Key]; ok && now.Sub(resolvedAt) < e.cooldown {
+			continue
+		}
+
+		alert := &Alert{
+			Key:       condition.Key,
+			Rule:      condition.Rule,
+			Severity:  condition.Severity,
+			State:     StateFiring,
+			Message:   condition.Message,
+			Labels:    condition.Labels,
+			StartedAt: since,
+			FiredAt:   now,
+		}
+		e.firing[condition.Key] = alert
+		delete(e.pending, condition.Key)
+		events = append(events, *alert)
+	}
+
+	// Condiciones que dejaron de cumplirse
+	for key := range e.pending {
+		if !seen[key] {
+			delete(e.pending, key)
+		}
+	}
+	for key, alert := range e.firing {
+		if seen[key] {
+			continue
+		}
+		alert.State = StateResolved
+		alert.ResolvedAt = now
+		events = append(events, *alert)
+		e.lastResolved[key] = now
+		delete(e.firing, key)
+	}
+	e.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Key < events[j].Key })
+
+	return events, e.notify(events)
+}
+
+// Active retorna las alertas disparadas que aún no se resuelven
+func (e *Engine) Active() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	alerts := make([]Alert, 0, len(e.firing))
+	for _, alert := range e.firing {
+		alerts = append(alerts, *alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].FiredAt.Before(alerts[j].FiredAt) })
+	return alerts
+}
+
+// notify envía los eventos a todos los notificadores
+func (e *Engine) notify(events []Alert) []error {
+	var errs []error
+
+	for _, alert := range events {
+		for _, notifier := range e.notifiers {
+			ctx, cancel := context.WithTimeout(context.Background(), e.notifyTimeout)
+			if err := notifier.Notify(ctx, alert); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", notifier.Name(), err))
+			}
+			cancel()
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,166 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder guarda las alertas notificadas
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+	err    error
+}
+
+func (r *recorder) Name() string { return "prueba" }
+
+func (r *recorder) Notify(ctx context.Context, alert Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+	return r.err
+}
+
+func condition(key string, forDuration time.Duration) Condition {
+	return Condition{Key: key, Rule: "carga_sku", Severity: SeverityWarning, Message: key + " alto", For: forDuration}
+}
+
+// eventKeys resume los eventos como "clave:estado"
+func eventKeys(events []Alert) string {
+	parts := make([]string, len(events))
+	for i, event := range events {
+		parts[i] = event.Key + ":" + event.State
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	type step struct {
+		at     time.Duration // Desde el inicio
+		active []Condition
+		want   string // Eventos esperados
+	}
+	tests := []struct {
+		name     string
+		cooldown time.Duration
+		steps    []step
+	}{
+		{
+			name: "dispara sin espera y deduplica",
+			steps: []step{
+				{0, []Condition{condition("a", 0)}, "a:firing"},
+				{time.Minute, []Condition{condition("a", 0)}, ""},
+				{2 * time.Minute, []Condition{condition("a", 0)}, ""},
+			},
+		},
+		{
+			name: "espera For antes de disparar",
+			steps: []step{
+				{0, []Condition{condition("a", 2*time.Minute)}, ""},
+				{time.Minute, []Condition{condition("a", 2*time.Minute)}, ""},
+				{2 * time.Minute, []Condition{condition("a", 2*time.Minute)}, "a:firing"},
+			},
+		},
+		{
+			name: "una pendiente que se interrumpe vuelve a esperar",
+			steps: []step{
+				{0, []Condition{condition("a", 2*time.Minute)}, ""},
+				{time.Minute, nil, ""},
+				{2 * time.Minute, []Condition{condition("a", 2*time.Minute)}, ""},
+				{4 * time.Minute, []Condition{condition("a", 2*time.Minute)}, "a:firing"},
+			},
+		},
+		{
+			name: "se resuelve cuando deja de cumplirse",
+			steps: []step{
+				{0, []Condition{condition("a", 0), condition("b", 0)}, "a:firing,b:firing"},
+				{time.Minute, []Condition{condition("b", 0)}, "a:resolved"},
+				{2 * time.Minute, nil, "b:resolved"},
+				{3 * time.Minute, nil, ""},
+			},
+		},
+		{
+			name:     "cooldown después de resolver",
+			cooldown: 10 * time.Minute,
+			steps: []step{
+				{0, []Condition{condition("a", 0)}, "a:firing"},
+				{time.Minute, nil, "a:resolved"},
+				{2 * time.Minute, []Condition{condition("a", 0), condition("b", 0)}, "b:firing"},
+				{10 * time.Minute, []Condition{condition("a", 0), condition("b", 0)}, ""},
+				{11 * time.Minute, []Condition{condition("a", 0), condition("b", 0)}, "a:firing"},
+			},
+		},
+	}
+
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &recorder{}
+			engine := NewEngine(tt.cooldown, notifier)
+			notified := 0
+
+			for i, s := range tt.steps {
+				events, errs := engine.Evaluate(start.Add(s.at), s.active)
+				if len(errs) > 0 {
+					t.Fatalf("paso %d: %v", i, errs)
+				}
+				if got := eventKeys(events); got != s.want {
+					t.Errorf("paso %d: eventos = %q, se esperaba %q", i, got, s.want)
+				}
+				if got := eventKeys(notifier.alerts[notified:]); got != s.want {
+					t.Errorf("paso %d: notificados = %q, se esperaba %q", i, got, s.want)
+				}
+				notified = len(notifier.alerts)
+			}
+		})
+	}
+}
+
+func TestEngineAlertTimes(t *testing.T) {
+	engine := NewEngine(0)
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	engine.Evaluate(start, []Condition{condition("a", time.Minute)})
+	events, _ := engine.Evaluate(start.Add(time.Minute), []Condition{condition("a", time.Minute)})
+	if len(events) != 1 || !events[0].StartedAt.Equal(start) || !events[0].FiredAt.Equal(start.Add(time.Minute)) {
+		t.Fatalf("disparo: %+v", events)
+	}
+
+	// La deduplicación actualiza el mensaje de la alerta activa
+	updated := condition("a", time.Minute)
+	updated.Message = "a muy alto"
+	engine.Evaluate(start.Add(2*time.Minute), []Condition{updated})
+	if active := engine.Active(); len(active) != 1 || active[0].Message != "a muy alto" {
+		t.Errorf("activas: %+v", active)
+	}
+
+	events, _ = engine.Evaluate(start.Add(3*time.Minute), nil)
+	if len(events) != 1 || !events[0].ResolvedAt.Equal(start.Add(3*time.Minute)) || events[0].Message != "a muy alto" {
+		t.Errorf("resolución: %+v", events)
+	}
+	if active := engine.Active(); len(active) != 0 {
+		t.Errorf("activas después de resolver: %+v", active)
+	}
+}
+
+func TestEngineNotifierErrors(t *testing.T) {
+	failing := &recorder{err: errors.New("sin conexión")}
+	working := &recorder{}
+	engine := NewEngine(0, failing, working)
+
+	events, errs := engine.Evaluate(time.Now(), []Condition{condition("a", 0)})
+	if len(events) != 1 {
+		t.Fatalf("eventos: %+v", events)
+	}
+	if len(errs) != 1 || errs[0].Error() != "prueba: sin conexión" {
+		t.Errorf("errores = %v", errs)
+	}
+	// Un notificador que falla no impide los demás
+	if len(working.alerts) != 1 {
+		t.Errorf("el segundo notificador recibió %d alertas", len(working.alerts))
+	}
+}
@@ -0,0 +1,175 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// WebhookNotifier envía la alerta como JSON por HTTP POST
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier crea un notificador webhook genérico
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name retorna el nombre del notificador
+func (wn *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify envía la alerta al webhook
+func (wn *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", wn.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook respondió con estado %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// EmailConfig configuración del notificador SMTP
+type EmailConfig struct {
+	Server   string // host:puerto
+	Username string // Vacío = sin autenticación
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailNotifier envía la alerta por correo usando SMTP
+type EmailNotifier struct {
+	config EmailConfig
+}
+
+// NewEmailNotifier crea un notificador SMTP
+func NewEmailNotifier(config EmailConfig) *EmailNotifier {
+	return &EmailNotifier{config: config}
+}
+
+// Name retorna el nombre del notificador
+func (en *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Notify envía la alerta por correo
+func (en *EmailNotifier) Notify(ctx context.Context, alert Alert) error {
+	var auth smtp.Auth
+	if en.config.Username != "" {
+		host := en.config.Server
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", en.config.Username, en.config.Password, host)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(en.config.Server, auth, en.config.From, en.config.To, en.buildMessage(alert))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildMessage arma el mensaje RFC 822
+func (en *EmailNotifier) buildMessage(alert Alert) []byte {
+	status := "ALERTA"
+	if alert.State == StateResolved {
+		status = "RESUELTA"
+	}
+
+	var msg bytes.Buffer
+	msg.WriteString(fmt.Sprintf("From: %s\r\n", en.config.From))
+	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(en.config.To, ", ")))
+	msg.WriteString(fmt.Sprintf("Subject: [Danich] %s %s: %s\r\n", status, alert.Rule, alert.Message))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(fmt.Sprintf("Estado: %s\r\n", alert.State))
+	msg.WriteString(fmt.Sprintf("Severidad: %s\r\n", alert.Severity))
+	msg.WriteString(fmt.Sprintf("Regla: %s\r\n", alert.Rule))
+	msg.WriteString(fmt.Sprintf("Mensaje: %s\r\n", alert.Message))
+	msg.WriteString(fmt.Sprintf("Desde: %s\r\n", alert.StartedAt.Format("2006-01-02 15:04:05")))
+	if alert.State == StateResolved {
+		msg.WriteString(fmt.Sprintf("Resuelta: %s\r\n", alert.ResolvedAt.Format("2006-01-02 15:04:05")))
+	}
+	for key, value := range alert.Labels {
+		msg.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
+	}
+
+	return msg.Bytes()
+}
+
+// CommandNotifier ejecuta un comando local por cada alerta. El comando recibe
+// la alerta en JSON por stdin y los campos principales como variables de entorno.
+type CommandNotifier struct {
+	path string
+	args []string
+}
+
+// NewCommandNotifier crea un notificador que ejecuta un comando local
+func NewCommandNotifier(path string, args []string) *CommandNotifier {
+	return &CommandNotifier{path: path, args: args}
+}
+
+// Name retorna el nombre del notificador
+func (cn *CommandNotifier) Name() string {
+	return "comando"
+}
+
+// Notify ejecuta el comando con los datos de la alerta
+func (cn *CommandNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, cn.path, cn.args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"DANICH_ALERT_STATE="+alert.State,
+		"DANICH_ALERT_RULE="+alert.Rule,
+		"DANICH_ALERT_KEY="+alert.Key,
+		"DANICH_ALERT_SEVERITY="+alert.Severity,
+		"DANICH_ALERT_MESSAGE="+alert.Message,
+	)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package alerts
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAlert() Alert {
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	return Alert{
+		Key:       "carga_sku/1/3J-D",
+		Rule:      "carga_sku",
+		Severity:  SeverityCritical,
+		State:     StateFiring,
+		Message:   "3J-D en 52%",
+		Labels:    map[string]string{"sorter": "1"},
+		StartedAt: start,
+		FiredAt:   start.Add(time.Minute),
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Alert
+	var contentType string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if r.Method != http.MethodPost {
+			t.Errorf("método = %s", r.Method)
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+	if err := notifier.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	if received.Key != "carga_sku/1/3J-D" || received.State != StateFiring || received.Labels["sorter"] != "1" {
+		t.Errorf("alerta recibida: %+v", received)
+	}
+
+	status = http.StatusInternalServerError
+	if err := notifier.Notify(context.Background(), testAlert()); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("error = %v, se esperaba el estado 500", err)
+	}
+}
+
+// smtpServer es un servidor SMTP mínimo que acepta un mensaje y lo entrega por messages
+type smtpServer struct {
+	addr     string
+	auth     chan string // Credenciales de AUTH PLAIN decodificadas
+	messages chan smtpMessage
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &smtpServer{addr: listener.Addr().String(), auth: make(chan string, 1), messages: make(chan smtpMessage, 1)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var message smtpMessage
+	reply("220 prueba ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"):
+			reply("250-prueba")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(command, "AUTH PLAIN "):
+			decoded, _ := base64.StdEncoding.DecodeString(line[len("AUTH PLAIN "):])
+			s.auth <- strings.ReplaceAll(string(decoded), "\x00", "|")
+			reply("235 ok")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			message.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 ok")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			message.to = append(message.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case command == "DATA":
+			reply("354 enviar")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			message.data = data.String()
+			s.messages <- message
+			reply("250 ok")
+		case command == "QUIT":
+			reply("221 chau")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	server := newSMTPServer(t)
+	notifier := NewEmailNotifier(EmailConfig{
+		Server:   server.addr,
+		Username: "monitor",
+		Password: "secreto",
+		From:     "monitor@planta.local",
+		To:       []string{"jefe@planta.local", "turno@planta.local"},
+	})
+
+	alert := testAlert()
+	alert.State = StateResolved
+	alert.ResolvedAt = alert.FiredAt.Add(5 * time.Minute)
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatal(err)
+	}
+
+	if auth := <-server.auth; auth != "|monitor|secreto" {
+		t.Errorf("AUTH = %q", auth)
+	}
+	message := <-server.messages
+	if message.from != "monitor@planta.local" || strings.Join(message.to, ",") != "jefe@planta.local,turno@planta.local" {
+		t.Errorf("sobre: %+v", message)
+	}
+	for _, want := range []string{
+		"Subject: [Danich] RESUELTA carga_sku: 3J-D en 52%",
+		"Severidad: critical",
+		"Resuelta: 2026-03-02 08:06:00",
+		"sorter: 1",
+	} {
+		if !strings.Contains(message.data, want) {
+			t.Errorf("el mensaje no contiene %q:\n%s", want, message.data)
+		}
+	}
+}
+
+func TestEmailNotifierTimeout(t *testing.T) {
+	// Un servidor que acepta la conexión y nunca saluda
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	notifier := NewEmailNotifier(EmailConfig{Server: listener.Addr().String(), From: "a@b", To: []string{"c@d"}})
+	if err := notifier.Notify(ctx, testAlert()); err != context.DeadlineExceeded {
+		t.Errorf("error = %v, se esperaba el timeout del contexto", err)
+	}
+}
+
+func TestCommandNotifier(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("el script de prueba usa sh")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "hook.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\ncat > \"$1\"\necho \"$DANICH_ALERT_STATE $DANICH_ALERT_SEVERITY $DANICH_ALERT_KEY\" > \"$2\"\n"), 0o755)
+	failing := filepath.Join(dir, "falla.sh")
+	os.WriteFile(failing, []byte("#!/bin/sh\necho 'sin destino' >&2\nexit 3\n"), 0o755)
+
+	stdin, env := filepath.Join(dir, "stdin.json"), filepath.Join(dir, "env.txt")
+	if err := NewCommandNotifier(script, []string{stdin, env}).Notify(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(stdin)
+	var received Alert
+	if err := json.Unmarshal(data, &received); err != nil || received.Message != "3J-D en 52%" {
+		t.Errorf("stdin = %s (%v)", data, err)
+	}
+	if data, _ := os.ReadFile(env); strings.TrimSpace(string(data)) != "firing critical carga_sku/1/3J-D" {
+		t.Errorf("variables = %q", data)
+	}
+
+	err := NewCommandNotifier(failing, nil).Notify(context.Background(), testAlert())
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "sin destino") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// adviceRequest es un snapshot pendiente de analizar por el advisor
+type adviceRequest struct {
+	snapshot   DataSnapshot
+	checkCount int
+	changes    []ChangeLog  // Cambios recientes al momento del snapshot
+	log        *slog.Logger // Logger del ciclo que generó el pedido
+}
+
+// AdviceWorker ejecuta el advisor fuera del loop de monitoreo. Los pedidos
+// esperan en un buffer de un solo lugar: si llega un snapshot más nuevo
+// mientras otro espera, el anterior se descarta porque ya no describe la
+// planta.
+type AdviceWorker struct {
+	requests chan adviceRequest
+	analyze  func(adviceRequest)
+	logger   *slog.Logger
+	dropped  atomic.Int64
+	wg       sync.WaitGroup
+}
+
+// NewAdviceWorker crea el worker; analyze se ejecuta en su goroutine
+func NewAdviceWorker(analyze func(adviceRequest), logger *slog.Logger) *AdviceWorker {
+	return &AdviceWorker{
+		requests: make(chan adviceRequest, 1),
+		analyze:  analyze,
+		logger:   loggerOrDefault(logger),
+	}
+}
+
+// Start inicia la goroutine del worker
+func (w *AdviceWorker) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for request := range w.requests {
+			w.analyze(request)
+		}
+	}()
+}
+
+// Submit encola un snapshot sin bloquear el loop de monitoreo. Retorna false
+// si reemplazó a un pedido que todavía no se analizaba.
+func (w *AdviceWorker) Submit(request adviceRequest) bool {
+	for {
+		select {
+		case w.requests <- request:
+			return true
+		default:
+		}
+
+		// Buffer ocupado: se descarta el pedido pendiente y se reintenta
+		select {
+		case stale := <-w.requests:
+			w.dropped.Add(1)
+			w.logger.Info("sugerencia descartada por un snapshot más nuevo",
+				"snapshot", stale.snapshot.Timestamp, "nuevo", request.snapshot.Timestamp)
+			w.requests <- request
+			return false
+		default:
+			// El worker tomó el pedido entre ambos select
+		}
+	}
+}
+
+// Dropped retorna cuántos pedidos se descartaron por llegar uno más nuevo
+func (w *AdviceWorker) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Stop deja de aceptar pedidos y espera a que termine el análisis en curso
+func (w *AdviceWorker) Stop() {
+	close(w.requests)
+	w.wg.Wait()
+}
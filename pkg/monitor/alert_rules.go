@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"danich/pkg/alerts"
+)
+
+// Tipos de reglas de alerta
+const (
+	RuleSKUAboveThreshold = "sku_sobre_umbral"
+	RuleScrapeFailing     = "scrape_fallido"
+	RuleHighDescarte      = "descarte_alto"
+	RuleDataQuality       = "calidad_datos"
+)
+
+// AlertRule define una regla de alerta sobre los datos de los snapshots
+type AlertRule struct {
+	Type      string
+	Threshold float64       // Porcentaje (sku_sobre_umbral, descarte_alto)
+	For       time.Duration // Tiempo que debe mantenerse la condición
+	Cycles    int           // Ciclos fallidos consecutivos (scrape_fallido)
+	SorterID  int           // 0 = todos los sorters
+	Severity  string
+	Check     string // Chequeo de calidad (calidad_datos); vacío = todos
+}
+
+// AlertInput reúne los datos evaluados por las reglas en un ciclo
+type AlertInput struct {
+	Snapshot       *DataSnapshot  // nil si el ciclo falló antes de crear el snapshot
+	SourceFailures map[string]int // Fuente -> ciclos fallidos consecutivos
+}
+
+// EvaluateAlertRules retorna las condiciones de alerta que se cumplen
+func EvaluateAlertRules(rules []AlertRule, input AlertInput) []alerts.Condition {
+	var conditions []alerts.Condition
+
+	for _, rule := range rules {
+		switch rule.Type {
+		case RuleSKUAboveThreshold:
+			conditions = append(conditions, skuAboveThreshold(rule, input.Snapshot, false)...)
+		case RuleHighDescarte:
+			conditions = append(conditions, skuAboveThreshold(rule, input.Snapshot, true)...)
+		case RuleScrapeFailing:
+			conditions = append(conditions, scrapeFailing(rule, input.SourceFailures)...)
+		case RuleDataQuality:
+			conditions = append(conditions, dataQuality(rule, input.Snapshot)...)
+		}
+	}
+
+	return conditions
+}
+
+// skuAboveThreshold genera condiciones para SKUs sobre el umbral.
+// Si onlyDescarte es true solo considera el SKU de descarte.
+func skuAboveThreshold(rule AlertRule, snapshot *DataSnapshot, onlyDescarte bool) []alerts.Condition {
+	if snapshot == nil {
+		return nil
+	}
+
+	var conditions []alerts.Condition
+	for sorterID, chartData := range snapshot.ChartData {
+		if chartData == nil || (rule.SorterID != 0 && rule.SorterID != sorterID) {
+			continue
+		}
+
+		for sku, percentage := range chartData.Percentages {
+			isDescarte := strings.EqualFold(sku, "descarte")
+			if onlyDescarte != isDescarte || percentage <= rule.Threshold {
+				continue
+			}
+
+			message := fmt.Sprintf("%s en %.1f%% en sorter %d (umbral %.0f%%)", sku, percentage, sorterID, rule.Threshold)
+			if onlyDescarte {
+				message = fmt.Sprintf("Descarte en %.1f%% en sorter %d (umbral %.0f%%)", percentage, sorterID, rule.Threshold)
+			}
+
+			conditions = append(conditions, alerts.Condition{
+				Key:      fmt.Sprintf("%s/%d/%s", rule.Type, sorterID, strings.ToUpper(sku)),
+				Rule:     rule.Type,
+				Severity: rule.Severity,
+				Message:  message,
+				For:      rule.For,
+				Labels: map[string]string{
+					"sorter":     fmt.Sprintf("%d", sorterID),
+					"sku":        sku,
+					"porcentaje": fmt.Sprintf("%.1f", percentage),
+				},
+			})
+		}
+	}
+
+	return conditions
+}
+
+// scrapeFailing genera condiciones para fuentes que fallan varios ciclos seguidos
+func scrapeFailing(rule AlertRule, failures map[string]int) []alerts.Condition {
+	sources := make([]string, 0, len(failures))
+	for source := range failures {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var conditions []alerts.Condition
+	for _, source := range sources {
+		count := failures[source]
+		if count < rule.Cycles {
+			continue
+		}
+		conditions = append(conditions, alerts.Condition{
+			Key:      fmt.Sprintf("%s/%s", rule.Type, source),
+			Rule:     rule.Type,
+			Severity: rule.Severity,
+			Message:  fmt.Sprintf("%s falla hace %d ciclos", source, count),
+			For:      rule.For,
+			Labels: map[string]string{
+				"fuente": source,
+				"ciclos": fmt.Sprintf("%d", count),
+			},
+		})
+	}
+
+	return conditions
+}
+
+// dataQuality genera una condición por cada problema de calidad del snapshot
+func dataQuality(rule AlertRule, snapshot *DataSnapshot) []alerts.Condition {
+	if snapshot == nil || snapshot.Quality == nil {
+		return nil
+	}
+
+	var conditions []alerts.Condition
+	for _, issue := range snapshot.Quality.Issues {
+		if rule.Check != "" && rule.Check != issue.Check {
+			continue
+		}
+		if rule.SorterID != 0 && rule.SorterID != issue.SorterID {
+			continue
+		}
+
+		conditions = append(conditions, alerts.Condition{
+			Key:      fmt.Sprintf("%s/%s", rule.Type, issue.Key()),
+			Rule:     rule.Type,
+			Severity: rule.Severity,
+			Message:  issue.Message,
+			For:      rule.For,
+			Labels: map[string]string{
+				"chequeo": issue.Check,
+				"sorter":  fmt.Sprintf("%d", issue.SorterID),
+				"sku":     issue.SKU,
+				"salida":  fmt.Sprintf("%d", issue.Salida),
+				"valor":   fmt.Sprintf("%.1f", issue.Value),
+			},
+		})
+	}
+
+	return conditions
+}
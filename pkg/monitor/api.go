@@ -0,0 +1,709 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed web
+var webAssets embed.FS
+
+// maxBodyBytes acota el cuerpo de los pedidos POST
+const maxBodyBytes = 1 << 20
+
+// MonitorControl son las acciones de la API que cambian el estado del monitor
+type MonitorControl interface {
+	AcknowledgeAdvice(usuario, nota string, adviceAt time.Time) (*AdviceRecord, error)
+	ReloadConfig() (*ConfigReload, error)
+}
+
+// APIServer expone el estado del monitor por HTTP y sirve el dashboard web.
+// Cada ruta exige un rol; las acciones privilegiadas quedan en la auditoría.
+type APIServer struct {
+	config      *SystemConfig
+	persistence *Persistence
+	store       Store
+	logger      *slog.Logger
+	mux         *http.ServeMux
+	whatIf      *WhatIfSimulator
+	assistant   *Assistant
+	control     MonitorControl
+	auth        *Authenticator
+	calendar    *ShiftCalendar
+
+	mu          sync.RWMutex
+	snapshot    *DataSnapshot
+	state       []byte                   // Último DashboardState en JSON
+	subscribers map[chan []byte]struct{} // Clientes SSE conectados
+}
+
+// NewAPIServer crea un nuevo servidor HTTP. Las preguntas (/api/ask) las
+// responde assistant; los reconocimientos y la recarga de configuración los
+// aplica control.
+func NewAPIServer(config *SystemConfig, persistence *Persistence, store Store, assistant *Assistant, control MonitorControl, logger *slog.Logger) *APIServer {
+	s := &APIServer{
+		config:      config,
+		persistence: persistence,
+		store:       store,
+		logger:      loggerOrDefault(logger),
+		mux:         http.NewServeMux(),
+		whatIf:      NewWhatIfSimulator(config),
+		assistant:   assistant,
+		control:     control,
+		auth:        NewAuthenticator(config.APIKeys, config.APIUsers),
+		calendar:    config.ShiftCalendar(),
+		subscribers: make(map[chan []byte]struct{}),
+	}
+
+	assets, _ := fs.Sub(webAssets, "web")
+	s.route("GET /", RoleViewer, http.FileServer(http.FS(assets)))
+	s.route("GET /api/snapshot", RoleViewer, http.HandlerFunc(s.handleSnapshot))
+	s.route("GET /api/state", RoleViewer, http.HandlerFunc(s.handleState))
+	s.route("GET /api/events", RoleViewer, http.HandlerFunc(s.handleEvents))
+	s.route("GET /api/rollups", RoleViewer, http.HandlerFunc(s.handleRollups))
+	s.route("GET /api/throughput", RoleViewer, http.HandlerFunc(s.handleThroughput))
+	s.route("GET /api/annotations", RoleViewer, http.HandlerFunc(s.handleAnnotations))
+	s.route("GET /api/me", RoleViewer, http.HandlerFunc(s.handleMe))
+	s.route("POST /api/whatif", RoleViewer, http.HandlerFunc(s.handleWhatIf))
+	s.route("POST /api/ask", RoleViewer, http.HandlerFunc(s.handleAsk))
+
+	s.route("POST /api/throughput", RoleSupervisor, http.HandlerFunc(s.handleSetThroughput))
+	s.route("POST /api/advice/ack", RoleSupervisor, http.HandlerFunc(s.handleAdviceAck))
+	s.route("POST /api/annotations", RoleSupervisor, http.HandlerFunc(s.handleAddAnnotation))
+
+	s.route("POST /api/config/reload", RoleAdmin, http.HandlerFunc(s.handleReloadConfig))
+	s.route("GET /api/export/features", RoleAdmin, http.HandlerFunc(s.handleExportFeatures))
+	s.route("GET /api/audit", RoleAdmin, http.HandlerFunc(s.handleAudit))
+
+	return s
+}
+
+// TLS indica si la API se sirve por HTTPS
+func (s *APIServer) TLS() bool {
+	return s.config.APITLSCert != ""
+}
+
+// Start inicia el servidor en segundo plano
+func (s *APIServer) Start() {
+	server := &http.Server{
+		Addr:              s.config.APIListen,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+
+	go func() {
+		var err error
+		if s.TLS() {
+			err = server.ListenAndServeTLS(s.config.APITLSCert, s.config.APITLSKey)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Error en API HTTP", "error", err)
+		}
+	}()
+
+	switch {
+	case !s.auth.Enabled():
+		s.logger.Warn("API sin autenticación (api.sin_autenticacion): cualquiera en la red puede consultarla y modificarla")
+	case !s.TLS():
+		s.logger.Warn("API con autenticación sin TLS: las credenciales viajan sin cifrar (configurar api.tls)")
+	}
+	s.logger.Info("API HTTP escuchando", "direccion", s.config.APIListen, "tls", s.TLS(), "autenticacion", s.auth.Enabled())
+}
+
+// SetCredentials reemplaza las claves y usuarios aceptados
+func (s *APIServer) SetCredentials(keys []APIKey, users []APIUser) {
+	s.auth.Update(keys, users)
+}
+
+// route registra un handler que exige al menos el rol indicado
+func (s *APIServer) route(pattern, role string, handler http.Handler) {
+	s.mux.Handle(pattern, s.authorize(pattern, role, handler))
+}
+
+// authorize autentica el pedido y verifica su rol. Los rechazos de rutas
+// privilegiadas quedan en la auditoría.
+func (s *APIServer) authorize(pattern, role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := s.auth.Authenticate(r)
+		if err != nil {
+			level := slog.LevelWarn
+			if err == errNoCredentials {
+				level = slog.LevelInfo // El navegador pregunta primero sin credenciales
+			}
+			s.logger.Log(r.Context(), level, "pedido a la API rechazado", "ruta", r.URL.Path, "remoto", r.RemoteAddr, "error", err)
+			if s.auth.HasUsers() {
+				w.Header().Set("WWW-Authenticate", `Basic realm="Danich", charset="UTF-8"`)
+			} else {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="Danich"`)
+			}
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		r = r.WithContext(withPrincipal(r.Context(), principal))
+		if !RoleAllows(principal.Role, role) {
+			if role != RoleViewer {
+				entry := newAuditEntry(r, pattern, "requiere el rol "+role, nil)
+				entry.Resultado = AuditDenied
+				s.record(entry)
+			}
+			writeError(w, http.StatusForbidden, fmt.Sprintf("se requiere el rol %s", role))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// audit registra una acción privilegiada hecha en el pedido
+func (s *APIServer) audit(r *http.Request, accion, detalle string, err error) {
+	s.record(newAuditEntry(r, accion, detalle, err))
+}
+
+// record guarda una entrada de auditoría y la deja también en el log
+func (s *APIServer) record(entry AuditEntry) {
+	if err := s.persistence.AppendAudit(entry); err != nil {
+		s.logger.Error("Error registrando auditoría", "accion", entry.Accion, "error", err)
+	}
+	s.logger.Info("acción auditada", "usuario", entry.Usuario, "rol", entry.Rol, "accion", entry.Accion,
+		"detalle", entry.Detalle, "resultado", entry.Resultado, "remoto", entry.Remoto)
+}
+
+// Publish publica el último snapshot y envía el estado a los clientes SSE
+func (s *APIServer) Publish(snapshot DataSnapshot, state DashboardState) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		s.logger.Warn("Error serializando estado del dashboard", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshot = &snapshot
+	s.state = data
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- data:
+		default:
+			// Cliente lento: se descarta el estado anterior pendiente
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- data
+		}
+	}
+}
+
+// handleState retorna el último estado del dashboard
+func (s *APIServer) handleState(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	state := s.state
+	s.mu.RUnlock()
+
+	if state == nil {
+		writeError(w, http.StatusServiceUnavailable, "aún no hay snapshots")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(state)
+}
+
+// handleEvents envía el estado del dashboard por server-sent events
+func (s *APIServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming no soportado")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan []byte, 1)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	if s.state != nil {
+		ch <- s.state
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-ch:
+			fmt.Fprintf(w, "event: state\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// handleSnapshot retorna el último snapshot capturado
+func (s *APIServer) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	snapshot := s.snapshot
+	s.mu.RUnlock()
+
+	if snapshot == nil {
+		writeError(w, http.StatusServiceUnavailable, "aún no hay snapshots")
+		return
+	}
+
+	writeJSON(w, snapshot)
+}
+
+// handleRollups consulta los agregados persistidos
+func (s *APIServer) handleRollups(w http.ResponseWriter, r *http.Request) {
+	query, err := rollupQueryFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, err := s.persistence.LoadRollups(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, records)
+}
+
+// handleThroughput retorna el throughput ingresado manualmente por sorter
+func (s *APIServer) handleThroughput(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.persistence.LoadThroughput()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	list := make([]ThroughputEntry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].SorterID < list[j].SorterID })
+	writeJSON(w, list)
+}
+
+// handleSetThroughput registra el throughput de un sorter. Se aplica desde el
+// próximo ciclo; por_minuto 0 vuelve al valor de la configuración.
+func (s *APIServer) handleSetThroughput(w http.ResponseWriter, r *http.Request) {
+	var entry ThroughputEntry
+	if err := decodeBody(w, r, &entry); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if entry.SorterID < 1 || (s.config.PackingSorters > 0 && entry.SorterID > s.config.PackingSorters) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("sorter inválido %d", entry.SorterID))
+		return
+	}
+	if entry.PerMinute < 0 {
+		writeError(w, http.StatusBadRequest, "por_minuto debe ser >= 0")
+		return
+	}
+
+	entry.UpdatedAt = time.Now()
+	err := s.persistence.SaveThroughput(entry)
+	s.audit(r, "throughput.ajustar", fmt.Sprintf("sorter %d: %g/min", entry.SorterID, entry.PerMinute), err)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.logger.Info("throughput manual actualizado", "sorter", entry.SorterID, "por_minuto", entry.PerMinute)
+	writeJSON(w, entry)
+}
+
+// handleWhatIf simula ediciones de assignments sobre el último snapshot. El
+// cuerpo es {"ediciones": [...]}; no modifica ningún dato real.
+func (s *APIServer) handleWhatIf(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Ediciones []WhatIfEdit `json:"ediciones"`
+	}
+	if err := decodeBody(w, r, &request); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	s.mu.RLock()
+	snapshot := s.snapshot
+	s.mu.RUnlock()
+	if snapshot == nil {
+		writeError(w, http.StatusServiceUnavailable, "aún no hay snapshots")
+		return
+	}
+
+	manual, err := s.persistence.LoadThroughput()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result, err := s.whatIf.Simulate(*snapshot, manual, request.Ediciones)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, result)
+}
+
+// handleAsk responde una pregunta con el LLM y las herramientas del asistente
+func (s *APIServer) handleAsk(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Pregunta string `json:"pregunta"`
+	}
+	if err := decodeBody(w, r, &request); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if strings.TrimSpace(request.Pregunta) == "" {
+		writeError(w, http.StatusBadRequest, "falta la pregunta")
+		return
+	}
+
+	answer, err := s.assistant.Ask(r.Context(), request.Pregunta, nil)
+	if err != nil {
+		s.logger.Warn("error respondiendo pregunta", "pregunta", request.Pregunta, "error", err)
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, answer)
+}
+
+// handleMe retorna quién hace el pedido y su rol (el dashboard muestra las
+// acciones que el rol permite)
+func (s *APIServer) handleMe(w http.ResponseWriter, r *http.Request) {
+	principal := PrincipalFrom(r.Context())
+	writeJSON(w, map[string]string{
+		"usuario": principal.Name,
+		"rol":     principal.Role,
+		"metodo":  principal.Method,
+	})
+}
+
+// handleAdviceAck reconoce la sugerencia vigente. El cuerpo es
+// {"advice_datetime": ..., "nota": ...}; advice_datetime evita reconocer una
+// sugerencia más nueva que la que se vio.
+func (s *APIServer) handleAdviceAck(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		AdviceAt time.Time `json:"advice_datetime"`
+		Nota     string    `json:"nota"`
+	}
+	if err := decodeBody(w, r, &request); err != nil && err != io.EOF {
+		writeBodyError(w, err)
+		return
+	}
+
+	usuario := PrincipalFrom(r.Context()).Name
+	record, err := s.control.AcknowledgeAdvice(usuario, strings.TrimSpace(request.Nota), request.AdviceAt)
+	detalle := "sugerencia vigente"
+	if record != nil {
+		detalle = fmt.Sprintf("%s %s (%s)", record.Advice.Accion, record.Advice.SKU, record.DateTime.Format("2006-01-02 15:04:05"))
+	}
+	s.audit(r, "sugerencia.reconocer", detalle, err)
+
+	switch {
+	case errors.Is(err, ErrNoAdvice):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrAdviceChanged), errors.Is(err, ErrAdviceAcked):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, record)
+	}
+}
+
+// handleAnnotations retorna las anotaciones del rango, la más reciente primero
+func (s *APIServer) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	from, to, err := timeRangeFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	annotations, err := s.persistence.LoadAnnotations(from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sort.SliceStable(annotations, func(i, j int) bool { return annotations[i].DateTime.After(annotations[j].DateTime) })
+	writeJSON(w, annotations)
+}
+
+// handleAddAnnotation agrega una anotación. El cuerpo es
+// {"texto": ..., "sorter_id": ..., "sku": ...}.
+func (s *APIServer) handleAddAnnotation(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Texto    string `json:"texto"`
+		SorterID int    `json:"sorter_id"`
+		SKU      string `json:"sku"`
+	}
+	if err := decodeBody(w, r, &request); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	request.Texto = strings.TrimSpace(request.Texto)
+	if request.Texto == "" {
+		writeError(w, http.StatusBadRequest, "falta el texto")
+		return
+	}
+	if len(request.Texto) > 2000 {
+		writeError(w, http.StatusBadRequest, "el texto no puede superar 2000 caracteres")
+		return
+	}
+	if request.SorterID < 0 || (s.config.PackingSorters > 0 && request.SorterID > s.config.PackingSorters) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("sorter inválido %d", request.SorterID))
+		return
+	}
+
+	now := time.Now()
+	annotation := Annotation{
+		DateTime:      now,
+		ProductionDay: s.calendar.ProductionDay(now),
+		Usuario:       PrincipalFrom(r.Context()).Name,
+		SorterID:      request.SorterID,
+		SKU:           strings.ToUpper(strings.TrimSpace(request.SKU)),
+		Texto:         request.Texto,
+	}
+	if shift, ok := s.calendar.Resolve(now); ok {
+		annotation.Shift = shift.Name
+	}
+
+	err := s.persistence.AppendAnnotation(annotation)
+	s.audit(r, "anotacion.agregar", truncateText(annotation.Texto, 80), err)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, annotation)
+}
+
+// handleReloadConfig vuelve a leer la configuración y aplica lo que se puede
+// cambiar sin reiniciar
+func (s *APIServer) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	result, err := s.control.ReloadConfig()
+	detalle := ""
+	if result != nil {
+		detalle = fmt.Sprintf("aplicados: %s; requieren reinicio: %s",
+			strings.Join(result.Applied, ", "), strings.Join(result.Restart, ", "))
+	}
+	s.audit(r, "config.recargar", detalle, err)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, result)
+}
+
+// handleExportFeatures descarga la matriz de features en CSV, como
+// monitor export features. Parámetros: desde, hasta, lags, ventana y
+// horizonte (minutos).
+func (s *APIServer) handleExportFeatures(w http.ResponseWriter, r *http.Request) {
+	from, to, err := timeRangeFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	opts := DefaultFeatureOptions()
+	params := r.URL.Query()
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"lags", &opts.Lags}, {"ventana", &opts.Window}} {
+		if value := params.Get(param.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("%s inválido %q", param.name, value))
+				return
+			}
+			*param.target = n
+		}
+	}
+	if value := params.Get("horizonte"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("horizonte inválido %q", value))
+			return
+		}
+		opts.Horizon = time.Duration(minutes) * time.Minute
+	}
+
+	detalle := fmt.Sprintf("desde %s hasta %s", formatRangeEnd(from), formatRangeEnd(to))
+	snapshots, err := s.store.Snapshots(from, to)
+	if err != nil {
+		s.audit(r, "exportar.features", detalle, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Los cambios posteriores al rango también cuentan para las etiquetas
+	changesTo := to
+	if !changesTo.IsZero() {
+		changesTo = changesTo.Add(opts.Horizon)
+	}
+	changes, err := s.store.Changes(time.Time{}, changesTo)
+	if err != nil {
+		s.audit(r, "exportar.features", detalle, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	matrix := BuildFeatureMatrix(snapshots, changes, opts)
+	s.audit(r, "exportar.features", fmt.Sprintf("%s, %d filas", detalle, len(matrix.Rows)), nil)
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="features_%s.csv"`, time.Now().Format("20060102_1504")))
+	if err := matrix.EncodeCSV(w); err != nil {
+		s.logger.Warn("Error enviando exportación", "error", err)
+	}
+}
+
+// handleAudit retorna la auditoría, la acción más reciente primero.
+// Parámetros: desde, hasta, usuario, accion y limite (200 por defecto).
+func (s *APIServer) handleAudit(w http.ResponseWriter, r *http.Request) {
+	from, to, err := timeRangeFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params := r.URL.Query()
+	limit := 200
+	if value := params.Get("limite"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limite inválido %q", value))
+			return
+		}
+	}
+
+	entries, err := s.persistence.LoadAudit(AuditQuery{
+		From:    from,
+		To:      to,
+		Usuario: params.Get("usuario"),
+		Accion:  params.Get("accion"),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].DateTime.After(entries[j].DateTime) })
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	writeJSON(w, entries)
+}
+
+// timeRangeFromRequest lee los parámetros desde y hasta
+func timeRangeFromRequest(r *http.Request) (from, to time.Time, err error) {
+	params := r.URL.Query()
+	if from, err = ParseTimeArg(params.Get("desde")); err != nil {
+		return
+	}
+	to, err = ParseTimeArg(params.Get("hasta"))
+	return
+}
+
+// formatRangeEnd muestra un extremo de un rango (cero = sin límite)
+func formatRangeEnd(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// rollupQueryFromRequest arma el filtro desde los parámetros de la URL
+func rollupQueryFromRequest(r *http.Request) (RollupQuery, error) {
+	params := r.URL.Query()
+	query := RollupQuery{
+		Window: params.Get("ventana"),
+		Kind:   params.Get("tipo"),
+		Key:    params.Get("clave"),
+	}
+
+	if err := ValidateWindow(query.Window); err != nil {
+		return query, err
+	}
+
+	if sorter := params.Get("sorter"); sorter != "" {
+		id, err := strconv.Atoi(sorter)
+		if err != nil {
+			return query, fmt.Errorf("sorter inválido %q", sorter)
+		}
+		query.SorterID = id
+	}
+
+	var err error
+	if query.From, err = ParseTimeArg(params.Get("desde")); err != nil {
+		return query, err
+	}
+	if query.To, err = ParseTimeArg(params.Get("hasta")); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
+
+// writeJSON responde con un cuerpo JSON
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		slog.Warn("Error escribiendo respuesta", "error", err)
+	}
+}
+
+// decodeBody decodifica el cuerpo JSON de un pedido, hasta maxBodyBytes
+func decodeBody(w http.ResponseWriter, r *http.Request, value interface{}) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(value)
+}
+
+// writeBodyError responde el error de decodeBody
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("el cuerpo supera los %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, "cuerpo inválido: "+err.Error())
+}
+
+// writeError responde con un error en JSON
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
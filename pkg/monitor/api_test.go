@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConfigRequiresAPICredentials(t *testing.T) {
+	clave := "[{nombre: grafana, rol: lector, sha256: " + HashAPIKey("clave") + "}]"
+	tests := []struct {
+		name    string
+		flags   map[string]string
+		wantErr bool
+	}{
+		{"sin API", map[string]string{}, false},
+		{"API sin credenciales", map[string]string{"api.listen": ":8080"}, true},
+		{"API abierta a propósito", map[string]string{"api.listen": ":8080", "api.sin_autenticacion": "true"}, false},
+		{"API con claves", map[string]string{"api.listen": ":8080", "api.claves": clave}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			_, err := readConfig(ConfigOptions{Flags: tt.flags})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, se esperaba error: %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "api.sin_autenticacion") {
+				t.Errorf("el error no menciona cómo dejar la API abierta: %v", err)
+			}
+		})
+	}
+}
+
+func TestAPIBodyLimit(t *testing.T) {
+	cfg := testConfig(t)
+	persistence := NewPersistence(cfg, discardLogger())
+	if err := persistence.EnsureDataFolder(); err != nil {
+		t.Fatal(err)
+	}
+	api := httptest.NewServer(NewAPIServer(cfg, persistence, NewJSONStore(persistence), nil, nil, discardLogger()).mux)
+	defer api.Close()
+
+	post := func(body string) int {
+		t.Helper()
+		resp, err := http.Post(api.URL+"/api/annotations", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post(`{"texto": "Parada de línea 3", "sorter_id": 1}`); status != http.StatusOK {
+		t.Errorf("anotación normal: status = %d", status)
+	}
+	if status := post(`{"texto": "` + strings.Repeat("x", maxBodyBytes) + `"}`); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("cuerpo grande: status = %d, se esperaba 413", status)
+	}
+	if status := post(`{"texto": `); status != http.StatusBadRequest {
+		t.Errorf("cuerpo inválido: status = %d, se esperaba 400", status)
+	}
+}
@@ -0,0 +1,433 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"danich/pkg/advisor"
+)
+
+// Valores por defecto de las herramientas del asistente
+const (
+	askHistoryHours  = 8
+	askChangesHours  = 72
+	askChangesLimit  = 10
+	askHistorySample = 12 // Puntos de muestra por serie de historial
+)
+
+// Assistant responde preguntas sobre la planta con el LLM configurado. El
+// modelo consulta los datos del monitor a través de herramientas: estado
+// actual, historial de un SKU, registro de cambios y simulación what-if.
+// Las herramientas solo leen a través de Store, que se puede usar mientras el
+// loop guarda.
+type Assistant struct {
+	store       Store
+	persistence *Persistence
+	whatIf      *WhatIfSimulator
+	advisor     *advisor.Advisor
+}
+
+// NewAssistant crea un asistente sobre el almacenamiento del monitor
+func NewAssistant(config *SystemConfig, store Store, persistence *Persistence, adv *advisor.Advisor) *Assistant {
+	return &Assistant{
+		store:       store,
+		persistence: persistence,
+		whatIf:      NewWhatIfSimulator(config),
+		advisor:     adv,
+	}
+}
+
+// Ask responde una pregunta. Si backend es nil se usa el modelo de Ollama en uso.
+func (as *Assistant) Ask(ctx context.Context, question string, backend advisor.ChatBackend) (*advisor.Answer, error) {
+	if backend == nil {
+		var err error
+		if backend, err = as.advisor.ChatBackend(); err != nil {
+			return nil, err
+		}
+	}
+	return as.advisor.Ask(ctx, question, as.Tools(), backend)
+}
+
+// Tools retorna las herramientas que puede usar el modelo
+func (as *Assistant) Tools() []advisor.ChatTool {
+	sorterID := advisor.ToolProperty{Type: "integer", Description: "Sorter (1 o 2)"}
+	sku := advisor.ToolProperty{Type: "string", Description: "SKU o prefijo de SKU, por ejemplo 3J o 3J-D"}
+
+	edit := advisor.ToolProperty{
+		Type: "object",
+		Properties: map[string]advisor.ToolProperty{
+			"accion":    {Type: "string", Enum: []string{WhatIfAddSalida, WhatIfRemoveSalida, WhatIfMoveSKU}},
+			"sku":       {Type: "string", Description: "SKU completo"},
+			"sorter_id": {Type: "integer", Description: "Sorter actual del SKU"},
+			"salida":    {Type: "integer", Description: "Salida a agregar o quitar"},
+			"a_sorter":  {Type: "integer", Description: "Sorter de destino (mover_sku)"},
+			"salidas":   {Type: "array", Items: &advisor.ToolProperty{Type: "integer"}, Description: "Salidas en el sorter de destino (mover_sku)"},
+		},
+	}
+
+	return []advisor.ChatTool{
+		advisor.NewChatTool("estado_actual",
+			"Carga actual de los sorters: porcentaje y salidas de cada SKU, y participación y utilización de cada salida.",
+			map[string]advisor.ToolProperty{"sorter_id": sorterID}, nil, as.currentState),
+		advisor.NewChatTool("historial_sku",
+			"Evolución del porcentaje de un SKU en los gráficos de los sorters durante las últimas horas.",
+			map[string]advisor.ToolProperty{
+				"sku":       sku,
+				"sorter_id": sorterID,
+				"horas":     {Type: "number", Description: fmt.Sprintf("Horas hacia atrás (por defecto %d)", askHistoryHours)},
+			}, []string{"sku"}, as.skuHistory),
+		advisor.NewChatTool("buscar_cambios",
+			"Busca en el registro de cambios de assignments (salidas agregadas o quitadas, SKUs movidos, introducidos o retirados), del más reciente al más antiguo.",
+			map[string]advisor.ToolProperty{
+				"sku":    sku,
+				"tipo":   {Type: "string", Enum: []string{EventLineAdded, EventLineRemoved, EventSKUMoved, EventSKUIntroduced, EventSKURetired}},
+				"horas":  {Type: "number", Description: fmt.Sprintf("Horas hacia atrás (por defecto %d)", askChangesHours)},
+				"limite": {Type: "integer", Description: fmt.Sprintf("Cantidad máxima de cambios (por defecto %d)", askChangesLimit)},
+			}, nil, as.searchChanges),
+		advisor.NewChatTool("simular",
+			"Simula cambios hipotéticos de assignments sobre el estado actual y compara la carga de las salidas y los desbalances antes y después. No modifica nada.",
+			map[string]advisor.ToolProperty{
+				"ediciones": {Type: "array", Items: &edit, Description: "Cambios a simular, en orden"},
+			}, []string{"ediciones"}, as.simulate),
+	}
+}
+
+// askSKU es un SKU en la respuesta de estado_actual
+type askSKU struct {
+	SKU        string  `json:"sku"`
+	Percentage float64 `json:"porcentaje"`
+	Salidas    []int   `json:"salidas"`
+}
+
+// askSalida es una salida en la respuesta de estado_actual
+type askSalida struct {
+	Salida      int      `json:"salida"`
+	SKUs        []string `json:"skus"`
+	Share       float64  `json:"participacion"` // Porcentaje del sorter que recibe la salida
+	Load        float64  `json:"carga,omitempty"`
+	Capacity    float64  `json:"capacidad,omitempty"`
+	Utilization float64  `json:"utilizacion,omitempty"`
+}
+
+// askSorter es un sorter en la respuesta de estado_actual
+type askSorter struct {
+	SorterID   int         `json:"sorter_id"`
+	Throughput float64     `json:"entrada,omitempty"`
+	SKUs       []askSKU    `json:"skus"`    // De mayor a menor porcentaje
+	Salidas    []askSalida `json:"salidas"` // De mayor a menor participación
+}
+
+// currentState implementa estado_actual
+func (as *Assistant) currentState(ctx context.Context, args advisor.ToolArgs) (any, error) {
+	snapshot, err := LatestSnapshot(as.store)
+	if err != nil {
+		return nil, err
+	}
+	only := args.Int("sorter_id", 0)
+
+	result := struct {
+		Timestamp string      `json:"timestamp"`
+		Shift     string      `json:"turno,omitempty"`
+		Unit      string      `json:"unidad,omitempty"`
+		Sorters   []askSorter `json:"sorters"`
+	}{Timestamp: snapshot.Timestamp, Shift: snapshot.Shift}
+	if snapshot.Capacity != nil {
+		result.Unit = snapshot.Capacity.UnitLabel()
+	}
+
+	sorterIDs := make([]int, 0, len(snapshot.ChartData))
+	for id := range snapshot.ChartData {
+		if only == 0 || id == only {
+			sorterIDs = append(sorterIDs, id)
+		}
+	}
+	sort.Ints(sorterIDs)
+
+	shares := lineShares(snapshot)
+	for _, id := range sorterIDs {
+		chartData := snapshot.ChartData[id]
+		if chartData == nil {
+			continue
+		}
+		sorter := askSorter{SorterID: id, SKUs: []askSKU{}, Salidas: []askSalida{}}
+		for _, sku := range orderedChartSKUs(chartData) {
+			salidas := getLinesForSKU(snapshot.Assignments, id, sku)
+			sort.Ints(salidas)
+			sorter.SKUs = append(sorter.SKUs, askSKU{SKU: sku, Percentage: round1(chartData.Percentages[sku]), Salidas: salidas})
+		}
+		sort.SliceStable(sorter.SKUs, func(i, j int) bool { return sorter.SKUs[i].Percentage > sorter.SKUs[j].Percentage })
+
+		for _, line := range shares {
+			if line.SorterID != id {
+				continue
+			}
+			salida := askSalida{Salida: line.Salida, SKUs: line.SKUs, Share: round1(line.Percentage)}
+			if load, ok := lineLoad(snapshot.Capacity, id, line.Salida); ok {
+				salida.Load = round1(load.Load)
+				salida.Capacity = round1(load.Capacity)
+				salida.Utilization = math.Round(load.Utilization*100) / 100
+			}
+			sorter.Salidas = append(sorter.Salidas, salida)
+		}
+		sort.SliceStable(sorter.Salidas, func(i, j int) bool { return sorter.Salidas[i].Share > sorter.Salidas[j].Share })
+
+		if snapshot.Capacity != nil {
+			for _, sc := range snapshot.Capacity.Sorters {
+				if sc.SorterID == id {
+					sorter.Throughput = round1(sc.Throughput)
+				}
+			}
+		}
+		result.Sorters = append(result.Sorters, sorter)
+	}
+
+	if only != 0 && len(result.Sorters) == 0 {
+		return nil, fmt.Errorf("el snapshot no tiene datos del sorter %d", only)
+	}
+	return result, nil
+}
+
+// askSeries resume el porcentaje de un SKU en un sorter
+type askSeries struct {
+	SorterID int        `json:"sorter_id"`
+	SKU      string     `json:"sku"`
+	Points   int        `json:"puntos"`
+	First    askPoint   `json:"primero"`
+	Last     askPoint   `json:"ultimo"`
+	Min      askPoint   `json:"minimo"`
+	Max      askPoint   `json:"maximo"`
+	Average  float64    `json:"promedio"`
+	Samples  []askPoint `json:"muestras"` // Puntos equiespaciados de la serie
+	points   []askPoint // Serie completa, solo para armar el resumen
+	sum      float64
+}
+
+// askPoint es un porcentaje en un instante
+type askPoint struct {
+	Time       string  `json:"hora"` // 2006-01-02 15:04
+	Percentage float64 `json:"porcentaje"`
+}
+
+// skuHistory implementa historial_sku
+func (as *Assistant) skuHistory(ctx context.Context, args advisor.ToolArgs) (any, error) {
+	sku := args.String("sku")
+	if sku == "" {
+		return nil, fmt.Errorf("falta el parámetro sku")
+	}
+	hours := args.Float("horas", askHistoryHours)
+	if hours <= 0 {
+		hours = askHistoryHours
+	}
+
+	to := time.Now()
+	from := to.Add(-time.Duration(hours * float64(time.Hour)))
+	points, err := as.store.PercentageHistory(PercentageQuery{
+		SKU:      sku,
+		SorterID: args.Int("sorter_id", 0),
+		From:     from,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		sorterID int
+		sku      string
+	}
+	series := make(map[key]*askSeries)
+	var order []key
+	for _, point := range points {
+		k := key{point.SorterID, point.SKU}
+		s := series[k]
+		if s == nil {
+			s = &askSeries{SorterID: point.SorterID, SKU: point.SKU}
+			series[k] = s
+			order = append(order, k)
+		}
+		p := askPoint{Time: point.DateTime.Format("2006-01-02 15:04"), Percentage: round1(point.Percentage)}
+		if len(s.points) == 0 || p.Percentage < s.Min.Percentage {
+			s.Min = p
+		}
+		if len(s.points) == 0 || p.Percentage > s.Max.Percentage {
+			s.Max = p
+		}
+		s.points = append(s.points, p)
+		s.sum += point.Percentage
+	}
+
+	result := struct {
+		SKU    string      `json:"sku"`
+		Desde  string      `json:"desde"`
+		Series []askSeries `json:"series"`
+	}{SKU: strings.ToUpper(sku), Desde: from.Format("2006-01-02 15:04"), Series: []askSeries{}}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].sorterID != order[j].sorterID {
+			return order[i].sorterID < order[j].sorterID
+		}
+		return order[i].sku < order[j].sku
+	})
+	for _, k := range order {
+		s := series[k]
+		s.Points = len(s.points)
+		s.First = s.points[0]
+		s.Last = s.points[len(s.points)-1]
+		s.Average = round1(s.sum / float64(len(s.points)))
+		s.Samples = samplePoints(s.points, askHistorySample)
+		result.Series = append(result.Series, *s)
+	}
+	return result, nil
+}
+
+// samplePoints elige hasta n puntos equiespaciados, incluyendo el primero y el último
+func samplePoints(points []askPoint, n int) []askPoint {
+	if len(points) <= n {
+		return points
+	}
+	samples := make([]askPoint, 0, n)
+	for i := 0; i < n; i++ {
+		samples = append(samples, points[i*(len(points)-1)/(n-1)])
+	}
+	return samples
+}
+
+// askChange es un cambio en la respuesta de buscar_cambios
+type askChange struct {
+	Timestamp   string        `json:"timestamp"`
+	Shift       string        `json:"turno,omitempty"`
+	Description string        `json:"descripcion"`
+	Events      []ChangeEvent `json:"eventos,omitempty"`
+	LoadShifts  []LoadShift   `json:"cambios_carga,omitempty"`
+}
+
+// searchChanges implementa buscar_cambios
+func (as *Assistant) searchChanges(ctx context.Context, args advisor.ToolArgs) (any, error) {
+	hours := args.Float("horas", askChangesHours)
+	if hours <= 0 {
+		hours = askChangesHours
+	}
+	limit := args.Int("limite", askChangesLimit)
+	if limit <= 0 {
+		limit = askChangesLimit
+	}
+	sku := normalizeSKU(args.String("sku"))
+	eventType := args.String("tipo")
+
+	from := time.Now().Add(-time.Duration(hours * float64(time.Hour)))
+	changes, err := as.store.Changes(from, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []askChange{}
+	total := 0
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		match := askChange{Timestamp: change.Timestamp, Shift: change.Shift, Description: change.Description}
+		for _, event := range change.Events {
+			if (sku == "" || strings.HasPrefix(normalizeSKU(event.SKU), sku)) && (eventType == "" || event.Type == eventType) {
+				match.Events = append(match.Events, event)
+			}
+		}
+		if eventType == "" {
+			for _, shift := range change.LoadShifts {
+				if sku == "" || strings.HasPrefix(normalizeSKU(shift.SKU), sku) {
+					match.LoadShifts = append(match.LoadShifts, shift)
+				}
+			}
+		}
+		if len(match.Events) == 0 && len(match.LoadShifts) == 0 {
+			continue
+		}
+		total++
+		if len(matches) < limit {
+			matches = append(matches, match)
+		}
+	}
+
+	return struct {
+		Desde   string      `json:"desde"`
+		Total   int         `json:"total"`
+		Cambios []askChange `json:"cambios"`
+	}{from.Format("2006-01-02 15:04"), total, matches}, nil
+}
+
+// simulate implementa simular
+func (as *Assistant) simulate(ctx context.Context, args advisor.ToolArgs) (any, error) {
+	data, err := json.Marshal(args["ediciones"])
+	if err != nil {
+		return nil, err
+	}
+	var edits []WhatIfEdit
+	if err := json.Unmarshal(data, &edits); err != nil {
+		return nil, fmt.Errorf("ediciones inválidas: %w", err)
+	}
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no hay ediciones para simular")
+	}
+
+	snapshot, err := LatestSnapshot(as.store)
+	if err != nil {
+		return nil, err
+	}
+	manual, err := as.persistence.LoadThroughput()
+	if err != nil {
+		return nil, err
+	}
+	result, err := as.whatIf.Simulate(snapshot, manual, edits)
+	if err != nil {
+		return nil, err
+	}
+
+	// Solo lo que cambia: el estado completo ya está en estado_actual
+	descriptions := make([]string, len(result.Edits))
+	for i, edit := range result.Edits {
+		descriptions[i] = edit.String()
+	}
+	return struct {
+		Timestamp  string              `json:"timestamp"`
+		Edits      []string            `json:"ediciones"`
+		Changes    []WhatIfLineChange  `json:"salidas_que_cambian"`
+		MaxBefore  float64             `json:"prioridad_max_antes"`
+		MaxAfter   float64             `json:"prioridad_max_despues"`
+		Imbalances []advisor.Imbalance `json:"desbalances_despues"`
+	}{
+		Timestamp:  result.Timestamp,
+		Edits:      descriptions,
+		Changes:    result.Changes,
+		MaxBefore:  round1(result.Before.MaxPriority),
+		MaxAfter:   round1(result.After.MaxPriority),
+		Imbalances: firstImbalances(result.After.Imbalances, 3),
+	}, nil
+}
+
+// lineLoad busca la carga absoluta de una salida en el reporte de capacidad
+func lineLoad(report *CapacityReport, sorterID, salida int) (LineLoad, bool) {
+	if report == nil {
+		return LineLoad{}, false
+	}
+	for _, line := range report.Lines {
+		if line.SorterID == sorterID && line.Salida == salida {
+			return line, true
+		}
+	}
+	return LineLoad{}, false
+}
+
+// firstImbalances retorna los primeros n desbalances
+func firstImbalances(imbalances []advisor.Imbalance, n int) []advisor.Imbalance {
+	if len(imbalances) > n {
+		return imbalances[:n]
+	}
+	return imbalances
+}
+
+// round1 redondea a un decimal
+func round1(value float64) float64 {
+	return math.Round(value*10) / 10
+}
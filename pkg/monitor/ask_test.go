@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"danich/pkg/advisor"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// Las herramientas leen el histórico mientras el loop sigue guardando
+// snapshots y cambios (go test -race)
+func TestAssistantToolsWhileSaving(t *testing.T) {
+	cfg := testConfig(t)
+	persistence := NewPersistence(cfg, discardLogger())
+	if err := persistence.EnsureDataFolder(); err != nil {
+		t.Fatal(err)
+	}
+	store := NewJSONStore(persistence)
+	start := time.Now().Add(-time.Hour)
+	if err := store.SaveSnapshot(testSnapshot(start, 1, map[string]float64{"3J-D": 40})); err != nil {
+		t.Fatal(err)
+	}
+
+	assistant := NewAssistant(cfg, store, persistence, advisor.NewAdvisor(cfg.Advisor, discardLogger()))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 30; i++ {
+			at := start.Add(time.Duration(i) * time.Minute)
+			store.SaveSnapshot(testSnapshot(at, 1, map[string]float64{"3J-D": 40 + float64(i)}))
+			store.SaveChange(ChangeLog{Timestamp: at.Format("2006-01-02 15:04:05"), Description: "cambio"})
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		backend := &advisor.ScriptedChat{Replies: []advisor.ChatMessage{
+			{ToolCalls: []advisor.ToolCall{
+				{Function: advisor.ToolCallFunction{Name: "estado_actual"}},
+				{Function: advisor.ToolCallFunction{Name: "historial_sku", Arguments: advisor.ToolArgs{"sku": "3j"}}},
+				{Function: advisor.ToolCallFunction{Name: "buscar_cambios"}},
+			}},
+			{Content: "listo"},
+		}}
+		answer, err := assistant.Ask(context.Background(), "¿cómo va 3J?", backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, step := range answer.Steps {
+			if step.Error != "" {
+				t.Errorf("%s: %s", step.Name, step.Error)
+			}
+		}
+	}
+	wg.Wait()
+}
+
+func TestAPIAskRequiresViewer(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": map[string]string{"role": "assistant", "content": "Todo en orden"},
+		})
+	}))
+	defer ollama.Close()
+
+	cfg := testConfig(t)
+	cfg.Advisor.OllamaURL = ollama.URL
+	cfg.APIKeys = []APIKey{
+		{Name: "pantalla", Role: RoleViewer, SHA256: HashAPIKey("clave-lector")},
+		{Name: "jefe", Role: RoleSupervisor, SHA256: HashAPIKey("clave-supervisor")},
+	}
+	persistence := NewPersistence(cfg, discardLogger())
+	if err := persistence.EnsureDataFolder(); err != nil {
+		t.Fatal(err)
+	}
+	store := NewJSONStore(persistence)
+	assistant := NewAssistant(cfg, store, persistence, advisor.NewAdvisor(cfg.Advisor, discardLogger()))
+	api := httptest.NewServer(NewAPIServer(cfg, persistence, store, assistant, nil, discardLogger()).mux)
+	defer api.Close()
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{"sin credenciales", "", "", http.StatusUnauthorized},
+		{"clave inválida", "X-API-Key", "otra-clave", http.StatusUnauthorized},
+		{"lector", "X-API-Key", "clave-lector", http.StatusOK},
+		{"supervisor con bearer", "Authorization", "Bearer clave-supervisor", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, api.URL+"/api/ask", strings.NewReader(`{"pregunta": "¿cómo va la planta?"}`))
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, se esperaba %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				if resp.Header.Get("WWW-Authenticate") == "" {
+					t.Error("falta WWW-Authenticate")
+				}
+				return
+			}
+			var answer advisor.Answer
+			if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+				t.Fatal(err)
+			}
+			if answer.Text != "Todo en orden" {
+				t.Errorf("respuesta = %q", answer.Text)
+			}
+		})
+	}
+}
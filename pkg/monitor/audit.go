@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"net/http"
+	"time"
+)
+
+// Resultados de una acción auditada
+const (
+	AuditOK     = "ok"
+	AuditDenied = "denegado"
+	AuditError  = "error"
+)
+
+// AuditEntry es una acción privilegiada sobre la API: quién, desde dónde,
+// qué y con qué resultado
+type AuditEntry struct {
+	DateTime  time.Time `json:"datetime"`
+	Usuario   string    `json:"usuario"`
+	Rol       string    `json:"rol"`
+	Metodo    string    `json:"metodo"` // clave | usuario | abierto
+	Remoto    string    `json:"remoto"`
+	Accion    string    `json:"accion"`
+	Detalle   string    `json:"detalle,omitempty"`
+	Resultado string    `json:"resultado"` // ok | denegado | error
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditQuery filtra la auditoría (valores vacíos = sin filtro)
+type AuditQuery struct {
+	From    time.Time
+	To      time.Time
+	Usuario string
+	Accion  string
+}
+
+// Matches indica si una entrada cumple el filtro
+func (q AuditQuery) Matches(entry AuditEntry) bool {
+	if !q.From.IsZero() && entry.DateTime.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !entry.DateTime.Before(q.To) {
+		return false
+	}
+	if q.Usuario != "" && entry.Usuario != q.Usuario {
+		return false
+	}
+	if q.Accion != "" && entry.Accion != q.Accion {
+		return false
+	}
+	return true
+}
+
+// newAuditEntry arma la entrada de una acción hecha en un pedido a la API
+func newAuditEntry(r *http.Request, accion, detalle string, err error) AuditEntry {
+	principal := PrincipalFrom(r.Context())
+	entry := AuditEntry{
+		DateTime:  time.Now(),
+		Usuario:   principal.Name,
+		Rol:       principal.Role,
+		Metodo:    principal.Method,
+		Remoto:    r.RemoteAddr,
+		Accion:    accion,
+		Detalle:   detalle,
+		Resultado: AuditOK,
+	}
+	if err != nil {
+		entry.Resultado = AuditError
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// truncateText acorta un texto para el detalle de la auditoría
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,279 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles de acceso a la API, de menor a mayor
+const (
+	RoleViewer     = "lector"     // Consulta el estado, el histórico y el asistente
+	RoleSupervisor = "supervisor" // Además reconoce sugerencias, anota y ajusta el throughput
+	RoleAdmin      = "admin"      // Además recarga la configuración, exporta datos y lee la auditoría
+)
+
+// Métodos de autenticación
+const (
+	AuthKey  = "clave"
+	AuthUser = "usuario"
+	AuthOpen = "abierto" // Sin credenciales configuradas
+)
+
+// minPasswordLength es el largo mínimo de las contraseñas de usuarios locales
+const minPasswordLength = 8
+
+// authCacheTTL es el tiempo que se recuerda una contraseña verificada. El
+// dashboard envía las credenciales en cada pedido y bcrypt es lento a propósito.
+const authCacheTTL = 5 * time.Minute
+
+// authCacheLimit acota las contraseñas recordadas
+const authCacheLimit = 256
+
+var roleLevels = map[string]int{RoleViewer: 1, RoleSupervisor: 2, RoleAdmin: 3}
+
+var (
+	errNoCredentials  = errors.New("faltan credenciales")
+	errBadCredentials = errors.New("credenciales inválidas")
+)
+
+// APIKey es una clave de API. Solo se guarda su SHA-256: las claves son
+// aleatorias, así que no necesitan un hash lento.
+type APIKey struct {
+	Name   string
+	Role   string
+	SHA256 string // Hex
+}
+
+// APIUser es un usuario local con su contraseña en bcrypt
+type APIUser struct {
+	Username string
+	Role     string
+	Hash     string
+}
+
+// Principal es quien hace un pedido a la API
+type Principal struct {
+	Name   string
+	Role   string
+	Method string // clave | usuario | abierto
+}
+
+// RoleAllows indica si role alcanza el rol requerido
+func RoleAllows(role, required string) bool {
+	return roleLevels[role] >= roleLevels[required]
+}
+
+// parseAPIKey valida una clave de API de la configuración
+func parseAPIKey(clave APIClaveConfig) (APIKey, error) {
+	if clave.Nombre == "" {
+		return APIKey{}, fmt.Errorf("clave sin nombre")
+	}
+	if _, ok := roleLevels[clave.Rol]; !ok {
+		return APIKey{}, fmt.Errorf("clave %q: rol inválido %q (lector, supervisor o admin)", clave.Nombre, clave.Rol)
+	}
+	hash := strings.ToLower(clave.SHA256)
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+		return APIKey{}, fmt.Errorf("clave %q: sha256 debe tener 64 dígitos hex", clave.Nombre)
+	}
+	return APIKey{Name: clave.Nombre, Role: clave.Rol, SHA256: hash}, nil
+}
+
+// parseAPIUser valida un usuario local de la configuración
+func parseAPIUser(usuario APIUsuarioConfig) (APIUser, error) {
+	if usuario.Usuario == "" || strings.Contains(usuario.Usuario, ":") {
+		return APIUser{}, fmt.Errorf("nombre de usuario inválido %q", usuario.Usuario)
+	}
+	if _, ok := roleLevels[usuario.Rol]; !ok {
+		return APIUser{}, fmt.Errorf("usuario %q: rol inválido %q (lector, supervisor o admin)", usuario.Usuario, usuario.Rol)
+	}
+	if _, err := bcrypt.Cost([]byte(usuario.Bcrypt)); err != nil {
+		return APIUser{}, fmt.Errorf("usuario %q: hash bcrypt inválido", usuario.Usuario)
+	}
+	return APIUser{Username: usuario.Usuario, Role: usuario.Rol, Hash: usuario.Bcrypt}, nil
+}
+
+// NewAPIKeySecret genera una clave de API aleatoria y su SHA-256
+func NewAPIKeySecret() (key, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	key = "dnch_" + base64.RawURLEncoding.EncodeToString(buf)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey retorna el SHA-256 hex de una clave de API
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPassword retorna el hash bcrypt de una contraseña
+func HashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("la contraseña debe tener al menos %d caracteres", minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Authenticator valida las credenciales de los pedidos a la API. Acepta
+// claves (Authorization: Bearer o X-API-Key) y usuarios locales (Basic, que
+// los navegadores piden solos al abrir el dashboard).
+type Authenticator struct {
+	mu       sync.RWMutex
+	keys     []APIKey
+	users    map[string]APIUser
+	verified map[string]time.Time // SHA-256 de usuario y contraseña -> vencimiento
+
+	dummyOnce sync.Once
+	dummyHash []byte // Para que un usuario inexistente tarde lo mismo
+}
+
+// NewAuthenticator crea un autenticador con las credenciales configuradas
+func NewAuthenticator(keys []APIKey, users []APIUser) *Authenticator {
+	a := &Authenticator{}
+	a.Update(keys, users)
+	return a
+}
+
+// Update reemplaza las credenciales (recarga de configuración)
+func (a *Authenticator) Update(keys []APIKey, users []APIUser) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.keys = append([]APIKey(nil), keys...)
+	a.users = make(map[string]APIUser, len(users))
+	for _, user := range users {
+		a.users[user.Username] = user
+	}
+	a.verified = make(map[string]time.Time)
+}
+
+// Enabled indica si hay credenciales configuradas. Sin credenciales la API
+// queda abierta, como antes de tener autenticación.
+func (a *Authenticator) Enabled() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.keys) > 0 || len(a.users) > 0
+}
+
+// HasUsers indica si hay usuarios locales (el navegador puede pedir usuario
+// y contraseña)
+func (a *Authenticator) HasUsers() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.users) > 0
+}
+
+// Authenticate identifica a quien hace el pedido
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if !a.Enabled() {
+		return Principal{Name: "anónimo", Role: RoleAdmin, Method: AuthOpen}, nil
+	}
+
+	if key := requestAPIKey(r); key != "" {
+		return a.checkKey(key)
+	}
+	if username, password, ok := r.BasicAuth(); ok {
+		return a.checkUser(username, password)
+	}
+	return Principal{}, errNoCredentials
+}
+
+// requestAPIKey retorna la clave de API del pedido, si tiene
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// checkKey busca la clave comparando todos los hashes en tiempo constante
+func (a *Authenticator) checkKey(key string) (Principal, error) {
+	hash := []byte(HashAPIKey(key))
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var found *APIKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(hash, []byte(a.keys[i].SHA256)) == 1 {
+			found = &a.keys[i]
+		}
+	}
+	if found == nil {
+		return Principal{}, errBadCredentials
+	}
+	return Principal{Name: found.Name, Role: found.Role, Method: AuthKey}, nil
+}
+
+// checkUser verifica usuario y contraseña, recordando las verificaciones
+// exitosas por un rato
+func (a *Authenticator) checkUser(username, password string) (Principal, error) {
+	sum := sha256.Sum256([]byte(username + "\x00" + password))
+	cacheKey := string(sum[:])
+
+	a.mu.RLock()
+	user, exists := a.users[username]
+	expires, cached := a.verified[cacheKey]
+	a.mu.RUnlock()
+
+	principal := Principal{Name: user.Username, Role: user.Role, Method: AuthUser}
+	if exists && cached && time.Now().Before(expires) {
+		return principal, nil
+	}
+
+	if !exists {
+		bcrypt.CompareHashAndPassword(a.dummy(), []byte(password))
+		return Principal{}, errBadCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Hash), []byte(password)); err != nil {
+		return Principal{}, errBadCredentials
+	}
+
+	a.mu.Lock()
+	if len(a.verified) >= authCacheLimit {
+		a.verified = make(map[string]time.Time)
+	}
+	a.verified[cacheKey] = time.Now().Add(authCacheTTL)
+	a.mu.Unlock()
+
+	return principal, nil
+}
+
+// dummy retorna un hash bcrypt cualquiera para comparar con usuarios inexistentes
+func (a *Authenticator) dummy() []byte {
+	a.dummyOnce.Do(func() {
+		a.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("danich-usuario-inexistente"), bcrypt.DefaultCost)
+	})
+	return a.dummyHash
+}
+
+type principalKey struct{}
+
+// withPrincipal agrega al contexto quien hace el pedido
+func withPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom retorna quien hace el pedido (vacío si no pasó por la autenticación)
+func PrincipalFrom(ctx context.Context) Principal {
+	principal, _ := ctx.Value(principalKey{}).(Principal)
+	return principal
+}
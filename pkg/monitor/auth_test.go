@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role, required string
+		want           bool
+	}{
+		{RoleViewer, RoleViewer, true},
+		{RoleViewer, RoleSupervisor, false},
+		{RoleViewer, RoleAdmin, false},
+		{RoleSupervisor, RoleViewer, true},
+		{RoleSupervisor, RoleSupervisor, true},
+		{RoleSupervisor, RoleAdmin, false},
+		{RoleAdmin, RoleViewer, true},
+		{RoleAdmin, RoleAdmin, true},
+		{"", RoleViewer, false},
+		{"root", RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := RoleAllows(tt.role, tt.required); got != tt.want {
+			t.Errorf("RoleAllows(%q, %q) = %v, esperaba %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestParseAPIKey(t *testing.T) {
+	hash := HashAPIKey("dnch_prueba")
+	tests := []struct {
+		name    string
+		clave   APIClaveConfig
+		wantErr string
+	}{
+		{"válida", APIClaveConfig{Nombre: "tablero", Rol: RoleViewer, SHA256: hash}, ""},
+		{"hash en mayúsculas", APIClaveConfig{Nombre: "tablero", Rol: RoleAdmin, SHA256: strings.ToUpper(hash)}, ""},
+		{"sin nombre", APIClaveConfig{Rol: RoleViewer, SHA256: hash}, "sin nombre"},
+		{"rol inválido", APIClaveConfig{Nombre: "tablero", Rol: "root", SHA256: hash}, "rol inválido"},
+		{"hash corto", APIClaveConfig{Nombre: "tablero", Rol: RoleViewer, SHA256: hash[:40]}, "64 dígitos"},
+		{"hash no hex", APIClaveConfig{Nombre: "tablero", Rol: RoleViewer, SHA256: strings.Repeat("z", 64)}, "64 dígitos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseAPIKey(tt.clave)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, esperaba %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.SHA256 != hash || key.Role != tt.clave.Rol {
+				t.Errorf("clave = %+v", key)
+			}
+		})
+	}
+}
+
+func TestParseAPIUser(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("contraseña"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		usuario APIUsuarioConfig
+		wantErr string
+	}{
+		{"válido", APIUsuarioConfig{Usuario: "ana", Rol: RoleSupervisor, Bcrypt: string(hash)}, ""},
+		{"sin nombre", APIUsuarioConfig{Rol: RoleSupervisor, Bcrypt: string(hash)}, "nombre de usuario inválido"},
+		{"dos puntos en el nombre", APIUsuarioConfig{Usuario: "ana:b", Rol: RoleSupervisor, Bcrypt: string(hash)}, "nombre de usuario inválido"},
+		{"rol inválido", APIUsuarioConfig{Usuario: "ana", Rol: "jefa", Bcrypt: string(hash)}, "rol inválido"},
+		{"hash inválido", APIUsuarioConfig{Usuario: "ana", Rol: RoleSupervisor, Bcrypt: "contraseña"}, "bcrypt inválido"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseAPIUser(tt.usuario)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error = %v, esperaba %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("contraseña"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuthenticator(
+		[]APIKey{
+			{Name: "tablero", Role: RoleViewer, SHA256: HashAPIKey("dnch_lector")},
+			{Name: "operacion", Role: RoleAdmin, SHA256: HashAPIKey("dnch_admin")},
+		},
+		[]APIUser{{Username: "ana", Role: RoleSupervisor, Hash: string(hash)}},
+	)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		basic   []string // Usuario y contraseña
+		want    Principal
+		wantErr error
+	}{
+		{name: "sin credenciales", wantErr: errNoCredentials},
+		{
+			name:    "X-API-Key",
+			headers: map[string]string{"X-API-Key": "dnch_lector"},
+			want:    Principal{Name: "tablero", Role: RoleViewer, Method: AuthKey},
+		},
+		{
+			name:    "Bearer sin distinguir mayúsculas",
+			headers: map[string]string{"Authorization": "bearer dnch_admin "},
+			want:    Principal{Name: "operacion", Role: RoleAdmin, Method: AuthKey},
+		},
+		{
+			name:    "X-API-Key tiene prioridad",
+			headers: map[string]string{"X-API-Key": "dnch_lector", "Authorization": "Bearer dnch_admin"},
+			want:    Principal{Name: "tablero", Role: RoleViewer, Method: AuthKey},
+		},
+		{
+			name:    "clave desconocida",
+			headers: map[string]string{"X-API-Key": "dnch_otra"},
+			wantErr: errBadCredentials,
+		},
+		{
+			name:  "usuario local",
+			basic: []string{"ana", "contraseña"},
+			want:  Principal{Name: "ana", Role: RoleSupervisor, Method: AuthUser},
+		},
+		{
+			name:  "usuario local desde el caché",
+			basic: []string{"ana", "contraseña"},
+			want:  Principal{Name: "ana", Role: RoleSupervisor, Method: AuthUser},
+		},
+		{name: "contraseña incorrecta", basic: []string{"ana", "otra"}, wantErr: errBadCredentials},
+		{name: "usuario inexistente", basic: []string{"beto", "contraseña"}, wantErr: errBadCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/status", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			if tt.basic != nil {
+				r.SetBasicAuth(tt.basic[0], tt.basic[1])
+			}
+
+			got, err := auth.Authenticate(r)
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, esperaba %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("principal = %+v, esperaba %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateOpen(t *testing.T) {
+	auth := NewAuthenticator(nil, nil)
+	got, err := auth.Authenticate(httptest.NewRequest("GET", "/api/status", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Role != RoleAdmin || got.Method != AuthOpen {
+		t.Errorf("principal = %+v, esperaba admin abierto", got)
+	}
+}
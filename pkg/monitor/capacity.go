@@ -0,0 +1,229 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Unidades de capacidad y throughput (por minuto)
+const (
+	CapacityUnitBoxes = "cajas"
+	CapacityUnitKg    = "kg"
+)
+
+// Orígenes del throughput de un sorter
+const (
+	ThroughputFromConfig = "config"
+	ThroughputFromManual = "manual"
+)
+
+// LineCapacity es la capacidad de una línea (salida) de un sorter
+type LineCapacity struct {
+	SorterID  int
+	Salida    int
+	PerMinute float64
+}
+
+// ThroughputEntry es un throughput ingresado manualmente (API o CLI)
+type ThroughputEntry struct {
+	SorterID  int       `json:"sorter_id"`
+	PerMinute float64   `json:"por_minuto"`
+	UpdatedAt time.Time `json:"actualizado"`
+}
+
+// CapacityReport expresa la carga del snapshot en unidades reales
+type CapacityReport struct {
+	Unit    string           `json:"unit"` // Por minuto
+	Sorters []SorterCapacity `json:"sorters"`
+	Lines   []LineLoad       `json:"lines"`
+}
+
+// SorterCapacity resume la entrada y la capacidad total de un sorter
+type SorterCapacity struct {
+	SorterID         int     `json:"sorter_id"`
+	Throughput       float64 `json:"throughput"`
+	ThroughputSource string  `json:"throughput_source"`
+	Capacity         float64 `json:"capacity"`    // Suma de las líneas con SKU
+	Utilization      float64 `json:"utilization"` // Throughput / capacidad
+	Unallocated      float64 `json:"unallocated"` // Volumen de SKUs sin salida asignada
+}
+
+// LineLoad es la carga absoluta de una salida
+type LineLoad struct {
+	SorterID    int      `json:"sorter_id"`
+	Salida      int      `json:"salida"`
+	SKUs        []string `json:"skus"`
+	Load        float64  `json:"load"`
+	Capacity    float64  `json:"capacity"`
+	Utilization float64  `json:"utilization"`
+}
+
+// Overloaded indica si la línea recibe más de lo que puede procesar
+func (ll LineLoad) Overloaded() bool {
+	return ll.Capacity > 0 && ll.Load > ll.Capacity
+}
+
+// Overloaded retorna las líneas sobrecargadas, de mayor a menor utilización
+func (r *CapacityReport) Overloaded() []LineLoad {
+	var lines []LineLoad
+	for _, line := range r.Lines {
+		if line.Overloaded() {
+			lines = append(lines, line)
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Utilization > lines[j].Utilization })
+	return lines
+}
+
+// SKULoad retorna el volumen de un SKU en un sorter y la capacidad de sus líneas
+func (r *CapacityReport) SKULoad(sorterID int, sku string) (volume, capacity float64) {
+	target := normalizeSKU(sku)
+	for _, line := range r.Lines {
+		if line.SorterID != sorterID {
+			continue
+		}
+		for _, lineSKU := range line.SKUs {
+			if normalizeSKU(lineSKU) == target {
+				volume += line.Load / float64(len(line.SKUs))
+				capacity += line.Capacity / float64(len(line.SKUs))
+			}
+		}
+	}
+	return volume, capacity
+}
+
+// UnitLabel retorna la unidad para mostrar (cajas/min, kg/min)
+func (r *CapacityReport) UnitLabel() string {
+	return r.Unit + "/min"
+}
+
+// CapacityModel convierte los porcentajes de los gráficos en volúmenes usando
+// el throughput de cada sorter y la capacidad de cada línea
+type CapacityModel struct {
+	unit        string
+	lineDefault float64
+	lines       map[string]float64 // "sorter-salida" -> capacidad
+	throughput  map[int]float64    // Desde config
+}
+
+// NewCapacityModel crea el modelo a partir de la configuración
+func NewCapacityModel(config *SystemConfig) *CapacityModel {
+	cm := &CapacityModel{
+		unit:        config.CapacityUnit,
+		lineDefault: config.LineCapacity,
+		lines:       make(map[string]float64),
+		throughput:  make(map[int]float64),
+	}
+	for _, line := range config.LineCapacities {
+		cm.lines[lineKey(line.SorterID, line.Salida)] = line.PerMinute
+	}
+	for sorterID, perMinute := range config.SorterThroughput {
+		cm.throughput[sorterID] = perMinute
+	}
+	return cm
+}
+
+// Enabled indica si hay capacidades configuradas
+func (cm *CapacityModel) Enabled() bool {
+	return cm.lineDefault > 0 || len(cm.lines) > 0
+}
+
+// LineCapacity retorna la capacidad de una salida
+func (cm *CapacityModel) LineCapacity(sorterID, salida int) float64 {
+	if capacity, ok := cm.lines[lineKey(sorterID, salida)]; ok {
+		return capacity
+	}
+	return cm.lineDefault
+}
+
+// Compute calcula la carga por salida y la utilización de cada sorter. El
+// throughput manual reemplaza al de la configuración. Los sorters sin
+// throughput o sin gráfico no se incluyen.
+func (cm *CapacityModel) Compute(snapshot DataSnapshot, manual map[int]ThroughputEntry) *CapacityReport {
+	if !cm.Enabled() {
+		return nil
+	}
+
+	report := &CapacityReport{Unit: cm.unit}
+
+	sorterIDs := make([]int, 0, len(snapshot.ChartData))
+	for sorterID, chartData := range snapshot.ChartData {
+		if chartData != nil {
+			sorterIDs = append(sorterIDs, sorterID)
+		}
+	}
+	sort.Ints(sorterIDs)
+
+	for _, sorterID := range sorterIDs {
+		throughput, source := cm.throughput[sorterID], ThroughputFromConfig
+		if entry, ok := manual[sorterID]; ok && entry.PerMinute > 0 {
+			throughput, source = entry.PerMinute, ThroughputFromManual
+		}
+		if throughput <= 0 {
+			continue
+		}
+
+		// Salidas de cada SKU en el sorter
+		salidasBySKU := make(map[string][]int)
+		for _, a := range snapshot.Assignments {
+			if a.SorterID == sorterID {
+				key := normalizeSKU(a.SKU)
+				salidasBySKU[key] = append(salidasBySKU[key], a.Salida)
+			}
+		}
+
+		lines := make(map[int]*LineLoad)
+		sorter := SorterCapacity{SorterID: sorterID, Throughput: throughput, ThroughputSource: source}
+
+		chartData := snapshot.ChartData[sorterID]
+		for _, sku := range orderedChartSKUs(chartData) {
+			volume := throughput * chartData.Percentages[sku] / 100
+			salidas := salidasBySKU[normalizeSKU(sku)]
+			if len(salidas) == 0 {
+				sorter.Unallocated += volume
+				continue
+			}
+
+			// El volumen del SKU se reparte en partes iguales entre sus líneas
+			for _, salida := range salidas {
+				line := lines[salida]
+				if line == nil {
+					line = &LineLoad{SorterID: sorterID, Salida: salida, Capacity: cm.LineCapacity(sorterID, salida)}
+					lines[salida] = line
+				}
+				line.SKUs = append(line.SKUs, sku)
+				line.Load += volume / float64(len(salidas))
+			}
+		}
+
+		salidas := make([]int, 0, len(lines))
+		for salida := range lines {
+			salidas = append(salidas, salida)
+		}
+		sort.Ints(salidas)
+
+		for _, salida := range salidas {
+			line := lines[salida]
+			if line.Capacity > 0 {
+				line.Utilization = line.Load / line.Capacity
+			}
+			sorter.Capacity += line.Capacity
+			report.Lines = append(report.Lines, *line)
+		}
+		if sorter.Capacity > 0 {
+			sorter.Utilization = throughput / sorter.Capacity
+		}
+		report.Sorters = append(report.Sorters, sorter)
+	}
+
+	if len(report.Sorters) == 0 {
+		return nil
+	}
+	return report
+}
+
+// lineKey identifica una salida de un sorter
+func lineKey(sorterID, salida int) string {
+	return fmt.Sprintf("%d-%d", sorterID, salida)
+}
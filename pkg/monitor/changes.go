@@ -1,9 +1,18 @@
 package monitor
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tipos de eventos de cambio en las asignaciones
+const (
+	EventLineAdded     = "line_added"     // Se agregó una salida a un SKU que ya estaba en el sorter
+	EventLineRemoved   = "line_removed"   // Se quitó una salida a un SKU que sigue en el sorter
+	EventSKUMoved      = "sku_moved"      // El SKU dejó un sorter y apareció en otro
+	EventSKUIntroduced = "sku_introduced" // El SKU no estaba asignado en ningún sorter
+	EventSKURetired    = "sku_retired"    // El SKU ya no está asignado en ningún sorter
 )
 
 // ChangeDetector maneja la detección de cambios
@@ -14,90 +23,226 @@ func NewChangeDetector() *ChangeDetector {
 	return &ChangeDetector{}
 }
 
-// HasChanges verifica si hay diferencias entre dos listas de assignments
-func (cd *ChangeDetector) HasChanges(old, new []Assignment) bool {
-	if len(old) != len(new) {
-		return true
-	}
-
-	oldJSON, _ := json.Marshal(old)
-	newJSON, _ := json.Marshal(new)
+// skuPlacement agrupa, para un SKU, el conjunto de salidas en cada sorter
+type skuPlacement struct {
+	sku     string
+	sorters map[int]map[int]bool
+}
 
-	return !bytes.Equal(oldJSON, newJSON)
+// HasChanges verifica si hay diferencias entre dos listas de assignments.
+// El orden de la lista no importa, solo el conjunto de salidas de cada SKU.
+func (cd *ChangeDetector) HasChanges(old, new []Assignment) bool {
+	return len(cd.DetectChanges(old, new).Events) > 0
 }
 
-// DetectChanges identifica los cambios específicos entre dos estados
+// DetectChanges identifica los cambios específicos entre dos estados.
+// Cada SKU se modela como un conjunto de salidas por sorter, de modo que un
+// SKU asignado a varias líneas (ej. [2,5,4]) se compara línea a línea.
 func (cd *ChangeDetector) DetectChanges(old, new []Assignment) ChangeDetail {
-	changes := ChangeDetail{
-		Added:    []Assignment{},
-		Removed:  []Assignment{},
-		Modified: []ModifiedAssignment{},
+	changes := ChangeDetail{Events: []ChangeEvent{}}
+
+	oldMap := groupBySKU(old)
+	newMap := groupBySKU(new)
+
+	keys := make(map[string]bool)
+	for key := range oldMap {
+		keys[key] = true
+	}
+	for key := range newMap {
+		keys[key] = true
 	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, key := range sortedKeys {
+		oldPlacement, inOld := oldMap[key]
+		newPlacement, inNew := newMap[key]
+
+		switch {
+		case !inOld:
+			for _, sorterID := range sortedSorters(newPlacement.sorters) {
+				changes.Events = append(changes.Events, ChangeEvent{
+					Type:     EventSKUIntroduced,
+					SKU:      newPlacement.sku,
+					SorterID: sorterID,
+					Salidas:  sortedSalidas(newPlacement.sorters[sorterID]),
+				})
+			}
 
-	oldMap := make(map[string]Assignment)
-	newMap := make(map[string]Assignment)
+		case !inNew:
+			for _, sorterID := range sortedSorters(oldPlacement.sorters) {
+				changes.Events = append(changes.Events, ChangeEvent{
+					Type:        EventSKURetired,
+					SKU:         oldPlacement.sku,
+					SorterID:    sorterID,
+					PrevSalidas: sortedSalidas(oldPlacement.sorters[sorterID]),
+				})
+			}
 
-	for _, a := range old {
-		key := fmt.Sprintf("%d-%s", a.SorterID, a.SKU)
-		oldMap[key] = a
+		default:
+			changes.Events = append(changes.Events, diffPlacement(oldPlacement, newPlacement)...)
+		}
 	}
 
-	for _, a := range new {
-		key := fmt.Sprintf("%d-%s", a.SorterID, a.SKU)
-		newMap[key] = a
+	return changes
+}
+
+// diffPlacement compara las salidas de un SKU presente en ambos estados
+func diffPlacement(old, new *skuPlacement) []ChangeEvent {
+	var events []ChangeEvent
+	sku := new.sku
+
+	var lost, gained []int
+	for _, sorterID := range sortedSorters(old.sorters) {
+		if _, exists := new.sorters[sorterID]; !exists {
+			lost = append(lost, sorterID)
+		}
+	}
+	for _, sorterID := range sortedSorters(new.sorters) {
+		if _, exists := old.sorters[sorterID]; !exists {
+			gained = append(gained, sorterID)
+		}
 	}
 
-	// Detectar modificados y agregados
-	for key, newAssignment := range newMap {
-		if oldAssignment, exists := oldMap[key]; exists {
-			if oldAssignment.Salida != newAssignment.Salida {
-				changes.Modified = append(changes.Modified, ModifiedAssignment{
-					Old: oldAssignment,
-					New: newAssignment,
+	// Sorters en ambos estados: comparar línea a línea
+	for _, sorterID := range sortedSorters(new.sorters) {
+		oldSalidas, exists := old.sorters[sorterID]
+		if !exists {
+			continue
+		}
+		newSalidas := new.sorters[sorterID]
+
+		for _, salida := range sortedSalidas(newSalidas) {
+			if !oldSalidas[salida] {
+				events = append(events, ChangeEvent{
+					Type:     EventLineAdded,
+					SKU:      sku,
+					SorterID: sorterID,
+					Salida:   salida,
+				})
+			}
+		}
+		for _, salida := range sortedSalidas(oldSalidas) {
+			if !newSalidas[salida] {
+				events = append(events, ChangeEvent{
+					Type:     EventLineRemoved,
+					SKU:      sku,
+					SorterID: sorterID,
+					Salida:   salida,
 				})
 			}
-		} else {
-			changes.Added = append(changes.Added, newAssignment)
 		}
 	}
 
-	// Detectar eliminados
-	for key, oldAssignment := range oldMap {
-		if _, exists := newMap[key]; !exists {
-			changes.Removed = append(changes.Removed, oldAssignment)
+	// Un sorter perdido y uno ganado se emparejan como movimiento
+	for len(lost) > 0 && len(gained) > 0 {
+		from, to := lost[0], gained[0]
+		lost, gained = lost[1:], gained[1:]
+		events = append(events, ChangeEvent{
+			Type:        EventSKUMoved,
+			SKU:         sku,
+			SorterID:    to,
+			FromSorter:  from,
+			Salidas:     sortedSalidas(new.sorters[to]),
+			PrevSalidas: sortedSalidas(old.sorters[from]),
+		})
+	}
+
+	// Lo que sobra son líneas nuevas o quitadas en sorters adicionales
+	for _, sorterID := range gained {
+		for _, salida := range sortedSalidas(new.sorters[sorterID]) {
+			events = append(events, ChangeEvent{
+				Type:     EventLineAdded,
+				SKU:      sku,
+				SorterID: sorterID,
+				Salida:   salida,
+			})
+		}
+	}
+	for _, sorterID := range lost {
+		for _, salida := range sortedSalidas(old.sorters[sorterID]) {
+			events = append(events, ChangeEvent{
+				Type:     EventLineRemoved,
+				SKU:      sku,
+				SorterID: sorterID,
+				Salida:   salida,
+			})
 		}
 	}
 
-	return changes
+	return events
 }
 
-// FormatChangeSummary crea un resumen textual de los cambios
-func (cd *ChangeDetector) FormatChangeSummary(changes ChangeDetail) string {
-	return fmt.Sprintf("Agregados: %d, Eliminados: %d, Modificados: %d",
-		len(changes.Added), len(changes.Removed), len(changes.Modified))
-}
+// groupBySKU agrupa los assignments por SKU normalizado (mayúsculas)
+func groupBySKU(assignments []Assignment) map[string]*skuPlacement {
+	result := make(map[string]*skuPlacement)
 
-// DisplayChanges muestra los cambios en consola
-func (cd *ChangeDetector) DisplayChanges(changes ChangeDetail) {
-	if len(changes.Added) > 0 {
-		fmt.Printf("\n➕ Agregados (%d):\n", len(changes.Added))
-		for _, a := range changes.Added {
-			fmt.Printf("   Sorter %d: %s → Salida %d\n", a.SorterID, a.SKU, a.Salida)
+	for _, a := range assignments {
+		key := strings.ToUpper(strings.TrimSpace(a.SKU))
+		placement, exists := result[key]
+		if !exists {
+			placement = &skuPlacement{sku: a.SKU, sorters: make(map[int]map[int]bool)}
+			result[key] = placement
 		}
+		if placement.sorters[a.SorterID] == nil {
+			placement.sorters[a.SorterID] = make(map[int]bool)
+		}
+		placement.sorters[a.SorterID][a.Salida] = true
 	}
 
-	if len(changes.Removed) > 0 {
-		fmt.Printf("\n➖ Eliminados (%d):\n", len(changes.Removed))
-		for _, a := range changes.Removed {
-			fmt.Printf("   Sorter %d: %s (era Salida %d)\n", a.SorterID, a.SKU, a.Salida)
-		}
+	return result
+}
+
+// sortedSorters retorna los IDs de sorter ordenados
+func sortedSorters(sorters map[int]map[int]bool) []int {
+	ids := make([]int, 0, len(sorters))
+	for id := range sorters {
+		ids = append(ids, id)
 	}
+	sort.Ints(ids)
+	return ids
+}
 
-	if len(changes.Modified) > 0 {
-		fmt.Printf("\n📝 Modificados (%d):\n", len(changes.Modified))
-		for _, m := range changes.Modified {
-			fmt.Printf("   Sorter %d: %s - Salida %d → %d\n",
-				m.New.SorterID, m.New.SKU, m.Old.Salida, m.New.Salida)
-		}
+// sortedSalidas retorna el conjunto de salidas como lista ordenada
+func sortedSalidas(salidas map[int]bool) []int {
+	list := make([]int, 0, len(salidas))
+	for salida := range salidas {
+		list = append(list, salida)
+	}
+	sort.Ints(list)
+	return list
+}
+
+// FormatChangeSummary crea un resumen textual de los cambios
+func (cd *ChangeDetector) FormatChangeSummary(changes ChangeDetail) string {
+	counts := make(map[string]int)
+	for _, event := range changes.Events {
+		counts[event.Type]++
+	}
+
+	return fmt.Sprintf("Líneas agregadas: %d, Líneas quitadas: %d, Movidos: %d, Nuevos: %d, Retirados: %d",
+		counts[EventLineAdded], counts[EventLineRemoved], counts[EventSKUMoved],
+		counts[EventSKUIntroduced], counts[EventSKURetired])
+}
+
+// Describe retorna una descripción legible del evento
+func (e ChangeEvent) Describe() string {
+	switch e.Type {
+	case EventLineAdded:
+		return fmt.Sprintf("➕ Sorter %d: %s → Salida %d agregada", e.SorterID, e.SKU, e.Salida)
+	case EventLineRemoved:
+		return fmt.Sprintf("➖ Sorter %d: %s → Salida %d quitada", e.SorterID, e.SKU, e.Salida)
+	case EventSKUMoved:
+		return fmt.Sprintf("🔀 %s movido: Sorter %d %v → Sorter %d %v",
+			e.SKU, e.FromSorter, e.PrevSalidas, e.SorterID, e.Salidas)
+	case EventSKUIntroduced:
+		return fmt.Sprintf("🆕 Sorter %d: %s introducido en salidas %v", e.SorterID, e.SKU, e.Salidas)
+	case EventSKURetired:
+		return fmt.Sprintf("🗑️  Sorter %d: %s retirado (era salidas %v)", e.SorterID, e.SKU, e.PrevSalidas)
+	default:
+		return fmt.Sprintf("%s: %s (sorter %d)", e.Type, e.SKU, e.SorterID)
 	}
 }
@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new []Assignment
+		want     []ChangeEvent
+	}{
+		{
+			name: "sin cambios aunque cambie el orden",
+			old:  []Assignment{{Salida: 2, SKU: "4J", SorterID: 1}, {Salida: 5, SKU: "4J", SorterID: 1}},
+			new:  []Assignment{{Salida: 5, SKU: "4J", SorterID: 1}, {Salida: 2, SKU: "4J", SorterID: 1}},
+			want: []ChangeEvent{},
+		},
+		{
+			name: "mayúsculas y espacios no cuentan",
+			old:  []Assignment{{Salida: 3, SKU: "xl", SorterID: 2}},
+			new:  []Assignment{{Salida: 3, SKU: " XL ", SorterID: 2}},
+			want: []ChangeEvent{},
+		},
+		{
+			name: "línea agregada y quitada en el mismo sorter",
+			old:  []Assignment{{Salida: 2, SKU: "4J", SorterID: 1}, {Salida: 5, SKU: "4J", SorterID: 1}},
+			new:  []Assignment{{Salida: 2, SKU: "4J", SorterID: 1}, {Salida: 4, SKU: "4J", SorterID: 1}},
+			want: []ChangeEvent{
+				{Type: EventLineAdded, SKU: "4J", SorterID: 1, Salida: 4},
+				{Type: EventLineRemoved, SKU: "4J", SorterID: 1, Salida: 5},
+			},
+		},
+		{
+			name: "SKU movido de sorter",
+			old:  []Assignment{{Salida: 1, SKU: "3J", SorterID: 1}, {Salida: 2, SKU: "3J", SorterID: 1}},
+			new:  []Assignment{{Salida: 7, SKU: "3J", SorterID: 2}},
+			want: []ChangeEvent{
+				{Type: EventSKUMoved, SKU: "3J", SorterID: 2, FromSorter: 1, Salidas: []int{7}, PrevSalidas: []int{1, 2}},
+			},
+		},
+		{
+			name: "sorter adicional se reporta como líneas agregadas",
+			old:  []Assignment{{Salida: 1, SKU: "XL", SorterID: 1}},
+			new:  []Assignment{{Salida: 1, SKU: "XL", SorterID: 1}, {Salida: 6, SKU: "XL", SorterID: 2}, {Salida: 8, SKU: "XL", SorterID: 2}},
+			want: []ChangeEvent{
+				{Type: EventLineAdded, SKU: "XL", SorterID: 2, Salida: 6},
+				{Type: EventLineAdded, SKU: "XL", SorterID: 2, Salida: 8},
+			},
+		},
+		{
+			name: "sorter que se deja se reporta como líneas quitadas",
+			old:  []Assignment{{Salida: 1, SKU: "XL", SorterID: 1}, {Salida: 6, SKU: "XL", SorterID: 2}},
+			new:  []Assignment{{Salida: 1, SKU: "XL", SorterID: 1}},
+			want: []ChangeEvent{
+				{Type: EventLineRemoved, SKU: "XL", SorterID: 2, Salida: 6},
+			},
+		},
+		{
+			name: "SKU introducido y retirado",
+			old:  []Assignment{{Salida: 3, SKU: "J", SorterID: 1}},
+			new:  []Assignment{{Salida: 3, SKU: "SJ", SorterID: 1}, {Salida: 9, SKU: "SJ", SorterID: 2}},
+			want: []ChangeEvent{
+				{Type: EventSKURetired, SKU: "J", SorterID: 1, PrevSalidas: []int{3}},
+				{Type: EventSKUIntroduced, SKU: "SJ", SorterID: 1, Salidas: []int{3}},
+				{Type: EventSKUIntroduced, SKU: "SJ", SorterID: 2, Salidas: []int{9}},
+			},
+		},
+	}
+
+	cd := NewChangeDetector()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cd.DetectChanges(tt.old, tt.new).Events
+			if len(got) == 0 && len(tt.want) == 0 {
+				if cd.HasChanges(tt.old, tt.new) {
+					t.Error("HasChanges = true sin eventos")
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("eventos:\n got  %+v\n want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffPlacementPairsMoves(t *testing.T) {
+	// Dos sorters perdidos y uno ganado: el primero se empareja como
+	// movimiento y el otro queda como líneas quitadas
+	old := groupBySKU([]Assignment{
+		{Salida: 1, SKU: "4J", SorterID: 1},
+		{Salida: 2, SKU: "4J", SorterID: 2},
+	})["4J"]
+	new := groupBySKU([]Assignment{{Salida: 5, SKU: "4J", SorterID: 3}})["4J"]
+
+	want := []ChangeEvent{
+		{Type: EventSKUMoved, SKU: "4J", SorterID: 3, FromSorter: 1, Salidas: []int{5}, PrevSalidas: []int{1}},
+		{Type: EventLineRemoved, SKU: "4J", SorterID: 2, Salida: 2},
+	}
+	if got := diffPlacement(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("eventos:\n got  %+v\n want %+v", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"danich/pkg/scraper"
+)
+
+// SourceAssignments es el nombre de la fuente de assignments
+const SourceAssignments = "assignments"
+
+// ChartSource retorna el nombre de la fuente del gráfico de un sorter
+func ChartSource(sorterID int) string {
+	return fmt.Sprintf("grafico_s%d", sorterID)
+}
+
+// Collector obtiene en paralelo los assignments y el gráfico de cada sorter,
+// cada fuente con su propio timeout
+type Collector struct {
+	fetchAssignments func(ctx context.Context) ([]Assignment, error)
+	scrapeChart      func(ctx context.Context, sorterID int) (*scraper.ChartData, error) // nil = sin captura de gráficos
+	sorters          int
+	fetchTimeout     time.Duration
+	chartTimeout     time.Duration
+	logger           *slog.Logger
+}
+
+// Collection es el resultado de consultar todas las fuentes en un ciclo
+type Collection struct {
+	Assignments   []Assignment
+	AssignmentsOK bool
+	Charts        []*scraper.ChartData // Solo los sorters capturados, en orden
+	Sources       []SourceStatus
+}
+
+// Partial indica si alguna fuente falló
+func (c Collection) Partial() bool {
+	for _, source := range c.Sources {
+		if !source.OK {
+			return true
+		}
+	}
+	return false
+}
+
+// Failed indica si fallaron todas las fuentes
+func (c Collection) Failed() bool {
+	for _, source := range c.Sources {
+		if source.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// NewCollector crea un colector de fuentes
+func NewCollector(fetcher *Fetcher, chartScraper *scraper.ChartScraper, sorters int, fetchTimeout, chartTimeout time.Duration, logger *slog.Logger) *Collector {
+	c := &Collector{
+		fetchAssignments: fetcher.FetchAssignmentsContext,
+		sorters:          sorters,
+		fetchTimeout:     fetchTimeout,
+		chartTimeout:     chartTimeout,
+		logger:           loggerOrDefault(logger),
+	}
+	if chartScraper != nil {
+		c.scrapeChart = chartScraper.ScrapeAssignmentContext
+	}
+	return c
+}
+
+// Collect consulta todas las fuentes en paralelo y espera a que terminen o
+// venza su timeout. Las fuentes que fallan quedan registradas en Sources.
+func (c *Collector) Collect(ctx context.Context) Collection {
+	var (
+		wg          sync.WaitGroup
+		assignments []Assignment
+		charts      = make([]*scraper.ChartData, c.sorters)
+	)
+
+	sourceCount := 1
+	if c.scrapeChart != nil {
+		sourceCount += c.sorters
+	}
+	sources := make([]SourceStatus, sourceCount)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var err error
+		sources[0] = c.run(ctx, SourceAssignments, c.fetchTimeout, func(ctx context.Context) error {
+			assignments, err = c.fetchAssignments(ctx)
+			return err
+		})
+	}()
+
+	if c.scrapeChart != nil {
+		for i := 0; i < c.sorters; i++ {
+			sorterID := i + 1
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sources[sorterID] = c.run(ctx, ChartSource(sorterID), c.chartTimeout, func(ctx context.Context) error {
+					chartData, err := c.scrapeChart(ctx, sorterID)
+					if err == nil {
+						chartData.SorterID = sorterID
+						charts[i] = chartData
+					}
+					return err
+				})
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	collection := Collection{
+		Assignments:   assignments,
+		AssignmentsOK: sources[0].OK,
+		Sources:       sources,
+	}
+	for _, chartData := range charts {
+		if chartData != nil {
+			collection.Charts = append(collection.Charts, chartData)
+		}
+	}
+
+	return collection
+}
+
+// run ejecuta una fuente con su timeout y mide su duración
+func (c *Collector) run(ctx context.Context, source string, timeout time.Duration, fetch func(context.Context) error) SourceStatus {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := fetch(ctx)
+	status := SourceStatus{
+		Source:     source,
+		OK:         err == nil,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		status.Error = err.Error()
+		c.logger.Warn("Error obteniendo fuente", "fuente", source, "duracion_ms", status.DurationMs, "error", err)
+	} else {
+		c.logger.Debug("fuente obtenida", "fuente", source, "duracion_ms", status.DurationMs)
+	}
+
+	return status
+}
@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"danich/pkg/scraper"
+)
+
+// testCollector arma un colector de dos sorters con fuentes simuladas
+func testCollector(assignments func(context.Context) ([]Assignment, error), charts map[int]func(context.Context) error) *Collector {
+	return &Collector{
+		fetchAssignments: assignments,
+		scrapeChart: func(ctx context.Context, sorterID int) (*scraper.ChartData, error) {
+			if err := charts[sorterID](ctx); err != nil {
+				return nil, err
+			}
+			return &scraper.ChartData{Percentages: map[string]float64{"4J": 100}}, nil
+		},
+		sorters:      2,
+		fetchTimeout: 50 * time.Millisecond,
+		chartTimeout: 50 * time.Millisecond,
+		logger:       discardLogger(),
+	}
+}
+
+func TestCollectorSources(t *testing.T) {
+	okAssignments := func(context.Context) ([]Assignment, error) {
+		return []Assignment{{Salida: 1, SKU: "4J", SorterID: 1}}, nil
+	}
+	failedAssignments := func(context.Context) ([]Assignment, error) {
+		return nil, errors.New("HTTP 503")
+	}
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("gráfico no encontrado") }
+	// Fuente lenta: solo termina cuando vence su timeout
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name        string
+		assignments func(context.Context) ([]Assignment, error)
+		charts      map[int]func(context.Context) error
+		wantOK      map[string]bool
+		wantCharts  []int
+		wantPartial bool
+		wantFailed  bool
+	}{
+		{
+			name:        "todas las fuentes responden",
+			assignments: okAssignments,
+			charts:      map[int]func(context.Context) error{1: ok, 2: ok},
+			wantOK:      map[string]bool{SourceAssignments: true, ChartSource(1): true, ChartSource(2): true},
+			wantCharts:  []int{1, 2},
+		},
+		{
+			name:        "gráfico que falla",
+			assignments: okAssignments,
+			charts:      map[int]func(context.Context) error{1: ok, 2: failing},
+			wantOK:      map[string]bool{SourceAssignments: true, ChartSource(1): true, ChartSource(2): false},
+			wantCharts:  []int{1},
+			wantPartial: true,
+		},
+		{
+			name:        "gráfico lento corta por timeout",
+			assignments: okAssignments,
+			charts:      map[int]func(context.Context) error{1: slow, 2: ok},
+			wantOK:      map[string]bool{SourceAssignments: true, ChartSource(1): false, ChartSource(2): true},
+			wantCharts:  []int{2},
+			wantPartial: true,
+		},
+		{
+			name:        "solo gráficos",
+			assignments: failedAssignments,
+			charts:      map[int]func(context.Context) error{1: ok, 2: ok},
+			wantOK:      map[string]bool{SourceAssignments: false, ChartSource(1): true, ChartSource(2): true},
+			wantCharts:  []int{1, 2},
+			wantPartial: true,
+		},
+		{
+			name:        "ninguna fuente responde",
+			assignments: failedAssignments,
+			charts:      map[int]func(context.Context) error{1: failing, 2: slow},
+			wantOK:      map[string]bool{SourceAssignments: false, ChartSource(1): false, ChartSource(2): false},
+			wantPartial: true,
+			wantFailed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := testCollector(tt.assignments, tt.charts).Collect(context.Background())
+
+			if len(collection.Sources) != len(tt.wantOK) {
+				t.Fatalf("%d fuentes, esperaba %d", len(collection.Sources), len(tt.wantOK))
+			}
+			for _, source := range collection.Sources {
+				if source.OK != tt.wantOK[source.Source] {
+					t.Errorf("%s OK = %v, esperaba %v", source.Source, source.OK, tt.wantOK[source.Source])
+				}
+				if !source.OK && source.Error == "" {
+					t.Errorf("%s falló sin error", source.Source)
+				}
+			}
+			if collection.AssignmentsOK != tt.wantOK[SourceAssignments] {
+				t.Errorf("AssignmentsOK = %v", collection.AssignmentsOK)
+			}
+
+			var sorters []int
+			for _, chart := range collection.Charts {
+				sorters = append(sorters, chart.SorterID)
+			}
+			if len(sorters) != len(tt.wantCharts) {
+				t.Fatalf("gráficos de los sorters %v, esperaba %v", sorters, tt.wantCharts)
+			}
+			for i := range sorters {
+				if sorters[i] != tt.wantCharts[i] {
+					t.Errorf("gráficos de los sorters %v, esperaba %v", sorters, tt.wantCharts)
+				}
+			}
+
+			if collection.Partial() != tt.wantPartial || collection.Failed() != tt.wantFailed {
+				t.Errorf("Partial = %v, Failed = %v, esperaba %v, %v",
+					collection.Partial(), collection.Failed(), tt.wantPartial, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestCollectorTimeout(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}
+	collector := testCollector(func(ctx context.Context) ([]Assignment, error) {
+		return nil, slow(ctx)
+	}, map[int]func(context.Context) error{1: slow, 2: slow})
+
+	start := time.Now()
+	collection := collector.Collect(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Collect tardó %v con timeouts de 50ms", elapsed)
+	}
+	for _, source := range collection.Sources {
+		if source.OK || source.Error != context.DeadlineExceeded.Error() {
+			t.Errorf("%s: OK = %v, error %q, esperaba timeout", source.Source, source.OK, source.Error)
+		}
+		if source.DurationMs < 50 || source.DurationMs > 1000 {
+			t.Errorf("%s: duración %.0fms, esperaba el timeout de 50ms", source.Source, source.DurationMs)
+		}
+	}
+}
+
+func TestCollectorCanceled(t *testing.T) {
+	// Sin timeout propio: solo el contexto del ciclo detiene la consulta
+	collector := testCollector(func(ctx context.Context) ([]Assignment, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, nil)
+	collector.scrapeChart = nil
+	collector.fetchTimeout = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	collection := collector.Collect(ctx)
+	if len(collection.Sources) != 1 || !collection.Failed() {
+		t.Fatalf("fuentes = %+v, esperaba solo assignments fallida", collection.Sources)
+	}
+	if collection.Sources[0].Error != context.Canceled.Error() {
+		t.Errorf("error = %q, esperaba la cancelación", collection.Sources[0].Error)
+	}
+}
@@ -1,12 +1,21 @@
 package monitor
 
 import (
+	"bytes"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"danich/pkg/advisor"
+	"danich/pkg/alerts"
+
+	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,18 +29,202 @@ type PackingConfig struct {
 }
 
 type MonitorConfig struct {
-	IntervaloSegundos int  `yaml:"intervalo_segundos"`
-	CaptureCharts     bool `yaml:"capture_charts"`
+	IntervaloSegundos int     `yaml:"intervalo_segundos"`
+	CaptureCharts     bool    `yaml:"capture_charts"`
+	RapidoSegundos    float64 `yaml:"rapido_segundos"` // Polling rápido tras un cambio (0 = deshabilitado)
+	RapidoMinutos     float64 `yaml:"rapido_minutos"`
+
+	TimeoutAssignmentsSegundos float64 `yaml:"timeout_assignments_segundos"`
+	TimeoutGraficoSegundos     float64 `yaml:"timeout_grafico_segundos"`
+
+	Pantalla string `yaml:"pantalla"` // auto | tui | texto
 }
 
 type DataConfig struct {
-	Folder string `yaml:"folder"`
+	Folder             string `yaml:"folder"`
+	UltimosAssignments string `yaml:"ultimos_assignments"`
+}
+
+type DriftConfig struct {
+	UmbralAbsoluto  float64 `yaml:"umbral_absoluto"`
+	UmbralRelativo  float64 `yaml:"umbral_relativo"`
+	VentanaCiclos   int     `yaml:"ventana_ciclos"`
+	DispararAdvisor bool    `yaml:"disparar_advisor"`
+}
+
+type CalidadConfig struct {
+	ToleranciaSuma float64 `yaml:"tolerancia_suma"` // Puntos porcentuales alrededor de 100
+}
+
+type CapacidadLineaConfig struct {
+	Sorter    int     `yaml:"sorter"`
+	Salida    int     `yaml:"salida"`
+	PorMinuto float64 `yaml:"por_minuto"`
+}
+
+type ThroughputConfig struct {
+	Sorter    int     `yaml:"sorter"`
+	PorMinuto float64 `yaml:"por_minuto"`
+}
+
+type CapacidadConfig struct {
+	Unidad         string                 `yaml:"unidad"`           // cajas | kg
+	LineaPorMinuto float64                `yaml:"linea_por_minuto"` // Capacidad por defecto de cada salida (0 = sin modelo)
+	Lineas         []CapacidadLineaConfig `yaml:"lineas"`           // Capacidades de salidas específicas
+	Throughput     []ThroughputConfig     `yaml:"throughput"`       // Entrada de fruta de cada sorter
+}
+
+type PronosticoConfig struct {
+	HorizontesMinutos []float64 `yaml:"horizontes_minutos"` // Vacío = sin pronóstico
+	Alfa              float64   `yaml:"alfa"`               // Suavizado del nivel
+	Beta              float64   `yaml:"beta"`               // Suavizado de la tendencia
+	Confianza         float64   `yaml:"confianza"`          // Nivel de los intervalos (0.9 = 90%)
+	Umbral            float64   `yaml:"umbral"`             // Porcentaje para avisos preventivos (0 = sin avisos)
+	IntervaloMinutos  float64   `yaml:"intervalo_minutos"`  // Tiempo entre pronósticos
+	HistorialHoras    float64   `yaml:"historial_horas"`    // Histórico usado al iniciar
+}
+
+type RollupsConfig struct {
+	UmbralPorcentaje float64 `yaml:"umbral_porcentaje"`
+}
+
+type PausaConfig struct {
+	Inicio string `yaml:"inicio"`
+	Fin    string `yaml:"fin"`
+}
+
+type TurnoConfig struct {
+	Nombre string        `yaml:"nombre"`
+	Inicio string        `yaml:"inicio"`
+	Fin    string        `yaml:"fin"`
+	Pausas []PausaConfig `yaml:"pausas"`
+}
+
+type ReglaAlertaConfig struct {
+	Tipo      string  `yaml:"tipo"`
+	Umbral    float64 `yaml:"umbral"`
+	Minutos   float64 `yaml:"minutos"`
+	Ciclos    int     `yaml:"ciclos"`
+	Sorter    int     `yaml:"sorter"`
+	Severidad string  `yaml:"severidad"`
+	Chequeo   string  `yaml:"chequeo"` // Solo calidad_datos; vacío = todos
+}
+
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+}
+
+type EmailConfig struct {
+	Servidor string   `yaml:"servidor"`
+	Usuario  string   `yaml:"usuario"`
+	Password string   `yaml:"password"`
+	De       string   `yaml:"de"`
+	Para     []string `yaml:"para"`
+}
+
+type ComandoConfig struct {
+	Ruta string   `yaml:"ruta"`
+	Args []string `yaml:"args"`
+}
+
+type NotificadoresConfig struct {
+	Webhook WebhookConfig `yaml:"webhook"`
+	Email   EmailConfig   `yaml:"email"`
+	Comando ComandoConfig `yaml:"comando"`
+}
+
+type AlertasConfig struct {
+	CooldownMinutos float64             `yaml:"cooldown_minutos"`
+	Reglas          []ReglaAlertaConfig `yaml:"reglas"`
+	Notificadores   NotificadoresConfig `yaml:"notificadores"`
+}
+
+type APITLSConfig struct {
+	Certificado string `yaml:"certificado"` // PEM con el certificado (y la cadena)
+	Clave       string `yaml:"clave"`       // PEM con la clave privada
+}
+
+type APIClaveConfig struct {
+	Nombre string `yaml:"nombre"`
+	Rol    string `yaml:"rol"`    // lector | supervisor | admin
+	SHA256 string `yaml:"sha256"` // Hash hex de la clave (monitor auth clave)
+}
+
+type APIUsuarioConfig struct {
+	Usuario string `yaml:"usuario"`
+	Rol     string `yaml:"rol"`
+	Bcrypt  string `yaml:"bcrypt"` // Hash de la contraseña (monitor auth usuario)
+}
+
+type APIConfig struct {
+	Listen           string             `yaml:"listen"`
+	TLS              APITLSConfig       `yaml:"tls"`
+	Claves           []APIClaveConfig   `yaml:"claves"`
+	Usuarios         []APIUsuarioConfig `yaml:"usuarios"`
+	SinAutenticacion bool               `yaml:"sin_autenticacion"` // Permitir la API sin claves ni usuarios
+}
+
+type PostgresConfig struct {
+	DSN               string `yaml:"dsn"` // Vacío = deshabilitado
+	Lote              int    `yaml:"lote"`
+	IntervaloSegundos int    `yaml:"intervalo_segundos"`
+}
+
+type RetencionConfig struct {
+	DiasCrudos    int    `yaml:"dias_crudos"`    // 0 = sin límite
+	Compresion    string `yaml:"compresion"`     // gzip | zstd
+	PresupuestoMB int64  `yaml:"presupuesto_mb"` // 0 = sin límite
+}
+
+type AdvisorYAMLConfig struct {
+	OllamaURL        string  `yaml:"ollama_url"`
+	OllamaModel      string  `yaml:"ollama_model"`
+	TimeoutSegundos  float64 `yaml:"timeout_segundos"`
+	IntervaloMinutos float64 `yaml:"intervalo_minutos"`
+	Idioma           string  `yaml:"idioma"`     // es | en
+	Plantillas       string  `yaml:"plantillas"` // Carpeta con es.tmpl / en.tmpl de la planta
+
+	ModelosRespaldo  []string `yaml:"modelos_respaldo"`  // En orden, si ollama_model no está disponible
+	DescargarModelos bool     `yaml:"descargar_modelos"` // /api/pull de los modelos faltantes al iniciar
+	Precalentar      bool     `yaml:"precalentar"`
+	SaludSegundos    float64  `yaml:"salud_segundos"` // Chequeo periódico de Ollama (0 = solo al iniciar)
+}
+
+type LogsConfig struct {
+	Nivel    string `yaml:"nivel"`    // debug | info | warn | error
+	Formato  string `yaml:"formato"`  // text | json
+	Rotacion string `yaml:"rotacion"` // tamano | dia | ninguna
+	TamanoMB int    `yaml:"tamano_mb"`
+	Archivos int    `yaml:"archivos"` // Archivos rotados a conservar
+	Stderr   bool   `yaml:"stderr"`   // Duplicar los registros en stderr
+}
+
+type AlmacenamientoConfig struct {
+	Tipo string `yaml:"tipo"` // json | sqlite
+	Ruta string `yaml:"ruta"` // Archivo de la base SQLite
 }
 
 type Config struct {
-	Packing PackingConfig `yaml:"packing"`
-	Monitor MonitorConfig `yaml:"monitor"`
-	Data    DataConfig    `yaml:"data"`
+	Packing    PackingConfig    `yaml:"packing"`
+	Monitor    MonitorConfig    `yaml:"monitor"`
+	Data       DataConfig       `yaml:"data"`
+	Drift      DriftConfig      `yaml:"drift"`
+	Rollups    RollupsConfig    `yaml:"rollups"`
+	Calidad    CalidadConfig    `yaml:"calidad"`
+	Capacidad  CapacidadConfig  `yaml:"capacidad"`
+	Pronostico PronosticoConfig `yaml:"pronostico"`
+	Turnos     []TurnoConfig    `yaml:"turnos"`
+	API        APIConfig        `yaml:"api"`
+	Alertas    AlertasConfig    `yaml:"alertas"`
+
+	Almacenamiento AlmacenamientoConfig `yaml:"almacenamiento"`
+	Postgres       PostgresConfig       `yaml:"postgres"`
+	Retencion      RetencionConfig      `yaml:"retencion"`
+	Advisor        AdvisorYAMLConfig    `yaml:"advisor"`
+	Logs           LogsConfig           `yaml:"logs"`
+
+	DiaProductivoInicio string `yaml:"dia_productivo_inicio"`
+	AssignmentsURL      string `yaml:"assignments_url"`
 }
 
 // SystemConfig contiene toda la configuración del sistema
@@ -39,82 +232,642 @@ type SystemConfig struct {
 	BaseURL             string
 	AssignmentsURL      string
 	CheckInterval       time.Duration
+	FastInterval        time.Duration // Polling rápido tras un cambio (0 = deshabilitado)
+	FastDuration        time.Duration
+	FetchTimeout        time.Duration // Timeout de la consulta de assignments
+	ChartTimeout        time.Duration // Timeout del scraping de cada gráfico
 	CaptureCharts       bool
+	DisplayMode         string // auto | tui | texto
 	DatasetFolder       string
 	CurrentSnapshotFile string
 	DatasetFile         string
 	ChangesLogFile      string
 	LastAssignmentsFile string
 	TrainingDataCSV     string
+	RollupsFile         string
+	AdviceLogFile       string
+	ReportsFolder       string
 
 	// Info del packing
 	PackingName    string
 	PackingSorters int
 	PackingLineas  int
 	PackingFruta   string
+
+	// Detección de desplazamientos de carga en los gráficos
+	DriftAbsThreshold  float64 // Puntos porcentuales
+	DriftRelThreshold  float64 // Fracción de la referencia
+	DriftWindow        int     // Ciclos de suavizado
+	DriftTriggerAdvice bool    // Ejecutar el advisor de inmediato al detectar un desplazamiento
+
+	// Agregación de porcentajes
+	RollupThreshold float64 // Umbral para el tiempo sobre umbral
+
+	// Calidad de datos
+	QualitySumTolerance float64 // Tolerancia de la suma de porcentajes por sorter
+
+	// Capacidad de las líneas y throughput de los sorters (por minuto)
+	CapacityUnit     string  // cajas | kg
+	LineCapacity     float64 // Capacidad por defecto de cada salida (0 = sin modelo)
+	LineCapacities   []LineCapacity
+	SorterThroughput map[int]float64
+	ThroughputFile   string // Throughput ingresado manualmente
+
+	// Pronóstico de carga por SKU y sorter
+	ForecastHorizons   []time.Duration // Vacío = deshabilitado
+	ForecastAlpha      float64
+	ForecastBeta       float64
+	ForecastConfidence float64
+	ForecastThreshold  float64 // 0 = sin avisos preventivos
+	ForecastInterval   time.Duration
+	ForecastHistory    time.Duration // Histórico con que se inicializan las series
+
+	// Turnos y día productivo
+	Shifts             []ShiftDefinition
+	ProductionDayStart time.Duration // Desde la medianoche
+
+	// API HTTP (vacío = deshabilitada). Sin claves ni usuarios no se pide
+	// autenticación, y solo se permite con APIAllowOpen.
+	APIListen       string
+	APIAllowOpen    bool
+	APITLSCert      string // Vacío = HTTP sin cifrar
+	APITLSKey       string
+	APIKeys         []APIKey
+	APIUsers        []APIUser
+	AuditFile       string
+	AnnotationsFile string
+	AdviceAcksFile  string
+
+	// Alertas
+	AlertCooldown    time.Duration
+	AlertRules       []AlertRule
+	AlertWebhookURL  string
+	AlertEmail       alerts.EmailConfig // Server vacío = deshabilitado
+	AlertCommand     string
+	AlertCommandArgs []string
+
+	// Almacenamiento del histórico
+	StoreType  string // json | sqlite
+	SQLitePath string
+
+	// Envío a PostgreSQL/TimescaleDB (DSN vacío = deshabilitado)
+	PostgresDSN           string
+	PostgresBatchSize     int
+	PostgresFlushInterval time.Duration
+	PostgresSpoolFile     string
+
+	// Retención y archivo de datos
+	RetentionDays      int    // Días con snapshots crudos (0 = sin límite)
+	ArchiveCompression string // gzip | zstd
+	DiskBudgetMB       int64  // 0 = sin límite
+	ArchiveFolder      string
+
+	// Advisor nativo (Ollama)
+	Advisor              advisor.AdvisorConfig
+	AdviceInterval       time.Duration // Tiempo entre análisis de balance
+	OllamaHealthInterval time.Duration // Entre chequeos de Ollama (0 = solo al iniciar)
+
+	// Logs estructurados
+	LogLevel     string // debug | info | warn | error
+	LogFormat    string // text | json
+	LogRotation  string // tamano | dia | ninguna
+	LogMaxSizeMB int
+	LogMaxFiles  int
+	LogStderr    bool
+	LogFolder    string
+
+	// Avisos de la carga (p. ej. config.yaml ausente). LoadConfigWith no
+	// escribe en la consola: los muestra quien llama.
+	Notes []string
+
+	// Origen de cada valor (clave YAML -> default, config.yaml, .env, env, flag)
+	origins map[string]string
+}
+
+// Orígenes de los valores de configuración, de menor a mayor prioridad
+const (
+	OriginDefault = "default"
+	OriginDotEnv  = ".env"
+	OriginEnv     = "env"
+	OriginFlag    = "flag"
+	OriginDerived = "derivado"
+)
+
+// envPrefix es el prefijo de las variables de entorno de configuración
+const envPrefix = "DANICH_"
+
+// envReserved son las variables DANICH_ que no corresponden a una clave: el
+// YAML a usar y las que el notificador por comando pasa a su proceso
+var envReserved = map[string]bool{
+	envPrefix + "CONFIG":         true,
+	envPrefix + "ALERT_STATE":    true,
+	envPrefix + "ALERT_RULE":     true,
+	envPrefix + "ALERT_KEY":      true,
+	envPrefix + "ALERT_SEVERITY": true,
+	envPrefix + "ALERT_MESSAGE":  true,
+}
+
+// ConfigOptions indica de dónde cargar la configuración
+type ConfigOptions struct {
+	File  string            // Ruta del YAML ("" = DANICH_CONFIG o config.yaml)
+	Flags map[string]string // Clave YAML -> valor pasado por flag
+}
+
+// ConfigValue es un valor efectivo de configuración con su origen
+type ConfigValue struct {
+	Key    string
+	Value  string
+	Origin string
+	EnvVar string
 }
 
-// LoadConfig carga la configuración desde config.yaml
+// LoadConfig carga la configuración por capas sin flags
 func LoadConfig() (*SystemConfig, error) {
+	return LoadConfigWith(ConfigOptions{})
+}
+
+// LoadConfigWith carga la configuración por capas: flags > variables de
+// entorno > archivo .env > config.yaml > valores por defecto. Cualquier clave
+// desconocida o valor inválido es un error. No escribe en la consola: los
+// subcomandos pueden emitir JSON por stdout.
+func LoadConfigWith(opts ConfigOptions) (*SystemConfig, error) {
+	return readConfig(opts)
+}
+
+// readConfig carga la configuración; también se usa para recargarla con el
+// monitor en marcha
+func readConfig(opts ConfigOptions) (*SystemConfig, error) {
 	// Valores por defecto
 	cfg := &SystemConfig{
-		BaseURL:             "http://192.168.121.2",
-		CheckInterval:       30 * time.Second,
-		CaptureCharts:       true,
-		DatasetFolder:       "training_data",
-		LastAssignmentsFile: "last_assignments.json",
+		BaseURL:               "http://192.168.121.2",
+		CheckInterval:         30 * time.Second,
+		FastDuration:          5 * time.Minute,
+		FetchTimeout:          10 * time.Second,
+		ChartTimeout:          30 * time.Second,
+		AdviceInterval:        5 * time.Minute,
+		CaptureCharts:         true,
+		DisplayMode:           DisplayAuto,
+		DatasetFolder:         "training_data",
+		LastAssignmentsFile:   "last_assignments.json",
+		DriftAbsThreshold:     10,
+		DriftRelThreshold:     0.3,
+		DriftWindow:           3,
+		RollupThreshold:       40,
+		QualitySumTolerance:   DefaultQualityOptions().SumTolerance,
+		CapacityUnit:          CapacityUnitBoxes,
+		ForecastHorizons:      []time.Duration{15 * time.Minute, 30 * time.Minute, 60 * time.Minute},
+		ForecastAlpha:         0.5,
+		ForecastBeta:          0.1,
+		ForecastConfidence:    0.9,
+		ForecastThreshold:     45,
+		ForecastInterval:      5 * time.Minute,
+		ForecastHistory:       2 * time.Hour,
+		AlertCooldown:         10 * time.Minute,
+		StoreType:             StoreJSON,
+		PostgresBatchSize:     500,
+		PostgresFlushInterval: time.Minute,
+		ArchiveCompression:    CompressionGzip,
+		Advisor:               advisor.DefaultConfig(),
+		OllamaHealthInterval:  time.Minute,
+		LogLevel:              "info",
+		LogFormat:             LogFormatText,
+		LogRotation:           LogRotateDay,
+		LogMaxSizeMB:          10,
+		LogMaxFiles:           14,
+		origins:               make(map[string]string),
 	}
 
-	// Intentar cargar config.yaml
-	data, err := os.ReadFile("config.yaml")
-	if err != nil {
-		log.Printf("⚠️  No se pudo cargar config.yaml, usando valores por defecto: %v\n", err)
-		cfg.initDerivedPaths()
-		return cfg, nil
+	dotEnv, err := godotenv.Read(".env")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error leyendo .env: %w", err)
+	}
+
+	// Capas en orden de prioridad creciente: clave -> (valor, origen)
+	type layered struct{ value, origin string }
+	values := make(map[string]layered)
+
+	file, explicit := opts.File, opts.File != ""
+	if !explicit {
+		if file, explicit = os.LookupEnv(envPrefix + "CONFIG"); !explicit {
+			file, explicit = dotEnv[envPrefix+"CONFIG"]
+		}
+	}
+	if file == "" {
+		file = "config.yaml"
 	}
 
-	var yamlConfig Config
-	if err := yaml.Unmarshal(data, &yamlConfig); err != nil {
-		log.Printf("⚠️  Error parseando config.yaml, usando valores por defecto: %v\n", err)
+	yamlValues, err := readYAMLLayer(file)
+	switch {
+	case err == nil:
+		for key, value := range yamlValues {
+			values[key] = layered{value, file}
+		}
+	case os.IsNotExist(err) && !explicit:
+		cfg.Notes = append(cfg.Notes, fmt.Sprintf("No se encontró %s, usando valores por defecto", file))
+	default:
+		return nil, err
+	}
+
+	for _, setting := range configSettings {
+		env := setting.EnvVar()
+		if value, ok := dotEnv[env]; ok {
+			values[setting.Key] = layered{value, OriginDotEnv}
+		}
+		if value, ok := os.LookupEnv(env); ok {
+			values[setting.Key] = layered{value, OriginEnv}
+		}
+		if value, ok := opts.Flags[setting.Key]; ok {
+			values[setting.Key] = layered{value, OriginFlag}
+		}
+	}
+
+	// Aplicar y validar cada valor
+	problems := unknownEnvVars(dotEnv)
+	for _, setting := range configSettings {
+		v, ok := values[setting.Key]
+		if !ok {
+			cfg.origins[setting.Key] = OriginDefault
+			continue
+		}
+		if err := setting.set(cfg, strings.TrimSpace(v.value)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s): %v", setting.Key, v.origin, err))
+			continue
+		}
+		cfg.origins[setting.Key] = v.origin
+	}
+
+	if len(problems) == 0 {
 		cfg.initDerivedPaths()
-		return cfg, nil
+		problems = cfg.validate()
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("configuración inválida:\n  - %s", strings.Join(problems, "\n  - "))
 	}
 
-	// Aplicar valores del YAML
-	if yamlConfig.Packing.URL != "" {
-		cfg.BaseURL = yamlConfig.Packing.URL
+	return cfg, nil
+}
+
+// unknownEnvVars retorna un problema por cada variable DANICH_ del entorno o
+// de .env que no corresponde a ninguna clave (típicamente un error de tipeo)
+func unknownEnvVars(dotEnv map[string]string) []string {
+	known := make(map[string]bool, len(configSettings))
+	for _, setting := range configSettings {
+		known[setting.EnvVar()] = true
 	}
 
-	if yamlConfig.Monitor.IntervaloSegundos > 0 {
-		cfg.CheckInterval = time.Duration(yamlConfig.Monitor.IntervaloSegundos) * time.Second
+	check := func(name, origin string) string {
+		upper := strings.ToUpper(name)
+		if !strings.HasPrefix(upper, envPrefix) || known[upper] || envReserved[upper] {
+			return ""
+		}
+		return fmt.Sprintf("%s (%s): variable desconocida", name, origin)
 	}
 
-	cfg.CaptureCharts = yamlConfig.Monitor.CaptureCharts
+	var problems []string
+	names := make([]string, 0, len(dotEnv))
+	for name := range dotEnv {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if problem := check(name, OriginDotEnv); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if problem := check(name, OriginEnv); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
 
-	if yamlConfig.Data.Folder != "" {
-		cfg.DatasetFolder = yamlConfig.Data.Folder
+// readYAMLLayer lee el YAML de forma estricta y lo aplana en clave -> valor.
+// Las listas se conservan como YAML para que las decodifique su setting.
+func readYAMLLayer(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
 
-	// Info del packing
-	cfg.PackingName = yamlConfig.Packing.Name
-	cfg.PackingSorters = yamlConfig.Packing.Sorters
-	cfg.PackingLineas = yamlConfig.Packing.Lineas
-	cfg.PackingFruta = yamlConfig.Packing.Fruta
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var schema Config
+	if err := decoder.Decode(&schema); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error en %s: %w", path, err)
+	}
 
-	// Inicializar rutas derivadas
-	cfg.initDerivedPaths()
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("error en %s: %w", path, err)
+	}
 
-	fmt.Printf("✓ Configuración cargada: %s (%s) - %d sorters, %d líneas\n",
-		cfg.PackingName, cfg.PackingFruta, cfg.PackingSorters, cfg.PackingLineas)
+	values := make(map[string]string)
+	if len(root.Content) == 0 {
+		return values, nil
+	}
+	if err := flattenYAML(root.Content[0], "", values); err != nil {
+		return nil, fmt.Errorf("error en %s: %w", path, err)
+	}
+	return values, nil
+}
 
-	return cfg, nil
+// flattenYAML recorre los mapas del YAML hasta llegar a una clave conocida
+func flattenYAML(node *yaml.Node, prefix string, values map[string]string) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("línea %d: se esperaba una sección en %q", node.Line, prefix)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key := keyNode.Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if _, known := findSetting(key); known {
+			if valueNode.Tag == "!!null" {
+				continue
+			}
+			if valueNode.Kind == yaml.ScalarNode {
+				values[key] = valueNode.Value
+				continue
+			}
+			raw, err := yaml.Marshal(valueNode)
+			if err != nil {
+				return err
+			}
+			values[key] = string(raw)
+			continue
+		}
+
+		if valueNode.Kind == yaml.MappingNode {
+			if err := flattenYAML(valueNode, key, values); err != nil {
+				return err
+			}
+			continue
+		}
+		if valueNode.Tag == "!!null" {
+			continue // Sección vacía
+		}
+		return fmt.Errorf("línea %d: clave desconocida %q", keyNode.Line, key)
+	}
+
+	return nil
+}
+
+// validate revisa las reglas que involucran más de un valor
+func (cfg *SystemConfig) validate() []string {
+	var problems []string
+
+	if u, err := url.Parse(cfg.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("packing.url: URL inválida %q", cfg.BaseURL))
+	}
+	if cfg.PackingSorters > 0 {
+		for _, rule := range cfg.AlertRules {
+			if rule.SorterID > cfg.PackingSorters {
+				problems = append(problems, fmt.Sprintf("alertas.reglas: sorter %d fuera de rango (packing.sorters = %d)",
+					rule.SorterID, cfg.PackingSorters))
+			}
+		}
+	}
+	if cfg.FastInterval > 0 && cfg.FastInterval >= cfg.CheckInterval {
+		problems = append(problems, fmt.Sprintf("monitor.rapido_segundos: %v debe ser menor que monitor.intervalo_segundos (%v)",
+			cfg.FastInterval, cfg.CheckInterval))
+	}
+	if cfg.PackingSorters > 0 {
+		for _, line := range cfg.LineCapacities {
+			if line.SorterID > cfg.PackingSorters {
+				problems = append(problems, fmt.Sprintf("capacidad.lineas: sorter %d fuera de rango (packing.sorters = %d)",
+					line.SorterID, cfg.PackingSorters))
+			}
+		}
+		for sorterID := range cfg.SorterThroughput {
+			if sorterID > cfg.PackingSorters {
+				problems = append(problems, fmt.Sprintf("capacidad.throughput: sorter %d fuera de rango (packing.sorters = %d)",
+					sorterID, cfg.PackingSorters))
+			}
+		}
+	}
+	if cfg.Advisor.PromptDir != "" {
+		if _, err := advisor.LoadPromptTemplates(cfg.Advisor.Language, cfg.Advisor.PromptDir); err != nil {
+			problems = append(problems, fmt.Sprintf("advisor.plantillas: %v", err))
+		}
+	}
+	for _, pair := range shiftOverlaps(cfg.Shifts) {
+		problems = append(problems, fmt.Sprintf("turnos: los turnos %s se solapan", pair))
+	}
+	if cfg.ForecastAlpha <= 0 || cfg.ForecastAlpha > 1 {
+		problems = append(problems, fmt.Sprintf("pronostico.alfa: %v debe estar entre 0 y 1", cfg.ForecastAlpha))
+	}
+	if cfg.ForecastBeta <= 0 || cfg.ForecastBeta > 1 {
+		problems = append(problems, fmt.Sprintf("pronostico.beta: %v debe estar entre 0 y 1", cfg.ForecastBeta))
+	}
+	if cfg.ForecastConfidence <= 0 || cfg.ForecastConfidence >= 1 {
+		problems = append(problems, fmt.Sprintf("pronostico.confianza: %v debe ser mayor que 0 y menor que 1", cfg.ForecastConfidence))
+	}
+	if cfg.AlertEmail.Server != "" && len(cfg.AlertEmail.To) == 0 {
+		problems = append(problems, "alertas.notificadores.email.para: falta al menos un destinatario")
+	}
+	if cfg.APIListen != "" && len(cfg.APIKeys) == 0 && len(cfg.APIUsers) == 0 && !cfg.APIAllowOpen {
+		problems = append(problems, "api.listen: la API no tiene credenciales (configurar api.claves o api.usuarios, o api.sin_autenticacion: true para dejarla abierta)")
+	}
+	if (cfg.APITLSCert == "") != (cfg.APITLSKey == "") {
+		problems = append(problems, "api.tls: se necesitan el certificado y la clave")
+	} else if cfg.APITLSCert != "" {
+		if _, err := tls.LoadX509KeyPair(cfg.APITLSCert, cfg.APITLSKey); err != nil {
+			problems = append(problems, fmt.Sprintf("api.tls: %v", err))
+		}
+	}
+
+	return problems
 }
 
 // initDerivedPaths inicializa las rutas que dependen de otras configuraciones
 func (cfg *SystemConfig) initDerivedPaths() {
-	cfg.AssignmentsURL = cfg.BaseURL + "/api/api/assignments_list"
+	if cfg.AssignmentsURL == "" {
+		cfg.AssignmentsURL = cfg.BaseURL + "/api/api/assignments_list"
+		cfg.markDerived("assignments_url")
+	}
 	cfg.CurrentSnapshotFile = filepath.Join(cfg.DatasetFolder, "current_snapshot.json")
 	cfg.DatasetFile = filepath.Join(cfg.DatasetFolder, "dataset.json")
 	cfg.ChangesLogFile = filepath.Join(cfg.DatasetFolder, "changes_log.json")
 	cfg.TrainingDataCSV = filepath.Join(cfg.DatasetFolder, "training_data.csv")
+	cfg.RollupsFile = filepath.Join(cfg.DatasetFolder, "rollups.jsonl")
+	cfg.AdviceLogFile = filepath.Join(cfg.DatasetFolder, "advice_log.json")
+	cfg.ReportsFolder = filepath.Join(cfg.DatasetFolder, "reportes")
+	cfg.PostgresSpoolFile = filepath.Join(cfg.DatasetFolder, "postgres_spool.jsonl")
+	cfg.ArchiveFolder = filepath.Join(cfg.DatasetFolder, "archivo")
+	cfg.LogFolder = filepath.Join(cfg.DatasetFolder, "logs")
+	cfg.ThroughputFile = filepath.Join(cfg.DatasetFolder, "throughput.json")
+	cfg.AuditFile = filepath.Join(cfg.DatasetFolder, "audit.jsonl")
+	cfg.AnnotationsFile = filepath.Join(cfg.DatasetFolder, "annotations.jsonl")
+	cfg.AdviceAcksFile = filepath.Join(cfg.DatasetFolder, "advice_acks.jsonl")
+	if cfg.SQLitePath == "" {
+		cfg.SQLitePath = filepath.Join(cfg.DatasetFolder, "danich.db")
+		cfg.markDerived("almacenamiento.ruta")
+	}
+	if cfg.ProductionDayStart == 0 && len(cfg.Shifts) > 0 && cfg.origin("dia_productivo_inicio") == OriginDefault {
+		cfg.ProductionDayStart = cfg.Shifts[0].Start
+		cfg.markDerived("dia_productivo_inicio")
+	}
+}
+
+// markDerived registra que un valor se calculó a partir de otros
+func (cfg *SystemConfig) markDerived(key string) {
+	if cfg.origins != nil {
+		cfg.origins[key] = OriginDerived
+	}
+}
+
+// origin retorna el origen de un valor
+func (cfg *SystemConfig) origin(key string) string {
+	if origin, ok := cfg.origins[key]; ok {
+		return origin
+	}
+	return OriginDefault
+}
+
+// Effective retorna todos los valores de configuración con su origen. Los
+// secretos se muestran enmascarados.
+func (cfg *SystemConfig) Effective() []ConfigValue {
+	values := make([]ConfigValue, 0, len(configSettings)+len(derivedPaths))
+	for _, setting := range configSettings {
+		value := setting.get(cfg)
+		if setting.Secret && value != "" {
+			value = "****"
+		}
+		values = append(values, ConfigValue{
+			Key:    setting.Key,
+			Value:  value,
+			Origin: cfg.origin(setting.Key),
+			EnvVar: setting.EnvVar(),
+		})
+	}
+
+	for _, derived := range derivedPaths {
+		values = append(values, ConfigValue{
+			Key:    derived.key,
+			Value:  derived.get(cfg),
+			Origin: OriginDerived + " (data.folder)",
+		})
+	}
+
+	return values
+}
+
+// ChangedKeys retorna las claves cuyo valor difiere en other. Las
+// credenciales de la API se comparan completas porque solo se muestran sus
+// nombres.
+func (cfg *SystemConfig) ChangedKeys(other *SystemConfig) []string {
+	var keys []string
+	for _, setting := range configSettings {
+		changed := setting.get(cfg) != setting.get(other)
+		switch setting.Key {
+		case "api.claves":
+			changed = !slices.Equal(cfg.APIKeys, other.APIKeys)
+		case "api.usuarios":
+			changed = !slices.Equal(cfg.APIUsers, other.APIUsers)
+		}
+		if changed {
+			keys = append(keys, setting.Key)
+		}
+	}
+	return keys
+}
+
+// ShiftCalendar crea el calendario de turnos configurado
+func (cfg *SystemConfig) ShiftCalendar() *ShiftCalendar {
+	return NewShiftCalendar(cfg.Shifts, cfg.ProductionDayStart)
+}
+
+// QualityOptions retorna las opciones de los chequeos de calidad de datos
+func (cfg *SystemConfig) QualityOptions() QualityOptions {
+	return QualityOptions{
+		SumTolerance: cfg.QualitySumTolerance,
+		Lineas:       cfg.PackingLineas,
+	}
+}
+
+// ForecastOptions retorna las opciones del pronóstico de carga
+func (cfg *SystemConfig) ForecastOptions() ForecastOptions {
+	return ForecastOptions{
+		Alpha:      cfg.ForecastAlpha,
+		Beta:       cfg.ForecastBeta,
+		Horizons:   cfg.ForecastHorizons,
+		Confidence: cfg.ForecastConfidence,
+		Threshold:  cfg.ForecastThreshold,
+	}
+}
+
+// parseShift convierte la definición YAML de un turno
+func parseShift(turno TurnoConfig) (ShiftDefinition, error) {
+	start, err := parseClock(turno.Inicio)
+	if err != nil {
+		return ShiftDefinition{}, err
+	}
+	end, err := parseClock(turno.Fin)
+	if err != nil {
+		return ShiftDefinition{}, err
+	}
+	shift := ShiftDefinition{Name: turno.Nombre, Start: start, End: end}
+
+	for _, pausa := range turno.Pausas {
+		breakStart, err := parseClock(pausa.Inicio)
+		if err != nil {
+			return ShiftDefinition{}, err
+		}
+		breakEnd, err := parseClock(pausa.Fin)
+		if err != nil {
+			return ShiftDefinition{}, err
+		}
+		shift.Breaks = append(shift.Breaks, ClockRange{Start: breakStart, End: breakEnd})
+	}
+
+	return shift, nil
+}
+
+// parseAlertRule convierte la definición YAML de una regla de alerta
+func parseAlertRule(regla ReglaAlertaConfig) (AlertRule, error) {
+	rule := AlertRule{
+		Type:      regla.Tipo,
+		Threshold: regla.Umbral,
+		For:       time.Duration(regla.Minutos * float64(time.Minute)),
+		Cycles:    regla.Ciclos,
+		SorterID:  regla.Sorter,
+		Severity:  regla.Severidad,
+		Check:     regla.Chequeo,
+	}
+
+	switch rule.Severity {
+	case "":
+		rule.Severity = alerts.SeverityWarning
+	case alerts.SeverityWarning, alerts.SeverityCritical:
+	default:
+		return rule, fmt.Errorf("severidad desconocida %q", regla.Severidad)
+	}
+
+	switch rule.Type {
+	case RuleSKUAboveThreshold, RuleHighDescarte:
+		if rule.Threshold <= 0 {
+			return rule, fmt.Errorf("la regla %s necesita umbral", rule.Type)
+		}
+	case RuleScrapeFailing:
+		if rule.Cycles <= 0 {
+			rule.Cycles = 3
+		}
+	case RuleDataQuality:
+		if rule.Check != "" && !slices.Contains(QualityChecks, rule.Check) {
+			return rule, fmt.Errorf("chequeo de calidad desconocido %q (válidos: %s)",
+				rule.Check, strings.Join(QualityChecks, ", "))
+		}
+	default:
+		return rule, fmt.Errorf("tipo de regla desconocido %q", regla.Tipo)
+	}
+
+	if rule.SorterID < 0 || rule.For < 0 {
+		return rule, fmt.Errorf("valores negativos en la regla %s", rule.Type)
+	}
+	return rule, nil
 }
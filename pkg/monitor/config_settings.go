@@ -0,0 +1,544 @@
+package monitor
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"danich/pkg/advisor"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSetting es un valor configurable. La clave es la ruta en config.yaml;
+// la variable de entorno y el flag se derivan de ella.
+type configSetting struct {
+	Key    string
+	Help   string
+	Secret bool // Se enmascara al mostrar la configuración
+	set    func(cfg *SystemConfig, raw string) error
+	get    func(cfg *SystemConfig) string
+}
+
+// EnvVar retorna la variable de entorno del valor (p. ej. DANICH_PACKING_URL)
+func (s configSetting) EnvVar() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.Key, ".", "_"))
+}
+
+// configSettings cubre todos los campos de SystemConfig y AdvisorConfig que
+// no son rutas derivadas de data.folder
+var configSettings = []configSetting{
+	stringSetting("packing.name", "Nombre del packing", func(c *SystemConfig) *string { return &c.PackingName }),
+	stringSetting("packing.url", "URL base del sistema del packing", func(c *SystemConfig) *string { return &c.BaseURL }),
+	intSetting("packing.sorters", "Cantidad de sorters", 0, func(c *SystemConfig) *int { return &c.PackingSorters }),
+	intSetting("packing.lineas", "Cantidad de salidas por sorter", 0, func(c *SystemConfig) *int { return &c.PackingLineas }),
+	stringSetting("packing.fruta", "Fruta procesada", func(c *SystemConfig) *string { return &c.PackingFruta }),
+	stringSetting("assignments_url", "URL de assignments (por defecto derivada de packing.url)", func(c *SystemConfig) *string { return &c.AssignmentsURL }),
+
+	durationSetting("monitor.intervalo_segundos", "Intervalo entre verificaciones", time.Second, func(c *SystemConfig) *time.Duration { return &c.CheckInterval }),
+	boolSetting("monitor.capture_charts", "Capturar los gráficos de porcentajes", func(c *SystemConfig) *bool { return &c.CaptureCharts }),
+	optionalDurationSetting("monitor.rapido_segundos", "Intervalo de polling rápido tras un cambio (0 = deshabilitado)", time.Second, func(c *SystemConfig) *time.Duration { return &c.FastInterval }),
+	durationSetting("monitor.rapido_minutos", "Duración del polling rápido", time.Minute, func(c *SystemConfig) *time.Duration { return &c.FastDuration }),
+	durationSetting("monitor.timeout_assignments_segundos", "Timeout de la consulta de assignments", time.Second, func(c *SystemConfig) *time.Duration { return &c.FetchTimeout }),
+	durationSetting("monitor.timeout_grafico_segundos", "Timeout del scraping de cada gráfico", time.Second, func(c *SystemConfig) *time.Duration { return &c.ChartTimeout }),
+	choiceSetting("monitor.pantalla", "Consola: tui a pantalla completa, texto con scroll o auto (tui si la salida es una terminal)", []string{DisplayAuto, DisplayTUI, DisplayText}, func(c *SystemConfig) *string { return &c.DisplayMode }),
+
+	stringSetting("data.folder", "Carpeta de datos", func(c *SystemConfig) *string { return &c.DatasetFolder }),
+	stringSetting("data.ultimos_assignments", "Archivo con los últimos assignments", func(c *SystemConfig) *string { return &c.LastAssignmentsFile }),
+
+	floatSetting("drift.umbral_absoluto", "Umbral de desplazamiento en puntos porcentuales", func(c *SystemConfig) *float64 { return &c.DriftAbsThreshold }),
+	floatSetting("drift.umbral_relativo", "Umbral de desplazamiento relativo a la referencia", func(c *SystemConfig) *float64 { return &c.DriftRelThreshold }),
+	intSetting("drift.ventana_ciclos", "Ciclos de suavizado", 1, func(c *SystemConfig) *int { return &c.DriftWindow }),
+	boolSetting("drift.disparar_advisor", "Ejecutar el advisor al detectar un desplazamiento", func(c *SystemConfig) *bool { return &c.DriftTriggerAdvice }),
+
+	floatSetting("rollups.umbral_porcentaje", "Umbral para el tiempo sobre umbral", func(c *SystemConfig) *float64 { return &c.RollupThreshold }),
+	floatSetting("calidad.tolerancia_suma", "Tolerancia de la suma de porcentajes por sorter (puntos)", func(c *SystemConfig) *float64 { return &c.QualitySumTolerance }),
+
+	choiceSetting("capacidad.unidad", "Unidad de capacidad y throughput (por minuto)", []string{CapacityUnitBoxes, CapacityUnitKg}, func(c *SystemConfig) *string { return &c.CapacityUnit }),
+	floatSetting("capacidad.linea_por_minuto", "Capacidad por defecto de cada salida (0 = sin modelo)", func(c *SystemConfig) *float64 { return &c.LineCapacity }),
+	{
+		Key:  "capacidad.lineas",
+		Help: "Capacidades de salidas específicas (lista YAML de {sorter, salida, por_minuto})",
+		set: func(c *SystemConfig, raw string) error {
+			var lineas []CapacidadLineaConfig
+			if err := decodeStrict(raw, &lineas); err != nil {
+				return err
+			}
+			c.LineCapacities = nil
+			for _, linea := range lineas {
+				if linea.Sorter < 1 || linea.Salida < 1 || linea.PorMinuto <= 0 {
+					return fmt.Errorf("línea inválida (sorter %d, salida %d, por_minuto %v)", linea.Sorter, linea.Salida, linea.PorMinuto)
+				}
+				c.LineCapacities = append(c.LineCapacities, LineCapacity{SorterID: linea.Sorter, Salida: linea.Salida, PerMinute: linea.PorMinuto})
+			}
+			return nil
+		},
+		get: func(c *SystemConfig) string {
+			var parts []string
+			for _, line := range c.LineCapacities {
+				parts = append(parts, fmt.Sprintf("S%d/%d=%g", line.SorterID, line.Salida, line.PerMinute))
+			}
+			return strings.Join(parts, ", ")
+		},
+	},
+	{
+		Key:  "capacidad.throughput",
+		Help: "Entrada de fruta de cada sorter (lista YAML de {sorter, por_minuto})",
+		set: func(c *SystemConfig, raw string) error {
+			var entradas []ThroughputConfig
+			if err := decodeStrict(raw, &entradas); err != nil {
+				return err
+			}
+			c.SorterThroughput = make(map[int]float64)
+			for _, entrada := range entradas {
+				if entrada.Sorter < 1 || entrada.PorMinuto <= 0 {
+					return fmt.Errorf("throughput inválido (sorter %d, por_minuto %v)", entrada.Sorter, entrada.PorMinuto)
+				}
+				c.SorterThroughput[entrada.Sorter] = entrada.PorMinuto
+			}
+			return nil
+		},
+		get: func(c *SystemConfig) string {
+			sorterIDs := make([]int, 0, len(c.SorterThroughput))
+			for sorterID := range c.SorterThroughput {
+				sorterIDs = append(sorterIDs, sorterID)
+			}
+			sort.Ints(sorterIDs)
+			var parts []string
+			for _, sorterID := range sorterIDs {
+				parts = append(parts, fmt.Sprintf("S%d=%g", sorterID, c.SorterThroughput[sorterID]))
+			}
+			return strings.Join(parts, ", ")
+		},
+	},
+
+	{
+		Key:  "pronostico.horizontes_minutos",
+		Help: "Horizontes del pronóstico de carga (lista YAML o separados por coma; vacío = deshabilitado)",
+		set: func(c *SystemConfig, raw string) error {
+			var minutes []float64
+			if strings.HasPrefix(raw, "[") || strings.HasPrefix(raw, "-") {
+				if err := decodeStrict(raw, &minutes); err != nil {
+					return err
+				}
+			} else if raw != "" {
+				for _, value := range strings.Split(raw, ",") {
+					m, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+					if err != nil {
+						return fmt.Errorf("horizonte inválido %q", value)
+					}
+					minutes = append(minutes, m)
+				}
+			}
+			c.ForecastHorizons = nil
+			for _, m := range minutes {
+				if m <= 0 {
+					return fmt.Errorf("se esperaban minutos > 0, no %v", m)
+				}
+				c.ForecastHorizons = append(c.ForecastHorizons, time.Duration(m*float64(time.Minute)))
+			}
+			return nil
+		},
+		get: func(c *SystemConfig) string {
+			var parts []string
+			for _, horizon := range c.ForecastHorizons {
+				parts = append(parts, strconv.FormatFloat(horizon.Minutes(), 'f', -1, 64))
+			}
+			return strings.Join(parts, ", ")
+		},
+	},
+	floatSetting("pronostico.alfa", "Suavizado del nivel (0..1)", func(c *SystemConfig) *float64 { return &c.ForecastAlpha }),
+	floatSetting("pronostico.beta", "Suavizado de la tendencia (0..1)", func(c *SystemConfig) *float64 { return &c.ForecastBeta }),
+	floatSetting("pronostico.confianza", "Nivel de confianza de los intervalos (0.9 = 90%)", func(c *SystemConfig) *float64 { return &c.ForecastConfidence }),
+	floatSetting("pronostico.umbral", "Porcentaje para los avisos preventivos (0 = sin avisos)", func(c *SystemConfig) *float64 { return &c.ForecastThreshold }),
+	durationSetting("pronostico.intervalo_minutos", "Tiempo entre pronósticos", time.Minute, func(c *SystemConfig) *time.Duration { return &c.ForecastInterval }),
+	durationSetting("pronostico.historial_horas", "Histórico con que se inicializa el pronóstico al arrancar", time.Hour, func(c *SystemConfig) *time.Duration { return &c.ForecastHistory }),
+
+	{
+		Key:  "turnos",
+		Help: "Turnos (lista YAML de {nombre, inicio, fin, pausas})",
+		set: func(c *SystemConfig, raw string) error {
+			var turnos []TurnoConfig
+			if err := decodeStrict(raw, &turnos); err != nil {
+				return err
+			}
+			c.Shifts = nil
+			for _, turno := range turnos {
+				shift, err := parseShift(turno)
+				if err != nil {
+					return fmt.Errorf("turno %q: %w", turno.Nombre, err)
+				}
+				c.Shifts = append(c.Shifts, shift)
+			}
+			return nil
+		},
+		get: func(c *SystemConfig) string {
+			var parts []string
+			for _, shift := range c.Shifts {
+				parts = append(parts, fmt.Sprintf("%s %s-%s", shift.Name, formatClock(shift.Start), formatClock(shift.End)))
+			}
+			return strings.Join(parts, ", ")
+		},
+	},
+	{
+		Key:  "dia_productivo_inicio",
+		Help: "Hora de inicio del día productivo (HH:MM)",
+		set: func(c *SystemConfig, raw string) error {
+			start, err := parseClock(raw)
+			c.ProductionDayStart = start
+			return err
+		},
+		get: func(c *SystemConfig) string { return formatClock(c.ProductionDayStart) },
+	},
+
+	stringSetting("api.listen", "Dirección de la API HTTP (vacío = deshabilitada)", func(c *SystemConfig) *string { return &c.APIListen }),
+	boolSetting("api.sin_autenticacion", "Permitir la API sin claves ni usuarios (cualquiera en la red puede consultarla y modificarla)", func(c *SystemConfig) *bool { return &c.APIAllowOpen }),
+	stringSetting("api.tls.certificado", "Certificado PEM para servir la API por HTTPS (vacío = HTTP)", func(c *SystemConfig) *string { return &c.APITLSCert }),
+	stringSetting("api.tls.clave", "Clave privada PEM del certificado", func(c *SystemConfig) *string { return &c.APITLSKey }),
+	{
+		Key:  "api.claves",
+		Help: "Claves de API (lista YAML de {nombre, rol, sha256}; ver monitor auth clave)",
+		set: func(c *SystemConfig, raw string) error {
+			var claves []APIClaveConfig
+			if err := decodeStrict(raw, &claves); err != nil {
+				return err
+			}
+			c.APIKeys = nil
+			seen := make(map[string]bool)
+			for _, clave := range claves {
+				key, err := parseAPIKey(clave)
+				if err != nil {
+					return err
+				}
+				if seen[key.Name] {
+					return fmt.Errorf("clave %q repetida", key.Name)
+				}
+				seen[key.Name] = true
+				c.APIKeys = append(c.APIKeys, key)
+			}
+			return nil
+		},
+		get: func(c *SystemConfig) string {
+			var parts []string
+			for _, key := range c.APIKeys {
+				parts = append(parts, fmt.Sprintf("%s (%s)", key.Name, key.Role))
+			}
+			return strings.Join(parts, ", ")
+		},
+	},
+	{
+		Key:  "api.usuarios",
+		Help: "Usuarios locales (lista YAML de {usuario, rol, bcrypt}; ver monitor auth usuario)",
+		set: func(c *SystemConfig, raw string) error {
+			var usuarios []APIUsuarioConfig
+			if err := decodeStrict(raw, &usuarios); err != nil {
+				return err
+			}
+			c.APIUsers = nil
+			seen := make(map[string]bool)
+			for _, usuario := range usuarios {
+				user, err := parseAPIUser(usuario)
+				if err != nil {
+					return err
+				}
+				if seen[user.Username] {
+					return fmt.Errorf("usuario %q repetido", user.Username)
+				}
+				seen[user.Username] = true
+				c.APIUsers = append(c.APIUsers, user)
+			}
+			return nil
+		},
+		get: func(c *SystemConfig) string {
+			var parts []string
+			for _, user := range c.APIUsers {
+				parts = append(parts, fmt.Sprintf("%s (%s)", user.Username, user.Role))
+			}
+			return strings.Join(parts, ", ")
+		},
+	},
+
+	durationSetting("alertas.cooldown_minutos", "Espera antes de volver a disparar una alerta", time.Minute, func(c *SystemConfig) *time.Duration { return &c.AlertCooldown }),
+	{
+		Key:  "alertas.reglas",
+		Help: "Reglas de alerta (lista YAML de {tipo, umbral, minutos, ciclos, sorter, severidad})",
+		set: func(c *SystemConfig, raw string) error {
+			var reglas []ReglaAlertaConfig
+			if err := decodeStrict(raw, &reglas); err != nil {
+				return err
+			}
+			c.AlertRules = nil
+			for _, regla := range reglas {
+				rule, err := parseAlertRule(regla)
+				if err != nil {
+					return err
+				}
+				c.AlertRules = append(c.AlertRules, rule)
+			}
+			return nil
+		},
+		get: func(c *SystemConfig) string {
+			var parts []string
+			for _, rule := range c.AlertRules {
+				parts = append(parts, rule.Type)
+			}
+			return strings.Join(parts, ", ")
+		},
+	},
+	stringSetting("alertas.notificadores.webhook.url", "URL del webhook de alertas", func(c *SystemConfig) *string { return &c.AlertWebhookURL }),
+	stringSetting("alertas.notificadores.email.servidor", "Servidor SMTP (host:puerto)", func(c *SystemConfig) *string { return &c.AlertEmail.Server }),
+	stringSetting("alertas.notificadores.email.usuario", "Usuario SMTP", func(c *SystemConfig) *string { return &c.AlertEmail.Username }),
+	secret(stringSetting("alertas.notificadores.email.password", "Contraseña SMTP", func(c *SystemConfig) *string { return &c.AlertEmail.Password })),
+	stringSetting("alertas.notificadores.email.de", "Remitente de las alertas", func(c *SystemConfig) *string { return &c.AlertEmail.From }),
+	listSetting("alertas.notificadores.email.para", "Destinatarios (lista YAML o separados por coma)", func(c *SystemConfig) *[]string { return &c.AlertEmail.To }),
+	stringSetting("alertas.notificadores.comando.ruta", "Comando a ejecutar por alerta", func(c *SystemConfig) *string { return &c.AlertCommand }),
+	listSetting("alertas.notificadores.comando.args", "Argumentos del comando", func(c *SystemConfig) *[]string { return &c.AlertCommandArgs }),
+
+	choiceSetting("almacenamiento.tipo", "Almacenamiento del histórico", []string{StoreJSON, StoreSQLite}, func(c *SystemConfig) *string { return &c.StoreType }),
+	stringSetting("almacenamiento.ruta", "Archivo de la base SQLite", func(c *SystemConfig) *string { return &c.SQLitePath }),
+
+	secret(stringSetting("postgres.dsn", "DSN de PostgreSQL (vacío = deshabilitado)", func(c *SystemConfig) *string { return &c.PostgresDSN })),
+	intSetting("postgres.lote", "Registros por lote", 1, func(c *SystemConfig) *int { return &c.PostgresBatchSize }),
+	durationSetting("postgres.intervalo_segundos", "Intervalo máximo entre envíos", time.Second, func(c *SystemConfig) *time.Duration { return &c.PostgresFlushInterval }),
+
+	intSetting("retencion.dias_crudos", "Días con snapshots crudos (0 = sin límite)", 0, func(c *SystemConfig) *int { return &c.RetentionDays }),
+	choiceSetting("retencion.compresion", "Compresión de los archivos históricos", []string{CompressionGzip, CompressionZstd}, func(c *SystemConfig) *string { return &c.ArchiveCompression }),
+	{
+		Key:  "retencion.presupuesto_mb",
+		Help: "Tamaño máximo de la carpeta de datos en MB (0 = sin límite)",
+		set: func(c *SystemConfig, raw string) error {
+			value, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || value < 0 {
+				return fmt.Errorf("se esperaba un entero >= 0, no %q", raw)
+			}
+			c.DiskBudgetMB = value
+			return nil
+		},
+		get: func(c *SystemConfig) string { return strconv.FormatInt(c.DiskBudgetMB, 10) },
+	},
+
+	stringSetting("advisor.ollama_url", "URL de Ollama", func(c *SystemConfig) *string { return &c.Advisor.OllamaURL }),
+	stringSetting("advisor.ollama_model", "Modelo de Ollama", func(c *SystemConfig) *string { return &c.Advisor.OllamaModel }),
+	durationSetting("advisor.timeout_segundos", "Timeout de las consultas a Ollama", time.Second, func(c *SystemConfig) *time.Duration { return &c.Advisor.Timeout }),
+	choiceSetting("advisor.idioma", "Idioma de los prompts del LLM", []string{advisor.LanguageES, advisor.LanguageEN}, func(c *SystemConfig) *string { return &c.Advisor.Language }),
+	stringSetting("advisor.plantillas", "Carpeta con las plantillas de prompt de la planta (vacío = incluidas)", func(c *SystemConfig) *string { return &c.Advisor.PromptDir }),
+	listSetting("advisor.modelos_respaldo", "Modelos de respaldo en orden (lista YAML o separados por coma)", func(c *SystemConfig) *[]string { return &c.Advisor.FallbackModels }),
+	boolSetting("advisor.descargar_modelos", "Descargar al iniciar los modelos que no estén instalados", func(c *SystemConfig) *bool { return &c.Advisor.PullMissing }),
+	boolSetting("advisor.precalentar", "Cargar el modelo en memoria antes de la primera sugerencia", func(c *SystemConfig) *bool { return &c.Advisor.WarmUp }),
+	optionalDurationSetting("advisor.salud_segundos", "Intervalo de los chequeos de Ollama (0 = solo al iniciar)", time.Second, func(c *SystemConfig) *time.Duration { return &c.OllamaHealthInterval }),
+	durationSetting("advisor.intervalo_minutos", "Intervalo entre análisis de balance", time.Minute, func(c *SystemConfig) *time.Duration { return &c.AdviceInterval }),
+
+	choiceSetting("logs.nivel", "Nivel mínimo de los logs", []string{"debug", "info", "warn", "error"}, func(c *SystemConfig) *string { return &c.LogLevel }),
+	choiceSetting("logs.formato", "Formato de los archivos de log", []string{LogFormatText, LogFormatJSON}, func(c *SystemConfig) *string { return &c.LogFormat }),
+	choiceSetting("logs.rotacion", "Rotación de los archivos de log", []string{LogRotateSize, LogRotateDay, LogRotateNone}, func(c *SystemConfig) *string { return &c.LogRotation }),
+	intSetting("logs.tamano_mb", "Tamaño máximo por archivo con rotación por tamaño", 1, func(c *SystemConfig) *int { return &c.LogMaxSizeMB }),
+	intSetting("logs.archivos", "Archivos rotados a conservar (0 = todos)", 0, func(c *SystemConfig) *int { return &c.LogMaxFiles }),
+	boolSetting("logs.stderr", "Duplicar los logs estructurados en stderr", func(c *SystemConfig) *bool { return &c.LogStderr }),
+}
+
+// derivedPaths son las rutas que se calculan a partir de data.folder
+var derivedPaths = []struct {
+	key string
+	get func(c *SystemConfig) string
+}{
+	{"(dataset)", func(c *SystemConfig) string { return c.DatasetFile }},
+	{"(snapshot actual)", func(c *SystemConfig) string { return c.CurrentSnapshotFile }},
+	{"(log de cambios)", func(c *SystemConfig) string { return c.ChangesLogFile }},
+	{"(log de advice)", func(c *SystemConfig) string { return c.AdviceLogFile }},
+	{"(csv de entrenamiento)", func(c *SystemConfig) string { return c.TrainingDataCSV }},
+	{"(rollups)", func(c *SystemConfig) string { return c.RollupsFile }},
+	{"(reportes)", func(c *SystemConfig) string { return c.ReportsFolder }},
+	{"(archivo)", func(c *SystemConfig) string { return c.ArchiveFolder }},
+	{"(spool postgres)", func(c *SystemConfig) string { return c.PostgresSpoolFile }},
+	{"(logs)", func(c *SystemConfig) string { return c.LogFolder }},
+	{"(throughput manual)", func(c *SystemConfig) string { return c.ThroughputFile }},
+	{"(auditoría)", func(c *SystemConfig) string { return c.AuditFile }},
+	{"(anotaciones)", func(c *SystemConfig) string { return c.AnnotationsFile }},
+	{"(reconocimientos)", func(c *SystemConfig) string { return c.AdviceAcksFile }},
+}
+
+// findSetting busca un valor configurable por su clave
+func findSetting(key string) (configSetting, bool) {
+	for _, setting := range configSettings {
+		if setting.Key == key {
+			return setting, true
+		}
+	}
+	return configSetting{}, false
+}
+
+// RegisterConfigFlags agrega un flag por cada valor configurable (con el mismo
+// nombre que su clave YAML) más -config. Las opciones quedan listas al
+// terminar fs.Parse.
+func RegisterConfigFlags(fs *flag.FlagSet) *ConfigOptions {
+	opts := &ConfigOptions{Flags: make(map[string]string)}
+	fs.StringVar(&opts.File, "config", "", "Archivo de configuración (por defecto DANICH_CONFIG o config.yaml)")
+
+	for _, setting := range configSettings {
+		key := setting.Key
+		fs.Func(key, setting.Help+" ["+setting.EnvVar()+"]", func(value string) error {
+			opts.Flags[key] = value
+			return nil
+		})
+	}
+	return opts
+}
+
+func secret(s configSetting) configSetting {
+	s.Secret = true
+	return s
+}
+
+func stringSetting(key, help string, field func(*SystemConfig) *string) configSetting {
+	return configSetting{
+		Key:  key,
+		Help: help,
+		set: func(c *SystemConfig, raw string) error {
+			*field(c) = raw
+			return nil
+		},
+		get: func(c *SystemConfig) string { return *field(c) },
+	}
+}
+
+func choiceSetting(key, help string, choices []string, field func(*SystemConfig) *string) configSetting {
+	return configSetting{
+		Key:  key,
+		Help: help + " (" + strings.Join(choices, " | ") + ")",
+		set: func(c *SystemConfig, raw string) error {
+			for _, choice := range choices {
+				if raw == choice {
+					*field(c) = raw
+					return nil
+				}
+			}
+			return fmt.Errorf("valor %q no permitido, opciones: %s", raw, strings.Join(choices, ", "))
+		},
+		get: func(c *SystemConfig) string { return *field(c) },
+	}
+}
+
+func intSetting(key, help string, min int, field func(*SystemConfig) *int) configSetting {
+	return configSetting{
+		Key:  key,
+		Help: help,
+		set: func(c *SystemConfig, raw string) error {
+			value, err := strconv.Atoi(raw)
+			if err != nil || value < min {
+				return fmt.Errorf("se esperaba un entero >= %d, no %q", min, raw)
+			}
+			*field(c) = value
+			return nil
+		},
+		get: func(c *SystemConfig) string { return strconv.Itoa(*field(c)) },
+	}
+}
+
+func floatSetting(key, help string, field func(*SystemConfig) *float64) configSetting {
+	return configSetting{
+		Key:  key,
+		Help: help,
+		set: func(c *SystemConfig, raw string) error {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil || value < 0 {
+				return fmt.Errorf("se esperaba un número >= 0, no %q", raw)
+			}
+			*field(c) = value
+			return nil
+		},
+		get: func(c *SystemConfig) string { return strconv.FormatFloat(*field(c), 'f', -1, 64) },
+	}
+}
+
+func boolSetting(key, help string, field func(*SystemConfig) *bool) configSetting {
+	return configSetting{
+		Key:  key,
+		Help: help,
+		set: func(c *SystemConfig, raw string) error {
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("se esperaba true o false, no %q", raw)
+			}
+			*field(c) = value
+			return nil
+		},
+		get: func(c *SystemConfig) string { return strconv.FormatBool(*field(c)) },
+	}
+}
+
+// durationSetting lee un número de unidades (segundos, minutos) mayor que cero
+func durationSetting(key, help string, unit time.Duration, field func(*SystemConfig) *time.Duration) configSetting {
+	return configSetting{
+		Key:  key,
+		Help: help,
+		set: func(c *SystemConfig, raw string) error {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil || value <= 0 {
+				return fmt.Errorf("se esperaba un número > 0, no %q", raw)
+			}
+			*field(c) = time.Duration(value * float64(unit))
+			return nil
+		},
+		get: func(c *SystemConfig) string {
+			return strconv.FormatFloat(float64(*field(c))/float64(unit), 'f', -1, 64)
+		},
+	}
+}
+
+// optionalDurationSetting es como durationSetting pero acepta 0 (deshabilitado)
+func optionalDurationSetting(key, help string, unit time.Duration, field func(*SystemConfig) *time.Duration) configSetting {
+	return configSetting{
+		Key:  key,
+		Help: help,
+		set: func(c *SystemConfig, raw string) error {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil || value < 0 {
+				return fmt.Errorf("se esperaba un número >= 0, no %q", raw)
+			}
+			*field(c) = time.Duration(value * float64(unit))
+			return nil
+		},
+		get: func(c *SystemConfig) string {
+			return strconv.FormatFloat(float64(*field(c))/float64(unit), 'f', -1, 64)
+		},
+	}
+}
+
+// listSetting acepta una lista YAML o valores separados por coma
+func listSetting(key, help string, field func(*SystemConfig) *[]string) configSetting {
+	return configSetting{
+		Key:  key,
+		Help: help,
+		set: func(c *SystemConfig, raw string) error {
+			var values []string
+			if strings.HasPrefix(raw, "[") || strings.HasPrefix(raw, "-") {
+				if err := decodeStrict(raw, &values); err != nil {
+					return err
+				}
+			} else if raw != "" {
+				for _, value := range strings.Split(raw, ",") {
+					values = append(values, strings.TrimSpace(value))
+				}
+			}
+			*field(c) = values
+			return nil
+		},
+		get: func(c *SystemConfig) string { return strings.Join(*field(c), ", ") },
+	}
+}
+
+// decodeStrict decodifica YAML rechazando claves desconocidas
+func decodeStrict(raw string, target interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.KnownFields(true)
+	return decoder.Decode(target)
+}
+
+// formatClock muestra una hora del día como HH:MM
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
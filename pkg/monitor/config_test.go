@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConfigUnknownEnvVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		dotEnv  string
+		wantErr string
+	}{
+		{name: "variable conocida", env: map[string]string{"DANICH_PACKING_NAME": "Planta"}},
+		{name: "archivo de configuración", env: map[string]string{"DANICH_CONFIG": "config.yaml"}},
+		{name: "variables del notificador", env: map[string]string{"DANICH_ALERT_RULE": "saturacion"}},
+		{name: "otro prefijo", env: map[string]string{"DANICHE_PACKNG_NAME": "x"}},
+		{
+			name:    "error de tipeo en el entorno",
+			env:     map[string]string{"DANICH_PACKNG_NAME": "Planta"},
+			wantErr: "DANICH_PACKNG_NAME (env): variable desconocida",
+		},
+		{
+			name:    "error de tipeo en .env",
+			dotEnv:  "DANICH_POSTGRES_DNS=postgres://localhost/danich\n",
+			wantErr: "DANICH_POSTGRES_DNS (.env): variable desconocida",
+		},
+		{
+			name:    "mayúsculas y minúsculas",
+			env:     map[string]string{"danich_monitor_intervalo": "10"},
+			wantErr: "danich_monitor_intervalo (env): variable desconocida",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if err := os.WriteFile("config.yaml", nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.dotEnv != "" {
+				if err := os.WriteFile(".env", []byte(tt.dotEnv), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := LoadConfigWith(ConfigOptions{})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("error inesperado: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, esperaba %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigMissingFileIsNote(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	cfg, err := LoadConfigWith(ConfigOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Notes) != 1 || !strings.Contains(cfg.Notes[0], "No se encontró config.yaml") {
+		t.Errorf("avisos = %q", cfg.Notes)
+	}
+
+	// Un archivo pedido explícitamente que no existe sigue siendo un error
+	if _, err := LoadConfigWith(ConfigOptions{File: "otro.yaml"}); err == nil {
+		t.Error("se esperaba error con un archivo explícito inexistente")
+	}
+}
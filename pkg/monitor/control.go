@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errores del reconocimiento de sugerencias
+var (
+	ErrNoAdvice      = errors.New("no hay una sugerencia vigente")
+	ErrAdviceChanged = errors.New("la sugerencia cambió desde que se mostró")
+	ErrAdviceAcked   = errors.New("la sugerencia ya fue reconocida")
+)
+
+// reloadableKeys son las claves que se aplican al recargar la configuración
+// sin reiniciar el monitor. Solo se leen dentro del ciclo o en la API, así
+// que se reemplazan entre ciclos.
+var reloadableKeys = map[string]bool{
+	"api.claves":                true,
+	"api.usuarios":              true,
+	"alertas.reglas":            true,
+	"advisor.intervalo_minutos": true,
+	"drift.disparar_advisor":    true,
+}
+
+// ConfigReload es el resultado de recargar la configuración
+type ConfigReload struct {
+	Applied []string `json:"aplicados"`
+	Restart []string `json:"requieren_reinicio"` // Cambiaron pero rigen desde el próximo inicio
+}
+
+// AcknowledgeAdvice registra que un supervisor reconoció la sugerencia
+// vigente. adviceAt es el DateTime de la sugerencia que vio el supervisor
+// (cero = la vigente, sea cual sea).
+func (m *Monitor) AcknowledgeAdvice(usuario, nota string, adviceAt time.Time) (*AdviceRecord, error) {
+	m.adviceMu.Lock()
+	defer m.adviceMu.Unlock()
+
+	if m.lastAdvice == nil {
+		return nil, ErrNoAdvice
+	}
+	if !adviceAt.IsZero() && !adviceAt.Equal(m.lastAdvice.DateTime) {
+		return nil, ErrAdviceChanged
+	}
+	if previous := m.lastAdvice.Ack; previous != nil {
+		return nil, fmt.Errorf("%w por %s a las %s", ErrAdviceAcked, previous.Usuario, previous.DateTime.Format("15:04"))
+	}
+
+	ack := AdviceAck{
+		DateTime: time.Now(),
+		AdviceAt: m.lastAdvice.DateTime,
+		Accion:   m.lastAdvice.Advice.Accion,
+		SKU:      m.lastAdvice.Advice.SKU,
+		Usuario:  usuario,
+		Nota:     nota,
+	}
+	if err := m.persistence.AppendAdviceAck(ack); err != nil {
+		return nil, err
+	}
+
+	record := *m.lastAdvice
+	record.Ack = &ack
+	m.lastAdvice = &record
+	if m.apiServer != nil && m.lastDashboard != nil {
+		state := *m.lastDashboard
+		state.Advice = &record
+		m.lastDashboard = &state
+		m.apiServer.Publish(m.lastDashSnapshot, state)
+	}
+
+	// La consola es del ciclo en curso; el aviso espera a que termine
+	go func() {
+		m.outputMu.Lock()
+		defer m.outputMu.Unlock()
+		m.display.ShowAdviceAck(ack)
+	}()
+
+	return &record, nil
+}
+
+// ReloadConfig vuelve a leer la configuración con las mismas opciones del
+// inicio y aplica las claves recargables entre ciclos. Una configuración
+// inválida no cambia nada.
+func (m *Monitor) ReloadConfig() (*ConfigReload, error) {
+	next, err := readConfig(m.options)
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range next.Notes {
+		m.logger.Warn(note)
+	}
+
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+
+	result := &ConfigReload{Applied: []string{}, Restart: []string{}}
+	for _, key := range m.config.ChangedKeys(next) {
+		if reloadableKeys[key] {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.Restart = append(result.Restart, key)
+		}
+	}
+
+	m.config.APIKeys = next.APIKeys
+	m.config.APIUsers = next.APIUsers
+	m.config.AlertRules = next.AlertRules
+	m.config.AdviceInterval = next.AdviceInterval
+	m.config.DriftTriggerAdvice = next.DriftTriggerAdvice
+	if m.apiServer != nil {
+		m.apiServer.SetCredentials(next.APIKeys, next.APIUsers)
+	}
+
+	m.logger.Info("configuración recargada", "aplicados", result.Applied, "requieren_reinicio", result.Restart)
+	return result, nil
+}
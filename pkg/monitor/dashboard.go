@@ -0,0 +1,178 @@
+package monitor
+
+import (
+	"sort"
+	"strings"
+
+	"danich/pkg/advisor"
+	"danich/pkg/alerts"
+)
+
+// maxRecentChanges es la cantidad de cambios que se muestran en el dashboard
+const maxRecentChanges = 20
+
+// DashboardSKU es un SKU en el gráfico de un sorter
+type DashboardSKU struct {
+	SKU        string  `json:"sku"`
+	Percentage float64 `json:"percentage"`
+	Salidas    []int   `json:"salidas"`
+}
+
+// DashboardSorter contiene las barras de un sorter en el orden del gráfico
+type DashboardSorter struct {
+	SorterID int            `json:"sorter_id"`
+	SKUs     []DashboardSKU `json:"skus"`
+}
+
+// DashboardSalida es una celda del mapa salida → SKU
+type DashboardSalida struct {
+	SorterID int      `json:"sorter_id"`
+	Salida   int      `json:"salida"`
+	SKUs     []string `json:"skus"`
+}
+
+// DashboardState es el estado que se envía al dashboard web en cada ciclo
+type DashboardState struct {
+	Timestamp     string                `json:"timestamp"`
+	CheckCount    int                   `json:"check_count"`
+	Shift         string                `json:"shift,omitempty"`
+	ProductionDay string                `json:"production_day,omitempty"`
+	Sorters       []DashboardSorter     `json:"sorters"`
+	Salidas       []DashboardSalida     `json:"salidas"`
+	Capacity      *CapacityReport       `json:"capacity,omitempty"`
+	Forecasts     []Forecast            `json:"forecasts,omitempty"`
+	Accuracy      []ForecastAccuracy    `json:"forecast_accuracy,omitempty"`
+	Changes       []ChangeLog           `json:"changes"` // Más reciente primero
+	Advice        *AdviceRecord         `json:"advice,omitempty"`
+	Alerts        []alerts.Alert        `json:"alerts"`
+	Ollama        *advisor.OllamaHealth `json:"ollama,omitempty"` // Último chequeo (sin LLM configurado = nil)
+}
+
+// buildDashboardState arma el estado del dashboard a partir del último snapshot
+func (m *Monitor) buildDashboardState(snapshot DataSnapshot, checkCount int) DashboardState {
+	state := DashboardState{
+		Timestamp:     snapshot.Timestamp,
+		CheckCount:    checkCount,
+		Shift:         snapshot.Shift,
+		ProductionDay: snapshot.ProductionDay,
+		Sorters:       []DashboardSorter{},
+		Salidas:       []DashboardSalida{},
+		Capacity:      snapshot.Capacity,
+		Forecasts:     m.forecasts,
+		Accuracy:      m.forecaster.Accuracy(),
+		Changes:       []ChangeLog{},
+		Advice:        m.latestAdvice(),
+		Alerts:        m.alertEngine.Active(),
+	}
+	if health := m.nativeAdvisor.Health(); !health.CheckedAt.IsZero() {
+		state.Ollama = &health
+	}
+
+	sorterIDs := make([]int, 0, len(snapshot.ChartData))
+	for sorterID := range snapshot.ChartData {
+		sorterIDs = append(sorterIDs, sorterID)
+	}
+	sort.Ints(sorterIDs)
+
+	for _, sorterID := range sorterIDs {
+		chartData := snapshot.ChartData[sorterID]
+		if chartData == nil {
+			continue
+		}
+		sorter := DashboardSorter{SorterID: sorterID, SKUs: []DashboardSKU{}}
+		for _, sku := range chartData.OrderedSKUs {
+			salidas := getLinesForSKU(snapshot.Assignments, sorterID, sku)
+			sort.Ints(salidas)
+			sorter.SKUs = append(sorter.SKUs, DashboardSKU{
+				SKU:        sku,
+				Percentage: chartData.Percentages[sku],
+				Salidas:    salidas,
+			})
+		}
+		state.Sorters = append(state.Sorters, sorter)
+	}
+
+	state.Salidas = salidaMap(snapshot.Assignments)
+
+	for i := len(m.recentChanges) - 1; i >= 0; i-- {
+		state.Changes = append(state.Changes, m.recentChanges[i])
+	}
+
+	return state
+}
+
+// salidaMap agrupa los assignments por sorter y salida
+func salidaMap(assignments []Assignment) []DashboardSalida {
+	type key struct{ sorterID, salida int }
+	cells := make(map[key]*DashboardSalida)
+
+	for _, a := range assignments {
+		k := key{a.SorterID, a.Salida}
+		cell, exists := cells[k]
+		if !exists {
+			cell = &DashboardSalida{SorterID: a.SorterID, Salida: a.Salida}
+			cells[k] = cell
+		}
+		cell.SKUs = append(cell.SKUs, strings.ToUpper(a.SKU))
+	}
+
+	result := make([]DashboardSalida, 0, len(cells))
+	for _, cell := range cells {
+		sort.Strings(cell.SKUs)
+		result = append(result, *cell)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SorterID != result[j].SorterID {
+			return result[i].SorterID < result[j].SorterID
+		}
+		return result[i].Salida < result[j].Salida
+	})
+
+	return result
+}
+
+// publishDashboard publica el estado del ciclo en la API y lo guarda para
+// volver a publicarlo cuando llegue una sugerencia del worker del advisor
+func (m *Monitor) publishDashboard(snapshot DataSnapshot, state DashboardState) {
+	if m.apiServer == nil {
+		return
+	}
+
+	m.adviceMu.Lock()
+	defer m.adviceMu.Unlock()
+
+	state.Advice = m.lastAdvice
+	m.lastDashboard = &state
+	m.lastDashSnapshot = snapshot
+	m.apiServer.Publish(snapshot, state)
+}
+
+// latestAdvice retorna la última sugerencia registrada
+func (m *Monitor) latestAdvice() *AdviceRecord {
+	m.adviceMu.Lock()
+	defer m.adviceMu.Unlock()
+	return m.lastAdvice
+}
+
+// setLatestAdvice reemplaza la última sugerencia y actualiza el dashboard
+// sin esperar al próximo ciclo
+func (m *Monitor) setLatestAdvice(record AdviceRecord) {
+	m.adviceMu.Lock()
+	defer m.adviceMu.Unlock()
+
+	m.lastAdvice = &record
+	if m.apiServer != nil && m.lastDashboard != nil {
+		state := *m.lastDashboard
+		state.Advice = &record
+		m.lastDashboard = &state
+		m.apiServer.Publish(m.lastDashSnapshot, state)
+	}
+}
+
+// rememberChange guarda un cambio en la lista de cambios recientes
+func (m *Monitor) rememberChange(change ChangeLog) {
+	m.recentChanges = append(m.recentChanges, change)
+	if len(m.recentChanges) > maxRecentChanges {
+		m.recentChanges = m.recentChanges[len(m.recentChanges)-maxRecentChanges:]
+	}
+}
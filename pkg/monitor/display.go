@@ -2,73 +2,176 @@ package monitor
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
 	"strings"
 	"time"
+
+	"danich/pkg/advisor"
+	"danich/pkg/alerts"
 )
 
-// Display maneja la visualización de estadísticas
+// Display es la vista de consola del monitor: presenta el avance de cada
+// ciclo en formato legible. Los registros estructurados van al logger.
 type Display struct {
 	config *SystemConfig
+	out    io.Writer
+	tui    *TUI // nil = texto con scroll
 }
 
-// NewDisplay crea un nuevo display
-func NewDisplay(config *SystemConfig) *Display {
-	return &Display{
+// NewDisplay crea un nuevo display. Con tui los mensajes van a su panel de
+// eventos y el estado de cada ciclo se dibuja a pantalla completa; sin tui
+// todo se escribe en stdout.
+func NewDisplay(config *SystemConfig, tui *TUI) *Display {
+	d := &Display{
 		config: config,
+		out:    os.Stdout,
+		tui:    tui,
+	}
+	if tui != nil {
+		d.out = tui
+	}
+	return d
+}
+
+// Start inicia la TUI, si corresponde
+func (d *Display) Start() {
+	if d.tui != nil {
+		d.tui.Start()
+	}
+}
+
+// Close restaura la terminal si se usaba la TUI
+func (d *Display) Close() {
+	if d.tui != nil {
+		d.tui.Close()
+	}
+}
+
+// ShowDashboard dibuja el estado del ciclo en la TUI; en modo texto el
+// estado ya se mostró con ShowStats
+func (d *Display) ShowDashboard(snapshot DataSnapshot, state DashboardState) {
+	if d.tui != nil {
+		d.tui.Update(snapshot, state)
+	}
+}
+
+// ShowSources actualiza los indicadores de las fuentes en la TUI; en modo
+// texto las fallas se informan con ShowSnapshot y los logs
+func (d *Display) ShowSources(sources []SourceStatus, failures map[string]int) {
+	if d.tui != nil {
+		d.tui.SetSources(sources, failures)
 	}
 }
 
 // ShowStats muestra las estadísticas del sistema
-func (d *Display) ShowStats(snapshot DataSnapshot, dataset TrainingDataset, startTime time.Time) {
+func (d *Display) ShowStats(snapshot DataSnapshot, totalSnapshots int, startTime time.Time) {
+	if d.tui != nil {
+		d.tui.SetStats(totalSnapshots, startTime)
+		return
+	}
 	duration := time.Since(startTime)
 
-	fmt.Println("\n" + d.repeat("-", 60))
-	fmt.Println("📊 Estadísticas de recolección:")
-	fmt.Printf("  • Total snapshots: %d\n", dataset.TotalSnapshots)
-	fmt.Printf("  • Tiempo de ejecución: %v\n", duration.Round(time.Second))
-	fmt.Printf("  • Assignments actuales: %d\n", snapshot.TotalCount)
+	fmt.Fprintln(d.out, "\n"+d.repeat("-", 60))
+	fmt.Fprintln(d.out, "📊 Estadísticas de recolección:")
+	fmt.Fprintf(d.out, "  • Total snapshots: %d\n", totalSnapshots)
+	fmt.Fprintf(d.out, "  • Tiempo de ejecución: %v\n", duration.Round(time.Second))
+	fmt.Fprintf(d.out, "  • Assignments actuales: %d\n", snapshot.TotalCount)
+	if snapshot.Shift != "" {
+		pause := ""
+		if snapshot.InBreak {
+			pause = " (en pausa)"
+		}
+		fmt.Fprintf(d.out, "  • Turno: %s%s - día productivo %s\n", snapshot.Shift, pause, snapshot.ProductionDay)
+	}
 
 	d.showSorterStats(snapshot)
 	d.showSalidaStats(snapshot)
+	d.showCapacity(snapshot)
 	d.showGlobalDistribution(snapshot)
 	d.showSorterDistributions(snapshot)
 	d.showSalidaDistributions(snapshot)
 
-	fmt.Println(d.repeat("-", 60))
+	fmt.Fprintln(d.out, d.repeat("-", 60))
+}
+
+// ShowAdvisorHealth muestra el estado de Ollama según el último chequeo
+func (d *Display) ShowAdvisorHealth(health advisor.OllamaHealth) {
+	if d.tui != nil {
+		return // La línea de salud de la TUI ya lo muestra
+	}
+	switch {
+	case health.CheckedAt.IsZero():
+		fmt.Fprintln(d.out, "🧠 Ollama: verificando modelos...")
+	case health.Healthy():
+		kind := ""
+		if health.Fallback {
+			kind = " (respaldo)"
+		}
+		fmt.Fprintf(d.out, "🧠 Ollama: ✓ %s%s %s · %d ms\n", health.Model, kind, health.Details, health.LatencyMs)
+		if len(health.Missing) > 0 {
+			fmt.Fprintf(d.out, "   No instalados: %s\n", strings.Join(health.Missing, ", "))
+		}
+	default:
+		fmt.Fprintf(d.out, "🧠 Ollama: ✗ %s (%d chequeo(s) fallidos) - solo reglas\n", health.Error, health.Failures)
+	}
 }
 
 // showSorterStats muestra estadísticas por sorter
 func (d *Display) showSorterStats(snapshot DataSnapshot) {
 	if len(snapshot.BySorter) > 0 {
-		fmt.Print("  • Por sorter: ")
+		fmt.Fprint(d.out, "  • Por sorter: ")
 		for i := 1; i <= d.config.PackingSorters; i++ {
 			if count, exists := snapshot.BySorter[i]; exists {
-				fmt.Printf("Sorter %d=%d ", i, count)
+				fmt.Fprintf(d.out, "Sorter %d=%d ", i, count)
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(d.out)
 	}
 }
 
 // showSalidaStats muestra estadísticas por salida
 func (d *Display) showSalidaStats(snapshot DataSnapshot) {
 	if len(snapshot.BySalida) > 0 {
-		fmt.Print("  • Por salida: ")
+		fmt.Fprint(d.out, "  • Por salida: ")
 		for salida := 1; salida <= d.config.PackingLineas; salida++ {
 			if count, exists := snapshot.BySalida[salida]; exists {
-				fmt.Printf("S%d=%d ", salida, count)
+				fmt.Fprintf(d.out, "S%d=%d ", salida, count)
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(d.out)
+	}
+}
+
+// showCapacity muestra la utilización de cada sorter y las salidas sobrecargadas
+func (d *Display) showCapacity(snapshot DataSnapshot) {
+	report := snapshot.Capacity
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintf(d.out, "\n  • Carga (%s):\n", report.UnitLabel())
+	for _, sorter := range report.Sorters {
+		fmt.Fprintf(d.out, "    Sorter %d: entrada %.1f (%s), capacidad %.1f - %.0f%%\n",
+			sorter.SorterID, sorter.Throughput, sorter.ThroughputSource, sorter.Capacity, sorter.Utilization*100)
+		if sorter.Unallocated > 0 {
+			fmt.Fprintf(d.out, "      %.1f sin salida asignada\n", sorter.Unallocated)
+		}
+	}
+	for _, line := range report.Overloaded() {
+		fmt.Fprintf(d.out, "    ⚠️  S%d salida %d (%s): %.1f de %.1f - %.0f%%\n",
+			line.SorterID, line.Salida, strings.Join(line.SKUs, ", "), line.Load, line.Capacity, line.Utilization*100)
 	}
 }
 
 // showGlobalDistribution muestra la distribución global
 func (d *Display) showGlobalDistribution(snapshot DataSnapshot) {
 	if len(snapshot.CalibrePercent) > 0 {
-		fmt.Println("\n  • Distribución global (promedio entre sorters):")
-		for sku, percent := range snapshot.CalibrePercent {
-			fmt.Printf("    - %s: %.0f%%\n", sku, percent)
+		fmt.Fprintln(d.out, "\n  • Distribución global (promedio entre sorters):")
+		for _, sku := range sortedSKUs(snapshot.CalibrePercent) {
+			fmt.Fprintf(d.out, "    - %s: %.0f%%\n", sku, snapshot.CalibrePercent[sku])
 		}
 	}
 }
@@ -76,13 +179,13 @@ func (d *Display) showGlobalDistribution(snapshot DataSnapshot) {
 // showSorterDistributions muestra distribuciones por sorter
 func (d *Display) showSorterDistributions(snapshot DataSnapshot) {
 	if len(snapshot.CalibreBySorter) > 0 && len(snapshot.ChartData) > 0 {
-		fmt.Println("\n  • Distribución por Sorter (datos reales del gráfico):")
+		fmt.Fprintln(d.out, "\n  • Distribución por Sorter (datos reales del gráfico):")
 		for sorterID := 1; sorterID <= d.config.PackingSorters; sorterID++ {
 			if chartData, hasChart := snapshot.ChartData[sorterID]; hasChart {
-				fmt.Printf("    Sorter %d:\n", sorterID)
+				fmt.Fprintf(d.out, "    Sorter %d:\n", sorterID)
 				for _, sku := range chartData.OrderedSKUs {
 					if dist, exists := snapshot.CalibreBySorter[sorterID][sku]; exists {
-						fmt.Printf("      %s: %.0f%%\n", sku, dist.Percentage)
+						fmt.Fprintf(d.out, "      %s: %.0f%%\n", sku, dist.Percentage)
 					}
 				}
 			}
@@ -93,18 +196,242 @@ func (d *Display) showSorterDistributions(snapshot DataSnapshot) {
 // showSalidaDistributions muestra distribuciones por salida
 func (d *Display) showSalidaDistributions(snapshot DataSnapshot) {
 	if len(snapshot.CalibreBySalida) > 0 {
-		fmt.Println("\n  • Distribución por Salida:")
+		fmt.Fprintln(d.out, "\n  • Distribución por Salida:")
 		for salida := 1; salida <= d.config.PackingLineas; salida++ {
 			if skus, exists := snapshot.CalibreBySalida[salida]; exists {
-				fmt.Printf("    Salida %d:\n", salida)
+				fmt.Fprintf(d.out, "    Salida %d:\n", salida)
+				percentages := make(map[string]float64, len(skus))
 				for sku, dist := range skus {
-					fmt.Printf("      %s: %.0f%%\n", sku, dist.Percentage)
+					percentages[sku] = dist.Percentage
+				}
+				for _, sku := range sortedSKUs(percentages) {
+					fmt.Fprintf(d.out, "      %s: %.0f%%\n", sku, skus[sku].Percentage)
 				}
 			}
 		}
 	}
 }
 
+// ShowHeader muestra el header del monitor
+func (d *Display) ShowHeader() {
+	fmt.Fprintln(d.out, "=== Monitor de Asignaciones - Recolección de Datos ===")
+	fmt.Fprintf(d.out, "URL: %s\n", d.config.AssignmentsURL)
+	fmt.Fprintf(d.out, "Intervalo de verificación: %v\n", d.config.CheckInterval)
+	fmt.Fprintf(d.out, "Carpeta de datos: %s\n", d.config.DatasetFolder)
+	fmt.Fprintf(d.out, "Captura de gráficos: %v\n", d.config.CaptureCharts)
+	fmt.Fprintf(d.out, "Logs: %s (%s, nivel %s)\n", d.config.LogFolder, d.config.LogFormat, d.config.LogLevel)
+	fmt.Fprintln(d.out, "Presiona Ctrl+C para detener")
+	fmt.Fprintln(d.out, d.repeat("=", 60))
+}
+
+// ShowReady muestra que un componente quedó listo
+func (d *Display) ShowReady(message string) {
+	fmt.Fprintf(d.out, "✓ %s\n", message)
+}
+
+// ShowCycleStart muestra el inicio de una verificación
+func (d *Display) ShowCycleStart(timestamp string, checkCount int) {
+	fmt.Fprintf(d.out, "\n[%s] Verificación #%d\n", timestamp, checkCount)
+}
+
+// ShowSnapshot muestra el resumen de lo capturado en el ciclo
+func (d *Display) ShowSnapshot(snapshot DataSnapshot) {
+	fmt.Fprintf(d.out, "✓ Obtenidos %d assignments\n", snapshot.TotalCount)
+	if len(snapshot.ChartData) > 0 {
+		fmt.Fprintf(d.out, "📊 Gráficos capturados: %d sorters con porcentajes reales\n", len(snapshot.ChartData))
+	}
+	if snapshot.Partial {
+		var failed []string
+		for _, source := range snapshot.Sources {
+			if !source.OK {
+				failed = append(failed, source.Source)
+			}
+		}
+		fmt.Fprintf(d.out, "⚠️  Snapshot parcial, fallaron: %s\n", strings.Join(failed, ", "))
+	}
+	if snapshot.Quality != nil && !snapshot.Quality.OK {
+		fmt.Fprintf(d.out, "🔎 Calidad de datos: %d problema(s)\n", len(snapshot.Quality.Issues))
+		for _, issue := range snapshot.Quality.Issues {
+			fmt.Fprintf(d.out, "   %s\n", issue.Message)
+		}
+	}
+}
+
+// ShowChanges muestra los cambios de asignación detectados
+func (d *Display) ShowChanges(changes ChangeDetail) {
+	fmt.Fprintln(d.out, "🔔 ¡CAMBIOS DETECTADOS!")
+	for _, e := range changes.Events {
+		fmt.Fprintf(d.out, "   %s\n", e.Describe())
+	}
+}
+
+// ShowFirstCapture indica que es la primera captura de datos
+func (d *Display) ShowFirstCapture() {
+	fmt.Fprintln(d.out, "📊 Primera captura de datos")
+}
+
+// ShowStaleAssignments indica que no se pudieron comparar assignments
+func (d *Display) ShowStaleAssignments() {
+	fmt.Fprintln(d.out, "⏸️  Sin assignments frescos: se omite la detección de cambios")
+}
+
+// ShowNoChanges indica que no hubo cambios de asignación
+func (d *Display) ShowNoChanges() {
+	fmt.Fprintln(d.out, "✓ Sin cambios")
+}
+
+// ShowLoadShifts muestra los desplazamientos de carga detectados
+func (d *Display) ShowLoadShifts(shifts []LoadShift) {
+	fmt.Fprintln(d.out, "📈 ¡DESPLAZAMIENTO DE CARGA DETECTADO!")
+	for _, s := range shifts {
+		fmt.Fprintf(d.out, "   Sorter %d: %s %.1f%% → %.1f%% (%+.1f)\n", s.SorterID, s.SKU, s.From, s.To, s.Delta)
+	}
+}
+
+// ShowExported indica que el snapshot se exportó al CSV de entrenamiento
+func (d *Display) ShowExported() {
+	fmt.Fprintln(d.out, "✓ Datos exportados a training_data.csv")
+}
+
+// ShowAlert muestra una alerta disparada o resuelta
+func (d *Display) ShowAlert(alert alerts.Alert) {
+	if alert.State == alerts.StateFiring {
+		fmt.Fprintf(d.out, "🚨 ALERTA [%s] %s\n", alert.Severity, alert.Message)
+	} else {
+		fmt.Fprintf(d.out, "✅ Alerta resuelta: %s\n", alert.Message)
+	}
+}
+
+// ShowForecasts muestra los SKUs que según el pronóstico superarán el umbral
+// y el error de los pronósticos ya comparados con lo observado
+func (d *Display) ShowForecasts(crossings []ThresholdCrossing, accuracy []ForecastAccuracy) {
+	for _, c := range crossings {
+		fmt.Fprintf(d.out, "🔮 %s en S%d: %.1f%% → %.1f%% en %d min (supera %.0f%% en ~%.0f min)\n",
+			c.SKU, c.SorterID, c.Current, c.Predicted, c.HorizonMin, c.Threshold, math.Ceil(c.Minutes))
+	}
+
+	var parts []string
+	for _, acc := range accuracy {
+		parts = append(parts, fmt.Sprintf("%d min: ±%.1f pts, %.0f%% en intervalo (n=%d)",
+			acc.HorizonMin, acc.MAE, acc.Coverage*100, acc.Count))
+	}
+	if len(parts) > 0 {
+		fmt.Fprintf(d.out, "🔮 Error del pronóstico: %s\n", strings.Join(parts, "; "))
+	}
+}
+
+// ShowAdviceAck informa que un supervisor reconoció la sugerencia vigente
+func (d *Display) ShowAdviceAck(ack AdviceAck) {
+	if d.tui != nil {
+		d.tui.SetAck(ack)
+		return
+	}
+	fmt.Fprintf(d.out, "👍 Sugerencia reconocida por %s", ack.Usuario)
+	if ack.Nota != "" {
+		fmt.Fprintf(d.out, ": %s", ack.Nota)
+	}
+	fmt.Fprintln(d.out)
+}
+
+// ShowAdvice muestra la sugerencia del advisor de forma visual
+func (d *Display) ShowAdvice(checkCount int, advice *advisor.Advice) {
+	if d.tui != nil {
+		d.tui.SetAdvice(*advice)
+	}
+	fmt.Fprintf(d.out, "\n🤖 ANÁLISIS DE BALANCE (Verificación #%d)\n", checkCount)
+	fmt.Fprintln(d.out, "═"+d.repeat("═", 48))
+
+	switch advice.Accion {
+	case "mantener":
+		fmt.Fprintf(d.out, "✅ %s\n", advice.Razon)
+
+	case "mover":
+		fmt.Fprintf(d.out, "💡 SUGERENCIA DE OPTIMIZACIÓN\n")
+		fmt.Fprintln(d.out, "═"+d.repeat("═", 48))
+		fmt.Fprintf(d.out, "SKU: %s\n", advice.SKU)
+		fmt.Fprintf(d.out, "Movimiento: Sorter %d → Sorter %d\n", advice.DeSorter, advice.ASorter)
+		fmt.Fprintf(d.out, "📋 Razón: %s\n", advice.Razon)
+		fmt.Fprintf(d.out, "🕐 Timestamp: %s\n", advice.Timestamp)
+		fmt.Fprintln(d.out, "═"+d.repeat("═", 48))
+
+	case "prevenir":
+		fmt.Fprintf(d.out, "⏳ AVISO PREVENTIVO\n")
+		fmt.Fprintf(d.out, "📋 %s\n", advice.Razon)
+
+	default:
+		fmt.Fprintf(d.out, "ℹ️  %s\n", advice.Razon)
+	}
+	d.showTrace(advice.Trace)
+}
+
+// showTrace resume la explicación de la sugerencia; el detalle completo se
+// consulta con el comando sugerencias
+func (d *Display) showTrace(trace *advisor.AdviceTrace) {
+	if trace == nil {
+		return
+	}
+	fmt.Fprintf(d.out, "🔎 Evaluados: %d desbalance(s) críticos, %d candidato(s) descartados\n",
+		len(trace.Imbalances), len(trace.Rejected))
+
+	switch llm := trace.LLM; {
+	case llm == nil:
+	case !llm.Used:
+		fmt.Fprintf(d.out, "🔎 LLM %s sin respuesta: %s (se usa la regla)\n", llm.Model, llm.Error)
+	case llm.Overridden():
+		fmt.Fprintf(d.out, "🔎 LLM %s cambió la regla (%s): era %s\n", llm.Model, strings.Join(llm.Changed, ", "), trace.Rule.Summary())
+	default:
+		fmt.Fprintf(d.out, "🔎 LLM %s confirmó la regla\n", llm.Model)
+	}
+}
+
+// ShowRetention muestra lo archivado y eliminado por la política de retención
+func (d *Display) ShowRetention(result RetentionResult) {
+	if len(result.ArchivedDays) > 0 {
+		fmt.Fprintf(d.out, "🗄️  Archivados %d snapshots de %d día(s): %s\n",
+			result.ArchivedSnapshots, len(result.ArchivedDays), strings.Join(result.ArchivedDays, ", "))
+	}
+	for _, path := range result.DeletedFiles {
+		fmt.Fprintf(d.out, "🗑️  Eliminado por presupuesto de disco: %s\n", path)
+	}
+}
+
+// ShowShiftReport indica dónde se guardó el reporte de un turno
+func (d *Display) ShowShiftReport(key, path string) {
+	fmt.Fprintf(d.out, "📋 Reporte de turno %s guardado en %s\n", key, path)
+}
+
+// ShowNextCheck muestra cuándo será la próxima verificación
+func (d *Display) ShowNextCheck(next time.Time, interval time.Duration, fast bool, stats SchedulerStats) {
+	if d.tui != nil {
+		d.tui.SetNext(next, interval, fast, stats)
+		return
+	}
+	mode := ""
+	if fast {
+		mode = ", polling rápido"
+	}
+	fmt.Fprintf(d.out, "\nPróxima verificación a las %s (cada %v%s)...\n", next.Format("15:04:05"), interval, mode)
+	if stats.Overruns > 0 {
+		fmt.Fprintf(d.out, "   ⏱️  Ciclos excedidos: %d (ticks omitidos: %d)\n", stats.Overruns, stats.SkippedTicks)
+	}
+}
+
+// sortedSKUs ordena los SKUs de mayor a menor porcentaje (y por nombre si
+// empatan), para no mostrarlos en el orden aleatorio de los mapas
+func sortedSKUs(percentages map[string]float64) []string {
+	skus := make([]string, 0, len(percentages))
+	for sku := range percentages {
+		skus = append(skus, sku)
+	}
+	sort.Slice(skus, func(i, j int) bool {
+		if percentages[skus[i]] != percentages[skus[j]] {
+			return percentages[skus[i]] > percentages[skus[j]]
+		}
+		return skus[i] < skus[j]
+	})
+	return skus
+}
+
 // repeat repite un string n veces
 func (d *Display) repeat(s string, count int) string {
 	return strings.Repeat(s, count)
@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"danich/pkg/scraper"
+)
+
+// DriftDetector detecta cambios en la mezcla de fruta de cada sorter
+// comparando los porcentajes suavizados contra un valor de referencia
+type DriftDetector struct {
+	absThreshold float64 // Diferencia mínima en puntos porcentuales
+	relThreshold float64 // Diferencia mínima relativa a la referencia (0.3 = 30%)
+	window       int     // Ciclos usados para suavizar
+
+	history  map[int]map[string][]float64 // sorter -> SKU -> últimos porcentajes
+	baseline map[int]map[string]float64   // sorter -> SKU -> referencia suavizada
+	cycles   map[int]int                  // sorter -> ciclos observados
+}
+
+// NewDriftDetector crea un nuevo detector de desplazamientos de carga
+func NewDriftDetector(absThreshold, relThreshold float64, window int) *DriftDetector {
+	if window < 1 {
+		window = 1
+	}
+	return &DriftDetector{
+		absThreshold: absThreshold,
+		relThreshold: relThreshold,
+		window:       window,
+		history:      make(map[int]map[string][]float64),
+		baseline:     make(map[int]map[string]float64),
+		cycles:       make(map[int]int),
+	}
+}
+
+// Observe registra los porcentajes de un ciclo y retorna los desplazamientos
+// que superan ambos umbrales. Tras reportar un desplazamiento la referencia
+// pasa a ser el nuevo valor suavizado.
+func (dd *DriftDetector) Observe(chartData map[int]*scraper.ChartData) []LoadShift {
+	var shifts []LoadShift
+
+	sorterIDs := make([]int, 0, len(chartData))
+	for sorterID := range chartData {
+		sorterIDs = append(sorterIDs, sorterID)
+	}
+	sort.Ints(sorterIDs)
+
+	for _, sorterID := range sorterIDs {
+		chart := chartData[sorterID]
+		if chart == nil {
+			continue
+		}
+		shifts = append(shifts, dd.observeSorter(sorterID, chart)...)
+	}
+
+	return shifts
+}
+
+// observeSorter procesa los porcentajes de un sorter
+func (dd *DriftDetector) observeSorter(sorterID int, chart *scraper.ChartData) []LoadShift {
+	if dd.history[sorterID] == nil {
+		dd.history[sorterID] = make(map[string][]float64)
+		dd.baseline[sorterID] = make(map[string]float64)
+	}
+	history := dd.history[sorterID]
+	baseline := dd.baseline[sorterID]
+	warm := dd.cycles[sorterID] >= dd.window
+	dd.cycles[sorterID]++
+
+	// Un SKU que desaparece del gráfico cuenta como 0%
+	current := make(map[string]float64)
+	for sku := range history {
+		current[sku] = 0
+	}
+	for sku, percentage := range chart.Percentages {
+		current[strings.ToUpper(sku)] = percentage
+	}
+
+	// SKUs nuevos en un sorter ya observado parten desde 0%
+	for sku := range current {
+		if _, tracked := history[sku]; !tracked && warm {
+			baseline[sku] = 0
+		}
+	}
+
+	var shifts []LoadShift
+	for sku, percentage := range current {
+		samples := append(history[sku], percentage)
+		if len(samples) > dd.window {
+			samples = samples[len(samples)-dd.window:]
+		}
+		history[sku] = samples
+
+		if len(samples) < dd.window {
+			continue
+		}
+
+		smoothed := mean(samples)
+		reference, hasReference := baseline[sku]
+		if !hasReference {
+			baseline[sku] = smoothed
+			continue
+		}
+
+		delta := smoothed - reference
+		relative := math.Abs(delta) / math.Max(reference, 1)
+		if math.Abs(delta) >= dd.absThreshold && relative >= dd.relThreshold {
+			shifts = append(shifts, LoadShift{
+				SorterID: sorterID,
+				SKU:      sku,
+				From:     reference,
+				To:       smoothed,
+				Delta:    delta,
+			})
+			baseline[sku] = smoothed
+		}
+
+		// Olvidar SKUs que llevan toda la ventana en 0%
+		if smoothed == 0 && baseline[sku] == 0 {
+			delete(history, sku)
+			delete(baseline, sku)
+		}
+	}
+
+	sort.Slice(shifts, func(i, j int) bool {
+		return math.Abs(shifts[i].Delta) > math.Abs(shifts[j].Delta)
+	})
+
+	return shifts
+}
+
+// FormatShiftSummary crea un resumen textual de los desplazamientos
+func FormatShiftSummary(shifts []LoadShift) string {
+	parts := make([]string, 0, len(shifts))
+	for _, s := range shifts {
+		parts = append(parts, fmt.Sprintf("S%d %s %.1f%%→%.1f%%", s.SorterID, s.SKU, s.From, s.To))
+	}
+	return "Desplazamiento de carga: " + strings.Join(parts, ", ")
+}
+
+// mean calcula el promedio de una lista de valores
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"danich/pkg/scraper"
+)
+
+func TestDriftDetectorObserve(t *testing.T) {
+	// Ventana de 2 ciclos, 5 puntos y 30% de la referencia
+	type step struct {
+		percentages map[string]float64
+		want        []LoadShift
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "mezcla estable",
+			steps: []step{
+				{percentages: map[string]float64{"4J": 50}},
+				{percentages: map[string]float64{"4J": 50}},
+				{percentages: map[string]float64{"4J": 52}},
+				{percentages: map[string]float64{"4J": 51}},
+			},
+		},
+		{
+			name: "subida reportada y nueva referencia",
+			steps: []step{
+				{percentages: map[string]float64{"4j": 20}},
+				{percentages: map[string]float64{"4j": 20}},
+				{
+					percentages: map[string]float64{"4j": 40},
+					want:        []LoadShift{{SorterID: 1, SKU: "4J", From: 20, To: 30, Delta: 10}},
+				},
+				{
+					percentages: map[string]float64{"4j": 40},
+					want:        []LoadShift{{SorterID: 1, SKU: "4J", From: 30, To: 40, Delta: 10}},
+				},
+			},
+		},
+		{
+			name: "bajo el umbral relativo",
+			steps: []step{
+				{percentages: map[string]float64{"4J": 80}},
+				{percentages: map[string]float64{"4J": 80}},
+				{percentages: map[string]float64{"4J": 88}},
+				{percentages: map[string]float64{"4J": 88}},
+			},
+		},
+		{
+			name: "SKU nuevo parte desde 0%",
+			steps: []step{
+				{percentages: map[string]float64{"4J": 100}},
+				{percentages: map[string]float64{"4J": 100}},
+				{percentages: map[string]float64{"4J": 80, "3J": 20}},
+				{
+					percentages: map[string]float64{"4J": 80, "3J": 20},
+					want:        []LoadShift{{SorterID: 1, SKU: "3J", From: 0, To: 20, Delta: 20}},
+				},
+			},
+		},
+		{
+			name: "SKU que desaparece cuenta como 0% y se olvida",
+			steps: []step{
+				{percentages: map[string]float64{"4J": 70, "3J": 30}},
+				{percentages: map[string]float64{"4J": 70, "3J": 30}},
+				{
+					percentages: map[string]float64{"4J": 100},
+					want:        []LoadShift{{SorterID: 1, SKU: "3J", From: 30, To: 15, Delta: -15}},
+				},
+				{
+					percentages: map[string]float64{"4J": 100},
+					want: []LoadShift{
+						{SorterID: 1, SKU: "4J", From: 70, To: 100, Delta: 30},
+						{SorterID: 1, SKU: "3J", From: 15, To: 0, Delta: -15},
+					},
+				},
+				{percentages: map[string]float64{"4J": 100}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dd := NewDriftDetector(5, 0.3, 2)
+			for i, s := range tt.steps {
+				got := dd.Observe(map[int]*scraper.ChartData{
+					1: {SorterID: 1, Timestamp: time.Now(), Percentages: s.percentages},
+				})
+				if !reflect.DeepEqual(got, s.want) {
+					t.Errorf("ciclo %d:\n got  %+v\n want %+v", i+1, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDriftDetectorSkipsMissingCharts(t *testing.T) {
+	dd := NewDriftDetector(5, 0.3, 1)
+	chart := map[int]*scraper.ChartData{1: {SorterID: 1, Percentages: map[string]float64{"4J": 10}}, 2: nil}
+	dd.Observe(chart)
+	chart[1].Percentages = map[string]float64{"4J": 40}
+	if got := dd.Observe(chart); len(got) != 1 || got[0].SorterID != 1 {
+		t.Errorf("desplazamientos = %+v, esperaba uno en el sorter 1", got)
+	}
+}
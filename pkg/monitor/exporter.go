@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Exporter maneja la exportación de datos a CSV
@@ -21,10 +22,30 @@ func NewExporter(datasetFolder string) *Exporter {
 	}
 }
 
+// csvHeaders son las columnas de training_data.csv
+var csvHeaders = []string{
+	"timestamp",
+	"sorter_id",
+	"sku",
+	"calibre",
+	"calidad",
+	"variedad",
+	"lineas",
+	"porcentaje",
+	"total_skus_activos",
+	"parcial",
+	"fuentes_fallidas",
+}
+
 // ExportToCSV exporta los datos a formato CSV para entrenamiento de ML
 func (e *Exporter) ExportToCSV(snapshot DataSnapshot) error {
 	csvFile := filepath.Join(e.datasetFolder, "training_data.csv")
 
+	// Un CSV con columnas anteriores se renombra para no mezclar formatos
+	if err := rotateOutdatedCSV(csvFile); err != nil {
+		return err
+	}
+
 	// Verificar si el archivo existe para decidir si escribir headers
 	fileExists := false
 	if _, err := os.Stat(csvFile); err == nil {
@@ -44,18 +65,7 @@ func (e *Exporter) ExportToCSV(snapshot DataSnapshot) error {
 
 	// Escribir headers solo si el archivo es nuevo
 	if !fileExists {
-		headers := []string{
-			"timestamp",
-			"sorter_id",
-			"sku",
-			"calibre",
-			"calidad",
-			"variedad",
-			"lineas",
-			"porcentaje",
-			"total_skus_activos",
-		}
-		if err := writer.Write(headers); err != nil {
+		if err := writer.Write(csvHeaders); err != nil {
 			return fmt.Errorf("error escribiendo headers: %v", err)
 		}
 	}
@@ -104,6 +114,18 @@ func (e *Exporter) createCSVRecord(snapshot DataSnapshot, sorterID int, sku stri
 	// Obtener líneas de selladora para este SKU
 	lineas := getSalidasForSKU(assignments, sorterID, sku)
 
+	// Fuentes que fallaron en el ciclo (snapshot parcial)
+	var failed []string
+	for _, source := range snapshot.Sources {
+		if !source.OK {
+			failed = append(failed, source.Source)
+		}
+	}
+	partial := "0"
+	if snapshot.Partial {
+		partial = "1"
+	}
+
 	return []string{
 		snapshot.Timestamp,
 		fmt.Sprintf("%d", sorterID),
@@ -114,9 +136,38 @@ func (e *Exporter) createCSVRecord(snapshot DataSnapshot, sorterID int, sku stri
 		lineas,
 		fmt.Sprintf("%.1f", percentage),
 		fmt.Sprintf("%d", totalSKUs),
+		partial,
+		strings.Join(failed, " "),
 	}
 }
 
+// rotateOutdatedCSV renombra el CSV si su encabezado no coincide con las
+// columnas actuales (training_data.csv -> training_data_YYYYMMDD-HHMMSS.csv)
+func rotateOutdatedCSV(csvFile string) error {
+	file, err := os.Open(csvFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	header, err := reader.Read()
+	file.Close()
+	if err != nil || strings.Join(header, ";") == strings.Join(csvHeaders, ";") {
+		return nil
+	}
+
+	ext := filepath.Ext(csvFile)
+	target := strings.TrimSuffix(csvFile, ext) + "_" + time.Now().Format("20060102-150405") + ext
+	if err := os.Rename(csvFile, target); err != nil {
+		return fmt.Errorf("error renombrando CSV con columnas anteriores: %w", err)
+	}
+	return nil
+}
+
 // getSalidasForSKU obtiene las líneas (salidas) asignadas a un SKU en formato "L1 L2 L3"
 func getSalidasForSKU(assignments []Assignment, sorterID int, sku string) string {
 	var salidas []int
@@ -141,3 +192,75 @@ func getSalidasForSKU(assignments []Assignment, sorterID int, sku string) string
 
 	return strings.Join(parts, " ")
 }
+
+// forecastCSVHeaders son las columnas de forecasts.csv
+var forecastCSVHeaders = []string{
+	"emitido",
+	"objetivo",
+	"sorter_id",
+	"sku",
+	"horizonte_min",
+	"porcentaje_al_emitir",
+	"pronostico",
+	"inferior",
+	"superior",
+	"real",
+	"error",
+	"dentro_intervalo",
+}
+
+// ExportForecasts agrega a forecasts.csv los pronósticos ya resueltos, cada
+// uno junto al porcentaje real observado en su horizonte
+func (e *Exporter) ExportForecasts(outcomes []ForecastOutcome) error {
+	if len(outcomes) == 0 {
+		return nil
+	}
+	csvFile := filepath.Join(e.datasetFolder, "forecasts.csv")
+
+	fileExists := false
+	if _, err := os.Stat(csvFile); err == nil {
+		fileExists = true
+	}
+
+	file, err := os.OpenFile(csvFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error abriendo archivo CSV: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = ';'
+	defer writer.Flush()
+
+	if !fileExists {
+		if err := writer.Write(forecastCSVHeaders); err != nil {
+			return fmt.Errorf("error escribiendo headers: %v", err)
+		}
+	}
+
+	for _, outcome := range outcomes {
+		inside := "0"
+		if outcome.InInterval {
+			inside = "1"
+		}
+		record := []string{
+			outcome.IssuedAt.Format("2006-01-02 15:04:05"),
+			outcome.Target.Format("2006-01-02 15:04:05"),
+			fmt.Sprintf("%d", outcome.SorterID),
+			outcome.SKU,
+			fmt.Sprintf("%d", outcome.HorizonMin),
+			fmt.Sprintf("%.1f", outcome.Current),
+			fmt.Sprintf("%.1f", outcome.Predicted),
+			fmt.Sprintf("%.1f", outcome.Lower),
+			fmt.Sprintf("%.1f", outcome.Upper),
+			fmt.Sprintf("%.1f", outcome.Actual),
+			fmt.Sprintf("%.1f", outcome.Error),
+			inside,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("error escribiendo registro: %v", err)
+		}
+	}
+
+	return nil
+}
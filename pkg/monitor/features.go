@@ -0,0 +1,484 @@
+package monitor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Tipos de columna de la matriz de features
+const (
+	FeatureTimestamp = "timestamp"
+	FeatureString    = "string"
+	FeatureInt       = "int"
+	FeatureDouble    = "double"
+)
+
+// FeatureOptions controla la construcción de la matriz de features
+type FeatureOptions struct {
+	Lags    int           // Cantidad de porcentajes rezagados por SKU
+	Window  int           // Muestras para media y pendiente móviles
+	Horizon time.Duration // Horizonte de las etiquetas de cambio
+}
+
+// DefaultFeatureOptions retorna las opciones por defecto
+func DefaultFeatureOptions() FeatureOptions {
+	return FeatureOptions{Lags: 3, Window: 5, Horizon: 15 * time.Minute}
+}
+
+// FeatureColumn describe una columna de la matriz
+type FeatureColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Nullable    bool   `json:"nullable"`
+	Description string `json:"description"`
+}
+
+// FeatureMatrix es la matriz ancha: una fila por snapshot y sorter, y un
+// bloque de columnas por SKU. Los valores nulos son nil.
+type FeatureMatrix struct {
+	Options FeatureOptions
+	SKUs    []string
+	Columns []FeatureColumn
+	Rows    [][]interface{}
+}
+
+// changeTouch es un cambio de asignación que afecta a un SKU en un sorter
+type changeTouch struct {
+	at       time.Time
+	sorterID int
+	sku      string
+}
+
+// BuildFeatureMatrix arma la matriz de features a partir de los snapshots y
+// de los cambios registrados (los posteriores a cada fila generan las etiquetas)
+func BuildFeatureMatrix(snapshots []DataSnapshot, changes []ChangeLog, opts FeatureOptions) FeatureMatrix {
+	if opts.Window < 2 {
+		opts.Window = 2
+	}
+
+	sorted := make([]DataSnapshot, len(snapshots))
+	copy(sorted, snapshots)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].DateTime.Before(sorted[j].DateTime) })
+
+	skus, sorterIDs := featureVocabulary(sorted)
+	touches := changeTouches(changes)
+
+	fm := FeatureMatrix{Options: opts, SKUs: skus}
+	fm.Columns = featureColumns(skus, opts)
+
+	// Historia de porcentajes por sorter y SKU (un punto por snapshot con gráfico)
+	type history struct {
+		times []time.Time
+		pct   map[string][]float64
+	}
+	histories := make(map[int]*history)
+	for _, sorterID := range sorterIDs {
+		histories[sorterID] = &history{pct: make(map[string][]float64)}
+	}
+
+	for _, snapshot := range sorted {
+		normalized := make(map[int]map[string]float64)
+		for _, sorterID := range sorterIDs {
+			if chart := snapshot.ChartData[sorterID]; chart != nil {
+				normalized[sorterID] = normalizedPercentages(chart.Percentages)
+				h := histories[sorterID]
+				h.times = append(h.times, snapshot.DateTime)
+				for _, sku := range skus {
+					h.pct[sku] = append(h.pct[sku], normalized[sorterID][sku])
+				}
+			}
+		}
+
+		for _, sorterID := range sorterIDs {
+			chart := snapshot.ChartData[sorterID]
+			if chart == nil {
+				continue
+			}
+			h := histories[sorterID]
+			ts := snapshot.DateTime
+
+			row := []interface{}{ts, sorterID, snapshot.Shift, snapshot.ProductionDay, len(chart.Percentages)}
+
+			if last, ok := lastTouch(touches, sorterID, "", ts); ok {
+				row = append(row, round2(ts.Sub(last).Seconds()))
+			} else {
+				row = append(row, nil)
+			}
+
+			for _, sku := range skus {
+				series := h.pct[sku]
+				n := len(series)
+				row = append(row, series[n-1])
+
+				for lag := 1; lag <= opts.Lags; lag++ {
+					if n-1-lag >= 0 {
+						row = append(row, series[n-1-lag])
+					} else {
+						row = append(row, nil)
+					}
+				}
+
+				start := n - opts.Window
+				if start < 0 {
+					start = 0
+				}
+				row = append(row, round2(mean(series[start:])))
+				if n-start >= 2 {
+					row = append(row, slope(h.times[start:], series[start:]))
+				} else {
+					row = append(row, nil)
+				}
+
+				row = append(row, len(getLinesForSKU(snapshot.Assignments, sorterID, sku)))
+				row = append(row, crossSorterDiff(normalized, sorterID, sku))
+			}
+
+			// Etiquetas
+			next, hasNext := nextTouch(touches, sorterID, "", ts)
+			row = append(row, boolToInt(hasNext && next.Sub(ts) <= opts.Horizon))
+			if hasNext {
+				row = append(row, round2(next.Sub(ts).Minutes()))
+			} else {
+				row = append(row, nil)
+			}
+			for _, sku := range skus {
+				skuNext, ok := nextTouch(touches, sorterID, sku, ts)
+				row = append(row, boolToInt(ok && skuNext.Sub(ts) <= opts.Horizon))
+			}
+
+			fm.Rows = append(fm.Rows, row)
+		}
+	}
+
+	return fm
+}
+
+// featureColumns define el esquema en el mismo orden en que se arman las filas
+func featureColumns(skus []string, opts FeatureOptions) []FeatureColumn {
+	columns := []FeatureColumn{
+		{"timestamp", FeatureTimestamp, false, "Instante del snapshot"},
+		{"sorter_id", FeatureInt, false, "Sorter de la fila"},
+		{"turno", FeatureString, false, "Turno del snapshot (vacío sin turnos configurados)"},
+		{"dia_productivo", FeatureString, false, "Día productivo del snapshot"},
+		{"total_skus_activos", FeatureInt, false, "SKUs con porcentaje en el gráfico del sorter"},
+		{"seg_desde_cambio", FeatureDouble, true, "Segundos desde el último cambio de asignación en el sorter"},
+	}
+
+	for _, sku := range skus {
+		columns = append(columns, FeatureColumn{sku + "_porcentaje", FeatureDouble, false,
+			fmt.Sprintf("Porcentaje de %s en el gráfico del sorter (0 si no aparece)", sku)})
+		for lag := 1; lag <= opts.Lags; lag++ {
+			columns = append(columns, FeatureColumn{fmt.Sprintf("%s_lag%d", sku, lag), FeatureDouble, true,
+				fmt.Sprintf("Porcentaje de %s %d muestra(s) antes", sku, lag)})
+		}
+		columns = append(columns,
+			FeatureColumn{fmt.Sprintf("%s_media%d", sku, opts.Window), FeatureDouble, false,
+				fmt.Sprintf("Media móvil del porcentaje de %s en las últimas %d muestras", sku, opts.Window)},
+			FeatureColumn{fmt.Sprintf("%s_pendiente%d", sku, opts.Window), FeatureDouble, true,
+				fmt.Sprintf("Pendiente (puntos por minuto) del porcentaje de %s en las últimas %d muestras", sku, opts.Window)},
+			FeatureColumn{sku + "_lineas", FeatureInt, false,
+				fmt.Sprintf("Salidas asignadas a %s en el sorter", sku)},
+			FeatureColumn{sku + "_dif_sorters", FeatureDouble, true,
+				fmt.Sprintf("Porcentaje de %s en el sorter menos el promedio en los otros sorters", sku)},
+		)
+	}
+
+	horizon := fmt.Sprintf("%.0f minutos", opts.Horizon.Minutes())
+	columns = append(columns,
+		FeatureColumn{"label_cambio", FeatureInt, false, "1 si hay un cambio de asignación en el sorter dentro de " + horizon},
+		FeatureColumn{"label_min_hasta_cambio", FeatureDouble, true, "Minutos hasta el próximo cambio de asignación en el sorter"},
+	)
+	for _, sku := range skus {
+		columns = append(columns, FeatureColumn{sku + "_label_cambio", FeatureInt, false,
+			fmt.Sprintf("1 si cambian las salidas de %s en el sorter dentro de %s", sku, horizon)})
+	}
+
+	return columns
+}
+
+// featureVocabulary retorna los SKUs (normalizados) y sorters presentes en los gráficos
+func featureVocabulary(snapshots []DataSnapshot) ([]string, []int) {
+	skuSet := make(map[string]bool)
+	sorterSet := make(map[int]bool)
+
+	for _, snapshot := range snapshots {
+		for sorterID, chart := range snapshot.ChartData {
+			if chart == nil {
+				continue
+			}
+			sorterSet[sorterID] = true
+			for sku := range chart.Percentages {
+				skuSet[strings.ToUpper(sku)] = true
+			}
+		}
+	}
+
+	skus := make([]string, 0, len(skuSet))
+	for sku := range skuSet {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+
+	sorterIDs := make([]int, 0, len(sorterSet))
+	for sorterID := range sorterSet {
+		sorterIDs = append(sorterIDs, sorterID)
+	}
+	sort.Ints(sorterIDs)
+
+	return skus, sorterIDs
+}
+
+// normalizedPercentages agrupa los porcentajes por SKU en mayúsculas
+func normalizedPercentages(percentages map[string]float64) map[string]float64 {
+	normalized := make(map[string]float64, len(percentages))
+	for sku, percentage := range percentages {
+		normalized[strings.ToUpper(sku)] += percentage
+	}
+	return normalized
+}
+
+// changeTouches extrae los cambios de asignación (no los desplazamientos de
+// carga) ordenados por tiempo
+func changeTouches(changes []ChangeLog) []changeTouch {
+	var touches []changeTouch
+
+	for _, change := range changes {
+		if change.ChangeType == "load_shift" {
+			continue
+		}
+		at := changeTime(change)
+		if at.IsZero() {
+			continue
+		}
+
+		for _, e := range change.Events {
+			touches = append(touches, changeTouch{at, e.SorterID, strings.ToUpper(e.SKU)})
+			if e.FromSorter != 0 {
+				touches = append(touches, changeTouch{at, e.FromSorter, strings.ToUpper(e.SKU)})
+			}
+		}
+
+		// Entradas con el formato anterior
+		legacy := append(append([]Assignment{}, change.Added...), change.Removed...)
+		for _, m := range change.Modified {
+			legacy = append(legacy, m.Old, m.New)
+		}
+		for _, a := range legacy {
+			touches = append(touches, changeTouch{at, a.SorterID, strings.ToUpper(a.SKU)})
+		}
+	}
+
+	sort.SliceStable(touches, func(i, j int) bool { return touches[i].at.Before(touches[j].at) })
+	return touches
+}
+
+// lastTouch busca el último cambio hasta ts en el sorter (y SKU si no es vacío)
+func lastTouch(touches []changeTouch, sorterID int, sku string, ts time.Time) (time.Time, bool) {
+	i := sort.Search(len(touches), func(i int) bool { return touches[i].at.After(ts) })
+	for i--; i >= 0; i-- {
+		if touches[i].sorterID == sorterID && (sku == "" || touches[i].sku == sku) {
+			return touches[i].at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextTouch busca el primer cambio posterior a ts en el sorter (y SKU si no es vacío)
+func nextTouch(touches []changeTouch, sorterID int, sku string, ts time.Time) (time.Time, bool) {
+	for i := sort.Search(len(touches), func(i int) bool { return touches[i].at.After(ts) }); i < len(touches); i++ {
+		if touches[i].sorterID == sorterID && (sku == "" || touches[i].sku == sku) {
+			return touches[i].at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// crossSorterDiff compara el porcentaje de un SKU con el promedio en los otros sorters
+func crossSorterDiff(normalized map[int]map[string]float64, sorterID int, sku string) interface{} {
+	var others []float64
+	for other, percentages := range normalized {
+		if other != sorterID {
+			others = append(others, percentages[sku])
+		}
+	}
+	if len(others) == 0 {
+		return nil
+	}
+
+	return round2(normalized[sorterID][sku] - mean(others))
+}
+
+// slope calcula la pendiente por mínimos cuadrados en puntos por minuto
+func slope(times []time.Time, values []float64) interface{} {
+	n := float64(len(values))
+	var sumX, sumY, sumXY, sumXX float64
+	for i, v := range values {
+		x := times[i].Sub(times[0]).Minutes()
+		sumX += x
+		sumY += v
+		sumXY += x * v
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return nil
+	}
+	return math.Round((n*sumXY-sumX*sumY)/denominator*10000) / 10000
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// WriteCSV escribe la matriz en CSV separado por punto y coma (nulos vacíos)
+func (fm FeatureMatrix) WriteCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := fm.EncodeCSV(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// EncodeCSV escribe la matriz en CSV en w (exportación por la API)
+func (fm FeatureMatrix) EncodeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+
+	header := make([]string, len(fm.Columns))
+	for i, column := range fm.Columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(fm.Columns))
+	for _, row := range fm.Rows {
+		for i, value := range row {
+			switch v := value.(type) {
+			case nil:
+				record[i] = ""
+			case time.Time:
+				record[i] = v.Format("2006-01-02 15:04:05")
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteParquet escribe la matriz en Parquet comprimido con zstd
+func (fm FeatureMatrix) WriteParquet(path string) error {
+	group := make(parquet.Group, len(fm.Columns))
+	for _, column := range fm.Columns {
+		var node parquet.Node
+		switch column.Type {
+		case FeatureTimestamp:
+			node = parquet.Timestamp(parquet.Millisecond)
+		case FeatureString:
+			node = parquet.String()
+		case FeatureInt:
+			node = parquet.Int(32)
+		default:
+			node = parquet.Leaf(parquet.DoubleType)
+		}
+		if column.Nullable {
+			node = parquet.Optional(node)
+		}
+		group[column.Name] = node
+	}
+	schema := parquet.NewSchema("features", group)
+
+	// El esquema ordena las columnas por nombre: mapear cada columna a su hoja
+	leafIndex := make(map[string]int)
+	for i, path := range schema.Columns() {
+		leafIndex[path[0]] = i
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := parquet.NewWriter(file, schema, parquet.Compression(&parquet.Zstd))
+	rows := make([]parquet.Row, 0, len(fm.Rows))
+	for _, values := range fm.Rows {
+		row := make(parquet.Row, len(fm.Columns))
+		for i, value := range values {
+			column := fm.Columns[i]
+			leaf := leafIndex[column.Name]
+			definition := 0
+			if column.Nullable {
+				definition = 1
+			}
+
+			var v parquet.Value
+			switch x := value.(type) {
+			case nil:
+				row[leaf] = parquet.NullValue().Level(0, 0, leaf)
+				continue
+			case time.Time:
+				v = parquet.Int64Value(x.UnixMilli())
+			case string:
+				v = parquet.ByteArrayValue([]byte(x))
+			case int:
+				v = parquet.Int32Value(int32(x))
+			case float64:
+				v = parquet.DoubleValue(x)
+			}
+			row[leaf] = v.Level(0, definition, leaf)
+		}
+		rows = append(rows, row)
+	}
+
+	if _, err := writer.WriteRows(rows); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// WriteSchema escribe la descripción de las columnas en JSON
+func (fm FeatureMatrix) WriteSchema(path string) error {
+	data, err := json.MarshalIndent(struct {
+		Lags      int             `json:"lags"`
+		Window    int             `json:"ventana"`
+		HorizonMn float64         `json:"horizonte_minutos"`
+		SKUs      []string        `json:"skus"`
+		Columns   []FeatureColumn `json:"columns"`
+	}{fm.Options.Lags, fm.Options.Window, fm.Options.Horizon.Minutes(), fm.SKUs, fm.Columns}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -21,7 +22,17 @@ func NewFetcher(assignmentsURL string) *Fetcher {
 
 // FetchAssignments obtiene los assignments actuales del API
 func (f *Fetcher) FetchAssignments() ([]Assignment, error) {
-	resp, err := http.Get(f.assignmentsURL)
+	return f.FetchAssignmentsContext(context.Background())
+}
+
+// FetchAssignmentsContext obtiene los assignments y se cancela con ctx
+func (f *Fetcher) FetchAssignmentsContext(ctx context.Context) ([]Assignment, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.assignmentsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creando request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error en GET: %w", err)
 	}
@@ -0,0 +1,328 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"danich/pkg/scraper"
+)
+
+// forecastMinObservations es la cantidad de muestras de una serie antes de pronosticarla
+const forecastMinObservations = 5
+
+// forecastErrorWeight es el peso de cada error nuevo en la varianza de los errores
+const forecastErrorWeight = 0.1
+
+// ForecastOptions parametriza el pronóstico de carga
+type ForecastOptions struct {
+	Alpha      float64         // Suavizado del nivel (0..1]
+	Beta       float64         // Suavizado de la tendencia (0..1]
+	Horizons   []time.Duration // Horizontes a pronosticar
+	Confidence float64         // Nivel de confianza de los intervalos (0..1)
+	Threshold  float64         // Porcentaje para los avisos preventivos (0 = sin avisos)
+}
+
+// Forecast es el pronóstico de la participación de un SKU en un sorter
+type Forecast struct {
+	IssuedAt   time.Time `json:"issued_at"`
+	Target     time.Time `json:"target"`
+	SorterID   int       `json:"sorter_id"`
+	SKU        string    `json:"sku"`
+	HorizonMin int       `json:"horizon_min"`
+	Current    float64   `json:"current"` // Último porcentaje observado
+	Predicted  float64   `json:"predicted"`
+	Lower      float64   `json:"lower"`
+	Upper      float64   `json:"upper"`
+	Trend      float64   `json:"trend"` // Puntos porcentuales por minuto
+}
+
+// ForecastOutcome es un pronóstico cuyo horizonte ya se cumplió, con el valor real
+type ForecastOutcome struct {
+	Forecast
+	Actual     float64 `json:"actual"`
+	Error      float64 `json:"error"` // Pronóstico - real
+	InInterval bool    `json:"in_interval"`
+}
+
+// ForecastAccuracy resume el error de los pronósticos de un horizonte
+type ForecastAccuracy struct {
+	HorizonMin int     `json:"horizon_min"`
+	Count      int     `json:"count"`
+	MAE        float64 `json:"mae"`      // Error absoluto medio en puntos porcentuales
+	Coverage   float64 `json:"coverage"` // Fracción de valores reales dentro del intervalo
+}
+
+// ThresholdCrossing es un SKU que según el pronóstico superará el umbral
+type ThresholdCrossing struct {
+	Forecast          // Pronóstico del primer horizonte sobre el umbral
+	Minutes   float64 `json:"minutes"` // Minutos estimados hasta cruzar el umbral
+	Threshold float64 `json:"threshold"`
+}
+
+// holtSeries es el estado del suavizado de Holt de un SKU en un sorter. La
+// tendencia se lleva por minuto para tolerar intervalos irregulares.
+type holtSeries struct {
+	sorterID     int
+	sku          string
+	level        float64
+	trend        float64
+	last         time.Time
+	lastValue    float64
+	variance     float64 // Varianza de los errores a un paso
+	step         float64 // Minutos entre muestras (promedio móvil)
+	observations int
+}
+
+// Forecaster pronostica la participación de cada SKU con el método lineal de
+// Holt (suavizado exponencial doble) y registra el error de sus pronósticos
+// una vez que se conoce el valor real
+type Forecaster struct {
+	opts    ForecastOptions
+	z       float64
+	series  map[string]*holtSeries
+	pending []Forecast
+	errors  map[int]*ForecastAccuracy // Horizonte -> acumulado
+}
+
+// NewForecaster crea un pronosticador
+func NewForecaster(opts ForecastOptions) *Forecaster {
+	opts.Horizons = slices.Clone(opts.Horizons)
+	slices.Sort(opts.Horizons)
+	return &Forecaster{
+		opts:   opts,
+		z:      math.Sqrt2 * math.Erfinv(opts.Confidence),
+		series: make(map[string]*holtSeries),
+		errors: make(map[int]*ForecastAccuracy),
+	}
+}
+
+// Enabled indica si hay horizontes configurados
+func (f *Forecaster) Enabled() bool {
+	return len(f.opts.Horizons) > 0
+}
+
+// Observe actualiza las series con los porcentajes de un snapshot y retorna
+// los pronósticos pendientes que se resolvieron con estos valores. Un SKU que
+// desaparece del gráfico de un sorter cuenta como 0%.
+func (f *Forecaster) Observe(t time.Time, chartData map[int]*scraper.ChartData) []ForecastOutcome {
+	current := make(map[string]float64)
+	for sorterID, chart := range chartData {
+		if chart == nil {
+			continue
+		}
+		for key, s := range f.series {
+			if s.sorterID == sorterID {
+				current[key] = 0
+			}
+		}
+		for sku, percentage := range chart.Percentages {
+			current[seriesKey(sorterID, sku)] = percentage
+			if _, ok := f.series[seriesKey(sorterID, sku)]; !ok {
+				f.series[seriesKey(sorterID, sku)] = &holtSeries{sorterID: sorterID, sku: normalizeSKU(sku)}
+			}
+		}
+	}
+
+	for key, value := range current {
+		f.series[key].update(t, value, f.opts, f.maxHorizon())
+	}
+
+	return f.resolve(t, current)
+}
+
+// update incorpora una muestra a la serie
+func (s *holtSeries) update(t time.Time, value float64, opts ForecastOptions, maxGap time.Duration) {
+	// Primera muestra o vuelta tras una pausa larga: se reinicia la serie
+	if s.observations == 0 || t.Sub(s.last) > maxGap {
+		*s = holtSeries{sorterID: s.sorterID, sku: s.sku, level: value, last: t, lastValue: value, observations: 1}
+		return
+	}
+
+	dt := t.Sub(s.last).Minutes()
+	if dt <= 0 {
+		return
+	}
+
+	predicted := s.level + s.trend*dt
+	err := value - predicted
+	level := opts.Alpha*value + (1-opts.Alpha)*predicted
+	s.trend = opts.Beta*(level-s.level)/dt + (1-opts.Beta)*s.trend
+	s.level = level
+
+	if s.observations == 1 {
+		s.variance = err * err
+		s.step = dt
+	} else {
+		s.variance = (1-forecastErrorWeight)*s.variance + forecastErrorWeight*err*err
+		s.step = (1-forecastErrorWeight)*s.step + forecastErrorWeight*dt
+	}
+
+	s.last = t
+	s.lastValue = value
+	s.observations++
+}
+
+// resolve compara los pronósticos vencidos con los valores observados
+func (f *Forecaster) resolve(t time.Time, current map[string]float64) []ForecastOutcome {
+	var outcomes []ForecastOutcome
+	remaining := f.pending[:0]
+
+	for _, forecast := range f.pending {
+		actual, observed := current[seriesKey(forecast.SorterID, forecast.SKU)]
+		if forecast.Target.After(t) || !observed {
+			remaining = append(remaining, forecast)
+			continue
+		}
+
+		outcome := ForecastOutcome{
+			Forecast:   forecast,
+			Actual:     actual,
+			Error:      forecast.Predicted - actual,
+			InInterval: actual >= forecast.Lower && actual <= forecast.Upper,
+		}
+		outcomes = append(outcomes, outcome)
+
+		acc := f.errors[forecast.HorizonMin]
+		if acc == nil {
+			acc = &ForecastAccuracy{HorizonMin: forecast.HorizonMin}
+			f.errors[forecast.HorizonMin] = acc
+		}
+		// MAE y cobertura se acumulan como promedios incrementales
+		acc.Count++
+		acc.MAE += (math.Abs(outcome.Error) - acc.MAE) / float64(acc.Count)
+		inside := 0.0
+		if outcome.InInterval {
+			inside = 1
+		}
+		acc.Coverage += (inside - acc.Coverage) / float64(acc.Count)
+	}
+
+	f.pending = remaining
+	return outcomes
+}
+
+// Issue pronostica todas las series con suficientes muestras para cada
+// horizonte y las deja pendientes de comparar con el valor real
+func (f *Forecaster) Issue(now time.Time) []Forecast {
+	var forecasts []Forecast
+	for _, s := range f.sortedSeries() {
+		// Series sin muestras suficientes o de SKUs que ya no están en el gráfico
+		if s.observations < forecastMinObservations || (s.lastValue == 0 && s.level < 0.5) {
+			continue
+		}
+		for _, horizon := range f.opts.Horizons {
+			forecasts = append(forecasts, f.forecast(s, now, horizon))
+		}
+	}
+
+	f.pending = append(f.pending, forecasts...)
+	return forecasts
+}
+
+// forecast proyecta una serie a un horizonte. El intervalo usa la varianza
+// del error a h pasos del método de Holt.
+func (f *Forecaster) forecast(s *holtSeries, now time.Time, horizon time.Duration) Forecast {
+	minutes := horizon.Minutes()
+	predicted := s.level + s.trend*minutes
+
+	steps := 1
+	if s.step > 0 {
+		steps = int(math.Ceil(minutes / s.step))
+	}
+	factor := 1.0
+	for j := 1; j < steps; j++ {
+		factor += math.Pow(f.opts.Alpha*(1+float64(j)*f.opts.Beta), 2)
+	}
+	margin := f.z * math.Sqrt(s.variance*factor)
+
+	return Forecast{
+		IssuedAt:   now,
+		Target:     now.Add(horizon),
+		SorterID:   s.sorterID,
+		SKU:        s.sku,
+		HorizonMin: int(minutes),
+		Current:    s.lastValue,
+		Predicted:  clampPercentage(predicted),
+		Lower:      clampPercentage(predicted - margin),
+		Upper:      clampPercentage(predicted + margin),
+		Trend:      s.trend,
+	}
+}
+
+// Crossings retorna los SKUs bajo el umbral que según su tendencia lo superarán
+// dentro del horizonte máximo, del más próximo al más lejano
+func (f *Forecaster) Crossings(forecasts []Forecast) []ThresholdCrossing {
+	if f.opts.Threshold <= 0 {
+		return nil
+	}
+
+	var crossings []ThresholdCrossing
+	seen := make(map[string]bool)
+	for _, forecast := range forecasts {
+		key := seriesKey(forecast.SorterID, forecast.SKU)
+		if seen[key] || forecast.Current >= f.opts.Threshold || forecast.Predicted < f.opts.Threshold || forecast.Trend <= 0 {
+			continue
+		}
+		seen[key] = true
+
+		s := f.series[key]
+		crossings = append(crossings, ThresholdCrossing{
+			Forecast:  forecast,
+			Minutes:   math.Max(0, (f.opts.Threshold-s.level)/s.trend),
+			Threshold: f.opts.Threshold,
+		})
+	}
+
+	sort.SliceStable(crossings, func(i, j int) bool { return crossings[i].Minutes < crossings[j].Minutes })
+	return crossings
+}
+
+// Accuracy retorna el error acumulado de los pronósticos por horizonte
+func (f *Forecaster) Accuracy() []ForecastAccuracy {
+	accuracy := make([]ForecastAccuracy, 0, len(f.errors))
+	for _, acc := range f.errors {
+		accuracy = append(accuracy, *acc)
+	}
+	sort.Slice(accuracy, func(i, j int) bool { return accuracy[i].HorizonMin < accuracy[j].HorizonMin })
+	return accuracy
+}
+
+// maxHorizon retorna el horizonte más largo, que también es la pausa máxima
+// antes de reiniciar una serie
+func (f *Forecaster) maxHorizon() time.Duration {
+	var max time.Duration
+	for _, horizon := range f.opts.Horizons {
+		if horizon > max {
+			max = horizon
+		}
+	}
+	return max
+}
+
+// sortedSeries retorna las series ordenadas por sorter y SKU
+func (f *Forecaster) sortedSeries() []*holtSeries {
+	series := make([]*holtSeries, 0, len(f.series))
+	for _, s := range f.series {
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].sorterID != series[j].sorterID {
+			return series[i].sorterID < series[j].sorterID
+		}
+		return series[i].sku < series[j].sku
+	})
+	return series
+}
+
+// seriesKey identifica la serie de un SKU en un sorter
+func seriesKey(sorterID int, sku string) string {
+	return fmt.Sprintf("%d/%s", sorterID, normalizeSKU(sku))
+}
+
+// clampPercentage limita un porcentaje a 0..100
+func clampPercentage(value float64) float64 {
+	return math.Min(100, math.Max(0, value))
+}
@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"danich/pkg/scraper"
+)
+
+// TestForecasterCoverage alimenta series sintéticas con ruido normal y
+// verifica que la cobertura de los intervalos no quede bajo la confianza
+// configurada (en horizontes largos el intervalo de Holt es conservador) y
+// que el error sea del orden del ruido
+func TestForecasterCoverage(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  func(minute int) float64
+		noise  float64
+		maxMAE float64
+	}{
+		{"nivel estable", func(int) float64 { return 40 }, 2, 3},
+		{"tendencia lenta", func(m int) float64 { return 20 + 0.05*float64(m) }, 2, 3},
+		{"ruido alto", func(int) float64 { return 50 }, 6, 8},
+	}
+
+	start := time.Date(2026, 3, 10, 6, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			forecaster := NewForecaster(ForecastOptions{
+				Alpha:      0.3,
+				Beta:       0.1,
+				Horizons:   []time.Duration{15 * time.Minute, 5 * time.Minute},
+				Confidence: 0.9,
+			})
+
+			for minute := 0; minute < 600; minute++ {
+				at := start.Add(time.Duration(minute) * time.Minute)
+				value := tt.level(minute) + rng.NormFloat64()*tt.noise
+				forecaster.Observe(at, map[int]*scraper.ChartData{
+					1: {SorterID: 1, Timestamp: at, Percentages: map[string]float64{"4J": value}},
+				})
+				forecaster.Issue(at)
+			}
+
+			accuracy := forecaster.Accuracy()
+			if len(accuracy) != 2 || accuracy[0].HorizonMin != 5 || accuracy[1].HorizonMin != 15 {
+				t.Fatalf("precisión = %+v, esperaba horizontes 5 y 15", accuracy)
+			}
+			for _, acc := range accuracy {
+				t.Logf("h=%d n=%d MAE=%.2f cobertura=%.2f", acc.HorizonMin, acc.Count, acc.MAE, acc.Coverage)
+				if acc.Coverage < 0.85 || acc.Coverage > 0.995 {
+					t.Errorf("h=%d: cobertura %.3f, esperaba entre 0.85 y 0.995", acc.HorizonMin, acc.Coverage)
+				}
+				if acc.MAE > tt.maxMAE {
+					t.Errorf("h=%d: MAE %.2f, esperaba a lo más %.2f", acc.HorizonMin, acc.MAE, tt.maxMAE)
+				}
+			}
+		})
+	}
+}
+
+func TestForecasterCrossings(t *testing.T) {
+	tests := []struct {
+		name  string
+		slope float64 // Puntos porcentuales por minuto
+		want  bool
+	}{
+		{"sube hacia el umbral", 0.5, true},
+		{"estable bajo el umbral", 0, false},
+		{"baja", -0.2, false},
+	}
+
+	start := time.Date(2026, 3, 10, 6, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecaster := NewForecaster(ForecastOptions{
+				Alpha:      0.5,
+				Beta:       0.5,
+				Horizons:   []time.Duration{30 * time.Minute},
+				Confidence: 0.9,
+				Threshold:  40,
+			})
+
+			var forecasts []Forecast
+			for minute := 0; minute < 20; minute++ {
+				at := start.Add(time.Duration(minute) * time.Minute)
+				forecaster.Observe(at, map[int]*scraper.ChartData{
+					2: {SorterID: 2, Timestamp: at, Percentages: map[string]float64{"3J": 20 + tt.slope*float64(minute)}},
+				})
+				forecasts = forecaster.Issue(at)
+			}
+
+			crossings := forecaster.Crossings(forecasts)
+			if got := len(crossings) == 1; got != tt.want {
+				t.Fatalf("cruces = %+v, esperaba cruce = %v", crossings, tt.want)
+			}
+			if tt.want {
+				// Desde 29.5% a 0.5 por minuto faltan unos 21 minutos
+				if c := crossings[0]; c.SorterID != 2 || c.Minutes < 18 || c.Minutes > 24 {
+					t.Errorf("cruce = %+v, esperaba sorter 2 en ~21 minutos", c)
+				}
+			}
+		})
+	}
+}
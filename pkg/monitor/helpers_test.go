@@ -0,0 +1,35 @@
+package monitor
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"danich/pkg/scraper"
+)
+
+// testConfig retorna la configuración por defecto trabajando en un
+// directorio temporal (con un config.yaml vacío y sin .env)
+func testConfig(t *testing.T) *SystemConfig {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("config.yaml", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := readConfig(ConfigOptions{})
+	if err != nil {
+		t.Fatalf("readConfig: %v", err)
+	}
+	return cfg
+}
+
+// testSnapshot arma un snapshot con los porcentajes del gráfico de un sorter
+func testSnapshot(at time.Time, sorterID int, percentages map[string]float64) DataSnapshot {
+	return DataSnapshot{
+		Timestamp: at.Format("2006-01-02 15:04:05"),
+		DateTime:  at,
+		ChartData: map[int]*scraper.ChartData{
+			sorterID: {SorterID: sorterID, Timestamp: at, Percentages: percentages},
+		},
+	}
+}
@@ -0,0 +1,364 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotación de los archivos de log
+const (
+	LogRotateSize = "tamano"
+	LogRotateDay  = "dia"
+	LogRotateNone = "ninguna"
+)
+
+// Formatos de los archivos de log
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// logFileName es el nombre del archivo de log activo dentro de LogFolder
+const logFileName = "monitor.log"
+
+// NewLogger crea el logger estructurado del monitor. Los registros se escriben
+// en LogFolder (texto o JSON, con rotación) y las advertencias y errores se
+// muestran además en console (stderr o la TUI) en formato legible.
+func NewLogger(config *SystemConfig, console io.Writer) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLogLevel(config.LogLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.MkdirAll(config.LogFolder, 0755); err != nil {
+		return nil, nil, fmt.Errorf("error creando carpeta de logs: %w", err)
+	}
+
+	writer, err := NewRotatingWriter(filepath.Join(config.LogFolder, logFileName),
+		config.LogRotation, int64(config.LogMaxSizeMB)*1024*1024, config.LogMaxFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	handlers := []slog.Handler{
+		newFormatHandler(writer, config.LogFormat, level),
+		NewConsoleHandler(console, slog.LevelWarn),
+	}
+	if config.LogStderr {
+		handlers = append(handlers, newFormatHandler(os.Stderr, config.LogFormat, level))
+	}
+
+	return slog.New(&fanoutHandler{handlers: handlers}), writer, nil
+}
+
+// ParseLogLevel interpreta un nivel de log (debug, info, warn, error)
+func ParseLogLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return level, fmt.Errorf("nivel de log inválido %q", value)
+	}
+	return level, nil
+}
+
+// newFormatHandler crea un handler de texto o JSON
+func newFormatHandler(w io.Writer, format string, level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == LogFormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// loggerOrDefault retorna el logger recibido o el logger por defecto si es nil
+func loggerOrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// RotatingWriter escribe en un archivo de log y lo rota por tamaño o por día,
+// conservando como máximo maxFiles archivos rotados
+type RotatingWriter struct {
+	mu       sync.Mutex
+	path     string
+	mode     string
+	maxBytes int64
+	maxFiles int
+
+	file *os.File
+	size int64
+	day  string // Día (YYYYMMDD) de los registros del archivo activo
+}
+
+// NewRotatingWriter abre (o crea) el archivo de log activo
+func NewRotatingWriter(path, mode string, maxBytes int64, maxFiles int) (*RotatingWriter, error) {
+	w := &RotatingWriter{
+		path:     path,
+		mode:     mode,
+		maxBytes: maxBytes,
+		maxFiles: maxFiles,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write escribe un registro, rotando antes el archivo si corresponde
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	if w.shouldRotate(now, len(p)) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close cierra el archivo activo
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// shouldRotate indica si el próximo registro debe ir a un archivo nuevo
+func (w *RotatingWriter) shouldRotate(now time.Time, pending int) bool {
+	switch w.mode {
+	case LogRotateSize:
+		return w.maxBytes > 0 && w.size > 0 && w.size+int64(pending) > w.maxBytes
+	case LogRotateDay:
+		return w.size > 0 && now.Format("20060102") != w.day
+	default:
+		return false
+	}
+}
+
+// open abre el archivo activo en modo append
+func (w *RotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error abriendo log %s: %w", w.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.day = info.ModTime().Format("20060102")
+	if w.size == 0 {
+		w.day = time.Now().Format("20060102")
+	}
+	return nil
+}
+
+// rotate renombra el archivo activo, abre uno nuevo y elimina los más antiguos
+func (w *RotatingWriter) rotate(now time.Time) error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	stamp := w.day
+	if w.mode == LogRotateSize {
+		stamp = now.Format("20060102-150405")
+	}
+
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+	target := fmt.Sprintf("%s-%s%s", base, stamp, ext)
+	for i := 1; fileExists(target); i++ {
+		target = fmt.Sprintf("%s-%s-%d%s", base, stamp, i, ext)
+	}
+
+	if err := os.Rename(w.path, target); err != nil {
+		return fmt.Errorf("error rotando log: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.day = now.Format("20060102")
+
+	return w.prune(base + "-*" + ext)
+}
+
+// prune elimina los archivos rotados más antiguos que excedan maxFiles
+func (w *RotatingWriter) prune(pattern string) error {
+	if w.maxFiles <= 0 {
+		return nil
+	}
+
+	rotated, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	modTimes := make(map[string]time.Time, len(rotated))
+	for _, path := range rotated {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	sort.SliceStable(rotated, func(i, j int) bool {
+		return modTimes[rotated[i]].Before(modTimes[rotated[j]])
+	})
+
+	for len(rotated) > w.maxFiles {
+		if err := os.Remove(rotated[0]); err != nil {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// fileExists indica si existe un archivo
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ConsoleHandler muestra advertencias y errores en la consola con el mismo
+// estilo que el resto de la salida del monitor:
+//
+//	⚠️  Error guardando snapshot: disco lleno (ciclo=12)
+type ConsoleHandler struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  slog.Level
+	prefix string // Prefijo de grupo para las claves
+	attrs  []slog.Attr
+}
+
+// NewConsoleHandler crea un handler de consola a partir del nivel indicado
+func NewConsoleHandler(out io.Writer, level slog.Level) *ConsoleHandler {
+	return &ConsoleHandler{
+		mu:    &sync.Mutex{},
+		out:   out,
+		level: level,
+	}
+}
+
+// Enabled indica si el nivel se muestra en la consola
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+// Handle escribe el registro en una línea
+func (h *ConsoleHandler) Handle(_ context.Context, record slog.Record) error {
+	var line strings.Builder
+
+	switch {
+	case record.Level >= slog.LevelError:
+		line.WriteString("❌ ")
+	case record.Level >= slog.LevelWarn:
+		line.WriteString("⚠️  ")
+	default:
+		line.WriteString("ℹ️  ")
+	}
+	line.WriteString(record.Message)
+
+	var details []string
+	addAttr := func(prefix string, attr slog.Attr) {
+		if attr.Key == "error" && prefix == "" {
+			fmt.Fprintf(&line, ": %v", attr.Value.Any())
+			return
+		}
+		details = append(details, fmt.Sprintf("%s%s=%v", prefix, attr.Key, attr.Value.Any()))
+	}
+	for _, attr := range h.attrs {
+		addAttr("", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addAttr(h.prefix, attr)
+		return true
+	})
+
+	if len(details) > 0 {
+		fmt.Fprintf(&line, " (%s)", strings.Join(details, " "))
+	}
+	line.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.out, line.String())
+	return err
+}
+
+// WithAttrs retorna un handler con atributos fijos
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		attr.Key = h.prefix + attr.Key
+		clone.attrs = append(clone.attrs, attr)
+	}
+	return &clone
+}
+
+// WithGroup retorna un handler que antepone el grupo a las claves
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// fanoutHandler reenvía cada registro a varios handlers
+type fanoutHandler struct {
+	handlers []slog.Handler
+}
+
+func (f *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var firstErr error
+	for _, h := range f.handlers {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, record.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (f *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(f.handlers))
+	for i, h := range f.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+func (f *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(f.handlers))
+	for i, h := range f.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
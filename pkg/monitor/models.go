@@ -3,6 +3,7 @@ package monitor
 import (
 	"time"
 
+	"danich/pkg/advisor"
 	"danich/pkg/scraper"
 )
 
@@ -29,11 +30,34 @@ type DataSnapshot struct {
 	BySalida    map[int]int                `json:"by_salida"`
 	ChartData   map[int]*scraper.ChartData `json:"chart_data,omitempty"`
 
+	// Turno y día productivo
+	Shift         string `json:"shift,omitempty"`
+	ProductionDay string `json:"production_day,omitempty"`
+	InBreak       bool   `json:"in_break,omitempty"`
+
 	// Distribuciones detalladas
 	CalibrePercent        map[string]float64                        `json:"calibre_percent,omitempty"`
 	CalibreBySorter       map[int]map[string]CalibreDistribution    `json:"calibre_by_sorter,omitempty"`
 	CalibreBySalida       map[int]map[string]CalibreDistribution    `json:"calibre_by_salida,omitempty"`
 	CalibreBySorterSalida map[string]map[string]CalibreDistribution `json:"calibre_by_sorter_salida,omitempty"`
+
+	// Reconciliación entre gráficos y assignments
+	Quality *QualityReport `json:"quality,omitempty"`
+
+	// Carga absoluta por salida (nil = sin modelo de capacidad o sin throughput)
+	Capacity *CapacityReport `json:"capacity,omitempty"`
+
+	// Estado de cada fuente del ciclo. Partial indica que alguna falló.
+	Sources []SourceStatus `json:"sources,omitempty"`
+	Partial bool           `json:"partial,omitempty"`
+}
+
+// SourceStatus es el resultado de una fuente de datos en un ciclo
+type SourceStatus struct {
+	Source     string  `json:"source"` // assignments, grafico_s1, grafico_s2...
+	OK         bool    `json:"ok"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
 }
 
 // TrainingDataset contiene todos los snapshots recolectados
@@ -46,15 +70,42 @@ type TrainingDataset struct {
 
 // ChangeLog registra un cambio detectado en el sistema
 type ChangeLog struct {
-	Timestamp   string               `json:"timestamp"`
-	ChangeType  string               `json:"change_type"`
-	Added       []Assignment         `json:"added,omitempty"`
-	Removed     []Assignment         `json:"removed,omitempty"`
-	Modified    []ModifiedAssignment `json:"modified,omitempty"`
-	Description string               `json:"description"`
+	Timestamp     string        `json:"timestamp"`
+	Shift         string        `json:"shift,omitempty"`
+	ProductionDay string        `json:"production_day,omitempty"`
+	ChangeType    string        `json:"change_type"`
+	Events        []ChangeEvent `json:"events,omitempty"`
+	LoadShifts    []LoadShift   `json:"load_shifts,omitempty"`
+	Description   string        `json:"description"`
+
+	// Formato anterior del log (listas crudas). Solo se conserva para no
+	// perder las entradas antiguas al reescribir changes_log.json.
+	Added    []Assignment         `json:"added,omitempty"`
+	Removed  []Assignment         `json:"removed,omitempty"`
+	Modified []ModifiedAssignment `json:"modified,omitempty"`
+}
+
+// ChangeEvent representa un cambio tipado en las asignaciones de un SKU
+type ChangeEvent struct {
+	Type        string `json:"type"`
+	SKU         string `json:"sku"`
+	SorterID    int    `json:"sorter_id"`              // Sorter afectado (destino si es un movimiento)
+	FromSorter  int    `json:"from_sorter,omitempty"`  // Sorter de origen (solo sku_moved)
+	Salida      int    `json:"salida,omitempty"`       // Salida agregada o quitada
+	Salidas     []int  `json:"salidas,omitempty"`      // Salidas actuales del SKU en el sorter
+	PrevSalidas []int  `json:"prev_salidas,omitempty"` // Salidas anteriores del SKU en el sorter
 }
 
-// ModifiedAssignment representa un assignment que cambió
+// LoadShift representa un desplazamiento significativo del porcentaje de un SKU
+type LoadShift struct {
+	SorterID int     `json:"sorter_id"`
+	SKU      string  `json:"sku"`
+	From     float64 `json:"from"`  // Referencia suavizada anterior
+	To       float64 `json:"to"`    // Valor suavizado actual
+	Delta    float64 `json:"delta"` // To - From en puntos porcentuales
+}
+
+// ModifiedAssignment representa un assignment que cambió (formato anterior del log)
 type ModifiedAssignment struct {
 	Old Assignment `json:"old"`
 	New Assignment `json:"new"`
@@ -62,7 +113,37 @@ type ModifiedAssignment struct {
 
 // ChangeDetail contiene el detalle de los cambios detectados
 type ChangeDetail struct {
-	Added    []Assignment
-	Removed  []Assignment
-	Modified []ModifiedAssignment
+	Events []ChangeEvent
+}
+
+// AdviceRecord registra una sugerencia entregada por el advisor
+type AdviceRecord struct {
+	DateTime      time.Time      `json:"datetime"`
+	Shift         string         `json:"shift,omitempty"`
+	ProductionDay string         `json:"production_day,omitempty"`
+	CheckCount    int            `json:"check_count"`
+	Advice        advisor.Advice `json:"advice"`
+	Ack           *AdviceAck     `json:"ack,omitempty"` // Reconocimiento de un supervisor
+}
+
+// AdviceAck registra que un supervisor reconoció una sugerencia
+type AdviceAck struct {
+	DateTime time.Time `json:"datetime"`
+	AdviceAt time.Time `json:"advice_datetime"` // DateTime de la sugerencia reconocida
+	Accion   string    `json:"accion"`
+	SKU      string    `json:"sku,omitempty"`
+	Usuario  string    `json:"usuario"`
+	Nota     string    `json:"nota,omitempty"`
+}
+
+// Annotation es una nota de un supervisor sobre la operación (una parada, un
+// cambio de fruta) para dar contexto al histórico
+type Annotation struct {
+	DateTime      time.Time `json:"datetime"`
+	Shift         string    `json:"shift,omitempty"`
+	ProductionDay string    `json:"production_day,omitempty"`
+	Usuario       string    `json:"usuario"`
+	SorterID      int       `json:"sorter_id,omitempty"`
+	SKU           string    `json:"sku,omitempty"`
+	Texto         string    `json:"texto"`
 }
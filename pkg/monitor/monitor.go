@@ -1,171 +1,534 @@
 package monitor
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"danich/pkg/advisor"
+	"danich/pkg/alerts"
 	"danich/pkg/scraper"
 )
 
 // Monitor coordina todo el sistema de monitoreo
 type Monitor struct {
 	config          *SystemConfig
-	fetcher         *Fetcher
+	options         ConfigOptions // Para recargar la configuración
+	logger          *slog.Logger
+	cycleLog        *slog.Logger // logger con el número del ciclo en curso
+	logFile         io.Closer
+	scheduler       *Scheduler
+	collector       *Collector
 	persistence     *Persistence
+	store           Store
+	postgresSink    *PostgresSink // nil si no está configurado
+	retention       *RetentionManager
+	lastRetention   time.Time
 	changeDetector  *ChangeDetector
+	driftDetector   *DriftDetector
+	rollupEngine    *RollupEngine
+	apiServer       *APIServer
+	calendar        *ShiftCalendar
+	shiftReporter   *ShiftReporter
+	currentShift    *ShiftWindow
 	snapshotBuilder *SnapshotBuilder
+	capacityModel   *CapacityModel
+	forecaster      *Forecaster
+	forecasts       []Forecast // Últimos pronósticos emitidos
+	lastForecastAt  time.Time
+	preventedAt     map[string]time.Time // Serie -> último aviso preventivo
 	exporter        *Exporter
 	display         *Display
-	chartScraper    *scraper.ChartScraper
 	nativeAdvisor   *advisor.Advisor
+	alertEngine     *alerts.Engine
+	sourceFailures  map[string]int // Fuente -> ciclos fallidos consecutivos
+	recentChanges   []ChangeLog
+	lastAdviceAt    time.Time
+	adviceWorker    *AdviceWorker
+
+	// outputMu serializa la salida de consola y las escrituras del ciclo con
+	// las del worker del advisor, que publica sus resultados entre ciclos
+	outputMu sync.Mutex
+
+	// adviceMu protege la última sugerencia y el último estado publicado en
+	// el dashboard, compartidos entre el loop y el worker del advisor
+	adviceMu         sync.Mutex
+	lastAdvice       *AdviceRecord
+	lastDashboard    *DashboardState
+	lastDashSnapshot DataSnapshot
 }
 
 // New crea un nuevo monitor con todas sus dependencias
-func New() (*Monitor, error) {
+func New(options ConfigOptions) (*Monitor, error) {
 	// Cargar configuración
-	config, err := LoadConfig()
+	config, err := LoadConfigWith(options)
 	if err != nil {
 		return nil, fmt.Errorf("error cargando configuración: %w", err)
 	}
 
+	// En la TUI las advertencias van a su panel de eventos: en stderr
+	// escribirían sobre la pantalla
+	var tui *TUI
+	console := io.Writer(os.Stderr)
+	if UseTUI(config.DisplayMode, os.Stdout) {
+		tui = NewTUI(os.Stdout, config)
+		console = tui
+	}
+
+	logger, logFile, err := NewLogger(config, console)
+	if err != nil {
+		return nil, fmt.Errorf("error inicializando logs: %w", err)
+	}
+	slog.SetDefault(logger)
+	for _, note := range config.Notes {
+		logger.Warn(note)
+	}
+
 	// Inicializar componentes
+	calendar := config.ShiftCalendar()
 	m := &Monitor{
-		config:         config,
-		fetcher:        NewFetcher(config.AssignmentsURL),
-		persistence:    NewPersistence(config),
-		changeDetector: NewChangeDetector(),
-		exporter:       NewExporter(config.DatasetFolder),
-		display:        NewDisplay(config),
+		config:          config,
+		options:         options,
+		logger:          logger,
+		cycleLog:        logger,
+		logFile:         logFile,
+		calendar:        calendar,
+		scheduler:       NewScheduler(config.CheckInterval, config.FastInterval, config.FastDuration, logger),
+		persistence:     NewPersistence(config, logger),
+		changeDetector:  NewChangeDetector(),
+		driftDetector:   NewDriftDetector(config.DriftAbsThreshold, config.DriftRelThreshold, config.DriftWindow),
+		rollupEngine:    NewRollupEngine(config.RollupThreshold, config.CheckInterval, calendar),
+		snapshotBuilder: NewSnapshotBuilder(calendar, config.QualityOptions(), logger),
+		capacityModel:   NewCapacityModel(config),
+		forecaster:      NewForecaster(config.ForecastOptions()),
+		preventedAt:     make(map[string]time.Time),
+		exporter:        NewExporter(config.DatasetFolder),
+		display:         NewDisplay(config, tui),
+		alertEngine:     alerts.NewEngine(config.AlertCooldown, buildNotifiers(config)...),
+		sourceFailures:  make(map[string]int),
+	}
+
+	m.display.ShowReady(fmt.Sprintf("Configuración cargada: %s (%s) - %d sorters, %d líneas",
+		config.PackingName, config.PackingFruta, config.PackingSorters, config.PackingLineas))
+
+	store, err := OpenStore(config, m.persistence)
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo almacenamiento: %w", err)
+	}
+	m.store = store
+	if stats, err := store.Stats(); err != nil {
+		logger.Warn("Error leyendo el histórico", "error", err)
+	} else if stats.TotalSnapshots == 0 {
+		m.display.ShowReady("Iniciando nuevo dataset")
+	} else {
+		m.display.ShowReady(fmt.Sprintf("Dataset cargado: %d snapshots desde %s",
+			stats.TotalSnapshots, stats.First.Format("2006-01-02 15:04:05")))
+	}
+
+	m.retention = NewRetentionManager(config, m.persistence, store)
+
+	if config.PostgresDSN != "" {
+		m.postgresSink = NewPostgresSink(config)
 	}
 
 	// Inicializar scraper si está habilitado
+	var chartScraper *scraper.ChartScraper
 	if config.CaptureCharts {
-		m.chartScraper = scraper.NewChartScraper(config.BaseURL)
-		m.snapshotBuilder = NewSnapshotBuilder(m.chartScraper)
-		fmt.Println("✓ Scraper de gráficos inicializado")
-	} else {
-		m.snapshotBuilder = NewSnapshotBuilder(nil)
+		chartScraper = scraper.NewChartScraper(config.BaseURL, logger)
+		m.display.ShowReady("Scraper de gráficos inicializado")
 	}
+	m.collector = NewCollector(NewFetcher(config.AssignmentsURL), chartScraper, m.sorterCount(),
+		config.FetchTimeout, config.ChartTimeout, logger)
 
 	// Inicializar advisor nativo
-	advisorConfig := advisor.DefaultConfig()
-	m.nativeAdvisor = advisor.NewAdvisor(advisorConfig)
-	fmt.Println("✓ Advisor nativo inicializado")
+	m.nativeAdvisor = advisor.NewAdvisor(config.Advisor, logger)
+	m.adviceWorker = NewAdviceWorker(m.generateAdvice, logger)
+	m.display.ShowReady("Advisor nativo inicializado")
+
+	if config.APIListen != "" {
+		assistant := NewAssistant(config, m.store, m.persistence, m.nativeAdvisor)
+		m.apiServer = NewAPIServer(config, m.persistence, m.store, assistant, m, logger)
+	}
+
+	m.shiftReporter = NewShiftReporter(config, m.store, m.nativeAdvisor)
 
 	return m, nil
 }
 
-// Run inicia el loop de monitoreo
-func (m *Monitor) Run() error {
-	m.printHeader()
+// Run inicia el loop de monitoreo hasta que ctx termine. Al terminar espera
+// el ciclo en curso y cierra los componentes (envío pendiente a PostgreSQL,
+// almacenamiento, pantalla y logs).
+func (m *Monitor) Run(ctx context.Context) error {
+	m.display.Start()
+	defer m.display.Close()
+	m.display.ShowHeader()
+	defer m.logFile.Close()
 
 	// Crear carpeta de datos
 	if err := m.persistence.EnsureDataFolder(); err != nil {
 		return fmt.Errorf("error creando carpeta de datos: %w", err)
 	}
 
+	if m.apiServer != nil {
+		m.apiServer.Start()
+		scheme := "http"
+		if m.apiServer.TLS() {
+			scheme = "https"
+		}
+		m.display.ShowReady(fmt.Sprintf("API y dashboard escuchando en %s://%s", scheme, m.config.APIListen))
+	}
+
+	defer m.store.Close()
+	if m.postgresSink != nil {
+		defer m.postgresSink.Close()
+	}
+
 	// Cargar estado inicial
-	dataset := m.persistence.LoadOrCreateDataset()
 	lastAssignments := m.persistence.LoadLastAssignments()
+	if changes, err := m.store.Changes(time.Time{}, time.Time{}); err == nil {
+		for _, change := range changes {
+			m.rememberChange(change)
+		}
+	}
+
+	if m.forecaster.Enabled() {
+		m.seedForecaster(time.Now())
+	}
+	m.seedRollups(time.Now())
+	defer m.flushRollups()
+
+	m.adviceWorker.Start()
+	defer m.adviceWorker.Stop()
+
+	if m.nativeAdvisor.LLMEnabled() {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go m.superviseOllama(ctx)
+	}
+
+	// Aviso en la consola (o el panel de eventos de la TUI) mientras termina el ciclo
+	stopNotice := context.AfterFunc(ctx, func() {
+		m.logger.Warn("deteniendo el monitor, se cancelan las consultas en curso")
+	})
+	defer stopNotice()
 
 	checkCount := 0
 	startTime := time.Now()
+	m.logger.Info("monitor iniciado",
+		"url", m.config.AssignmentsURL,
+		"intervalo", m.config.CheckInterval,
+		"almacenamiento", m.config.StoreType)
 
-	// Loop principal
+	// Loop principal: cada ciclo comienza en un tick alineado al reloj
 	for {
+		tick, err := m.scheduler.Wait(ctx)
+		if err != nil {
+			m.logger.Info("monitor detenido", "ciclos", checkCount)
+			return nil
+		}
 		checkCount++
-		if err := m.runCycle(checkCount, &dataset, &lastAssignments, startTime); err != nil {
-			log.Printf("❌ Error en ciclo #%d: %v\n", checkCount, err)
+
+		m.outputMu.Lock()
+		if err := m.runCycle(ctx, checkCount, tick, &lastAssignments, startTime); err != nil {
+			m.cycleLog.Error("Error en el ciclo", "error", err)
 		}
+		m.scheduler.Finish(tick, time.Now())
 
-		fmt.Printf("\nPróxima verificación en %v...\n", m.config.CheckInterval)
-		time.Sleep(m.config.CheckInterval)
+		now := time.Now()
+		m.display.ShowNextCheck(m.scheduler.Next(now), m.scheduler.Interval(now), m.scheduler.Fast(now), m.scheduler.Stats())
+		m.outputMu.Unlock()
 	}
 }
 
-// runCycle ejecuta un ciclo completo de monitoreo
-func (m *Monitor) runCycle(checkCount int, dataset *TrainingDataset, lastAssignments *[]Assignment, startTime time.Time) error {
-	now := time.Now()
+// runCycle ejecuta un ciclo completo de monitoreo. now es la hora programada
+// del tick, que se usa como timestamp del snapshot. Si ctx se cancela durante
+// la consulta de fuentes, el ciclo se descarta.
+func (m *Monitor) runCycle(ctx context.Context, checkCount int, now time.Time, lastAssignments *[]Assignment, startTime time.Time) error {
 	timestamp := now.Format("2006-01-02 15:04:05")
+	m.cycleLog = m.logger.With("ciclo", checkCount)
 
-	fmt.Printf("\n[%s] Verificación #%d\n", timestamp, checkCount)
+	m.display.ShowCycleStart(timestamp, checkCount)
 
-	// 1. Obtener assignments actuales
-	currentAssignments, err := m.fetcher.FetchAssignments()
-	if err != nil {
-		return fmt.Errorf("error obteniendo assignments: %w", err)
+	// 1. Consultar assignments y gráficos en paralelo
+	collection := m.collector.Collect(ctx)
+	if ctx.Err() != nil {
+		m.cycleLog.Warn("ciclo interrumpido, se descarta la captura")
+		return nil
+	}
+	for _, source := range collection.Sources {
+		m.recordSource(source.Source, source.OK)
+	}
+	m.display.ShowSources(collection.Sources, m.sourceFailures)
+	if collection.Failed() {
+		m.evaluateAlerts(now, nil)
+		return fmt.Errorf("ninguna fuente respondió")
 	}
-	fmt.Printf("✓ Obtenidos %d assignments\n", len(currentAssignments))
-
-	// 2. Crear snapshot
-	snapshot := m.snapshotBuilder.CreateSnapshot(now, currentAssignments)
 
-	// 3. Detectar cambios
-	hasChanged := m.changeDetector.HasChanges(*lastAssignments, currentAssignments)
+	// Sin assignments los gráficos se mapean con los últimos conocidos
+	currentAssignments := collection.Assignments
+	if !collection.AssignmentsOK {
+		currentAssignments = *lastAssignments
+	}
 
-	if hasChanged || len(*lastAssignments) == 0 {
+	// 2. Crear snapshot
+	snapshot := m.snapshotBuilder.CreateSnapshot(now, currentAssignments, collection.Charts)
+	snapshot.Sources = collection.Sources
+	snapshot.Partial = collection.Partial()
+	if m.capacityModel.Enabled() {
+		manual, err := m.persistence.LoadThroughput()
+		if err != nil {
+			m.cycleLog.Warn("Error leyendo throughput manual", "error", err)
+		}
+		snapshot.Capacity = m.capacityModel.Compute(snapshot, manual)
+	}
+	m.display.ShowSnapshot(snapshot)
+	m.cycleLog.Info("snapshot capturado",
+		"assignments", snapshot.TotalCount,
+		"sorters_con_grafico", len(snapshot.ChartData),
+		"parcial", snapshot.Partial,
+		"turno", snapshot.Shift)
+	m.evaluateAlerts(now, &snapshot)
+
+	// 3. Detectar cambios (solo con assignments frescos)
+	hasChanged := collection.AssignmentsOK && m.changeDetector.HasChanges(*lastAssignments, currentAssignments)
+
+	if !collection.AssignmentsOK {
+		m.display.ShowStaleAssignments()
+	} else if hasChanged || len(*lastAssignments) == 0 {
 		if err := m.handleChanges(timestamp, hasChanged, *lastAssignments, currentAssignments, snapshot); err != nil {
-			log.Printf("⚠️  Error manejando cambios: %v\n", err)
+			m.cycleLog.Warn("Error manejando cambios", "error", err)
 		}
 		*lastAssignments = currentAssignments
 	} else {
-		fmt.Println("✓ Sin cambios")
+		m.display.ShowNoChanges()
 	}
 
-	// 4. Actualizar dataset
-	dataset.Snapshots = append(dataset.Snapshots, snapshot)
-	dataset.TotalSnapshots = len(dataset.Snapshots)
-	dataset.CollectionEnd = now
+	// 4. Detectar desplazamientos de carga en los gráficos
+	shifts := m.driftDetector.Observe(snapshot.ChartData)
+	if len(shifts) > 0 {
+		m.handleLoadShifts(timestamp, shifts, snapshot)
+	}
+
+	// 5. Pronosticar la carga y comparar pronósticos vencidos con lo observado
+	if m.forecaster.Enabled() && len(snapshot.ChartData) > 0 {
+		m.forecastLoad(now, snapshot, checkCount)
+	}
+
+	// 6. Persistir snapshot
+	if err := m.store.SaveSnapshot(snapshot); err != nil {
+		m.cycleLog.Warn("Error guardando snapshot", "error", err)
+	}
+
+	if m.postgresSink != nil {
+		m.postgresSink.AddSnapshot(snapshot)
+		if err := m.postgresSink.FlushIfDue(); err != nil {
+			m.cycleLog.Warn("Error enviando a PostgreSQL", "error", err)
+		}
+	}
 
-	// 5. Persistir datos
-	if err := m.persistence.SaveDataset(*dataset); err != nil {
-		log.Printf("⚠️  Error guardando dataset: %v\n", err)
+	if err := m.persistence.AppendRollups(m.rollupEngine.Add(snapshot)); err != nil {
+		m.cycleLog.Warn("Error guardando rollups", "error", err)
 	}
 
-	// 6. Exportar a CSV
+	m.applyRetention(now)
+
+	// 7. Exportar a CSV
 	if len(snapshot.ChartData) > 0 {
 		if err := m.exporter.ExportToCSV(snapshot); err != nil {
-			log.Printf("⚠️  Error exportando a CSV: %v\n", err)
+			m.cycleLog.Warn("Error exportando a CSV", "error", err)
 		} else {
-			fmt.Println("✓ Datos exportados a training_data.csv")
+			m.display.ShowExported()
 		}
 	}
 
-	// 7. Mostrar estadísticas
-	m.display.ShowStats(snapshot, *dataset, startTime)
+	// 8. Mostrar estadísticas
+	stats, err := m.store.Stats()
+	if err != nil {
+		m.cycleLog.Warn("Error leyendo estadísticas del almacenamiento", "error", err)
+	}
+	m.display.ShowStats(snapshot, stats.TotalSnapshots, startTime)
+	if m.nativeAdvisor.LLMEnabled() {
+		m.display.ShowAdvisorHealth(m.nativeAdvisor.Health())
+	}
 
-	// 8. Analizar balance y generar sugerencias
-	if checkCount%10 == 0 && len(snapshot.ChartData) >= 2 { // Cada 5 minutos
-		m.generateAdvice(snapshot, checkCount)
+	// 9. Analizar balance en el worker del advisor (la sugerencia se muestra
+	// y se guarda cuando Ollama responde, sin demorar el próximo ciclo)
+	adviceDue := now.Sub(m.lastAdviceAt) >= m.config.AdviceInterval
+	if len(shifts) > 0 && m.config.DriftTriggerAdvice {
+		adviceDue = true
+	}
+	if adviceDue && len(snapshot.ChartData) >= 2 {
+		m.lastAdviceAt = now
+		m.adviceWorker.Submit(adviceRequest{
+			snapshot:   snapshot,
+			checkCount: checkCount,
+			changes:    slices.Clone(m.recentChanges),
+			log:        m.cycleLog,
+		})
 	}
 
+	// 10. Reporte de fin de turno
+	m.trackShift(now)
+
+	// 11. Publicar estado en el dashboard y en la TUI
+	state := m.buildDashboardState(snapshot, checkCount)
+	m.publishDashboard(snapshot, state)
+	m.display.ShowDashboard(snapshot, state)
+
 	return nil
 }
 
+// trackShift detecta el cambio de turno y genera el reporte del turno que terminó
+func (m *Monitor) trackShift(now time.Time) {
+	window, inShift := m.calendar.Resolve(now)
+
+	if m.currentShift != nil && (!inShift || window.Key() != m.currentShift.Key()) {
+		m.generateShiftReport(*m.currentShift)
+	}
+
+	if inShift {
+		m.currentShift = &window
+	} else {
+		m.currentShift = nil
+	}
+}
+
+// generateShiftReport genera y guarda el reporte de un turno
+func (m *Monitor) generateShiftReport(window ShiftWindow) {
+	report, err := m.shiftReporter.Build(window)
+	if err != nil {
+		m.cycleLog.Warn("Error generando reporte de turno", "turno", window.Key(), "error", err)
+		return
+	}
+
+	path, err := m.persistence.SaveReport(report)
+	if err != nil {
+		m.cycleLog.Warn("Error guardando reporte de turno", "turno", window.Key(), "error", err)
+		return
+	}
+
+	m.display.ShowShiftReport(window.Key(), path)
+	m.cycleLog.Info("reporte de turno guardado", "turno", window.Key(), "ruta", path)
+}
+
+// applyRetention aplica la política de retención como máximo una vez por hora
+func (m *Monitor) applyRetention(now time.Time) {
+	if !m.retention.Enabled() || now.Sub(m.lastRetention) < time.Hour {
+		return
+	}
+	m.lastRetention = now
+
+	result, err := m.retention.Apply(now)
+	if err != nil {
+		m.cycleLog.Warn("Error aplicando retención", "error", err)
+		return
+	}
+
+	m.display.ShowRetention(result)
+	if len(result.ArchivedDays) > 0 || len(result.DeletedFiles) > 0 {
+		m.cycleLog.Info("retención aplicada",
+			"dias_archivados", result.ArchivedDays,
+			"snapshots_archivados", result.ArchivedSnapshots,
+			"archivos_eliminados", len(result.DeletedFiles))
+	}
+	if result.OverBudget {
+		m.cycleLog.Warn("La carpeta de datos excede el presupuesto de disco", "presupuesto_mb", m.config.DiskBudgetMB)
+	}
+}
+
+// recordSource actualiza el contador de fallos consecutivos de una fuente
+func (m *Monitor) recordSource(source string, ok bool) {
+	if ok {
+		m.sourceFailures[source] = 0
+	} else {
+		m.sourceFailures[source]++
+	}
+}
+
+// sorterCount retorna la cantidad de sorters del packing
+func (m *Monitor) sorterCount() int {
+	if m.config.PackingSorters > 0 {
+		return m.config.PackingSorters
+	}
+	return 2
+}
+
+// evaluateAlerts evalúa las reglas de alerta y muestra los eventos
+func (m *Monitor) evaluateAlerts(now time.Time, snapshot *DataSnapshot) {
+	if len(m.config.AlertRules) == 0 {
+		return
+	}
+
+	conditions := EvaluateAlertRules(m.config.AlertRules, AlertInput{
+		Snapshot:       snapshot,
+		SourceFailures: m.sourceFailures,
+	})
+
+	events, errs := m.alertEngine.Evaluate(now, conditions)
+	for _, alert := range events {
+		m.display.ShowAlert(alert)
+		m.cycleLog.Info("alerta",
+			"regla", alert.Rule,
+			"estado", alert.State,
+			"severidad", alert.Severity,
+			"mensaje", alert.Message,
+			"etiquetas", alert.Labels)
+	}
+	for _, err := range errs {
+		m.cycleLog.Warn("Error notificando alerta", "error", err)
+	}
+}
+
+// buildNotifiers crea los notificadores de alertas configurados
+func buildNotifiers(config *SystemConfig) []alerts.Notifier {
+	var notifiers []alerts.Notifier
+
+	if config.AlertWebhookURL != "" {
+		notifiers = append(notifiers, alerts.NewWebhookNotifier(config.AlertWebhookURL))
+	}
+	if config.AlertEmail.Server != "" {
+		notifiers = append(notifiers, alerts.NewEmailNotifier(config.AlertEmail))
+	}
+	if config.AlertCommand != "" {
+		notifiers = append(notifiers, alerts.NewCommandNotifier(config.AlertCommand, config.AlertCommandArgs))
+	}
+
+	return notifiers
+}
+
 // handleChanges maneja la detección y registro de cambios
 func (m *Monitor) handleChanges(timestamp string, hasChanged bool, old, new []Assignment, snapshot DataSnapshot) error {
 	if hasChanged {
-		fmt.Println("🔔 ¡CAMBIOS DETECTADOS!")
-
 		changes := m.changeDetector.DetectChanges(old, new)
-		m.changeDetector.DisplayChanges(changes)
+		m.display.ShowChanges(changes)
+		m.scheduler.Boost(snapshot.DateTime)
+		for _, e := range changes.Events {
+			m.cycleLog.Info("cambio de asignación",
+				"tipo", e.Type,
+				"sorter", e.SorterID,
+				"sku", e.SKU,
+				"salidas", e.Salidas)
+		}
 
 		// Registrar cambios
-		if err := m.persistence.LogChange(ChangeLog{
-			Timestamp:   timestamp,
-			ChangeType:  "update",
-			Added:       changes.Added,
-			Removed:     changes.Removed,
-			Modified:    changes.Modified,
-			Description: m.changeDetector.FormatChangeSummary(changes),
+		if err := m.logChange(ChangeLog{
+			Timestamp:     timestamp,
+			Shift:         snapshot.Shift,
+			ProductionDay: snapshot.ProductionDay,
+			ChangeType:    "update",
+			Events:        changes.Events,
+			Description:   m.changeDetector.FormatChangeSummary(changes),
 		}); err != nil {
 			return err
 		}
 	} else {
-		fmt.Println("📊 Primera captura de datos")
+		m.display.ShowFirstCapture()
 	}
 
 	// Guardar snapshot y assignments
@@ -176,42 +539,206 @@ func (m *Monitor) handleChanges(timestamp string, hasChanged bool, old, new []As
 	return m.persistence.SaveLastAssignments(new)
 }
 
-// generateAdvice genera sugerencias usando el advisor nativo
-func (m *Monitor) generateAdvice(snapshot DataSnapshot, checkCount int) {
-	fmt.Printf("\n🤖 ANÁLISIS DE BALANCE (Verificación #%d)\n", checkCount)
-	fmt.Println("═" + strings.Repeat("═", 48))
+// logChange persiste un cambio y lo agrega a los cambios recientes
+func (m *Monitor) logChange(change ChangeLog) error {
+	m.rememberChange(change)
+	if m.postgresSink != nil {
+		m.postgresSink.AddChange(change)
+	}
+	return m.store.SaveChange(change)
+}
+
+// handleLoadShifts registra los desplazamientos de carga detectados
+func (m *Monitor) handleLoadShifts(timestamp string, shifts []LoadShift, snapshot DataSnapshot) {
+	m.display.ShowLoadShifts(shifts)
+	m.scheduler.Boost(snapshot.DateTime)
+	for _, s := range shifts {
+		m.cycleLog.Info("desplazamiento de carga",
+			"sorter", s.SorterID,
+			"sku", s.SKU,
+			"desde", s.From,
+			"hasta", s.To,
+			"delta", s.Delta)
+	}
+
+	if err := m.logChange(ChangeLog{
+		Timestamp:     timestamp,
+		Shift:         snapshot.Shift,
+		ProductionDay: snapshot.ProductionDay,
+		ChangeType:    "load_shift",
+		LoadShifts:    shifts,
+		Description:   FormatShiftSummary(shifts),
+	}); err != nil {
+		m.cycleLog.Warn("Error registrando desplazamiento de carga", "error", err)
+	}
+}
+
+// seedForecaster inicializa las series del pronóstico con el histórico reciente
+func (m *Monitor) seedForecaster(now time.Time) {
+	snapshots, err := m.store.Snapshots(now.Add(-m.config.ForecastHistory), time.Time{})
+	if err != nil {
+		m.logger.Warn("Error leyendo histórico para el pronóstico", "error", err)
+		return
+	}
+	for _, snapshot := range snapshots {
+		m.forecaster.Observe(snapshot.DateTime, snapshot.ChartData)
+	}
+	m.logger.Info("pronóstico inicializado", "snapshots", len(snapshots))
+}
+
+// seedRollups reconstruye las ventanas de agregación abiertas con los
+// snapshots guardados antes de reiniciar. Las ventanas que cierran durante la
+// reconstrucción ya están en rollups.jsonl y se descartan.
+func (m *Monitor) seedRollups(now time.Time) {
+	snapshots, err := m.store.Snapshots(m.rollupEngine.OpenSince(now), time.Time{})
+	if err != nil {
+		m.logger.Warn("Error leyendo histórico para los agregados", "error", err)
+		return
+	}
+	for _, snapshot := range snapshots {
+		m.rollupEngine.Add(snapshot)
+	}
+	m.logger.Info("agregados inicializados", "snapshots", len(snapshots))
+}
 
-	// Convertir snapshot a formato del advisor nativo
-	state := m.convertToAdvisorState(snapshot)
+// flushRollups guarda las ventanas abiertas al detener el monitor
+func (m *Monitor) flushRollups() {
+	if err := m.persistence.AppendRollups(m.rollupEngine.Flush()); err != nil {
+		m.logger.Error("Error guardando agregados", "error", err)
+	}
+}
+
+// forecastLoad actualiza el pronóstico con el snapshot, exporta los
+// pronósticos resueltos y cada pronostico.intervalo_minutos emite nuevos
+// pronósticos y avisos preventivos
+func (m *Monitor) forecastLoad(now time.Time, snapshot DataSnapshot, checkCount int) {
+	outcomes := m.forecaster.Observe(now, snapshot.ChartData)
+	if err := m.exporter.ExportForecasts(outcomes); err != nil {
+		m.cycleLog.Warn("Error exportando pronósticos", "error", err)
+	}
+
+	if now.Sub(m.lastForecastAt) < m.config.ForecastInterval {
+		return
+	}
+	m.lastForecastAt = now
+	m.forecasts = m.forecaster.Issue(now)
+
+	crossings := m.forecaster.Crossings(m.forecasts)
+	m.display.ShowForecasts(crossings, m.forecaster.Accuracy())
+	m.cycleLog.Info("pronóstico emitido", "pronosticos", len(m.forecasts), "sobre_umbral", len(crossings))
+
+	// Un aviso por serie como máximo cada advisor.intervalo_minutos
+	var pending []advisor.LoadForecast
+	for _, crossing := range crossings {
+		key := seriesKey(crossing.SorterID, crossing.SKU)
+		if now.Sub(m.preventedAt[key]) < m.config.AdviceInterval {
+			continue
+		}
+		pending = append(pending, advisor.LoadForecast{
+			SorterID:  crossing.SorterID,
+			SKU:       crossing.SKU,
+			Current:   crossing.Current,
+			Predicted: crossing.Predicted,
+			Lower:     crossing.Lower,
+			Upper:     crossing.Upper,
+			Minutes:   crossing.Minutes,
+		})
+	}
+
+	advice := m.nativeAdvisor.PreventiveAdvice(pending, m.config.ForecastThreshold)
+	if advice == nil {
+		return
+	}
+	m.preventedAt[seriesKey(advice.DeSorter, advice.SKU)] = now
+	m.display.ShowAdvice(checkCount, advice)
+	m.recordAdvice(m.cycleLog, snapshot, checkCount, advice)
+}
+
+// superviseOllama verifica los modelos al iniciar (descarga y precalentamiento
+// incluidos) y luego los chequea cada advisor.salud_segundos. Corre aparte
+// para que una descarga no demore el monitoreo.
+func (m *Monitor) superviseOllama(ctx context.Context) {
+	health := m.nativeAdvisor.Prepare(ctx)
+	m.logger.Info("chequeo inicial de Ollama",
+		"modelo", health.Model, "respaldo", health.Fallback, "faltantes", health.Missing, "error", health.Error)
+
+	if m.config.OllamaHealthInterval > 0 {
+		m.nativeAdvisor.RunHealthProbes(ctx, m.config.OllamaHealthInterval)
+	}
+}
+
+// generateAdvice genera sugerencias usando el advisor nativo. Se ejecuta en
+// el worker del advisor: la consulta a Ollama corre en paralelo con el ciclo
+// y el resultado se publica entre ciclos.
+func (m *Monitor) generateAdvice(request adviceRequest) {
+	// Convertir snapshot a formato del advisor nativo, con el historial reciente
+	var previous []AdviceRecord
+	if last := m.latestAdvice(); last != nil {
+		previous = append(previous, *last)
+	}
+	state := AdvisorState(request.snapshot, request.changes, previous)
 
 	// Obtener advice del advisor nativo
 	advice, err := m.nativeAdvisor.GetAdvice(state)
 	if err != nil {
-		fmt.Printf("⚠️ Error obteniendo sugerencia: %v\n", err)
+		request.log.Warn("Error obteniendo sugerencia", "error", err)
 		return
 	}
 
-	// Mostrar sugerencia
-	m.displayAdvice(advice)
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+
+	m.display.ShowAdvice(request.checkCount, advice)
+	m.recordAdvice(request.log, request.snapshot, request.checkCount, advice)
+}
+
+// recordAdvice registra una sugerencia en el log y en el histórico y la
+// publica en el dashboard
+func (m *Monitor) recordAdvice(log *slog.Logger, snapshot DataSnapshot, checkCount int, advice *advisor.Advice) {
+	log.Info("sugerencia del advisor",
+		"accion", advice.Accion,
+		"sku", advice.SKU,
+		"de_sorter", advice.DeSorter,
+		"a_sorter", advice.ASorter,
+		"razon", advice.Razon)
+
+	record := AdviceRecord{
+		DateTime:      snapshot.DateTime,
+		Shift:         snapshot.Shift,
+		ProductionDay: snapshot.ProductionDay,
+		CheckCount:    checkCount,
+		Advice:        *advice,
+	}
+	m.setLatestAdvice(record)
+
+	if err := m.store.SaveAdvice(record); err != nil {
+		log.Warn("Error registrando advice", "error", err)
+	}
 }
 
 // convertToAdvisorState convierte DataSnapshot a advisor.SystemState
-func (m *Monitor) convertToAdvisorState(snapshot DataSnapshot) advisor.SystemState {
+func convertToAdvisorState(snapshot DataSnapshot) advisor.SystemState {
 	state := advisor.SystemState{
 		Timestamp: snapshot.DateTime,
 		Sorter1:   advisor.SorterData{SKUs: make(map[string]advisor.SKUInfo)},
 		Sorter2:   advisor.SorterData{SKUs: make(map[string]advisor.SKUInfo)},
 	}
+	if snapshot.Capacity != nil {
+		state.Unit = snapshot.Capacity.UnitLabel()
+	}
 
 	// Convertir datos del Sorter 1
 	if chartData, exists := snapshot.ChartData[1]; exists {
 		for sku, percentage := range chartData.Percentages {
 			if percentage > 0 {
-				lines := m.getLinesForSKU(snapshot.Assignments, 1, sku)
-				state.Sorter1.SKUs[sku] = advisor.SKUInfo{
+				info := advisor.SKUInfo{
 					Percentage: percentage,
-					Lines:      lines,
+					Lines:      getLinesForSKU(snapshot.Assignments, 1, sku),
+				}
+				if snapshot.Capacity != nil {
+					info.Volume, info.Capacity = snapshot.Capacity.SKULoad(1, sku)
 				}
+				state.Sorter1.SKUs[sku] = info
 			}
 		}
 	}
@@ -220,11 +747,14 @@ func (m *Monitor) convertToAdvisorState(snapshot DataSnapshot) advisor.SystemSta
 	if chartData, exists := snapshot.ChartData[2]; exists {
 		for sku, percentage := range chartData.Percentages {
 			if percentage > 0 {
-				lines := m.getLinesForSKU(snapshot.Assignments, 2, sku)
-				state.Sorter2.SKUs[sku] = advisor.SKUInfo{
+				info := advisor.SKUInfo{
 					Percentage: percentage,
-					Lines:      lines,
+					Lines:      getLinesForSKU(snapshot.Assignments, 2, sku),
+				}
+				if snapshot.Capacity != nil {
+					info.Volume, info.Capacity = snapshot.Capacity.SKULoad(2, sku)
 				}
+				state.Sorter2.SKUs[sku] = info
 			}
 		}
 	}
@@ -233,7 +763,7 @@ func (m *Monitor) convertToAdvisorState(snapshot DataSnapshot) advisor.SystemSta
 }
 
 // getLinesForSKU obtiene las líneas asignadas a un SKU en un sorter
-func (m *Monitor) getLinesForSKU(assignments []Assignment, sorterID int, sku string) []int {
+func getLinesForSKU(assignments []Assignment, sorterID int, sku string) []int {
 	var lines []int
 	skuUpper := strings.ToUpper(sku)
 
@@ -256,26 +786,6 @@ func (m *Monitor) getLinesForSKU(assignments []Assignment, sorterID int, sku str
 	return lines
 }
 
-// displayAdvice muestra la sugerencia del advisor de forma visual
-func (m *Monitor) displayAdvice(advice *advisor.Advice) {
-	switch advice.Accion {
-	case "mantener":
-		fmt.Printf("✅ %s\n", advice.Razon)
-
-	case "mover":
-		fmt.Printf("💡 SUGERENCIA DE OPTIMIZACIÓN\n")
-		fmt.Println("═" + strings.Repeat("═", 48))
-		fmt.Printf("SKU: %s\n", advice.SKU)
-		fmt.Printf("Movimiento: Sorter %d → Sorter %d\n", advice.DeSorter, advice.ASorter)
-		fmt.Printf("📋 Razón: %s\n", advice.Razon)
-		fmt.Printf("🕐 Timestamp: %s\n", advice.Timestamp)
-		fmt.Println("═" + strings.Repeat("═", 48))
-
-	default:
-		fmt.Printf("ℹ️  %s\n", advice.Razon)
-	}
-}
-
 // analyzeBalance analiza el balance y genera sugerencias
 // analyzeBalance - función deshabilitada temporalmente
 /*
@@ -333,15 +843,3 @@ func (m *Monitor) analyzeBalance(snapshot DataSnapshot) *advisor.BalanceAdvice {
 	return advice
 }
 */
-
-// printHeader muestra el header del monitor
-func (m *Monitor) printHeader() {
-	separator := "============================================================"
-	fmt.Println("=== Monitor de Asignaciones - Recolección de Datos ===")
-	fmt.Printf("URL: %s\n", m.config.AssignmentsURL)
-	fmt.Printf("Intervalo de verificación: %v\n", m.config.CheckInterval)
-	fmt.Printf("Carpeta de datos: %s\n", m.config.DatasetFolder)
-	fmt.Printf("Captura de gráficos: %v\n", m.config.CaptureCharts)
-	fmt.Println("Presiona Ctrl+C para detener")
-	fmt.Println(separator)
-}
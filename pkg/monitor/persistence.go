@@ -1,24 +1,30 @@
 package monitor
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
 // Persistence maneja el almacenamiento de datos
 type Persistence struct {
 	config *SystemConfig
+	logger *slog.Logger
 }
 
-// NewPersistence crea un nuevo manejador de persistencia
-func NewPersistence(config *SystemConfig) *Persistence {
+// NewPersistence crea un nuevo manejador de persistencia. Si logger es nil
+// usa el logger por defecto
+func NewPersistence(config *SystemConfig, logger *slog.Logger) *Persistence {
 	return &Persistence{
 		config: config,
+		logger: loggerOrDefault(logger),
 	}
 }
 
@@ -31,7 +37,7 @@ func (p *Persistence) EnsureDataFolder() error {
 func (p *Persistence) LoadOrCreateDataset() TrainingDataset {
 	data, err := ioutil.ReadFile(p.config.DatasetFile)
 	if err != nil {
-		fmt.Println("✓ Iniciando nuevo dataset")
+		p.logger.Debug("iniciando nuevo dataset", "archivo", p.config.DatasetFile)
 		return TrainingDataset{
 			CollectionStart: time.Now(),
 			CollectionEnd:   time.Now(),
@@ -42,7 +48,7 @@ func (p *Persistence) LoadOrCreateDataset() TrainingDataset {
 
 	var dataset TrainingDataset
 	if err := json.Unmarshal(data, &dataset); err != nil {
-		log.Printf("⚠ Error al cargar dataset, creando nuevo: %v\n", err)
+		p.logger.Warn("Error al cargar dataset, creando nuevo", "archivo", p.config.DatasetFile, "error", err)
 		return TrainingDataset{
 			CollectionStart: time.Now(),
 			CollectionEnd:   time.Now(),
@@ -51,9 +57,9 @@ func (p *Persistence) LoadOrCreateDataset() TrainingDataset {
 		}
 	}
 
-	fmt.Printf("✓ Dataset cargado: %d snapshots desde %s\n",
-		dataset.TotalSnapshots,
-		dataset.CollectionStart.Format("2006-01-02 15:04:05"))
+	p.logger.Debug("dataset cargado",
+		"snapshots", dataset.TotalSnapshots,
+		"desde", dataset.CollectionStart.Format("2006-01-02 15:04:05"))
 
 	return dataset
 }
@@ -79,7 +85,57 @@ func (p *Persistence) SaveLastAssignments(assignments []Assignment) error {
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(p.config.LastAssignmentsFile, data, 0644)
+	return writeFileAtomic(p.config.LastAssignmentsFile, data, 0644)
+}
+
+// LoadThroughput carga el throughput ingresado manualmente por sorter
+func (p *Persistence) LoadThroughput() (map[int]ThroughputEntry, error) {
+	entries := make(map[int]ThroughputEntry)
+	data, err := ioutil.ReadFile(p.config.ThroughputFile)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return entries, err
+	}
+
+	var list []ThroughputEntry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return entries, fmt.Errorf("error leyendo %s: %w", p.config.ThroughputFile, err)
+	}
+	for _, entry := range list {
+		entries[entry.SorterID] = entry
+	}
+	return entries, nil
+}
+
+// SaveThroughput guarda el throughput manual de un sorter. Un valor 0 lo
+// elimina y vuelve a regir el de la configuración.
+func (p *Persistence) SaveThroughput(entry ThroughputEntry) error {
+	entries, err := p.LoadThroughput()
+	if err != nil {
+		return err
+	}
+	if entry.PerMinute > 0 {
+		entries[entry.SorterID] = entry
+	} else {
+		delete(entries, entry.SorterID)
+	}
+
+	list := make([]ThroughputEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].SorterID < list[j].SorterID })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := p.EnsureDataFolder(); err != nil {
+		return err
+	}
+	return writeFileAtomic(p.config.ThroughputFile, data, 0644)
 }
 
 // SaveSnapshot guarda un snapshot individual
@@ -88,7 +144,7 @@ func (p *Persistence) SaveSnapshot(snapshot DataSnapshot, filename string) error
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(filename, data, 0644)
+	return writeFileAtomic(filename, data, 0644)
 }
 
 // SaveDataset guarda el dataset completo
@@ -99,15 +155,60 @@ func (p *Persistence) SaveDataset(dataset TrainingDataset) error {
 	}
 
 	// Guardar dataset completo
-	if err := ioutil.WriteFile(p.config.DatasetFile, data, 0644); err != nil {
+	if err := writeFileAtomic(p.config.DatasetFile, data, 0644); err != nil {
 		return err
 	}
 
-	// También guardar snapshot diario
-	dailyFile := filepath.Join(p.config.DatasetFolder,
-		fmt.Sprintf("snapshots_%s.json", time.Now().Format("20060102")))
+	// También guardar el archivo diario, particionado por día productivo para
+	// que un turno nocturno no quede repartido en dos archivos. Solo contiene
+	// los snapshots de ese día.
+	n := len(dataset.Snapshots)
+	if n == 0 {
+		return nil
+	}
+	day := p.SnapshotDay(dataset.Snapshots[n-1])
+	var daySnapshots []DataSnapshot
+	for i := n - 1; i >= 0 && p.SnapshotDay(dataset.Snapshots[i]) == day; i-- {
+		daySnapshots = append(daySnapshots, dataset.Snapshots[i])
+	}
+	for i, j := 0, len(daySnapshots)-1; i < j; i, j = i+1, j-1 {
+		daySnapshots[i], daySnapshots[j] = daySnapshots[j], daySnapshots[i]
+	}
+
+	return p.WriteDailyFile(day, daySnapshots)
+}
+
+// SnapshotDay retorna el día productivo de un snapshot, calculándolo para los
+// snapshots anteriores a los turnos
+func (p *Persistence) SnapshotDay(snapshot DataSnapshot) string {
+	if snapshot.ProductionDay != "" {
+		return snapshot.ProductionDay
+	}
+	return p.config.ShiftCalendar().ProductionDay(snapshot.DateTime)
+}
+
+// DailyFile retorna la ruta del archivo diario de un día productivo
+func (p *Persistence) DailyFile(day string) string {
+	return filepath.Join(p.config.DatasetFolder,
+		fmt.Sprintf("snapshots_%s.json", strings.ReplaceAll(day, "-", "")))
+}
 
-	return ioutil.WriteFile(dailyFile, data, 0644)
+// WriteDailyFile guarda los snapshots de un día productivo
+func (p *Persistence) WriteDailyFile(day string, snapshots []DataSnapshot) error {
+	daily := TrainingDataset{
+		TotalSnapshots: len(snapshots),
+		Snapshots:      snapshots,
+	}
+	if len(snapshots) > 0 {
+		daily.CollectionStart = snapshots[0].DateTime
+		daily.CollectionEnd = snapshots[len(snapshots)-1].DateTime
+	}
+
+	data, err := json.MarshalIndent(daily, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.DailyFile(day), data, 0644)
 }
 
 // LogChange registra un cambio en el log
@@ -125,5 +226,254 @@ func (p *Persistence) LogChange(change ChangeLog) error {
 		return err
 	}
 
-	return ioutil.WriteFile(p.config.ChangesLogFile, data, 0644)
+	return writeFileAtomic(p.config.ChangesLogFile, data, 0644)
+}
+
+// LoadChanges carga el log de cambios completo
+func (p *Persistence) LoadChanges() ([]ChangeLog, error) {
+	data, err := ioutil.ReadFile(p.config.ChangesLogFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ChangeLog{}, nil
+		}
+		return nil, err
+	}
+
+	var logs []ChangeLog
+	if err := json.Unmarshal(data, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// LogAdvice registra una sugerencia en el log de advice
+func (p *Persistence) LogAdvice(record AdviceRecord) error {
+	records, _ := p.LoadAdvice()
+	records = append(records, record)
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(p.config.AdviceLogFile, data, 0644)
+}
+
+// LoadAdvice carga el log de sugerencias
+func (p *Persistence) LoadAdvice() ([]AdviceRecord, error) {
+	data, err := ioutil.ReadFile(p.config.AdviceLogFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []AdviceRecord{}, nil
+		}
+		return nil, err
+	}
+
+	var records []AdviceRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// SaveReport guarda un reporte de turno en JSON y en texto
+func (p *Persistence) SaveReport(report ShiftReport) (string, error) {
+	if err := os.MkdirAll(p.config.ReportsFolder, 0755); err != nil {
+		return "", err
+	}
+
+	base := filepath.Join(p.config.ReportsFolder,
+		fmt.Sprintf("turno_%s_%s", strings.ReplaceAll(report.ProductionDay, "-", ""), sanitizeFileName(report.Shift)))
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(base+".json", data, 0644); err != nil {
+		return "", err
+	}
+
+	if err := writeFileAtomic(base+".txt", []byte(FormatShiftReport(report)), 0644); err != nil {
+		return "", err
+	}
+
+	return base + ".txt", nil
+}
+
+// sanitizeFileName reemplaza caracteres no aptos para nombres de archivo
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// AppendRollups agrega rollups al archivo JSONL de agregados
+func (p *Persistence) AppendRollups(records []RollupRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(p.config.RollupsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadRollups lee los rollups persistidos que cumplen el filtro. Una ventana
+// guardada a medias al detener el monitor se vuelve a guardar completa al
+// cerrar después de reiniciar: queda la última versión.
+func (p *Persistence) LoadRollups(query RollupQuery) ([]RollupRecord, error) {
+	file, err := os.Open(p.config.RollupsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []RollupRecord{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	records := []RollupRecord{}
+	index := make(map[rollupKey]int)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record RollupRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // Línea truncada por un corte abrupto
+		}
+		if !query.Matches(record) {
+			continue
+		}
+
+		key := record.recordKey()
+		if i, seen := index[key]; seen {
+			records[i] = record
+			continue
+		}
+		index[key] = len(records)
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// writeFileAtomic reemplaza un archivo escribiendo primero uno temporal en la
+// misma carpeta: quien lo lea al mismo tiempo ve la versión anterior o la
+// nueva, nunca una a medio escribir
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op después del rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// AppendAudit agrega una entrada a la auditoría
+func (p *Persistence) AppendAudit(entry AuditEntry) error {
+	return p.appendJSONLine(p.config.AuditFile, entry)
+}
+
+// LoadAudit lee las entradas de la auditoría que cumplen el filtro
+func (p *Persistence) LoadAudit(query AuditQuery) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	err := readJSONLines(p.config.AuditFile, func(line []byte) {
+		var entry AuditEntry
+		if json.Unmarshal(line, &entry) == nil && query.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	})
+	return entries, err
+}
+
+// AppendAnnotation guarda una anotación
+func (p *Persistence) AppendAnnotation(annotation Annotation) error {
+	return p.appendJSONLine(p.config.AnnotationsFile, annotation)
+}
+
+// LoadAnnotations lee las anotaciones del rango (extremos cero = sin límite)
+func (p *Persistence) LoadAnnotations(from, to time.Time) ([]Annotation, error) {
+	annotations := []Annotation{}
+	err := readJSONLines(p.config.AnnotationsFile, func(line []byte) {
+		var annotation Annotation
+		if json.Unmarshal(line, &annotation) != nil {
+			return
+		}
+		if (!from.IsZero() && annotation.DateTime.Before(from)) || (!to.IsZero() && !annotation.DateTime.Before(to)) {
+			return
+		}
+		annotations = append(annotations, annotation)
+	})
+	return annotations, err
+}
+
+// AppendAdviceAck registra el reconocimiento de una sugerencia
+func (p *Persistence) AppendAdviceAck(ack AdviceAck) error {
+	return p.appendJSONLine(p.config.AdviceAcksFile, ack)
+}
+
+// appendJSONLine agrega un registro a un archivo JSONL. Cada registro se
+// escribe de una vez, así que los pedidos concurrentes no se mezclan.
+func (p *Persistence) appendJSONLine(path string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := p.EnsureDataFolder(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readJSONLines recorre las líneas de un archivo JSONL (inexistente = vacío)
+func readJSONLines(path string, handle func(line []byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		handle(scanner.Bytes())
+	}
+	return scanner.Err()
 }
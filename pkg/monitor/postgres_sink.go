@@ -0,0 +1,474 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Tipos de registro que se envían a PostgreSQL
+const (
+	sinkPercentage  = "percentage"
+	sinkAssignment  = "assignment"
+	sinkChangeEvent = "change_event"
+	sinkLoadShift   = "load_shift"
+)
+
+// postgresTimeout limita cada operación contra PostgreSQL para no frenar el ciclo
+const postgresTimeout = 10 * time.Second
+
+// postgresFlushBudget es el tiempo tras el cual un envío deja de empezar lotes
+// nuevos; lo que queda sigue en el spool para el próximo envío
+const postgresFlushBudget = 20 * time.Second
+
+// postgresSchema crea (o actualiza) las tablas. Todas tienen clave (ts, sorter_id, sku, ...)
+// para que los reenvíos desde el spool sean idempotentes.
+var postgresSchema = []string{
+	`CREATE TABLE IF NOT EXISTS danich_chart_percentages (
+		ts             TIMESTAMPTZ NOT NULL,
+		sorter_id      INTEGER NOT NULL,
+		sku            TEXT NOT NULL,
+		percentage     DOUBLE PRECISION NOT NULL,
+		shift          TEXT NOT NULL DEFAULT '',
+		production_day TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (ts, sorter_id, sku)
+	)`,
+	`CREATE TABLE IF NOT EXISTS danich_assignments (
+		ts             TIMESTAMPTZ NOT NULL,
+		sorter_id      INTEGER NOT NULL,
+		sku            TEXT NOT NULL,
+		salidas        INTEGER[] NOT NULL,
+		shift          TEXT NOT NULL DEFAULT '',
+		production_day TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (ts, sorter_id, sku)
+	)`,
+	`CREATE TABLE IF NOT EXISTS danich_change_events (
+		ts             TIMESTAMPTZ NOT NULL,
+		sorter_id      INTEGER NOT NULL,
+		sku            TEXT NOT NULL,
+		type           TEXT NOT NULL,
+		salida         INTEGER NOT NULL DEFAULT 0, -- Línea agregada o quitada (0 en otros eventos)
+		from_sorter    INTEGER NOT NULL DEFAULT 0,
+		salidas        INTEGER[] NOT NULL,
+		prev_salidas   INTEGER[] NOT NULL,
+		shift          TEXT NOT NULL DEFAULT '',
+		production_day TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (ts, sorter_id, sku, type, salida)
+	)`,
+	// Tablas creadas antes de la columna salida: dos líneas agregadas al
+	// mismo SKU en un cambio chocaban en la clave
+	`ALTER TABLE danich_change_events ADD COLUMN IF NOT EXISTS salida INTEGER NOT NULL DEFAULT 0`,
+	`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.key_column_usage
+			WHERE table_name = 'danich_change_events' AND constraint_name = 'danich_change_events_pkey'
+				AND column_name = 'salida') THEN
+			ALTER TABLE danich_change_events DROP CONSTRAINT danich_change_events_pkey,
+				ADD PRIMARY KEY (ts, sorter_id, sku, type, salida);
+		END IF;
+	END $$`,
+	`CREATE TABLE IF NOT EXISTS danich_load_shifts (
+		ts             TIMESTAMPTZ NOT NULL,
+		sorter_id      INTEGER NOT NULL,
+		sku            TEXT NOT NULL,
+		from_pct       DOUBLE PRECISION NOT NULL,
+		to_pct         DOUBLE PRECISION NOT NULL,
+		delta          DOUBLE PRECISION NOT NULL,
+		shift          TEXT NOT NULL DEFAULT '',
+		production_day TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (ts, sorter_id, sku)
+	)`,
+}
+
+// postgresTables son las tablas que se convierten en hypertables si hay TimescaleDB
+var postgresTables = []string{
+	"danich_chart_percentages",
+	"danich_assignments",
+	"danich_change_events",
+	"danich_load_shifts",
+}
+
+// SinkRecord es una fila pendiente de enviar a PostgreSQL. Se serializa tal
+// cual en el spool mientras la base no está disponible.
+type SinkRecord struct {
+	Kind          string    `json:"kind"`
+	Timestamp     time.Time `json:"ts"`
+	SorterID      int       `json:"sorter_id"`
+	SKU           string    `json:"sku"`
+	Shift         string    `json:"shift,omitempty"`
+	ProductionDay string    `json:"production_day,omitempty"`
+
+	Percentage  float64 `json:"percentage,omitempty"`
+	Salidas     []int   `json:"salidas,omitempty"`
+	EventType   string  `json:"event_type,omitempty"`
+	Salida      int     `json:"salida,omitempty"` // line_added / line_removed
+	FromSorter  int     `json:"from_sorter,omitempty"`
+	PrevSalidas []int   `json:"prev_salidas,omitempty"`
+	From        float64 `json:"from,omitempty"`
+	To          float64 `json:"to,omitempty"`
+	Delta       float64 `json:"delta,omitempty"`
+}
+
+// PostgresSink envía snapshots y cambios a PostgreSQL/TimescaleDB en lotes.
+// Si la base no responde, los registros se guardan en un spool en disco y se
+// reenvían cuando vuelve la conexión.
+type PostgresSink struct {
+	dsn           string
+	batchSize     int
+	flushInterval time.Duration
+	spoolFile     string
+
+	conn      *pgx.Conn
+	pending   []SinkRecord
+	lastFlush time.Time
+	sendBatch func(ctx context.Context, records []SinkRecord) error // send, salvo en pruebas
+}
+
+// NewPostgresSink crea el sink; la conexión se abre en el primer envío
+func NewPostgresSink(config *SystemConfig) *PostgresSink {
+	ps := &PostgresSink{
+		dsn:           config.PostgresDSN,
+		batchSize:     config.PostgresBatchSize,
+		flushInterval: config.PostgresFlushInterval,
+		spoolFile:     config.PostgresSpoolFile,
+		lastFlush:     time.Now(),
+	}
+	ps.sendBatch = ps.send
+	return ps
+}
+
+// AddSnapshot encola los porcentajes y assignments del snapshot
+func (ps *PostgresSink) AddSnapshot(snapshot DataSnapshot) {
+	for sorterID, chartData := range snapshot.ChartData {
+		if chartData == nil {
+			continue
+		}
+		for sku, percentage := range chartData.Percentages {
+			ps.pending = append(ps.pending, SinkRecord{
+				Kind:          sinkPercentage,
+				Timestamp:     snapshot.DateTime,
+				SorterID:      sorterID,
+				SKU:           strings.ToUpper(sku),
+				Shift:         snapshot.Shift,
+				ProductionDay: snapshot.ProductionDay,
+				Percentage:    percentage,
+			})
+		}
+	}
+
+	// Un registro por SKU y sorter con sus salidas, para respetar la clave
+	type key struct {
+		sorterID int
+		sku      string
+	}
+	salidas := make(map[key][]int)
+	for _, a := range snapshot.Assignments {
+		k := key{a.SorterID, strings.ToUpper(a.SKU)}
+		salidas[k] = append(salidas[k], a.Salida)
+	}
+	for k, lines := range salidas {
+		sort.Ints(lines)
+		ps.pending = append(ps.pending, SinkRecord{
+			Kind:          sinkAssignment,
+			Timestamp:     snapshot.DateTime,
+			SorterID:      k.sorterID,
+			SKU:           k.sku,
+			Shift:         snapshot.Shift,
+			ProductionDay: snapshot.ProductionDay,
+			Salidas:       lines,
+		})
+	}
+}
+
+// AddChange encola los eventos y desplazamientos de carga de un cambio
+func (ps *PostgresSink) AddChange(change ChangeLog) {
+	ts := changeTime(change)
+
+	for _, e := range change.Events {
+		ps.pending = append(ps.pending, SinkRecord{
+			Kind:          sinkChangeEvent,
+			Timestamp:     ts,
+			SorterID:      e.SorterID,
+			SKU:           strings.ToUpper(e.SKU),
+			Shift:         change.Shift,
+			ProductionDay: change.ProductionDay,
+			EventType:     e.Type,
+			Salida:        e.Salida,
+			FromSorter:    e.FromSorter,
+			Salidas:       e.Salidas,
+			PrevSalidas:   e.PrevSalidas,
+		})
+	}
+
+	for _, s := range change.LoadShifts {
+		ps.pending = append(ps.pending, SinkRecord{
+			Kind:          sinkLoadShift,
+			Timestamp:     ts,
+			SorterID:      s.SorterID,
+			SKU:           strings.ToUpper(s.SKU),
+			Shift:         change.Shift,
+			ProductionDay: change.ProductionDay,
+			From:          s.From,
+			To:            s.To,
+			Delta:         s.Delta,
+		})
+	}
+}
+
+// FlushIfDue envía los registros pendientes si se juntó un lote completo o
+// pasó el intervalo de envío
+func (ps *PostgresSink) FlushIfDue() error {
+	if len(ps.pending) < ps.batchSize && time.Since(ps.lastFlush) < ps.flushInterval {
+		return nil
+	}
+	return ps.Flush()
+}
+
+// Flush envía el spool y los registros pendientes en lotes de batchSize, cada
+// uno en su transacción. El spool se recorta a medida que confirma cada lote;
+// si un lote falla (o se agota postgresFlushBudget) lo que falta queda en el
+// spool para el próximo intento.
+func (ps *PostgresSink) Flush() error {
+	ps.lastFlush = time.Now()
+
+	spooled, err := ps.readSpool()
+	if err != nil {
+		return err
+	}
+	records := append(spooled, ps.pending...)
+	ps.pending = nil
+	if len(records) == 0 {
+		return nil
+	}
+
+	var sendErr error
+	sent := 0
+	for sent < len(records) && time.Since(ps.lastFlush) < postgresFlushBudget {
+		end := sent + ps.batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+		sendErr = ps.sendBatch(ctx, records[sent:end])
+		cancel()
+		if sendErr != nil {
+			break
+		}
+
+		sent = end
+		if len(spooled) > 0 {
+			if err := ps.writeSpool(records[sent:]); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Sin spool previo (o sin ningún lote confirmado) basta con agregar al
+	// spool lo pendiente que no se envió
+	if len(spooled) == 0 || sent == 0 {
+		from := sent
+		if from < len(spooled) {
+			from = len(spooled)
+		}
+		if err := ps.appendSpool(records[from:]); err != nil {
+			if sendErr != nil {
+				return fmt.Errorf("%v (y error escribiendo spool: %w)", sendErr, err)
+			}
+			return err
+		}
+	}
+
+	if sendErr != nil {
+		return fmt.Errorf("PostgreSQL no disponible, %d registros en spool: %w", len(records)-sent, sendErr)
+	}
+	return nil
+}
+
+// Close envía lo pendiente y cierra la conexión
+func (ps *PostgresSink) Close() error {
+	err := ps.Flush()
+	if ps.conn != nil {
+		ps.conn.Close(context.Background())
+		ps.conn = nil
+	}
+	return err
+}
+
+// send escribe un lote de registros dentro de una transacción
+func (ps *PostgresSink) send(ctx context.Context, records []SinkRecord) error {
+	conn, err := ps.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		ps.dropConnection()
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, r := range records {
+		queueUpsert(batch, r)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		ps.dropConnection()
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		ps.dropConnection()
+		return err
+	}
+	return nil
+}
+
+// queueUpsert agrega el upsert de un registro al lote
+func queueUpsert(batch *pgx.Batch, r SinkRecord) {
+	switch r.Kind {
+	case sinkPercentage:
+		batch.Queue(`INSERT INTO danich_chart_percentages (ts, sorter_id, sku, percentage, shift, production_day)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (ts, sorter_id, sku) DO UPDATE SET percentage = EXCLUDED.percentage,
+				shift = EXCLUDED.shift, production_day = EXCLUDED.production_day`,
+			r.Timestamp, r.SorterID, r.SKU, r.Percentage, r.Shift, r.ProductionDay)
+	case sinkAssignment:
+		batch.Queue(`INSERT INTO danich_assignments (ts, sorter_id, sku, salidas, shift, production_day)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (ts, sorter_id, sku) DO UPDATE SET salidas = EXCLUDED.salidas,
+				shift = EXCLUDED.shift, production_day = EXCLUDED.production_day`,
+			r.Timestamp, r.SorterID, r.SKU, nonNilInts(r.Salidas), r.Shift, r.ProductionDay)
+	case sinkChangeEvent:
+		batch.Queue(`INSERT INTO danich_change_events (ts, sorter_id, sku, type, salida, from_sorter, salidas, prev_salidas, shift, production_day)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (ts, sorter_id, sku, type, salida) DO UPDATE SET from_sorter = EXCLUDED.from_sorter,
+				salidas = EXCLUDED.salidas, prev_salidas = EXCLUDED.prev_salidas`,
+			r.Timestamp, r.SorterID, r.SKU, r.EventType, r.Salida, r.FromSorter, nonNilInts(r.Salidas), nonNilInts(r.PrevSalidas),
+			r.Shift, r.ProductionDay)
+	case sinkLoadShift:
+		batch.Queue(`INSERT INTO danich_load_shifts (ts, sorter_id, sku, from_pct, to_pct, delta, shift, production_day)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (ts, sorter_id, sku) DO UPDATE SET from_pct = EXCLUDED.from_pct,
+				to_pct = EXCLUDED.to_pct, delta = EXCLUDED.delta`,
+			r.Timestamp, r.SorterID, r.SKU, r.From, r.To, r.Delta, r.Shift, r.ProductionDay)
+	}
+}
+
+// connect abre la conexión si hace falta y crea el esquema
+func (ps *PostgresSink) connect(ctx context.Context) (*pgx.Conn, error) {
+	if ps.conn != nil && !ps.conn.IsClosed() {
+		return ps.conn, nil
+	}
+
+	conn, err := pgx.Connect(ctx, ps.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range postgresSchema {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			conn.Close(ctx)
+			return nil, fmt.Errorf("error creando esquema: %w", err)
+		}
+	}
+
+	var timescale bool
+	if err := conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')`).Scan(&timescale); err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+	if timescale {
+		for _, table := range postgresTables {
+			if _, err := conn.Exec(ctx, `SELECT create_hypertable($1::regclass, 'ts', if_not_exists => TRUE, migrate_data => TRUE)`, table); err != nil {
+				conn.Close(ctx)
+				return nil, fmt.Errorf("error creando hypertable %s: %w", table, err)
+			}
+		}
+	}
+
+	ps.conn = conn
+	return conn, nil
+}
+
+// dropConnection descarta la conexión para reconectar en el próximo envío
+func (ps *PostgresSink) dropConnection() {
+	if ps.conn != nil {
+		ps.conn.Close(context.Background())
+		ps.conn = nil
+	}
+}
+
+// readSpool lee los registros guardados durante una caída
+func (ps *PostgresSink) readSpool() ([]SinkRecord, error) {
+	file, err := os.Open(ps.spoolFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []SinkRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record SinkRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // Línea truncada por un corte de energía
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// writeSpool reemplaza el spool por los registros que faltan enviar
+func (ps *PostgresSink) writeSpool(records []SinkRecord) error {
+	if len(records) == 0 {
+		if err := os.Remove(ps.spoolFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(ps.spoolFile, buf.Bytes(), 0644)
+}
+
+// appendSpool agrega registros al spool en disco
+func (ps *PostgresSink) appendSpool(records []SinkRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(ps.spoolFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeSender registra los lotes enviados y falla en el envío número failAt
+type fakeSender struct {
+	batches [][]string
+	failAt  int // 0 = nunca
+}
+
+func (f *fakeSender) send(ctx context.Context, records []SinkRecord) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("envío sin timeout")
+	}
+	if f.failAt > 0 && len(f.batches)+1 == f.failAt {
+		f.failAt = 0
+		return errors.New("conexión rechazada")
+	}
+	var skus []string
+	for _, record := range records {
+		skus = append(skus, record.SKU)
+	}
+	f.batches = append(f.batches, skus)
+	return nil
+}
+
+func sinkRecords(skus ...string) []SinkRecord {
+	records := make([]SinkRecord, len(skus))
+	for i, sku := range skus {
+		records[i] = SinkRecord{Kind: sinkPercentage, Timestamp: time.Unix(int64(i), 0), SorterID: 1, SKU: sku}
+	}
+	return records
+}
+
+func spoolSKUs(t *testing.T, ps *PostgresSink) []string {
+	t.Helper()
+	records, err := ps.readSpool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var skus []string
+	for _, record := range records {
+		skus = append(skus, record.SKU)
+	}
+	return skus
+}
+
+func TestPostgresSinkFlushChunks(t *testing.T) {
+	cfg := testConfig(t)
+	if err := os.MkdirAll(cfg.DatasetFolder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg.PostgresBatchSize = 3
+	ps := NewPostgresSink(cfg)
+	sender := &fakeSender{failAt: 2}
+	ps.sendBatch = sender.send
+
+	// Falla el segundo lote: el primero quedó confirmado y el resto va al spool
+	ps.pending = sinkRecords("A", "B", "C", "D", "E", "F", "G")
+	err := ps.Flush()
+	if err == nil || !strings.Contains(err.Error(), "4 registros en spool") {
+		t.Fatalf("error = %v", err)
+	}
+	if len(ps.pending) != 0 {
+		t.Errorf("quedaron %d pendientes en memoria", len(ps.pending))
+	}
+	if got := spoolSKUs(t, ps); !reflect.DeepEqual(got, []string{"D", "E", "F", "G"}) {
+		t.Errorf("spool = %v", got)
+	}
+
+	// Con un lote pendiente y el spool a medio enviar, el spool se recorta por lote
+	ps.pending = sinkRecords("H", "I")
+	sender.failAt = 3
+	if err := ps.Flush(); err == nil {
+		t.Fatal("se esperaba el error del tercer lote")
+	}
+	if got := spoolSKUs(t, ps); !reflect.DeepEqual(got, []string{"G", "H", "I"}) {
+		t.Errorf("spool después de un lote = %v", got)
+	}
+
+	// Vuelve la base: se envía todo en orden y se borra el spool
+	if err := ps.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"A", "B", "C"}, {"D", "E", "F"}, {"G", "H", "I"}}
+	if !reflect.DeepEqual(sender.batches, want) {
+		t.Errorf("lotes = %v, se esperaba %v", sender.batches, want)
+	}
+	if _, err := os.Stat(cfg.PostgresSpoolFile); !os.IsNotExist(err) {
+		t.Errorf("el spool sigue en disco: %v", err)
+	}
+}
+
+func TestPostgresSinkLineEvents(t *testing.T) {
+	ps := NewPostgresSink(testConfig(t))
+
+	// Dos líneas agregadas al mismo SKU en un cambio
+	ps.AddChange(ChangeLog{
+		Timestamp: "2026-03-10 10:00:00",
+		Events: []ChangeEvent{
+			{Type: EventLineAdded, SKU: "4j", SorterID: 1, Salida: 4},
+			{Type: EventLineAdded, SKU: "4j", SorterID: 1, Salida: 6},
+		},
+	})
+
+	if len(ps.pending) != 2 {
+		t.Fatalf("%d registros pendientes, esperaba 2", len(ps.pending))
+	}
+	batch := &pgx.Batch{}
+	keys := make(map[string]bool)
+	for _, record := range ps.pending {
+		queueUpsert(batch, record)
+		keys[fmt.Sprintf("%v/%d/%s/%s/%d", record.Timestamp, record.SorterID, record.SKU, record.EventType, record.Salida)] = true
+	}
+	if len(keys) != 2 {
+		t.Errorf("los eventos comparten clave: %v", keys)
+	}
+
+	for i, query := range batch.QueuedQueries {
+		if !strings.Contains(query.SQL, "ON CONFLICT (ts, sorter_id, sku, type, salida)") {
+			t.Errorf("upsert %d sin salida en la clave: %s", i, query.SQL)
+		}
+		if got, want := query.Arguments[4], ps.pending[i].Salida; got != want {
+			t.Errorf("upsert %d: salida = %v, esperaba %d", i, got, want)
+		}
+	}
+
+	// La salida sobrevive al spool
+	if err := os.MkdirAll(filepath.Dir(ps.spoolFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.appendSpool(ps.pending); err != nil {
+		t.Fatal(err)
+	}
+	spooled, err := ps.readSpool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spooled) != 2 || spooled[0].Salida != 4 || spooled[1].Salida != 6 {
+		t.Errorf("spool = %+v", spooled)
+	}
+}
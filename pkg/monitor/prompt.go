@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+
+	"danich/pkg/advisor"
+)
+
+// promptHistoryLimit es la cantidad máxima de entradas del historial del prompt
+const promptHistoryLimit = 6
+
+// promptHistoryWindow es la antigüedad máxima de las entradas del historial
+const promptHistoryWindow = 2 * time.Hour
+
+// AdvisorState convierte un snapshot al estado que analiza el advisor, con
+// los cambios y sugerencias de las dos horas anteriores como historial
+func AdvisorState(snapshot DataSnapshot, changes []ChangeLog, advice []AdviceRecord) advisor.SystemState {
+	state := convertToAdvisorState(snapshot)
+	state.History = promptHistory(snapshot.DateTime, changes, advice)
+	return state
+}
+
+// promptHistory arma el historial del prompt: las últimas entradas anteriores
+// a now, de la más antigua a la más nueva
+func promptHistory(now time.Time, changes []ChangeLog, advice []AdviceRecord) []advisor.HistoryEntry {
+	type entry struct {
+		at time.Time
+		advisor.HistoryEntry
+	}
+	var entries []entry
+	include := func(at time.Time) bool {
+		return !at.IsZero() && !at.After(now) && now.Sub(at) <= promptHistoryWindow
+	}
+
+	for _, change := range changes {
+		if at := changeTime(change); include(at) && change.Description != "" {
+			entries = append(entries, entry{at, advisor.HistoryEntry{
+				Time: at.Format("15:04"), Kind: advisor.HistoryChange, Description: change.Description,
+			}})
+		}
+	}
+	for _, record := range advice {
+		// La sugerencia del mismo snapshot es la que se está generando
+		if include(record.DateTime) && record.DateTime.Before(now) {
+			entries = append(entries, entry{record.DateTime, advisor.HistoryEntry{
+				Time: record.DateTime.Format("15:04"), Kind: advisor.HistoryAdvice, Description: record.Advice.Razon,
+			}})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].at.Before(entries[j].at) })
+	if len(entries) > promptHistoryLimit {
+		entries = entries[len(entries)-promptHistoryLimit:]
+	}
+
+	history := make([]advisor.HistoryEntry, len(entries))
+	for i, e := range entries {
+		history[i] = e.HistoryEntry
+	}
+	return history
+}
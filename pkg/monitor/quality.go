@@ -0,0 +1,187 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Chequeos de calidad de datos de un snapshot
+const (
+	QualitySumMismatch      = "suma_porcentajes"      // Los porcentajes de un sorter no suman ~100
+	QualityUnmappedSKU      = "sku_sin_salida"        // SKU con porcentaje en el gráfico pero sin salida asignada
+	QualityMissingSKU       = "sku_sin_grafico"       // SKU asignado que no aparece en el gráfico
+	QualityDuplicateSalida  = "salida_duplicada"      // Salida asignada más de una vez en el mismo sorter
+	QualitySalidaOutOfRange = "salida_fuera_de_rango" // Número de salida fuera de 1..packing.lineas
+)
+
+// QualityChecks lista todos los chequeos de calidad
+var QualityChecks = []string{
+	QualitySumMismatch,
+	QualityUnmappedSKU,
+	QualityMissingSKU,
+	QualityDuplicateSalida,
+	QualitySalidaOutOfRange,
+}
+
+// QualityOptions parametriza los chequeos de calidad
+type QualityOptions struct {
+	SumTolerance float64 // Puntos porcentuales aceptados alrededor de 100
+	Lineas       int     // Salidas por sorter (0 = sin límite superior)
+}
+
+// DefaultQualityOptions retorna las opciones por defecto de los chequeos
+func DefaultQualityOptions() QualityOptions {
+	return QualityOptions{SumTolerance: 2}
+}
+
+// QualityIssue es un problema de calidad detectado en un snapshot
+type QualityIssue struct {
+	Check    string  `json:"check"`
+	SorterID int     `json:"sorter_id,omitempty"`
+	SKU      string  `json:"sku,omitempty"`
+	Salida   int     `json:"salida,omitempty"`
+	Value    float64 `json:"value,omitempty"`
+	Message  string  `json:"message"`
+}
+
+// Key identifica el problema entre ciclos (para deduplicar alertas)
+func (qi QualityIssue) Key() string {
+	subject := normalizeSKU(qi.SKU)
+	if subject == "" && qi.Salida != 0 {
+		subject = fmt.Sprintf("salida%d", qi.Salida)
+	}
+	return fmt.Sprintf("%s/%d/%s", qi.Check, qi.SorterID, subject)
+}
+
+// QualityReport es el resultado de la validación de un snapshot
+type QualityReport struct {
+	OK          bool            `json:"ok"`
+	SumBySorter map[int]float64 `json:"sum_by_sorter,omitempty"`
+	Issues      []QualityIssue  `json:"issues,omitempty"`
+}
+
+// CheckQuality reconcilia los gráficos con los assignments del snapshot. Los
+// SKUs se comparan sin distinguir mayúsculas.
+func CheckQuality(snapshot DataSnapshot, opts QualityOptions) QualityReport {
+	report := QualityReport{SumBySorter: make(map[int]float64)}
+
+	report.Issues = append(report.Issues, checkSalidas(snapshot.Assignments, opts.Lineas)...)
+
+	sorterIDs := make([]int, 0, len(snapshot.ChartData))
+	for sorterID, chartData := range snapshot.ChartData {
+		if chartData != nil {
+			sorterIDs = append(sorterIDs, sorterID)
+		}
+	}
+	sort.Ints(sorterIDs)
+
+	for _, sorterID := range sorterIDs {
+		chartData := snapshot.ChartData[sorterID]
+
+		// Suma de porcentajes
+		sum := 0.0
+		for _, percentage := range chartData.Percentages {
+			sum += percentage
+		}
+		report.SumBySorter[sorterID] = sum
+		if len(chartData.Percentages) > 0 && math.Abs(sum-100) > opts.SumTolerance {
+			report.Issues = append(report.Issues, QualityIssue{
+				Check:    QualitySumMismatch,
+				SorterID: sorterID,
+				Value:    sum,
+				Message:  fmt.Sprintf("Sorter %d: los porcentajes suman %.1f%%", sorterID, sum),
+			})
+		}
+
+		// SKUs del gráfico contra SKUs asignados
+		assigned := make(map[string]string) // Normalizado -> SKU original
+		for _, a := range snapshot.Assignments {
+			if a.SorterID == sorterID {
+				assigned[normalizeSKU(a.SKU)] = a.SKU
+			}
+		}
+		inChart := make(map[string]bool)
+		for _, sku := range orderedChartSKUs(chartData) {
+			percentage := chartData.Percentages[sku]
+			inChart[normalizeSKU(sku)] = true
+			if _, ok := assigned[normalizeSKU(sku)]; !ok && percentage > 0 {
+				report.Issues = append(report.Issues, QualityIssue{
+					Check:    QualityUnmappedSKU,
+					SorterID: sorterID,
+					SKU:      sku,
+					Value:    percentage,
+					Message:  fmt.Sprintf("Sorter %d: %s tiene %.1f%% pero sin salidas asignadas", sorterID, sku, percentage),
+				})
+			}
+		}
+
+		missing := make([]string, 0)
+		for normalized, sku := range assigned {
+			if !inChart[normalized] {
+				missing = append(missing, sku)
+			}
+		}
+		sort.Strings(missing)
+		for _, sku := range missing {
+			report.Issues = append(report.Issues, QualityIssue{
+				Check:    QualityMissingSKU,
+				SorterID: sorterID,
+				SKU:      sku,
+				Message:  fmt.Sprintf("Sorter %d: %s está asignado pero no aparece en el gráfico", sorterID, sku),
+			})
+		}
+	}
+
+	report.OK = len(report.Issues) == 0
+	return report
+}
+
+// checkSalidas detecta salidas duplicadas y fuera de rango en los assignments
+func checkSalidas(assignments []Assignment, lineas int) []QualityIssue {
+	type sorterSalida struct{ sorterID, salida int }
+
+	var issues []QualityIssue
+	skusBySalida := make(map[sorterSalida][]string)
+	var order []sorterSalida
+
+	for _, a := range assignments {
+		if a.Salida < 1 || (lineas > 0 && a.Salida > lineas) {
+			limit := ">= 1"
+			if lineas > 0 {
+				limit = fmt.Sprintf("1..%d", lineas)
+			}
+			issues = append(issues, QualityIssue{
+				Check:    QualitySalidaOutOfRange,
+				SorterID: a.SorterID,
+				SKU:      a.SKU,
+				Salida:   a.Salida,
+				Message:  fmt.Sprintf("Sorter %d: salida %d de %s fuera de rango (%s)", a.SorterID, a.Salida, a.SKU, limit),
+			})
+		}
+
+		key := sorterSalida{a.SorterID, a.Salida}
+		if _, seen := skusBySalida[key]; !seen {
+			order = append(order, key)
+		}
+		skusBySalida[key] = append(skusBySalida[key], a.SKU)
+	}
+
+	for _, key := range order {
+		skus := skusBySalida[key]
+		if len(skus) < 2 {
+			continue
+		}
+		issues = append(issues, QualityIssue{
+			Check:    QualityDuplicateSalida,
+			SorterID: key.sorterID,
+			Salida:   key.salida,
+			Value:    float64(len(skus)),
+			Message: fmt.Sprintf("Sorter %d: salida %d asignada %d veces (%s)",
+				key.sorterID, key.salida, len(skus), strings.Join(skus, ", ")),
+		})
+	}
+
+	return issues
+}
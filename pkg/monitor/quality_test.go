@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckQuality(t *testing.T) {
+	at := time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)
+	tests := []struct {
+		name        string
+		percentages map[string]float64
+		assignments []Assignment
+		lineas      int
+		want        []string // Key de cada problema, en orden
+	}{
+		{
+			name:        "snapshot consistente",
+			percentages: map[string]float64{"4J": 60, "3J": 39},
+			assignments: []Assignment{{Salida: 1, SKU: "4J", SorterID: 1}, {Salida: 2, SKU: "3J", SorterID: 1}},
+			lineas:      10,
+		},
+		{
+			name:        "porcentajes que no suman 100",
+			percentages: map[string]float64{"4J": 60, "3J": 30},
+			assignments: []Assignment{{Salida: 1, SKU: "4J", SorterID: 1}, {Salida: 2, SKU: "3J", SorterID: 1}},
+			want:        []string{"suma_porcentajes/1/"},
+		},
+		{
+			name:        "SKU del gráfico sin salida",
+			percentages: map[string]float64{"4J": 60, "3J": 40, "XL": 0},
+			assignments: []Assignment{{Salida: 1, SKU: "4J", SorterID: 1}},
+			want:        []string{"sku_sin_salida/1/3J"},
+		},
+		{
+			name:        "SKU asignado que no está en el gráfico",
+			percentages: map[string]float64{"4J": 100},
+			assignments: []Assignment{{Salida: 1, SKU: "4J", SorterID: 1}, {Salida: 2, SKU: "XL", SorterID: 1}, {Salida: 3, SKU: "SJ", SorterID: 2}},
+			want:        []string{"sku_sin_grafico/1/XL"},
+		},
+		{
+			name:        "salida asignada dos veces",
+			percentages: map[string]float64{"4J": 50, "3J": 50},
+			assignments: []Assignment{{Salida: 4, SKU: "4J", SorterID: 1}, {Salida: 4, SKU: "3J", SorterID: 1}, {Salida: 4, SKU: "3J", SorterID: 2}},
+			want:        []string{"salida_duplicada/1/salida4"},
+		},
+		{
+			name:        "salidas fuera de rango",
+			percentages: map[string]float64{"4J": 50, "3J": 50},
+			assignments: []Assignment{{Salida: 0, SKU: "4J", SorterID: 1}, {Salida: 12, SKU: "3J", SorterID: 1}, {Salida: 10, SKU: "3J", SorterID: 1}},
+			lineas:      10,
+			want:        []string{"salida_fuera_de_rango/1/4J", "salida_fuera_de_rango/1/3J"},
+		},
+		{
+			name:        "sin límite de líneas solo se rechaza la salida 0",
+			percentages: map[string]float64{"4J": 50, "3J": 50},
+			assignments: []Assignment{{Salida: 0, SKU: "4J", SorterID: 1}, {Salida: 40, SKU: "3J", SorterID: 1}},
+			want:        []string{"salida_fuera_de_rango/1/4J"},
+		},
+		{
+			name:        "mayúsculas y espacios no cuentan",
+			percentages: map[string]float64{"4j": 70, " sj": 30},
+			assignments: []Assignment{{Salida: 1, SKU: "4J", SorterID: 1}, {Salida: 2, SKU: "SJ ", SorterID: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := testSnapshot(at, 1, tt.percentages)
+			snapshot.Assignments = tt.assignments
+			opts := DefaultQualityOptions()
+			opts.Lineas = tt.lineas
+
+			report := CheckQuality(snapshot, opts)
+			var got []string
+			for _, issue := range report.Issues {
+				got = append(got, issue.Key())
+				if issue.Message == "" {
+					t.Errorf("%s sin mensaje", issue.Key())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problemas:\n got  %v\n want %v", got, tt.want)
+			}
+			if report.OK != (len(tt.want) == 0) {
+				t.Errorf("OK = %v con %d problemas", report.OK, len(got))
+			}
+		})
+	}
+}
+
+func TestCheckQualityValues(t *testing.T) {
+	snapshot := testSnapshot(time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local), 1, map[string]float64{"4J": 80, "XL": 25})
+	snapshot.Assignments = []Assignment{{Salida: 3, SKU: "4J", SorterID: 1}, {Salida: 3, SKU: "3J", SorterID: 1}}
+
+	report := CheckQuality(snapshot, DefaultQualityOptions())
+	if report.SumBySorter[1] != 105 {
+		t.Errorf("suma = %v, esperaba 105", report.SumBySorter[1])
+	}
+
+	want := map[string]float64{
+		"salida_duplicada/1/salida3": 2,
+		"suma_porcentajes/1/":        105,
+		"sku_sin_salida/1/XL":        25,
+		"sku_sin_grafico/1/3J":       0,
+	}
+	got := make(map[string]float64)
+	for _, issue := range report.Issues {
+		got[issue.Key()] = issue.Value
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("valores:\n got  %v\n want %v", got, want)
+	}
+}
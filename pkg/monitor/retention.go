@@ -0,0 +1,475 @@
+package monitor
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Formatos de compresión de los archivos históricos
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// RetentionResult resume una pasada de retención
+type RetentionResult struct {
+	ArchivedDays      []string
+	ArchivedSnapshots int
+	RollupRecords     int      // Agregados generados para ventanas que no los tenían
+	DeletedFiles      []string // Archivos eliminados por el presupuesto de disco
+	BytesBefore       int64
+	BytesAfter        int64
+	OverBudget        bool // El presupuesto sigue excedido sin más archivos que borrar
+}
+
+// RetentionManager aplica la política de retención: los snapshots crudos se
+// guardan N días, después quedan solo los agregados y un archivo comprimido
+// por día, que se borra (del más antiguo al más nuevo) si se excede el
+// presupuesto de disco. Los cambios y sugerencias nunca se eliminan.
+type RetentionManager struct {
+	config      *SystemConfig
+	persistence *Persistence
+	store       Store
+}
+
+// NewRetentionManager crea el administrador de retención
+func NewRetentionManager(config *SystemConfig, persistence *Persistence, store Store) *RetentionManager {
+	return &RetentionManager{
+		config:      config,
+		persistence: persistence,
+		store:       store,
+	}
+}
+
+// Enabled indica si hay alguna política configurada
+func (rm *RetentionManager) Enabled() bool {
+	return rm.config.RetentionDays > 0 || rm.config.DiskBudgetMB > 0
+}
+
+// Apply archiva los días productivos fuera del período de retención y hace
+// cumplir el presupuesto de disco
+func (rm *RetentionManager) Apply(now time.Time) (RetentionResult, error) {
+	var result RetentionResult
+	result.BytesBefore = folderSize(rm.config.DatasetFolder)
+
+	if rm.config.RetentionDays > 0 {
+		if err := rm.archiveOldDays(now, &result); err != nil {
+			return result, err
+		}
+	}
+
+	if rm.config.DiskBudgetMB > 0 {
+		if err := rm.enforceBudget(&result); err != nil {
+			return result, err
+		}
+	}
+
+	result.BytesAfter = folderSize(rm.config.DatasetFolder)
+	return result, nil
+}
+
+// archiveOldDays mueve a archivos comprimidos los días anteriores al período de retención
+func (rm *RetentionManager) archiveOldDays(now time.Time, result *RetentionResult) error {
+	firstKept := rm.config.ShiftCalendar().ProductionDay(now.AddDate(0, 0, -rm.config.RetentionDays))
+
+	// Los días productivos crecen con el tiempo, así que basta con leer hasta
+	// el inicio calendario del primer día retenido más un margen de un día
+	dayStart, err := time.ParseInLocation("2006-01-02", firstKept, time.Local)
+	if err != nil {
+		return err
+	}
+	candidates, err := rm.store.Snapshots(time.Time{}, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	byDay := make(map[string][]DataSnapshot)
+	var last time.Time
+	for _, snapshot := range candidates {
+		day := rm.persistence.SnapshotDay(snapshot)
+		if day >= firstKept {
+			continue
+		}
+		byDay[day] = append(byDay[day], snapshot)
+		if snapshot.DateTime.After(last) {
+			last = snapshot.DateTime
+		}
+	}
+	if len(byDay) == 0 {
+		return nil
+	}
+
+	existing, err := rm.rollupKeys()
+	if err != nil {
+		return err
+	}
+
+	days := make([]string, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	for _, day := range days {
+		snapshots := byDay[day]
+
+		// Solo las ventanas que el monitor no guardó en vivo
+		engine := NewRollupEngine(rm.config.RollupThreshold, rm.config.CheckInterval, rm.config.ShiftCalendar())
+		var generated []RollupRecord
+		for _, snapshot := range snapshots {
+			generated = append(generated, engine.Add(snapshot)...)
+		}
+		generated = append(generated, engine.Flush()...)
+
+		var records []RollupRecord
+		for _, record := range generated {
+			if key := record.recordKey(); !existing[key] {
+				existing[key] = true
+				records = append(records, record)
+			}
+		}
+		SortRollups(records)
+		if err := rm.persistence.AppendRollups(records); err != nil {
+			return fmt.Errorf("error generando agregados del %s: %w", day, err)
+		}
+		result.RollupRecords += len(records)
+
+		if err := rm.writeArchive(day, snapshots); err != nil {
+			return fmt.Errorf("error archivando %s: %w", day, err)
+		}
+		if err := os.Remove(rm.persistence.DailyFile(day)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		result.ArchivedDays = append(result.ArchivedDays, day)
+		result.ArchivedSnapshots += len(snapshots)
+	}
+
+	// Solo se eliminan los snapshots después de archivarlos
+	if _, err := rm.store.PruneSnapshots(last.Add(time.Millisecond)); err != nil {
+		return fmt.Errorf("error eliminando snapshots archivados: %w", err)
+	}
+
+	return nil
+}
+
+// rollupKeys retorna las ventanas que ya tienen agregados guardados
+func (rm *RetentionManager) rollupKeys() (map[rollupKey]bool, error) {
+	records, err := rm.persistence.LoadRollups(RollupQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[rollupKey]bool, len(records))
+	for _, record := range records {
+		keys[record.recordKey()] = true
+	}
+	return keys, nil
+}
+
+// ArchiveFile retorna la ruta del archivo comprimido de un día
+func (rm *RetentionManager) ArchiveFile(day string) string {
+	extension := ".gz"
+	if rm.config.ArchiveCompression == CompressionZstd {
+		extension = ".zst"
+	}
+	return filepath.Join(rm.config.ArchiveFolder,
+		fmt.Sprintf("snapshots_%s.json%s", strings.ReplaceAll(day, "-", ""), extension))
+}
+
+// writeArchive guarda los snapshots de un día comprimidos, combinándolos con
+// un archivo previo del mismo día si existe
+func (rm *RetentionManager) writeArchive(day string, snapshots []DataSnapshot) error {
+	if err := os.MkdirAll(rm.config.ArchiveFolder, 0755); err != nil {
+		return err
+	}
+
+	path := rm.ArchiveFile(day)
+	if previous, err := ReadArchive(path); err == nil {
+		snapshots = mergeSnapshots(previous, snapshots)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	var writer io.WriteCloser
+	if rm.config.ArchiveCompression == CompressionZstd {
+		writer, err = zstd.NewWriter(file)
+		if err != nil {
+			file.Close()
+			return err
+		}
+	} else {
+		writer = gzip.NewWriter(file)
+	}
+
+	if err := json.NewEncoder(writer).Encode(snapshots); err != nil {
+		writer.Close()
+		file.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// ReadArchive lee un archivo histórico comprimido con gzip o zstd
+func ReadArchive(path string) ([]DataSnapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader
+	switch filepath.Ext(path) {
+	case ".gz":
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	case ".zst":
+		decoder, err := zstd.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		reader = decoder
+	default:
+		reader = file
+	}
+
+	var snapshots []DataSnapshot
+	if err := json.NewDecoder(reader).Decode(&snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// enforceBudget borra los archivos históricos más antiguos hasta respetar el presupuesto
+func (rm *RetentionManager) enforceBudget(result *RetentionResult) error {
+	budget := rm.config.DiskBudgetMB * 1024 * 1024
+	size := folderSize(rm.config.DatasetFolder)
+	if size <= budget {
+		return nil
+	}
+
+	archives, err := filepath.Glob(filepath.Join(rm.config.ArchiveFolder, "snapshots_*.json.*"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(archives) // El nombre incluye la fecha: el más antiguo primero
+
+	for _, archive := range archives {
+		if size <= budget {
+			break
+		}
+		info, err := os.Stat(archive)
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(archive); err != nil {
+			return err
+		}
+		size -= info.Size()
+		result.DeletedFiles = append(result.DeletedFiles, archive)
+	}
+
+	result.OverBudget = size > budget
+	return nil
+}
+
+// CompactResult resume la reparación de una carpeta de datos
+type CompactResult struct {
+	Snapshots      int // Snapshots únicos encontrados
+	DailyFiles     int // Archivos diarios reescritos
+	ChangesBefore  int
+	ChangesAfter   int // Sin duplicados exactos
+	RetentionApply RetentionResult
+}
+
+// CompactFolder repara la carpeta de datos: junta los snapshots de dataset.json
+// y de los archivos diarios (que antes eran copias completas del dataset),
+// reescribe cada archivo diario solo con su día, quita los cambios duplicados
+// sin perder ninguno y aplica la retención configurada
+func CompactFolder(config *SystemConfig) (CompactResult, error) {
+	var result CompactResult
+	persistence := NewPersistence(config, nil)
+	bytesBefore := folderSize(config.DatasetFolder)
+
+	snapshots := persistence.LoadOrCreateDataset().Snapshots
+	dailyFiles, err := filepath.Glob(filepath.Join(config.DatasetFolder, "snapshots_*.json"))
+	if err != nil {
+		return result, err
+	}
+	for _, path := range dailyFiles {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return result, err
+		}
+		var daily TrainingDataset
+		if err := json.Unmarshal(data, &daily); err != nil {
+			return result, fmt.Errorf("error leyendo %s: %w", path, err)
+		}
+		snapshots = mergeSnapshots(snapshots, daily.Snapshots)
+	}
+	result.Snapshots = len(snapshots)
+
+	// Archivos diarios con solo los snapshots de su día
+	byDay := make(map[string][]DataSnapshot)
+	for _, snapshot := range snapshots {
+		day := persistence.SnapshotDay(snapshot)
+		byDay[day] = append(byDay[day], snapshot)
+	}
+	// Primero se escriben los reemplazos (cada uno de forma atómica) y solo
+	// después se borran los archivos que quedaron sin día: un corte a mitad
+	// de camino no pierde snapshots
+	written := make(map[string]bool, len(byDay))
+	for day, daySnapshots := range byDay {
+		if err := persistence.WriteDailyFile(day, daySnapshots); err != nil {
+			return result, err
+		}
+		written[persistence.DailyFile(day)] = true
+		result.DailyFiles++
+	}
+	for _, path := range dailyFiles {
+		if written[path] {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return result, err
+		}
+	}
+
+	// Cambios sin duplicados exactos, en orden cronológico
+	changes, err := persistence.LoadChanges()
+	if err != nil {
+		return result, err
+	}
+	result.ChangesBefore = len(changes)
+	changes = dedupChanges(changes)
+	result.ChangesAfter = len(changes)
+	data, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return result, err
+	}
+	if err := writeFileAtomic(config.ChangesLogFile, data, 0644); err != nil {
+		return result, err
+	}
+
+	// Dataset principal (o la base SQLite) con la unión de los snapshots
+	if config.StoreType == StoreSQLite {
+		store, err := OpenSQLiteStore(config.SQLitePath)
+		if err != nil {
+			return result, err
+		}
+		for _, snapshot := range snapshots {
+			if err := store.SaveSnapshot(snapshot); err != nil {
+				store.Close()
+				return result, err
+			}
+		}
+		for _, change := range changes {
+			if err := store.SaveChange(change); err != nil {
+				store.Close()
+				return result, err
+			}
+		}
+		store.Close()
+	} else if len(snapshots) > 0 {
+		dataset := TrainingDataset{
+			CollectionStart: snapshots[0].DateTime,
+			CollectionEnd:   snapshots[len(snapshots)-1].DateTime,
+			TotalSnapshots:  len(snapshots),
+			Snapshots:       snapshots,
+		}
+		if err := persistence.SaveDataset(dataset); err != nil {
+			return result, err
+		}
+	}
+
+	store, err := OpenStore(config, persistence)
+	if err != nil {
+		return result, err
+	}
+	defer store.Close()
+
+	result.RetentionApply, err = NewRetentionManager(config, persistence, store).Apply(time.Now())
+	result.RetentionApply.BytesBefore = bytesBefore
+	return result, err
+}
+
+// mergeSnapshots une dos listas de snapshots sin repetir instantes, en orden cronológico
+func mergeSnapshots(a, b []DataSnapshot) []DataSnapshot {
+	seen := make(map[int64]bool, len(a)+len(b))
+	merged := make([]DataSnapshot, 0, len(a)+len(b))
+
+	for _, list := range [][]DataSnapshot{a, b} {
+		for _, snapshot := range list {
+			key := snapshot.DateTime.UnixMilli()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, snapshot)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].DateTime.Before(merged[j].DateTime) })
+	return merged
+}
+
+// dedupChanges quita los cambios repetidos exactamente y los ordena por tiempo
+func dedupChanges(changes []ChangeLog) []ChangeLog {
+	seen := make(map[string]bool, len(changes))
+	result := make([]ChangeLog, 0, len(changes))
+
+	for _, change := range changes {
+		data, err := json.Marshal(change)
+		if err == nil {
+			if seen[string(data)] {
+				continue
+			}
+			seen[string(data)] = true
+		}
+		result = append(result, change)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return changeTime(result[i]).Before(changeTime(result[j])) })
+	return result
+}
+
+// folderSize suma el tamaño de los archivos de una carpeta y sus subcarpetas
+func folderSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,196 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// retentionFixture guarda 20 minutos de snapshots del 2026-03-10 y uno del
+// 2026-03-20 en un store JSON con 2 días de retención
+func retentionFixture(t *testing.T) (*SystemConfig, *Persistence, *JSONStore, []DataSnapshot) {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.RetentionDays = 2
+	persistence := NewPersistence(cfg, discardLogger())
+	if err := persistence.EnsureDataFolder(); err != nil {
+		t.Fatal(err)
+	}
+	store := NewJSONStore(persistence)
+
+	var old []DataSnapshot
+	start := time.Date(2026, 3, 10, 10, 0, 0, 0, time.Local)
+	for at := start; at.Before(start.Add(20 * time.Minute)); at = at.Add(30 * time.Second) {
+		old = append(old, testSnapshot(at, 1, map[string]float64{"4J": 60, "3J": 40}))
+	}
+	recent := testSnapshot(time.Date(2026, 3, 20, 9, 0, 0, 0, time.Local), 1, map[string]float64{"4J": 100})
+	for _, snapshot := range append(old, recent) {
+		if err := store.SaveSnapshot(snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg, persistence, store, old
+}
+
+// rollupLines cuenta las líneas del archivo de rollups (con repetidos)
+func rollupLines(t *testing.T, cfg *SystemConfig) int {
+	t.Helper()
+	data, err := os.ReadFile(cfg.RollupsFile)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestRetentionArchivesOldDays(t *testing.T) {
+	// Rollups que el monitor ya guardó en vivo antes de archivar el día
+	tests := []struct {
+		name string
+		live func(records []RollupRecord) []RollupRecord
+	}{
+		{"sin agregados previos", func([]RollupRecord) []RollupRecord { return nil }},
+		{"día ya agregado en vivo", func(records []RollupRecord) []RollupRecord { return records }},
+		{"agregado en vivo a medias", func(records []RollupRecord) []RollupRecord { return records[:len(records)/2] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, persistence, store, old := retentionFixture(t)
+
+			engine := NewRollupEngine(cfg.RollupThreshold, cfg.CheckInterval, cfg.ShiftCalendar())
+			var all []RollupRecord
+			for _, snapshot := range old {
+				all = append(all, engine.Add(snapshot)...)
+			}
+			all = append(all, engine.Flush()...)
+			SortRollups(all)
+			live := tt.live(all)
+			if err := persistence.AppendRollups(live); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := NewRetentionManager(cfg, persistence, store).Apply(time.Date(2026, 3, 20, 12, 0, 0, 0, time.Local))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.ArchivedDays) != 1 || result.ArchivedDays[0] != "2026-03-10" || result.ArchivedSnapshots != len(old) {
+				t.Errorf("archivados = %v (%d snapshots), esperaba 2026-03-10 (%d)",
+					result.ArchivedDays, result.ArchivedSnapshots, len(old))
+			}
+			if want := len(all) - len(live); result.RollupRecords != want {
+				t.Errorf("agregados generados = %d, esperaba %d", result.RollupRecords, want)
+			}
+			if got := rollupLines(t, cfg); got != len(all) {
+				t.Errorf("%d líneas en rollups.jsonl, esperaba %d sin repetidos", got, len(all))
+			}
+
+			archived, err := ReadArchive(NewRetentionManager(cfg, persistence, store).ArchiveFile("2026-03-10"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(archived) != len(old) {
+				t.Errorf("%d snapshots en el archivo, esperaba %d", len(archived), len(old))
+			}
+			if _, err := os.Stat(persistence.DailyFile("2026-03-10")); !os.IsNotExist(err) {
+				t.Errorf("el archivo diario sigue en disco: %v", err)
+			}
+
+			remaining, err := store.Snapshots(time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(remaining) != 1 {
+				t.Errorf("quedaron %d snapshots, esperaba solo el reciente", len(remaining))
+			}
+		})
+	}
+}
+
+func TestCompactFolderRepairsDailyFiles(t *testing.T) {
+	cfg := testConfig(t)
+	persistence := NewPersistence(cfg, discardLogger())
+	if err := persistence.EnsureDataFolder(); err != nil {
+		t.Fatal(err)
+	}
+
+	day1 := []DataSnapshot{
+		testSnapshot(time.Date(2026, 3, 10, 10, 0, 0, 0, time.Local), 1, map[string]float64{"4J": 100}),
+		testSnapshot(time.Date(2026, 3, 10, 11, 0, 0, 0, time.Local), 1, map[string]float64{"4J": 100}),
+	}
+	day2 := []DataSnapshot{
+		testSnapshot(time.Date(2026, 3, 11, 10, 0, 0, 0, time.Local), 1, map[string]float64{"3J": 100}),
+	}
+
+	// Formato anterior: cada archivo diario era una copia del dataset completo,
+	// y uno quedó con un nombre que no corresponde a ningún día con datos
+	writeJSON := func(path string, value interface{}) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeJSON(cfg.DatasetFile, TrainingDataset{Snapshots: day1[:1]})
+	writeJSON(persistence.DailyFile("2026-03-10"), TrainingDataset{Snapshots: append(append([]DataSnapshot{}, day1...), day2...)})
+	stale := persistence.DailyFile("2026-03-09")
+	writeJSON(stale, TrainingDataset{Snapshots: day2})
+
+	change := ChangeLog{Timestamp: "2026-03-10 10:30:00", ChangeType: "assignments", Description: "4J +salida 3"}
+	other := ChangeLog{Timestamp: "2026-03-10 09:00:00", ChangeType: "assignments", Description: "3J -salida 1"}
+	writeJSON(cfg.ChangesLogFile, []ChangeLog{change, other, change})
+
+	result, err := CompactFolder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Snapshots != 3 || result.DailyFiles != 2 || result.ChangesBefore != 3 || result.ChangesAfter != 2 {
+		t.Errorf("resultado = %+v", result)
+	}
+
+	readDaily := func(day string) []DataSnapshot {
+		data, err := os.ReadFile(persistence.DailyFile(day))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var daily TrainingDataset
+		if err := json.Unmarshal(data, &daily); err != nil {
+			t.Fatal(err)
+		}
+		return daily.Snapshots
+	}
+	if got := readDaily("2026-03-10"); len(got) != 2 {
+		t.Errorf("%d snapshots en el archivo del 10, esperaba 2", len(got))
+	}
+	if got := readDaily("2026-03-11"); len(got) != 1 {
+		t.Errorf("%d snapshots en el archivo del 11, esperaba 1", len(got))
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("el archivo diario sin datos sigue en disco: %v", err)
+	}
+
+	changes, err := persistence.LoadChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Description != other.Description {
+		t.Errorf("cambios = %+v, esperaba 2 en orden cronológico", changes)
+	}
+	if dataset := persistence.LoadOrCreateDataset(); dataset.TotalSnapshots != 3 {
+		t.Errorf("dataset con %d snapshots, esperaba 3", dataset.TotalSnapshots)
+	}
+
+	// Sin temporales a medio escribir
+	leftovers, _ := filepath.Glob(filepath.Join(cfg.DatasetFolder, ".*.tmp*"))
+	if len(leftovers) > 0 {
+		t.Errorf("quedaron temporales: %v", leftovers)
+	}
+}
@@ -0,0 +1,346 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ventanas de agregación soportadas
+const (
+	Window5Min  = "5m"
+	WindowHour  = "1h"
+	WindowShift = "turno"
+)
+
+// Tipos de clave agregada
+const (
+	RollupKindSKU     = "sku"
+	RollupKindCalibre = "calibre"
+)
+
+// RollupRecord resume los porcentajes de un SKU o calibre en un sorter
+// durante una ventana de tiempo
+type RollupRecord struct {
+	Window        string    `json:"window"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Shift         string    `json:"shift,omitempty"`          // Solo ventana "turno"
+	ProductionDay string    `json:"production_day,omitempty"` // Solo ventana "turno"
+	SorterID      int       `json:"sorter_id"`
+	Kind          string    `json:"kind"`
+	Key           string    `json:"key"`
+	Samples       int       `json:"samples"`
+	Mean          float64   `json:"mean"`
+	Min           float64   `json:"min"`
+	Max           float64   `json:"max"`
+	P50           float64   `json:"p50"`
+	P90           float64   `json:"p90"`
+	P95           float64   `json:"p95"`
+	Threshold     float64   `json:"threshold"`
+	SecondsAbove  float64   `json:"seconds_above"` // Segundos sobre el umbral
+}
+
+// rollupKey identifica un bucket abierto
+type rollupKey struct {
+	window   string
+	start    time.Time
+	sorterID int
+	kind     string
+	key      string
+}
+
+// recordKey identifica la ventana de un rollup guardado (el inicio en UTC,
+// para no depender de la zona con que se leyó)
+func (r RollupRecord) recordKey() rollupKey {
+	return rollupKey{window: r.Window, start: r.Start.UTC(), sorterID: r.SorterID, kind: r.Kind, key: r.Key}
+}
+
+// rollupBucket acumula las muestras de una ventana
+type rollupBucket struct {
+	end          time.Time
+	shift        string
+	day          string
+	values       []float64
+	secondsAbove float64
+}
+
+// RollupEngine agrega los porcentajes de los snapshots en ventanas de 5 minutos,
+// horas y turnos
+type RollupEngine struct {
+	threshold   float64
+	nominalStep time.Duration
+	shifts      *ShiftCalendar
+
+	open     map[rollupKey]*rollupBucket
+	lastSeen map[int]time.Time // sorter -> última muestra
+}
+
+// NewRollupEngine crea un nuevo motor de agregación. nominalStep es el
+// intervalo esperado entre muestras y limita el tiempo atribuido a cada una.
+func NewRollupEngine(threshold float64, nominalStep time.Duration, shifts *ShiftCalendar) *RollupEngine {
+	return &RollupEngine{
+		threshold:   threshold,
+		nominalStep: nominalStep,
+		shifts:      shifts,
+		open:        make(map[rollupKey]*rollupBucket),
+		lastSeen:    make(map[int]time.Time),
+	}
+}
+
+// Add incorpora un snapshot y retorna los rollups de las ventanas que cerraron
+func (re *RollupEngine) Add(snapshot DataSnapshot) []RollupRecord {
+	// Hora local: los snapshots leídos del histórico pueden venir en otra zona
+	// y las ventanas se identifican por su inicio
+	at := snapshot.DateTime.Local()
+	closed := re.closeBefore(at)
+
+	for sorterID, chartData := range snapshot.ChartData {
+		if chartData == nil {
+			continue
+		}
+
+		step := re.nominalStep
+		if last, ok := re.lastSeen[sorterID]; ok {
+			if elapsed := at.Sub(last); elapsed < 2*re.nominalStep {
+				step = elapsed
+			}
+		}
+		re.lastSeen[sorterID] = at
+
+		// Un SKU o calibre que desaparece del gráfico cuenta como 0% mientras
+		// tenga alguna ventana abierta en el sorter
+		samples := map[string]map[string]float64{RollupKindSKU: {}, RollupKindCalibre: {}}
+		for bk := range re.open {
+			if bk.sorterID == sorterID {
+				samples[bk.kind][bk.key] = 0
+			}
+		}
+		calibres := make(map[string]float64)
+		for sku, percentage := range chartData.Percentages {
+			samples[RollupKindSKU][strings.ToUpper(sku)] = percentage
+			calibres[ExtractCalibre(sku)] += percentage
+		}
+		for calibre, percentage := range calibres {
+			samples[RollupKindCalibre][calibre] = percentage
+		}
+
+		for kind, values := range samples {
+			for key, percentage := range values {
+				re.addSample(at, sorterID, kind, key, percentage, step)
+			}
+		}
+	}
+
+	return closed
+}
+
+// Flush cierra todas las ventanas abiertas (por ejemplo al detener el monitor)
+func (re *RollupEngine) Flush() []RollupRecord {
+	return re.closeBefore(time.Time{})
+}
+
+// OpenSince retorna el inicio de la ventana más antigua que contiene t: los
+// snapshots desde ese instante reconstruyen las ventanas abiertas
+func (re *RollupEngine) OpenSince(t time.Time) time.Time {
+	since := t
+	for _, window := range re.windowsFor(t) {
+		if window.Start.Before(since) {
+			since = window.Start
+		}
+	}
+	return since
+}
+
+// addSample agrega una muestra a todas las ventanas que la contienen
+func (re *RollupEngine) addSample(t time.Time, sorterID int, kind, key string, value float64, step time.Duration) {
+	for _, window := range re.windowsFor(t) {
+		bk := rollupKey{window: window.Name, start: window.Start, sorterID: sorterID, kind: kind, key: key}
+		bucket, exists := re.open[bk]
+		if !exists {
+			bucket = &rollupBucket{end: window.End, shift: window.Shift, day: window.ProductionDay}
+			re.open[bk] = bucket
+		}
+		bucket.values = append(bucket.values, value)
+		if value > re.threshold {
+			bucket.secondsAbove += step.Seconds()
+		}
+	}
+}
+
+// rollupWindow es una ventana concreta en la que cae una muestra
+type rollupWindow struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+	Shift string
+
+	ProductionDay string
+}
+
+// windowsFor retorna las ventanas que contienen el instante t
+func (re *RollupEngine) windowsFor(t time.Time) []rollupWindow {
+	hourStart := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	fiveStart := hourStart.Add(time.Duration(t.Minute()/5) * 5 * time.Minute)
+
+	windows := []rollupWindow{
+		{Name: Window5Min, Start: fiveStart, End: fiveStart.Add(5 * time.Minute)},
+		{Name: WindowHour, Start: hourStart, End: hourStart.Add(time.Hour)},
+	}
+
+	if shift, ok := re.shifts.Resolve(t); ok {
+		windows = append(windows, rollupWindow{
+			Name:          WindowShift,
+			Start:         shift.Start,
+			End:           shift.End,
+			Shift:         shift.Name,
+			ProductionDay: shift.ProductionDay,
+		})
+	}
+
+	return windows
+}
+
+// closeBefore cierra las ventanas que terminan antes de t (todas si t es cero)
+func (re *RollupEngine) closeBefore(t time.Time) []RollupRecord {
+	var records []RollupRecord
+
+	for bk, bucket := range re.open {
+		if !t.IsZero() && t.Before(bucket.end) {
+			continue
+		}
+		records = append(records, re.summarize(bk, bucket))
+		delete(re.open, bk)
+	}
+
+	SortRollups(records)
+	return records
+}
+
+// summarize calcula las estadísticas de un bucket
+func (re *RollupEngine) summarize(bk rollupKey, bucket *rollupBucket) RollupRecord {
+	values := append([]float64(nil), bucket.values...)
+	sort.Float64s(values)
+
+	return RollupRecord{
+		Window:        bk.window,
+		Start:         bk.start,
+		End:           bucket.end,
+		Shift:         bucket.shift,
+		ProductionDay: bucket.day,
+		SorterID:      bk.sorterID,
+		Kind:          bk.kind,
+		Key:           bk.key,
+		Samples:       len(values),
+		Mean:          round2(mean(values)),
+		Min:           values[0],
+		Max:           values[len(values)-1],
+		P50:           round2(percentile(values, 50)),
+		P90:           round2(percentile(values, 90)),
+		P95:           round2(percentile(values, 95)),
+		Threshold:     re.threshold,
+		SecondsAbove:  math.Round(bucket.secondsAbove),
+	}
+}
+
+// SortRollups ordena rollups por inicio, ventana, sorter y clave
+func SortRollups(records []RollupRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if a.Window != b.Window {
+			return a.Window < b.Window
+		}
+		if a.SorterID != b.SorterID {
+			return a.SorterID < b.SorterID
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Key < b.Key
+	})
+}
+
+// RollupQuery filtra rollups persistidos. Los campos vacíos no filtran.
+type RollupQuery struct {
+	Window   string
+	SorterID int
+	Kind     string
+	Key      string // Prefijo de SKU o calibre, sin distinguir mayúsculas
+	From     time.Time
+	To       time.Time
+}
+
+// Matches indica si un rollup cumple el filtro
+func (q RollupQuery) Matches(r RollupRecord) bool {
+	if q.Window != "" && r.Window != q.Window {
+		return false
+	}
+	if q.SorterID != 0 && r.SorterID != q.SorterID {
+		return false
+	}
+	if q.Kind != "" && r.Kind != q.Kind {
+		return false
+	}
+	if q.Key != "" && !strings.HasPrefix(strings.ToUpper(r.Key), strings.ToUpper(q.Key)) {
+		return false
+	}
+	if !q.From.IsZero() && r.End.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && r.Start.After(q.To) {
+		return false
+	}
+	return true
+}
+
+// ValidateWindow verifica que el nombre de ventana sea conocido
+func ValidateWindow(window string) error {
+	switch window {
+	case "", Window5Min, WindowHour, WindowShift:
+		return nil
+	default:
+		return fmt.Errorf("ventana desconocida %q (use %s, %s o %s)", window, Window5Min, WindowHour, WindowShift)
+	}
+}
+
+// percentile calcula el percentil p (0-100) de valores ordenados con interpolación lineal
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	weight := rank - float64(lower)
+	return sorted[lower]*(1-weight) + sorted[upper]*weight
+}
+
+// round2 redondea a dos decimales
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// ParseTimeArg interpreta una fecha de consulta en hora local
+// ("2006-01-02", "2006-01-02 15:04" o RFC3339)
+func ParseTimeArg(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("fecha inválida %q", value)
+}
@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		p      float64
+		want   float64
+	}{
+		{"vacío", nil, 50, 0},
+		{"un valor", []float64{7}, 95, 7},
+		{"mediana impar", []float64{1, 2, 3}, 50, 2},
+		{"mediana par interpola", []float64{1, 2, 3, 4}, 50, 2.5},
+		{"p90 interpola", []float64{10, 20, 30, 40, 50}, 90, 46},
+		{"p95 de diez", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 95, 9.55},
+		{"mínimo", []float64{3, 5, 8}, 0, 3},
+		{"máximo", []float64{3, 5, 8}, 100, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := round2(percentile(tt.values, tt.p)); got != tt.want {
+				t.Errorf("percentile(%v, %v) = %v, se esperaba %v", tt.values, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+// rollupFor busca el rollup de una ventana y clave
+func rollupFor(records []RollupRecord, window, kind, key string) (RollupRecord, bool) {
+	for _, record := range records {
+		if record.Window == window && record.Kind == kind && record.Key == key {
+			return record, true
+		}
+	}
+	return RollupRecord{}, false
+}
+
+func TestRollupEngineStats(t *testing.T) {
+	engine := NewRollupEngine(40, time.Minute, nil)
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local)
+
+	// 3J-D desaparece del gráfico en el cuarto minuto: cuenta como 0%
+	charts := []map[string]float64{
+		{"3J-D": 30, "3J-E": 10},
+		{"3J-D": 50, "3J-E": 10},
+		{"3J-D": 45, "3J-E": 20},
+		{"3J-E": 25},
+	}
+	for i, percentages := range charts {
+		if closed := engine.Add(testSnapshot(start.Add(time.Duration(i)*time.Minute), 1, percentages)); len(closed) > 0 {
+			t.Fatalf("cerró ventanas antes de tiempo: %+v", closed)
+		}
+	}
+	closed := engine.Add(testSnapshot(start.Add(5*time.Minute), 1, map[string]float64{"3J-E": 30}))
+
+	sku, ok := rollupFor(closed, Window5Min, RollupKindSKU, "3J-D")
+	if !ok {
+		t.Fatalf("falta el rollup de 3J-D: %+v", closed)
+	}
+	if sku.Samples != 4 || sku.Min != 0 || sku.Max != 50 || sku.Mean != 31.25 || sku.P50 != 37.5 {
+		t.Errorf("3J-D: %+v", sku)
+	}
+	if sku.SecondsAbove != 120 {
+		t.Errorf("segundos sobre el umbral = %v, se esperaban 120", sku.SecondsAbove)
+	}
+
+	calibre, ok := rollupFor(closed, Window5Min, RollupKindCalibre, "Triple_Jumbo")
+	if !ok || calibre.Samples != 4 || calibre.Max != 65 || calibre.Min != 25 {
+		t.Errorf("calibre Triple_Jumbo: %+v (%v)", calibre, ok)
+	}
+
+	// En la hora abierta 3J-D sigue sumando ceros
+	hour := engine.Flush()
+	if record, ok := rollupFor(hour, WindowHour, RollupKindSKU, "3J-D"); !ok || record.Samples != 5 || record.Min != 0 {
+		t.Errorf("hora de 3J-D: %+v (%v)", record, ok)
+	}
+	// Un SKU que nunca tuvo ventana abierta no aparece
+	if _, ok := rollupFor(hour, WindowHour, RollupKindSKU, "3J-F"); ok {
+		t.Error("rollup de un SKU que nunca apareció")
+	}
+}
+
+func TestRollupEngineSeedAfterRestart(t *testing.T) {
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local)
+	var snapshots []DataSnapshot
+	for i := 0; i < 40; i++ {
+		snapshots = append(snapshots, testSnapshot(start.Add(time.Duration(i)*time.Minute), 1, map[string]float64{"3J-D": float64(i)}))
+	}
+
+	// Sin reinicio
+	continuous := NewRollupEngine(40, time.Minute, nil)
+	for _, snapshot := range snapshots {
+		continuous.Add(snapshot)
+	}
+	want, _ := rollupFor(continuous.Flush(), WindowHour, RollupKindSKU, "3J-D")
+
+	// Reinicio a los 30 minutos: el motor nuevo se reconstruye desde el
+	// histórico, con las horas en UTC como las devuelve SQLite
+	restarted := NewRollupEngine(40, time.Minute, nil)
+	now := start.Add(30 * time.Minute)
+	since := restarted.OpenSince(now)
+	if !since.Equal(start) {
+		t.Fatalf("OpenSince = %v, se esperaba %v", since, start)
+	}
+	for _, snapshot := range snapshots[:30] {
+		snapshot.DateTime = snapshot.DateTime.UTC()
+		restarted.Add(snapshot)
+	}
+	for _, snapshot := range snapshots[30:] {
+		restarted.Add(snapshot)
+	}
+	got, _ := rollupFor(restarted.Flush(), WindowHour, RollupKindSKU, "3J-D")
+
+	if got.Samples != want.Samples || got.Mean != want.Mean || got.P95 != want.P95 || !got.Start.Equal(want.Start) {
+		t.Errorf("después del reinicio = %+v, se esperaba %+v", got, want)
+	}
+}
+
+func TestLoadRollupsKeepsLastVersion(t *testing.T) {
+	cfg := testConfig(t)
+	persistence := NewPersistence(cfg, discardLogger())
+	if err := persistence.EnsureDataFolder(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local)
+	partial := RollupRecord{Window: WindowHour, Start: start, End: start.Add(time.Hour), SorterID: 1, Kind: RollupKindSKU, Key: "3J-D", Samples: 20}
+	other := partial
+	other.Key = "3J-E"
+	complete := partial
+	complete.Start = start.UTC()
+	complete.Samples = 120
+
+	for _, records := range [][]RollupRecord{{partial, other}, {complete}} {
+		if err := persistence.AppendRollups(records); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := persistence.LoadRollups(RollupQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Key != "3J-D" || records[0].Samples != 120 || records[1].Key != "3J-E" {
+		t.Errorf("rollups = %+v", records)
+	}
+}
@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Scheduler programa los ciclos del monitor en límites del reloj (por ejemplo
+// :00 y :30 con un intervalo de 30s), de modo que la duración de cada ciclo no
+// desplace a los siguientes. Si un ciclo excede el intervalo, los ticks que ya
+// pasaron se omiten en lugar de acumularse. Tras un cambio puede pasar un
+// tiempo en polling rápido antes de volver al intervalo base.
+type Scheduler struct {
+	base    time.Duration
+	fast    time.Duration // 0 = sin polling rápido
+	fastFor time.Duration
+	logger  *slog.Logger
+
+	fastUntil time.Time
+	started   bool
+	stats     SchedulerStats
+}
+
+// SchedulerStats resume el comportamiento del scheduler
+type SchedulerStats struct {
+	Overruns     int // Ciclos que duraron más que el intervalo
+	SkippedTicks int // Ticks omitidos por ciclos excedidos
+}
+
+// NewScheduler crea un scheduler con intervalo base y polling rápido opcional
+func NewScheduler(base, fast, fastFor time.Duration, logger *slog.Logger) *Scheduler {
+	if fast <= 0 || fast >= base {
+		fast = 0
+	}
+	return &Scheduler{
+		base:    base,
+		fast:    fast,
+		fastFor: fastFor,
+		logger:  loggerOrDefault(logger),
+	}
+}
+
+// Wait bloquea hasta el próximo tick y retorna su hora programada. El primer
+// tick es inmediato. Si ctx termina antes retorna su error.
+func (s *Scheduler) Wait(ctx context.Context) (time.Time, error) {
+	now := time.Now()
+	if !s.started {
+		s.started = true
+		return now, ctx.Err()
+	}
+
+	next := s.Next(now)
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return next, nil
+	case <-ctx.Done():
+		return time.Time{}, ctx.Err()
+	}
+}
+
+// Next retorna el próximo límite del reloj según el intervalo vigente
+func (s *Scheduler) Next(now time.Time) time.Time {
+	interval := s.Interval(now)
+	return now.Truncate(interval).Add(interval)
+}
+
+// Interval retorna el intervalo vigente (rápido o base)
+func (s *Scheduler) Interval(now time.Time) time.Duration {
+	if s.Fast(now) {
+		return s.fast
+	}
+	if !s.fastUntil.IsZero() {
+		s.fastUntil = time.Time{}
+		s.logger.Info("polling vuelve al intervalo base", "intervalo", s.base)
+	}
+	return s.base
+}
+
+// Fast indica si el polling rápido está activo
+func (s *Scheduler) Fast(now time.Time) bool {
+	return s.fast > 0 && now.Before(s.fastUntil)
+}
+
+// Boost activa (o extiende) el polling rápido desde now
+func (s *Scheduler) Boost(now time.Time) {
+	if s.fast == 0 {
+		return
+	}
+	if !s.Fast(now) {
+		s.logger.Info("polling rápido activado", "intervalo", s.fast, "duracion", s.fastFor)
+	}
+	s.fastUntil = now.Add(s.fastFor)
+}
+
+// Finish registra el fin de un ciclo que comenzó en tick y detecta si se
+// excedió el intervalo
+func (s *Scheduler) Finish(tick, end time.Time) {
+	interval := s.Interval(tick)
+	elapsed := end.Sub(tick)
+	if elapsed <= interval {
+		return
+	}
+
+	skipped := int(elapsed / interval)
+	s.stats.Overruns++
+	s.stats.SkippedTicks += skipped
+	s.logger.Warn("El ciclo excedió el intervalo",
+		"duracion", elapsed.Round(time.Millisecond),
+		"intervalo", interval,
+		"ticks_omitidos", skipped)
+}
+
+// Stats retorna los contadores de ciclos excedidos y ticks omitidos
+func (s *Scheduler) Stats() SchedulerStats {
+	return s.stats
+}
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSchedulerWaitCancel(t *testing.T) {
+	scheduler := NewScheduler(time.Hour, 0, 0, discardLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// El primer tick es inmediato
+	if _, err := scheduler.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	if _, err := scheduler.Wait(ctx); err != context.Canceled {
+		t.Fatalf("error = %v, se esperaba context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait tardó %v en notar la cancelación", elapsed)
+	}
+}
+
+func TestSchedulerNext(t *testing.T) {
+	base := time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		fast    time.Duration
+		boostAt time.Duration // Desde base; negativo = sin polling rápido
+		now     time.Duration
+		want    time.Duration
+	}{
+		{"próximo límite", 5 * time.Second, -1, 7 * time.Second, 30 * time.Second},
+		{"en el límite pasa al siguiente", 5 * time.Second, -1, 30 * time.Second, time.Minute},
+		{"ciclo largo salta ticks", 5 * time.Second, -1, 95 * time.Second, 2 * time.Minute},
+		{"polling rápido", 5 * time.Second, 0, 7 * time.Second, 10 * time.Second},
+		{"polling rápido vencido", 5 * time.Second, 0, 62 * time.Second, 90 * time.Second},
+		{"rápido mayor que la base se ignora", 45 * time.Second, 0, 7 * time.Second, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := NewScheduler(30*time.Second, tt.fast, time.Minute, discardLogger())
+			if tt.boostAt >= 0 {
+				scheduler.Boost(base.Add(tt.boostAt))
+			}
+			if got := scheduler.Next(base.Add(tt.now)); !got.Equal(base.Add(tt.want)) {
+				t.Errorf("Next = %s, esperaba %s", got.Format("15:04:05"), base.Add(tt.want).Format("15:04:05"))
+			}
+		})
+	}
+}
+
+func TestSchedulerFinishOverrun(t *testing.T) {
+	tick := time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		boost       bool
+		elapsed     time.Duration
+		wantOverrun int
+		wantSkipped int
+	}{
+		{"dentro del intervalo", false, 20 * time.Second, 0, 0},
+		{"justo el intervalo", false, 30 * time.Second, 0, 0},
+		{"excede por poco", false, 31 * time.Second, 1, 1},
+		{"excede varios ticks", false, 95 * time.Second, 1, 3},
+		{"excede el intervalo rápido", true, 12 * time.Second, 1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := NewScheduler(30*time.Second, 5*time.Second, time.Minute, discardLogger())
+			if tt.boost {
+				scheduler.Boost(tick)
+			}
+			scheduler.Finish(tick, tick.Add(tt.elapsed))
+			want := SchedulerStats{Overruns: tt.wantOverrun, SkippedTicks: tt.wantSkipped}
+			if got := scheduler.Stats(); got != want {
+				t.Errorf("Stats = %+v, esperaba %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"danich/pkg/advisor"
+)
+
+// SKUShare resume la participación de un SKU en un sorter durante un turno
+type SKUShare struct {
+	SKU  string  `json:"sku"`
+	Mean float64 `json:"mean"`
+	Max  float64 `json:"max"`
+}
+
+// ShiftReport resume lo ocurrido en un turno
+type ShiftReport struct {
+	Shift         string             `json:"shift"`
+	ProductionDay string             `json:"production_day"`
+	Start         time.Time          `json:"start"`
+	End           time.Time          `json:"end"`
+	GeneratedAt   time.Time          `json:"generated_at"`
+	Snapshots     int                `json:"snapshots"`
+	SKUMix        map[int][]SKUShare `json:"sku_mix"`        // Sorter -> SKUs por participación promedio
+	Reassignments int                `json:"reassignments"`  // Eventos de cambio de asignación
+	EventsByType  map[string]int     `json:"events_by_type"` // Tipo de evento -> cantidad
+	LoadShifts    int                `json:"load_shifts"`
+	// Segundos con desbalance entre sorters (sin contar pausas)
+	ImbalanceSeconds float64        `json:"imbalance_seconds"`
+	Advice           []AdviceRecord `json:"advice"`
+}
+
+// ShiftReporter genera los reportes de fin de turno
+type ShiftReporter struct {
+	config  *SystemConfig
+	store   Store
+	advisor *advisor.Advisor
+}
+
+// NewShiftReporter crea un nuevo generador de reportes de turno
+func NewShiftReporter(config *SystemConfig, store Store, nativeAdvisor *advisor.Advisor) *ShiftReporter {
+	return &ShiftReporter{
+		config:  config,
+		store:   store,
+		advisor: nativeAdvisor,
+	}
+}
+
+// Build arma el reporte de un turno a partir de los snapshots, cambios y advice registrados
+func (sr *ShiftReporter) Build(window ShiftWindow) (ShiftReport, error) {
+	report := ShiftReport{
+		Shift:         window.Name,
+		ProductionDay: window.ProductionDay,
+		Start:         window.Start,
+		End:           window.End,
+		GeneratedAt:   time.Now(),
+		SKUMix:        make(map[int][]SKUShare),
+		EventsByType:  make(map[string]int),
+		Advice:        []AdviceRecord{},
+	}
+
+	inShift := func(t time.Time) bool {
+		return !t.Before(window.Start) && t.Before(window.End)
+	}
+
+	snapshots, err := sr.store.Snapshots(window.Start, window.End)
+	if err != nil {
+		return report, fmt.Errorf("error cargando snapshots: %w", err)
+	}
+
+	var shiftSnapshots []DataSnapshot
+	for _, snapshot := range snapshots {
+		if inShift(snapshot.DateTime) {
+			shiftSnapshots = append(shiftSnapshots, snapshot)
+		}
+	}
+	report.Snapshots = len(shiftSnapshots)
+
+	sr.summarizeMix(&report, shiftSnapshots)
+	report.ImbalanceSeconds = sr.imbalanceSeconds(window, shiftSnapshots)
+
+	changes, err := sr.store.Changes(window.Start, window.End)
+	if err != nil {
+		return report, fmt.Errorf("error cargando cambios: %w", err)
+	}
+	for _, change := range changes {
+		if !inShift(changeTime(change)) {
+			continue
+		}
+		for _, event := range change.Events {
+			report.EventsByType[event.Type]++
+			report.Reassignments++
+		}
+		report.Reassignments += len(change.Added) + len(change.Removed) + len(change.Modified)
+		report.LoadShifts += len(change.LoadShifts)
+	}
+
+	adviceRecords, err := sr.store.Advice(window.Start, window.End)
+	if err != nil {
+		return report, fmt.Errorf("error cargando advice: %w", err)
+	}
+	for _, record := range adviceRecords {
+		if inShift(record.DateTime) {
+			report.Advice = append(report.Advice, record)
+		}
+	}
+
+	return report, nil
+}
+
+// summarizeMix calcula la participación promedio y máxima de cada SKU por sorter
+func (sr *ShiftReporter) summarizeMix(report *ShiftReport, snapshots []DataSnapshot) {
+	type accumulator struct {
+		sum, max float64
+	}
+	sums := make(map[int]map[string]*accumulator)
+	samples := make(map[int]int)
+
+	for _, snapshot := range snapshots {
+		for sorterID, chartData := range snapshot.ChartData {
+			if chartData == nil {
+				continue
+			}
+			samples[sorterID]++
+			if sums[sorterID] == nil {
+				sums[sorterID] = make(map[string]*accumulator)
+			}
+			for sku, percentage := range chartData.Percentages {
+				acc := sums[sorterID][sku]
+				if acc == nil {
+					acc = &accumulator{}
+					sums[sorterID][sku] = acc
+				}
+				acc.sum += percentage
+				if percentage > acc.max {
+					acc.max = percentage
+				}
+			}
+		}
+	}
+
+	for sorterID, skus := range sums {
+		shares := make([]SKUShare, 0, len(skus))
+		for sku, acc := range skus {
+			shares = append(shares, SKUShare{
+				SKU:  sku,
+				Mean: round2(acc.sum / float64(samples[sorterID])),
+				Max:  acc.max,
+			})
+		}
+		sort.Slice(shares, func(i, j int) bool {
+			return shares[i].Mean > shares[j].Mean
+		})
+		report.SKUMix[sorterID] = shares
+	}
+}
+
+// imbalanceSeconds suma el tiempo en que el advisor detecta desbalances
+func (sr *ShiftReporter) imbalanceSeconds(window ShiftWindow, snapshots []DataSnapshot) float64 {
+	total := 0.0
+
+	for i, snapshot := range snapshots {
+		if len(snapshot.ChartData) < 2 || window.InBreak(snapshot.DateTime) {
+			continue
+		}
+
+		// Cada snapshot representa el tiempo hasta el siguiente, con un máximo de dos intervalos
+		step := sr.config.CheckInterval
+		if i+1 < len(snapshots) {
+			if gap := snapshots[i+1].DateTime.Sub(snapshot.DateTime); gap < 2*sr.config.CheckInterval {
+				step = gap
+			}
+		}
+
+		if len(sr.advisor.Imbalances(convertToAdvisorState(snapshot))) > 0 {
+			total += step.Seconds()
+		}
+	}
+
+	return total
+}
+
+// FormatShiftReport genera la versión legible de un reporte de turno
+func FormatShiftReport(report ShiftReport) string {
+	var sb strings.Builder
+	separator := strings.Repeat("═", 60)
+
+	sb.WriteString(separator + "\n")
+	sb.WriteString(fmt.Sprintf("REPORTE DE TURNO %s - Día productivo %s\n", report.Shift, report.ProductionDay))
+	sb.WriteString(fmt.Sprintf("%s → %s\n", report.Start.Format("2006-01-02 15:04"), report.End.Format("2006-01-02 15:04")))
+	sb.WriteString(separator + "\n")
+	sb.WriteString(fmt.Sprintf("Snapshots: %d\n", report.Snapshots))
+	sb.WriteString(fmt.Sprintf("Reasignaciones: %d\n", report.Reassignments))
+
+	types := make([]string, 0, len(report.EventsByType))
+	for eventType := range report.EventsByType {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	for _, eventType := range types {
+		sb.WriteString(fmt.Sprintf("  - %s: %d\n", eventType, report.EventsByType[eventType]))
+	}
+
+	sb.WriteString(fmt.Sprintf("Desplazamientos de carga: %d\n", report.LoadShifts))
+	sb.WriteString(fmt.Sprintf("Tiempo con desbalance: %v\n",
+		(time.Duration(report.ImbalanceSeconds) * time.Second).Round(time.Second)))
+
+	sorters := make([]int, 0, len(report.SKUMix))
+	for sorterID := range report.SKUMix {
+		sorters = append(sorters, sorterID)
+	}
+	sort.Ints(sorters)
+
+	sb.WriteString("\nMezcla de SKUs por sorter (promedio / máximo):\n")
+	for _, sorterID := range sorters {
+		sb.WriteString(fmt.Sprintf("  Sorter %d:\n", sorterID))
+		for _, share := range report.SKUMix[sorterID] {
+			sb.WriteString(fmt.Sprintf("    %-28s %5.1f%% / %5.1f%%\n", share.SKU, share.Mean, share.Max))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\nSugerencias entregadas: %d\n", len(report.Advice)))
+	for _, record := range report.Advice {
+		sb.WriteString(fmt.Sprintf("  [%s] %s: %s\n",
+			record.DateTime.Format("15:04"), record.Advice.Accion, record.Advice.Razon))
+	}
+
+	return sb.String()
+}
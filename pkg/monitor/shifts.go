@@ -0,0 +1,200 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+// ClockRange es un rango horario dentro del día (desde la medianoche)
+type ClockRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ShiftDefinition define un turno de trabajo con hora de inicio y fin (HH:MM).
+// Si el fin es anterior al inicio el turno cruza la medianoche.
+type ShiftDefinition struct {
+	Name   string
+	Start  time.Duration // Desde la medianoche
+	End    time.Duration // Desde la medianoche
+	Breaks []ClockRange  // Pausas dentro del turno
+}
+
+// TimeRange es un intervalo concreto de tiempo
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ShiftWindow es una ocurrencia concreta de un turno
+type ShiftWindow struct {
+	Name          string
+	ProductionDay string // Día productivo al que pertenece (2006-01-02)
+	Start         time.Time
+	End           time.Time
+	Breaks        []TimeRange
+}
+
+// Key identifica la ocurrencia del turno
+func (sw ShiftWindow) Key() string {
+	return sw.ProductionDay + "/" + sw.Name
+}
+
+// InBreak indica si t cae dentro de una pausa del turno
+func (sw ShiftWindow) InBreak(t time.Time) bool {
+	for _, b := range sw.Breaks {
+		if !t.Before(b.Start) && t.Before(b.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShiftCalendar resuelve a qué turno y día productivo pertenece un instante
+type ShiftCalendar struct {
+	shifts   []ShiftDefinition
+	dayStart time.Duration // Hora en que comienza el día productivo
+}
+
+// NewShiftCalendar crea un calendario a partir de las definiciones de turnos.
+// dayStart es la hora de inicio del día productivo para instantes fuera de turno.
+func NewShiftCalendar(shifts []ShiftDefinition, dayStart time.Duration) *ShiftCalendar {
+	return &ShiftCalendar{shifts: shifts, dayStart: dayStart}
+}
+
+// Enabled indica si hay turnos configurados
+func (sc *ShiftCalendar) Enabled() bool {
+	return sc != nil && len(sc.shifts) > 0
+}
+
+// Resolve retorna el turno que contiene el instante t. Los bordes se arman
+// con la hora de reloj de cada día, así que un turno que cruza un cambio de
+// horario dura una hora más o menos.
+func (sc *ShiftCalendar) Resolve(t time.Time) (ShiftWindow, bool) {
+	if !sc.Enabled() {
+		return ShiftWindow{}, false
+	}
+
+	for _, shift := range sc.shifts {
+		// Un turno nocturno que contiene t pudo haber empezado el día anterior.
+		// El día se ancla al mediodía porque la medianoche puede no existir.
+		for _, dayOffset := range []int{0, -1} {
+			day := time.Date(t.Year(), t.Month(), t.Day()+dayOffset, 12, 0, 0, 0, t.Location())
+			start := clockOn(day, 0, shift.Start)
+			end := clockOn(day, 0, shift.End)
+			if shift.End <= shift.Start {
+				end = clockOn(day, 1, shift.End)
+			}
+			if t.Before(start) || !t.Before(end) {
+				continue
+			}
+
+			window := ShiftWindow{
+				Name:          shift.Name,
+				ProductionDay: day.Format("2006-01-02"),
+				Start:         start,
+				End:           end,
+			}
+			for _, b := range shift.Breaks {
+				window.Breaks = append(window.Breaks, concreteBreak(day, shift, b))
+			}
+			return window, true
+		}
+	}
+
+	return ShiftWindow{}, false
+}
+
+// ProductionDay retorna el día productivo de t: el del turno que lo contiene
+// o, fuera de turno, el día calendario desplazado por el inicio del día productivo
+func (sc *ShiftCalendar) ProductionDay(t time.Time) string {
+	if window, ok := sc.Resolve(t); ok {
+		return window.ProductionDay
+	}
+	if sc == nil {
+		return t.Format("2006-01-02")
+	}
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if clock < sc.dayStart {
+		return time.Date(t.Year(), t.Month(), t.Day()-1, 12, 0, 0, 0, t.Location()).Format("2006-01-02")
+	}
+	return t.Format("2006-01-02")
+}
+
+// concreteBreak ubica una pausa dentro de la ocurrencia del turno que empieza en day
+func concreteBreak(day time.Time, shift ShiftDefinition, b ClockRange) TimeRange {
+	// En turnos nocturnos, las pausas antes del inicio son del día siguiente
+	startDay := 0
+	if b.Start < shift.Start {
+		startDay = 1
+	}
+	endDay := startDay
+	if b.End <= b.Start {
+		endDay++
+	}
+	return TimeRange{Start: clockOn(day, startDay, b.Start), End: clockOn(day, endDay, b.End)}
+}
+
+// clockOn retorna la hora de reloj clock (desde la medianoche) del día
+// day+days. Se usa time.Date para respetar los cambios de horario.
+func clockOn(day time.Time, days int, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day()+days,
+		int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+// shiftOverlaps retorna los pares de turnos cuyos horarios se solapan
+func shiftOverlaps(shifts []ShiftDefinition) []string {
+	const day = 24 * time.Hour
+	span := func(shift ShiftDefinition) (time.Duration, time.Duration) {
+		if shift.End <= shift.Start {
+			return shift.Start, shift.End + day
+		}
+		return shift.Start, shift.End
+	}
+
+	var problems []string
+	for i := range shifts {
+		aStart, aEnd := span(shifts[i])
+		for j := i + 1; j < len(shifts); j++ {
+			bStart, bEnd := span(shifts[j])
+			for _, offset := range []time.Duration{-day, 0, day} {
+				if aStart < bEnd+offset && bStart+offset < aEnd {
+					problems = append(problems, fmt.Sprintf("%s y %s", shifts[i].Name, shifts[j].Name))
+					break
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// parseClock convierte "HH:MM" en la duración desde la medianoche
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("hora inválida %q (formato HH:MM)", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Window retorna la ocurrencia del turno name que comienza en el día productivo day (2006-01-02)
+func (sc *ShiftCalendar) Window(day, name string) (ShiftWindow, bool) {
+	if !sc.Enabled() {
+		return ShiftWindow{}, false
+	}
+
+	// Se parsea en UTC: en hora local la medianoche puede no existir
+	date, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return ShiftWindow{}, false
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.Local)
+
+	for _, shift := range sc.shifts {
+		if shift.Name == name {
+			return sc.Resolve(clockOn(date, 0, shift.Start))
+		}
+	}
+
+	return ShiftWindow{}, false
+}
@@ -0,0 +1,180 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// testShifts retorna tres turnos de 8 horas, el nocturno con una pausa
+// después de la medianoche
+func testShifts() []ShiftDefinition {
+	return []ShiftDefinition{
+		{Name: "Día", Start: 6 * time.Hour, End: 14 * time.Hour,
+			Breaks: []ClockRange{{Start: 10 * time.Hour, End: 10*time.Hour + 15*time.Minute}}},
+		{Name: "Tarde", Start: 14 * time.Hour, End: 22 * time.Hour},
+		{Name: "Noche", Start: 22 * time.Hour, End: 6 * time.Hour,
+			Breaks: []ClockRange{{Start: 2 * time.Hour, End: 2*time.Hour + 30*time.Minute}}},
+	}
+}
+
+func TestShiftCalendarResolve(t *testing.T) {
+	// Santiago deja el horario de verano el 2026-04-05 a las 00:00 (vuelve a
+	// las 23:00) y lo retoma el 2026-09-06 a las 00:00 (salta a la 01:00)
+	loc, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Window interpreta el día productivo en hora local
+	local := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = local })
+
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name       string
+		t          time.Time
+		wantShift  string
+		wantDay    string
+		wantStart  time.Time
+		wantLength time.Duration
+	}{
+		{"turno de día", at(3, 10, 9, 0), "Día", "2026-03-10", at(3, 10, 6, 0), 8 * time.Hour},
+		{"noche antes de medianoche", at(3, 10, 23, 30), "Noche", "2026-03-10", at(3, 10, 22, 0), 8 * time.Hour},
+		{"noche después de medianoche", at(3, 11, 3, 0), "Noche", "2026-03-10", at(3, 10, 22, 0), 8 * time.Hour},
+		{"borde de inicio", at(3, 11, 6, 0), "Día", "2026-03-11", at(3, 11, 6, 0), 8 * time.Hour},
+		{"borde de fin", at(3, 11, 22, 0), "Noche", "2026-03-11", at(3, 11, 22, 0), 8 * time.Hour},
+		{"fin del horario de verano", at(4, 5, 3, 0), "Noche", "2026-04-04", at(4, 4, 22, 0), 9 * time.Hour},
+		{"día después del cambio", at(4, 5, 9, 0), "Día", "2026-04-05", at(4, 5, 6, 0), 8 * time.Hour},
+		{"inicio del horario de verano", at(9, 6, 3, 0), "Noche", "2026-09-05", at(9, 5, 22, 0), 7 * time.Hour},
+		{"día con medianoche inexistente", at(9, 6, 7, 0), "Día", "2026-09-06", at(9, 6, 6, 0), 8 * time.Hour},
+	}
+
+	calendar := NewShiftCalendar(testShifts(), 6*time.Hour)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, ok := calendar.Resolve(tt.t)
+			if !ok {
+				t.Fatalf("Resolve(%v) sin turno", tt.t)
+			}
+			if window.Name != tt.wantShift || window.ProductionDay != tt.wantDay {
+				t.Errorf("turno = %s, esperaba %s/%s", window.Key(), tt.wantDay, tt.wantShift)
+			}
+			if !window.Start.Equal(tt.wantStart) {
+				t.Errorf("inicio = %v, esperaba %v", window.Start, tt.wantStart)
+			}
+			if got := window.End.Sub(window.Start); got != tt.wantLength {
+				t.Errorf("duración = %v, esperaba %v", got, tt.wantLength)
+			}
+			if got := calendar.ProductionDay(tt.t); got != tt.wantDay {
+				t.Errorf("ProductionDay = %s, esperaba %s", got, tt.wantDay)
+			}
+
+			same, ok := calendar.Window(tt.wantDay, tt.wantShift)
+			if !ok || !same.Start.Equal(window.Start) || !same.End.Equal(window.End) {
+				t.Errorf("Window(%s, %s) = %v..%v, esperaba %v..%v",
+					tt.wantDay, tt.wantShift, same.Start, same.End, window.Start, window.End)
+			}
+		})
+	}
+}
+
+func TestShiftWindowBreaks(t *testing.T) {
+	loc, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar := NewShiftCalendar(testShifts(), 6*time.Hour)
+
+	window, ok := calendar.Resolve(time.Date(2026, 3, 10, 23, 0, 0, 0, loc))
+	if !ok {
+		t.Fatal("sin turno")
+	}
+	tests := []struct {
+		t    time.Time
+		want bool
+	}{
+		{time.Date(2026, 3, 10, 23, 0, 0, 0, loc), false},
+		{time.Date(2026, 3, 11, 2, 0, 0, 0, loc), true},
+		{time.Date(2026, 3, 11, 2, 29, 0, 0, loc), true},
+		{time.Date(2026, 3, 11, 2, 30, 0, 0, loc), false},
+		{time.Date(2026, 3, 10, 2, 10, 0, 0, loc), false}, // La pausa es del día siguiente
+	}
+	for _, tt := range tests {
+		if got := window.InBreak(tt.t); got != tt.want {
+			t.Errorf("InBreak(%v) = %v, esperaba %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestShiftCalendarOutsideShifts(t *testing.T) {
+	loc, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar := NewShiftCalendar(testShifts()[:1], 6*time.Hour)
+
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Date(2026, 3, 11, 3, 0, 0, 0, loc), "2026-03-10"},
+		{time.Date(2026, 3, 11, 18, 0, 0, 0, loc), "2026-03-11"},
+		{time.Date(2026, 9, 6, 0, 30, 0, 0, loc), "2026-09-05"}, // 00:30 no existe: queda 01:30
+	}
+	for _, tt := range tests {
+		if _, ok := calendar.Resolve(tt.t); ok {
+			t.Errorf("Resolve(%v) encontró un turno", tt.t)
+		}
+		if got := calendar.ProductionDay(tt.t); got != tt.want {
+			t.Errorf("ProductionDay(%v) = %s, esperaba %s", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestShiftOverlaps(t *testing.T) {
+	shift := func(name string, start, end time.Duration) ShiftDefinition {
+		return ShiftDefinition{Name: name, Start: start, End: end}
+	}
+	h := time.Hour
+
+	tests := []struct {
+		name   string
+		shifts []ShiftDefinition
+		want   int
+	}{
+		{"tres turnos contiguos", testShifts(), 0},
+		{"nocturno y diurno contiguos", []ShiftDefinition{shift("Noche", 22*h, 6*h), shift("Día", 6*h, 14*h)}, 0},
+		{"diurnos solapados", []ShiftDefinition{shift("Día", 6*h, 14*h), shift("Tarde", 13*h, 22*h)}, 1},
+		{"nocturno solapa la mañana", []ShiftDefinition{shift("Noche", 22*h, 6*h), shift("Madrugada", 5*h, 7*h)}, 1},
+		{"ambos cruzan la medianoche", []ShiftDefinition{shift("A", 23*h, 1*h), shift("B", 30*time.Minute, 2*h)}, 1},
+		{"turno de 24 horas", []ShiftDefinition{shift("Continuo", 6*h, 6*h), shift("Día", 8*h, 9*h)}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shiftOverlaps(tt.shifts); len(got) != tt.want {
+				t.Errorf("solapes = %v, esperaba %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigRejectsOverlappingShifts(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Shifts = []ShiftDefinition{
+		{Name: "Día", Start: 6 * time.Hour, End: 15 * time.Hour},
+		{Name: "Tarde", Start: 14 * time.Hour, End: 22 * time.Hour},
+	}
+
+	var found bool
+	for _, problem := range cfg.validate() {
+		found = found || strings.HasPrefix(problem, "turnos:")
+	}
+	if !found {
+		t.Errorf("validate no rechazó los turnos solapados: %v", cfg.validate())
+	}
+}
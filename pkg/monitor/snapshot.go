@@ -2,7 +2,7 @@ package monitor
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -11,18 +11,34 @@ import (
 
 // SnapshotBuilder construye snapshots del estado del sistema
 type SnapshotBuilder struct {
-	chartScraper *scraper.ChartScraper
+	calendar *ShiftCalendar
+	quality  QualityOptions
+	logger   *slog.Logger
 }
 
 // NewSnapshotBuilder crea un nuevo constructor de snapshots
-func NewSnapshotBuilder(chartScraper *scraper.ChartScraper) *SnapshotBuilder {
+func NewSnapshotBuilder(calendar *ShiftCalendar, quality QualityOptions, logger *slog.Logger) *SnapshotBuilder {
 	return &SnapshotBuilder{
-		chartScraper: chartScraper,
+		calendar: calendar,
+		quality:  quality,
+		logger:   loggerOrDefault(logger),
 	}
 }
 
-// CreateSnapshot genera un snapshot completo del estado actual
-func (sb *SnapshotBuilder) CreateSnapshot(timestamp time.Time, assignments []Assignment) DataSnapshot {
+// CreateSnapshot genera un snapshot completo a partir de los assignments y los
+// gráficos capturados (puede no haber gráficos)
+func (sb *SnapshotBuilder) CreateSnapshot(timestamp time.Time, assignments []Assignment, chartDataList []*scraper.ChartData) DataSnapshot {
+	snapshot := sb.newSnapshot(timestamp, assignments)
+	if len(chartDataList) > 0 {
+		sb.applyChartData(&snapshot, chartDataList, assignments)
+	}
+
+	sb.checkQuality(&snapshot)
+	return snapshot
+}
+
+// newSnapshot crea el snapshot con turno, día productivo y contadores básicos
+func (sb *SnapshotBuilder) newSnapshot(timestamp time.Time, assignments []Assignment) DataSnapshot {
 	snapshot := DataSnapshot{
 		Timestamp:             timestamp.Format("2006-01-02 15:04:05"),
 		DateTime:              timestamp,
@@ -35,28 +51,42 @@ func (sb *SnapshotBuilder) CreateSnapshot(timestamp time.Time, assignments []Ass
 		CalibreBySorterSalida: make(map[string]map[string]CalibreDistribution),
 	}
 
+	// Turno y día productivo
+	snapshot.ProductionDay = sb.calendar.ProductionDay(timestamp)
+	if shift, ok := sb.calendar.Resolve(timestamp); ok {
+		snapshot.Shift = shift.Name
+		snapshot.InBreak = shift.InBreak(timestamp)
+	}
+
 	// Contadores básicos
 	for _, a := range assignments {
 		snapshot.BySorter[a.SorterID]++
 		snapshot.BySalida[a.Salida]++
 	}
 
-	// Capturar datos de gráficos si está disponible
-	if sb.chartScraper != nil {
-		sb.captureChartData(&snapshot, assignments)
-	}
-
 	return snapshot
 }
 
-// captureChartData captura los porcentajes reales de los gráficos
-func (sb *SnapshotBuilder) captureChartData(snapshot *DataSnapshot, assignments []Assignment) {
-	chartDataList, err := sb.chartScraper.ScrapeBothSorters()
-	if err != nil {
-		log.Printf("⚠ Error capturando gráficos: %v", err)
-		return
+// checkQuality valida el snapshot y adjunta el reporte de calidad
+func (sb *SnapshotBuilder) checkQuality(snapshot *DataSnapshot) {
+	report := CheckQuality(*snapshot, sb.quality)
+	snapshot.Quality = &report
+
+	for _, issue := range report.Issues {
+		sb.logger.Debug("problema de calidad de datos",
+			"chequeo", issue.Check,
+			"sorter", issue.SorterID,
+			"sku", issue.SKU,
+			"salida", issue.Salida,
+			"detalle", issue.Message)
+	}
+	if !report.OK {
+		sb.logger.Info("snapshot con problemas de calidad", "problemas", len(report.Issues))
 	}
+}
 
+// applyChartData incorpora los gráficos al snapshot y calcula las distribuciones
+func (sb *SnapshotBuilder) applyChartData(snapshot *DataSnapshot, chartDataList []*scraper.ChartData, assignments []Assignment) {
 	snapshot.ChartData = make(map[int]*scraper.ChartData)
 
 	// Procesar datos de cada sorter
@@ -78,18 +108,29 @@ func (sb *SnapshotBuilder) captureChartData(snapshot *DataSnapshot, assignments
 
 	// Calcular distribución global
 	sb.calculateGlobalDistribution(snapshot, chartDataList)
+}
 
-	log.Printf("📊 Gráficos capturados: %d sorters con porcentajes reales", len(chartDataList))
+// RebuildSnapshot reconstruye un snapshot completo a partir de los datos
+// normalizados (assignments y gráficos) guardados en un Store
+func RebuildSnapshot(timestamp time.Time, assignments []Assignment, chartDataList []*scraper.ChartData) DataSnapshot {
+	sb := NewSnapshotBuilder(nil, DefaultQualityOptions(), slog.New(slog.DiscardHandler))
+	return sb.CreateSnapshot(timestamp, assignments, chartDataList)
 }
 
-// mapPercentagesToOutputs mapea los porcentajes a las salidas físicas
+// mapPercentagesToOutputs mapea los porcentajes a las salidas físicas. Los
+// SKUs del gráfico y de los assignments se comparan sin distinguir mayúsculas.
 func (sb *SnapshotBuilder) mapPercentagesToOutputs(snapshot *DataSnapshot, chartData *scraper.ChartData, assignments []Assignment) {
+	percentages := make(map[string]float64, len(chartData.Percentages))
+	for sku, percentage := range chartData.Percentages {
+		percentages[normalizeSKU(sku)] = percentage
+	}
+
 	for _, assignment := range assignments {
 		if assignment.SorterID != chartData.SorterID {
 			continue
 		}
 
-		realPercent, exists := chartData.Percentages[assignment.SKU]
+		realPercent, exists := percentages[normalizeSKU(assignment.SKU)]
 		if !exists {
 			continue
 		}
@@ -140,6 +181,11 @@ func (sb *SnapshotBuilder) calculateGlobalDistribution(snapshot *DataSnapshot, c
 	}
 }
 
+// normalizeSKU normaliza un SKU para comparar gráficos y assignments
+func normalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
+
 // ExtractCalibre extrae el calibre del SKU (formato: CALIBRE-CALIDAD-VARIEDAD-LOTE)
 func ExtractCalibre(sku string) string {
 	if strings.ToLower(sku) == "descarte" {
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tipos de almacenamiento soportados
+const (
+	StoreJSON   = "json"
+	StoreSQLite = "sqlite"
+)
+
+// Store persiste el histórico del monitor: snapshots (con sus assignments y
+// porcentajes de gráficos), cambios y sugerencias. Los rangos de tiempo con
+// valor cero no filtran.
+type Store interface {
+	SaveSnapshot(snapshot DataSnapshot) error
+	SaveChange(change ChangeLog) error
+	SaveAdvice(record AdviceRecord) error
+
+	Snapshots(from, to time.Time) ([]DataSnapshot, error)
+	Changes(from, to time.Time) ([]ChangeLog, error)
+	Advice(from, to time.Time) ([]AdviceRecord, error)
+	PercentageHistory(query PercentageQuery) ([]PercentagePoint, error)
+	Stats() (StoreStats, error)
+
+	// PruneSnapshots elimina los snapshots anteriores a before (los cambios y
+	// sugerencias nunca se eliminan) y retorna cuántos eliminó
+	PruneSnapshots(before time.Time) (int, error)
+
+	Close() error
+}
+
+// StoreStats resume el contenido del histórico
+type StoreStats struct {
+	TotalSnapshots int       `json:"total_snapshots"`
+	First          time.Time `json:"first"`
+	Last           time.Time `json:"last"`
+}
+
+// PercentageQuery filtra el histórico de porcentajes de los gráficos
+type PercentageQuery struct {
+	SKU           string // Prefijo de SKU, sin distinguir mayúsculas
+	SorterID      int    // 0 = todos
+	MinPercentage float64
+	From          time.Time
+	To            time.Time
+}
+
+// PercentagePoint es el porcentaje de un SKU en un sorter en un instante
+type PercentagePoint struct {
+	DateTime   time.Time `json:"datetime"`
+	SorterID   int       `json:"sorter_id"`
+	SKU        string    `json:"sku"`
+	Percentage float64   `json:"percentage"`
+}
+
+// OpenStore abre el almacenamiento configurado
+func OpenStore(config *SystemConfig, persistence *Persistence) (Store, error) {
+	switch config.StoreType {
+	case "", StoreJSON:
+		return NewJSONStore(persistence), nil
+	case StoreSQLite:
+		return OpenSQLiteStore(config.SQLitePath)
+	default:
+		return nil, fmt.Errorf("tipo de almacenamiento desconocido: %q", config.StoreType)
+	}
+}
+
+// LatestSnapshot retorna el snapshot más reciente del histórico
+func LatestSnapshot(store Store) (DataSnapshot, error) {
+	stats, err := store.Stats()
+	if err != nil {
+		return DataSnapshot{}, err
+	}
+	if stats.TotalSnapshots == 0 {
+		return DataSnapshot{}, fmt.Errorf("el histórico no tiene snapshots")
+	}
+
+	snapshots, err := store.Snapshots(stats.Last, time.Time{})
+	if err != nil {
+		return DataSnapshot{}, err
+	}
+	if len(snapshots) == 0 {
+		return DataSnapshot{}, fmt.Errorf("no se encontró el snapshot de %s", stats.Last.Format("2006-01-02 15:04:05"))
+	}
+	return snapshots[len(snapshots)-1], nil
+}
+
+// inRange indica si t está dentro del rango [from, to]
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && t.After(to) {
+		return false
+	}
+	return true
+}
+
+// changeTime interpreta el timestamp de un cambio en hora local
+func changeTime(change ChangeLog) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04:05", change.Timestamp, time.Local)
+	return t
+}
@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSONStore implementa Store sobre los archivos JSON de la carpeta de datos
+// (dataset.json, changes_log.json y advice_log.json). El loop escribe
+// mientras la API y el advisor leen, así que todo pasa por mu.
+type JSONStore struct {
+	mu          sync.RWMutex
+	persistence *Persistence
+	dataset     TrainingDataset
+}
+
+// NewJSONStore crea un Store JSON cargando el dataset existente
+func NewJSONStore(persistence *Persistence) *JSONStore {
+	return &JSONStore{
+		persistence: persistence,
+		dataset:     persistence.LoadOrCreateDataset(),
+	}
+}
+
+// SaveSnapshot agrega el snapshot al dataset y lo guarda
+func (js *JSONStore) SaveSnapshot(snapshot DataSnapshot) error {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	js.dataset.Snapshots = append(js.dataset.Snapshots, snapshot)
+	js.dataset.TotalSnapshots = len(js.dataset.Snapshots)
+	js.dataset.CollectionEnd = snapshot.DateTime
+	return js.persistence.SaveDataset(js.dataset)
+}
+
+// SaveChange agrega un cambio a changes_log.json
+func (js *JSONStore) SaveChange(change ChangeLog) error {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	return js.persistence.LogChange(change)
+}
+
+// SaveAdvice agrega una sugerencia a advice_log.json
+func (js *JSONStore) SaveAdvice(record AdviceRecord) error {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	return js.persistence.LogAdvice(record)
+}
+
+// Snapshots retorna los snapshots del rango
+func (js *JSONStore) Snapshots(from, to time.Time) ([]DataSnapshot, error) {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	var result []DataSnapshot
+	for _, snapshot := range js.dataset.Snapshots {
+		if inRange(snapshot.DateTime, from, to) {
+			result = append(result, snapshot)
+		}
+	}
+	return result, nil
+}
+
+// Changes retorna los cambios del rango
+func (js *JSONStore) Changes(from, to time.Time) ([]ChangeLog, error) {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	changes, err := js.persistence.LoadChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ChangeLog
+	for _, change := range changes {
+		if inRange(changeTime(change), from, to) {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// Advice retorna las sugerencias del rango
+func (js *JSONStore) Advice(from, to time.Time) ([]AdviceRecord, error) {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	records, err := js.persistence.LoadAdvice()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []AdviceRecord
+	for _, record := range records {
+		if inRange(record.DateTime, from, to) {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+// PercentageHistory recorre el dataset completo buscando los porcentajes pedidos
+func (js *JSONStore) PercentageHistory(query PercentageQuery) ([]PercentagePoint, error) {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	prefix := strings.ToUpper(query.SKU)
+	var points []PercentagePoint
+
+	for _, snapshot := range js.dataset.Snapshots {
+		if !inRange(snapshot.DateTime, query.From, query.To) {
+			continue
+		}
+		for sorterID, chartData := range snapshot.ChartData {
+			if chartData == nil || (query.SorterID != 0 && sorterID != query.SorterID) {
+				continue
+			}
+			for sku, percentage := range chartData.Percentages {
+				if !strings.HasPrefix(strings.ToUpper(sku), prefix) || percentage < query.MinPercentage {
+					continue
+				}
+				points = append(points, PercentagePoint{
+					DateTime:   snapshot.DateTime,
+					SorterID:   sorterID,
+					SKU:        sku,
+					Percentage: percentage,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		if !points[i].DateTime.Equal(points[j].DateTime) {
+			return points[i].DateTime.Before(points[j].DateTime)
+		}
+		if points[i].SorterID != points[j].SorterID {
+			return points[i].SorterID < points[j].SorterID
+		}
+		return points[i].SKU < points[j].SKU
+	})
+
+	return points, nil
+}
+
+// Stats retorna el resumen del dataset
+func (js *JSONStore) Stats() (StoreStats, error) {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	stats := StoreStats{TotalSnapshots: len(js.dataset.Snapshots)}
+	if n := len(js.dataset.Snapshots); n > 0 {
+		stats.First = js.dataset.Snapshots[0].DateTime
+		stats.Last = js.dataset.Snapshots[n-1].DateTime
+	}
+	return stats, nil
+}
+
+// PruneSnapshots quita del dataset los snapshots anteriores a before
+func (js *JSONStore) PruneSnapshots(before time.Time) (int, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	kept := make([]DataSnapshot, 0, len(js.dataset.Snapshots))
+	for _, snapshot := range js.dataset.Snapshots {
+		if !snapshot.DateTime.Before(before) {
+			kept = append(kept, snapshot)
+		}
+	}
+
+	pruned := len(js.dataset.Snapshots) - len(kept)
+	if pruned == 0 {
+		return 0, nil
+	}
+
+	js.dataset.Snapshots = kept
+	js.dataset.TotalSnapshots = len(kept)
+	if len(kept) > 0 {
+		js.dataset.CollectionStart = kept[0].DateTime
+	}
+	return pruned, js.persistence.SaveDataset(js.dataset)
+}
+
+// Close no requiere liberar recursos
+func (js *JSONStore) Close() error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJSONStoreConcurrentAccess(t *testing.T) {
+	cfg := testConfig(t)
+	persistence := NewPersistence(cfg, discardLogger())
+	if err := persistence.EnsureDataFolder(); err != nil {
+		t.Fatal(err)
+	}
+	store := NewJSONStore(persistence)
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			at := start.Add(time.Duration(i) * time.Minute)
+			if err := store.SaveSnapshot(testSnapshot(at, 1, map[string]float64{"SKU-A": float64(i)})); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := store.SaveChange(ChangeLog{Timestamp: at.Format("2006-01-02 15:04:05")}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := store.PercentageHistory(PercentageQuery{SKU: "sku"}); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := store.Changes(time.Time{}, time.Time{}); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := store.Stats(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	stats, err := store.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalSnapshots != 50 {
+		t.Errorf("TotalSnapshots = %d, se esperaba 50", stats.TotalSnapshots)
+	}
+	changes, err := store.Changes(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 50 {
+		t.Errorf("cambios = %d, se esperaban 50", len(changes))
+	}
+
+	// Las escrituras atómicas no dejan temporales en la carpeta
+	leftovers, _ := filepath.Glob(filepath.Join(cfg.DatasetFolder, ".*.tmp*"))
+	if len(leftovers) > 0 {
+		t.Errorf("quedaron temporales: %v", leftovers)
+	}
+	if _, err := os.Stat(cfg.DatasetFile); err != nil {
+		t.Errorf("no se escribió el dataset: %v", err)
+	}
+}
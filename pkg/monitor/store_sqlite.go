@@ -0,0 +1,695 @@
+package monitor
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"danich/pkg/scraper"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations contiene el esquema en versiones sucesivas. La versión
+// aplicada se guarda en PRAGMA user_version.
+var sqliteMigrations = []string{
+	`CREATE TABLE snapshots (
+		id             INTEGER PRIMARY KEY,
+		ts             INTEGER NOT NULL UNIQUE, -- Unix milisegundos
+		shift          TEXT NOT NULL DEFAULT '',
+		production_day TEXT NOT NULL DEFAULT '',
+		in_break       INTEGER NOT NULL DEFAULT 0,
+		total_count    INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX idx_snapshots_day ON snapshots(production_day, shift);
+
+	CREATE TABLE assignments (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id) ON DELETE CASCADE,
+		sorter_id   INTEGER NOT NULL,
+		salida      INTEGER NOT NULL,
+		sku         TEXT NOT NULL,
+		sku_norm    TEXT NOT NULL
+	);
+	CREATE INDEX idx_assignments_snapshot ON assignments(snapshot_id);
+	CREATE INDEX idx_assignments_sorter_sku ON assignments(sorter_id, sku_norm);
+
+	CREATE TABLE chart_percentages (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id) ON DELETE CASCADE,
+		sorter_id   INTEGER NOT NULL,
+		position    INTEGER NOT NULL, -- Orden en el gráfico
+		sku         TEXT NOT NULL,
+		sku_norm    TEXT NOT NULL,
+		percentage  REAL NOT NULL
+	);
+	CREATE INDEX idx_chart_snapshot ON chart_percentages(snapshot_id);
+	CREATE INDEX idx_chart_sorter_sku ON chart_percentages(sorter_id, sku_norm, percentage);
+	CREATE INDEX idx_chart_sku ON chart_percentages(sku_norm, percentage);
+
+	CREATE TABLE changes (
+		id             INTEGER PRIMARY KEY,
+		ts             INTEGER NOT NULL,
+		change_type    TEXT NOT NULL,
+		shift          TEXT NOT NULL DEFAULT '',
+		production_day TEXT NOT NULL DEFAULT '',
+		description    TEXT NOT NULL DEFAULT '',
+		legacy_json    TEXT NOT NULL DEFAULT '', -- Listas added/removed/modified del formato anterior
+		UNIQUE(ts, change_type, description)
+	);
+	CREATE INDEX idx_changes_ts ON changes(ts);
+
+	CREATE TABLE change_events (
+		change_id    INTEGER NOT NULL REFERENCES changes(id) ON DELETE CASCADE,
+		type         TEXT NOT NULL,
+		sku          TEXT NOT NULL,
+		sku_norm     TEXT NOT NULL,
+		sorter_id    INTEGER NOT NULL,
+		from_sorter  INTEGER NOT NULL DEFAULT 0,
+		salida       INTEGER NOT NULL DEFAULT 0,
+		salidas      TEXT NOT NULL DEFAULT '[]',
+		prev_salidas TEXT NOT NULL DEFAULT '[]'
+	);
+	CREATE INDEX idx_change_events_change ON change_events(change_id);
+	CREATE INDEX idx_change_events_sku ON change_events(sku_norm, sorter_id);
+
+	CREATE TABLE load_shifts (
+		change_id INTEGER NOT NULL REFERENCES changes(id) ON DELETE CASCADE,
+		sorter_id INTEGER NOT NULL,
+		sku       TEXT NOT NULL,
+		sku_norm  TEXT NOT NULL,
+		from_pct  REAL NOT NULL,
+		to_pct    REAL NOT NULL,
+		delta     REAL NOT NULL
+	);
+	CREATE INDEX idx_load_shifts_change ON load_shifts(change_id);
+	CREATE INDEX idx_load_shifts_sku ON load_shifts(sku_norm, sorter_id);
+
+	CREATE TABLE advice (
+		id             INTEGER PRIMARY KEY,
+		ts             INTEGER NOT NULL,
+		shift          TEXT NOT NULL DEFAULT '',
+		production_day TEXT NOT NULL DEFAULT '',
+		check_count    INTEGER NOT NULL DEFAULT 0,
+		accion         TEXT NOT NULL,
+		sku            TEXT NOT NULL DEFAULT '',
+		de_sorter      INTEGER NOT NULL DEFAULT 0,
+		a_sorter       INTEGER NOT NULL DEFAULT 0,
+		razon          TEXT NOT NULL DEFAULT '',
+		advice_json    TEXT NOT NULL, -- Advice completo
+		UNIQUE(ts, accion, sku)
+	);
+	CREATE INDEX idx_advice_ts ON advice(ts);`,
+
+	// Estado de las fuentes de cada ciclo (snapshots parciales)
+	`ALTER TABLE snapshots ADD COLUMN partial INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE snapshots ADD COLUMN sources_json TEXT NOT NULL DEFAULT '';`,
+
+	// Capacidad de las salidas y reconciliación de cada snapshot
+	`ALTER TABLE snapshots ADD COLUMN capacity_json TEXT NOT NULL DEFAULT '';
+	ALTER TABLE snapshots ADD COLUMN quality_json TEXT NOT NULL DEFAULT '';`,
+}
+
+// SQLiteStore implementa Store sobre una base SQLite normalizada
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStore abre (o crea) la base y aplica las migraciones pendientes
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	store := &SQLiteStore{db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// migrate aplica las migraciones de esquema pendientes
+func (ss *SQLiteStore) migrate() error {
+	var version int
+	if err := ss.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := ss.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error en migración %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveSnapshot guarda el snapshot con sus assignments y porcentajes.
+// Un snapshot con el mismo instante se ignora.
+func (ss *SQLiteStore) SaveSnapshot(snapshot DataSnapshot) error {
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sources := ""
+	if len(snapshot.Sources) > 0 {
+		data, err := json.Marshal(snapshot.Sources)
+		if err != nil {
+			return err
+		}
+		sources = string(data)
+	}
+	capacity, err := optionalJSON(snapshot.Capacity != nil, snapshot.Capacity)
+	if err != nil {
+		return err
+	}
+	quality, err := optionalJSON(snapshot.Quality != nil, snapshot.Quality)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`INSERT OR IGNORE INTO snapshots (ts, shift, production_day, in_break, total_count, partial, sources_json,
+			capacity_json, quality_json)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		snapshot.DateTime.UnixMilli(), snapshot.Shift, snapshot.ProductionDay, snapshot.InBreak, snapshot.TotalCount,
+		snapshot.Partial, sources, capacity, quality)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil
+	}
+	snapshotID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range snapshot.Assignments {
+		if _, err := tx.Exec(`INSERT INTO assignments (snapshot_id, sorter_id, salida, sku, sku_norm) VALUES (?, ?, ?, ?, ?)`,
+			snapshotID, a.SorterID, a.Salida, a.SKU, strings.ToUpper(a.SKU)); err != nil {
+			return err
+		}
+	}
+
+	for sorterID, chartData := range snapshot.ChartData {
+		if chartData == nil {
+			continue
+		}
+		for position, sku := range orderedChartSKUs(chartData) {
+			if _, err := tx.Exec(`INSERT INTO chart_percentages (snapshot_id, sorter_id, position, sku, sku_norm, percentage)
+				VALUES (?, ?, ?, ?, ?, ?)`,
+				snapshotID, sorterID, position, sku, strings.ToUpper(sku), chartData.Percentages[sku]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// optionalJSON serializa value si present, o retorna "" para la columna
+func optionalJSON(present bool, value interface{}) (string, error) {
+	if !present {
+		return "", nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// orderedChartSKUs retorna los SKUs en el orden del gráfico, agregando al
+// final los que no figuren en OrderedSKUs
+func orderedChartSKUs(chartData *scraper.ChartData) []string {
+	seen := make(map[string]bool)
+	skus := make([]string, 0, len(chartData.Percentages))
+	for _, sku := range chartData.OrderedSKUs {
+		if _, exists := chartData.Percentages[sku]; exists && !seen[sku] {
+			seen[sku] = true
+			skus = append(skus, sku)
+		}
+	}
+
+	var rest []string
+	for sku := range chartData.Percentages {
+		if !seen[sku] {
+			rest = append(rest, sku)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(skus, rest...)
+}
+
+// SaveChange guarda un cambio con sus eventos y desplazamientos de carga
+func (ss *SQLiteStore) SaveChange(change ChangeLog) error {
+	legacy := ""
+	if len(change.Added) > 0 || len(change.Removed) > 0 || len(change.Modified) > 0 {
+		data, err := json.Marshal(ChangeLog{Added: change.Added, Removed: change.Removed, Modified: change.Modified})
+		if err != nil {
+			return err
+		}
+		legacy = string(data)
+	}
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT OR IGNORE INTO changes (ts, change_type, shift, production_day, description, legacy_json)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		changeTime(change).UnixMilli(), change.ChangeType, change.Shift, change.ProductionDay, change.Description, legacy)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil
+	}
+	changeID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range change.Events {
+		salidas, _ := json.Marshal(nonNilInts(e.Salidas))
+		prevSalidas, _ := json.Marshal(nonNilInts(e.PrevSalidas))
+		if _, err := tx.Exec(`INSERT INTO change_events (change_id, type, sku, sku_norm, sorter_id, from_sorter, salida, salidas, prev_salidas)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			changeID, e.Type, e.SKU, strings.ToUpper(e.SKU), e.SorterID, e.FromSorter, e.Salida, string(salidas), string(prevSalidas)); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range change.LoadShifts {
+		if _, err := tx.Exec(`INSERT INTO load_shifts (change_id, sorter_id, sku, sku_norm, from_pct, to_pct, delta)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			changeID, s.SorterID, s.SKU, strings.ToUpper(s.SKU), s.From, s.To, s.Delta); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SaveAdvice guarda una sugerencia
+func (ss *SQLiteStore) SaveAdvice(record AdviceRecord) error {
+	data, err := json.Marshal(record.Advice)
+	if err != nil {
+		return err
+	}
+
+	_, err = ss.db.Exec(`INSERT OR IGNORE INTO advice (ts, shift, production_day, check_count, accion, sku, de_sorter, a_sorter, razon, advice_json)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.DateTime.UnixMilli(), record.Shift, record.ProductionDay, record.CheckCount,
+		record.Advice.Accion, record.Advice.SKU, record.Advice.DeSorter, record.Advice.ASorter, record.Advice.Razon, string(data))
+	return err
+}
+
+// Snapshots reconstruye los snapshots del rango
+func (ss *SQLiteStore) Snapshots(from, to time.Time) ([]DataSnapshot, error) {
+	where, args := tsRange("s.ts", from, to)
+
+	type snapshotRow struct {
+		ts            int64
+		shift, day    string
+		inBreak       bool
+		partial       bool
+		sources       string
+		capacity      string
+		quality       string
+		assignments   []Assignment
+		chartBySorter map[int]*scraper.ChartData
+	}
+	rows := make(map[int64]*snapshotRow)
+	var order []int64
+
+	query, err := ss.db.Query(`SELECT s.id, s.ts, s.shift, s.production_day, s.in_break, s.partial, s.sources_json,
+		s.capacity_json, s.quality_json
+		FROM snapshots s`+where+` ORDER BY s.ts`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for query.Next() {
+		var id int64
+		row := &snapshotRow{chartBySorter: make(map[int]*scraper.ChartData)}
+		if err := query.Scan(&id, &row.ts, &row.shift, &row.day, &row.inBreak, &row.partial, &row.sources,
+			&row.capacity, &row.quality); err != nil {
+			query.Close()
+			return nil, err
+		}
+		rows[id] = row
+		order = append(order, id)
+	}
+	query.Close()
+	if err := query.Err(); err != nil {
+		return nil, err
+	}
+
+	query, err = ss.db.Query(`SELECT a.snapshot_id, a.sorter_id, a.salida, a.sku FROM assignments a
+		JOIN snapshots s ON s.id = a.snapshot_id`+where+` ORDER BY a.rowid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for query.Next() {
+		var id int64
+		var a Assignment
+		if err := query.Scan(&id, &a.SorterID, &a.Salida, &a.SKU); err != nil {
+			query.Close()
+			return nil, err
+		}
+		if row := rows[id]; row != nil {
+			row.assignments = append(row.assignments, a)
+		}
+	}
+	query.Close()
+	if err := query.Err(); err != nil {
+		return nil, err
+	}
+
+	query, err = ss.db.Query(`SELECT c.snapshot_id, c.sorter_id, c.sku, c.percentage FROM chart_percentages c
+		JOIN snapshots s ON s.id = c.snapshot_id`+where+` ORDER BY c.snapshot_id, c.sorter_id, c.position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for query.Next() {
+		var id int64
+		var sorterID int
+		var sku string
+		var percentage float64
+		if err := query.Scan(&id, &sorterID, &sku, &percentage); err != nil {
+			query.Close()
+			return nil, err
+		}
+		row := rows[id]
+		if row == nil {
+			continue
+		}
+		chart := row.chartBySorter[sorterID]
+		if chart == nil {
+			chart = &scraper.ChartData{
+				SorterID:    sorterID,
+				Timestamp:   time.UnixMilli(row.ts),
+				Percentages: make(map[string]float64),
+			}
+			row.chartBySorter[sorterID] = chart
+		}
+		chart.Percentages[sku] = percentage
+		chart.OrderedSKUs = append(chart.OrderedSKUs, sku)
+		chart.TotalSKUs++
+	}
+	query.Close()
+	if err := query.Err(); err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]DataSnapshot, 0, len(order))
+	for _, id := range order {
+		row := rows[id]
+
+		sorterIDs := make([]int, 0, len(row.chartBySorter))
+		for sorterID := range row.chartBySorter {
+			sorterIDs = append(sorterIDs, sorterID)
+		}
+		sort.Ints(sorterIDs)
+		chartDataList := make([]*scraper.ChartData, 0, len(sorterIDs))
+		for _, sorterID := range sorterIDs {
+			chartDataList = append(chartDataList, row.chartBySorter[sorterID])
+		}
+
+		snapshot := RebuildSnapshot(time.UnixMilli(row.ts), row.assignments, chartDataList)
+		snapshot.Shift = row.shift
+		snapshot.ProductionDay = row.day
+		snapshot.InBreak = row.inBreak
+		snapshot.Partial = row.partial
+		if row.sources != "" {
+			if err := json.Unmarshal([]byte(row.sources), &snapshot.Sources); err != nil {
+				return nil, fmt.Errorf("estado de fuentes inválido en snapshot %d: %w", id, err)
+			}
+		}
+		if row.capacity != "" {
+			if err := json.Unmarshal([]byte(row.capacity), &snapshot.Capacity); err != nil {
+				return nil, fmt.Errorf("capacidad inválida en snapshot %d: %w", id, err)
+			}
+		}
+		// La reconciliación guardada usa la tolerancia configurada al capturar
+		if row.quality != "" {
+			snapshot.Quality = nil
+			if err := json.Unmarshal([]byte(row.quality), &snapshot.Quality); err != nil {
+				return nil, fmt.Errorf("reconciliación inválida en snapshot %d: %w", id, err)
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// Changes retorna los cambios del rango
+func (ss *SQLiteStore) Changes(from, to time.Time) ([]ChangeLog, error) {
+	where, args := tsRange("c.ts", from, to)
+
+	rows, err := ss.db.Query(`SELECT c.id, c.ts, c.change_type, c.shift, c.production_day, c.description, c.legacy_json
+		FROM changes c`+where+` ORDER BY c.ts, c.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*ChangeLog)
+	var order []int64
+	for rows.Next() {
+		var id, ts int64
+		var legacy string
+		change := &ChangeLog{}
+		if err := rows.Scan(&id, &ts, &change.ChangeType, &change.Shift, &change.ProductionDay, &change.Description, &legacy); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		change.Timestamp = time.UnixMilli(ts).Format("2006-01-02 15:04:05")
+		if legacy != "" {
+			var old ChangeLog
+			if err := json.Unmarshal([]byte(legacy), &old); err == nil {
+				change.Added, change.Removed, change.Modified = old.Added, old.Removed, old.Modified
+			}
+		}
+		byID[id] = change
+		order = append(order, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = ss.db.Query(`SELECT e.change_id, e.type, e.sku, e.sorter_id, e.from_sorter, e.salida, e.salidas, e.prev_salidas
+		FROM change_events e JOIN changes c ON c.id = e.change_id`+where+` ORDER BY e.rowid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var e ChangeEvent
+		var salidas, prevSalidas string
+		if err := rows.Scan(&id, &e.Type, &e.SKU, &e.SorterID, &e.FromSorter, &e.Salida, &salidas, &prevSalidas); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		json.Unmarshal([]byte(salidas), &e.Salidas)
+		json.Unmarshal([]byte(prevSalidas), &e.PrevSalidas)
+		if change := byID[id]; change != nil {
+			change.Events = append(change.Events, e)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = ss.db.Query(`SELECT l.change_id, l.sorter_id, l.sku, l.from_pct, l.to_pct, l.delta
+		FROM load_shifts l JOIN changes c ON c.id = l.change_id`+where+` ORDER BY l.rowid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var s LoadShift
+		if err := rows.Scan(&id, &s.SorterID, &s.SKU, &s.From, &s.To, &s.Delta); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if change := byID[id]; change != nil {
+			change.LoadShifts = append(change.LoadShifts, s)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	changes := make([]ChangeLog, 0, len(order))
+	for _, id := range order {
+		changes = append(changes, *byID[id])
+	}
+	return changes, nil
+}
+
+// Advice retorna las sugerencias del rango
+func (ss *SQLiteStore) Advice(from, to time.Time) ([]AdviceRecord, error) {
+	where, args := tsRange("ts", from, to)
+
+	rows, err := ss.db.Query(`SELECT ts, shift, production_day, check_count, advice_json FROM advice`+where+` ORDER BY ts, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []AdviceRecord{}
+	for rows.Next() {
+		var ts int64
+		var data string
+		var record AdviceRecord
+		if err := rows.Scan(&ts, &record.Shift, &record.ProductionDay, &record.CheckCount, &data); err != nil {
+			return nil, err
+		}
+		record.DateTime = time.UnixMilli(ts)
+		if err := json.Unmarshal([]byte(data), &record.Advice); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// PercentageHistory consulta los porcentajes usando los índices por sorter y SKU
+func (ss *SQLiteStore) PercentageHistory(query PercentageQuery) ([]PercentagePoint, error) {
+	where, args := tsRange("s.ts", query.From, query.To)
+	conditions := []string{"c.percentage >= ?"}
+	args = append(args, query.MinPercentage)
+
+	if query.SKU != "" {
+		// Rango de prefijo para aprovechar el índice sobre sku_norm
+		prefix := strings.ToUpper(query.SKU)
+		conditions = append(conditions, "c.sku_norm >= ? AND c.sku_norm < ?")
+		args = append(args, prefix, prefix+"\U0010FFFF")
+	}
+	if query.SorterID != 0 {
+		conditions = append(conditions, "c.sorter_id = ?")
+		args = append(args, query.SorterID)
+	}
+
+	if where == "" {
+		where = " WHERE "
+	} else {
+		where += " AND "
+	}
+	where += strings.Join(conditions, " AND ")
+
+	rows, err := ss.db.Query(`SELECT s.ts, c.sorter_id, c.sku, c.percentage FROM chart_percentages c
+		JOIN snapshots s ON s.id = c.snapshot_id`+where+` ORDER BY s.ts, c.sorter_id, c.sku`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []PercentagePoint{}
+	for rows.Next() {
+		var ts int64
+		var point PercentagePoint
+		if err := rows.Scan(&ts, &point.SorterID, &point.SKU, &point.Percentage); err != nil {
+			return nil, err
+		}
+		point.DateTime = time.UnixMilli(ts)
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+// Stats retorna el resumen de la base
+func (ss *SQLiteStore) Stats() (StoreStats, error) {
+	var stats StoreStats
+	var first, last sql.NullInt64
+
+	if err := ss.db.QueryRow(`SELECT COUNT(*), MIN(ts), MAX(ts) FROM snapshots`).Scan(&stats.TotalSnapshots, &first, &last); err != nil {
+		return stats, err
+	}
+	if first.Valid {
+		stats.First = time.UnixMilli(first.Int64)
+		stats.Last = time.UnixMilli(last.Int64)
+	}
+
+	return stats, nil
+}
+
+// PruneSnapshots elimina los snapshots anteriores a before y compacta el archivo
+func (ss *SQLiteStore) PruneSnapshots(before time.Time) (int, error) {
+	result, err := ss.db.Exec(`DELETE FROM snapshots WHERE ts < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+
+	pruned, _ := result.RowsAffected()
+	if pruned > 0 {
+		if _, err := ss.db.Exec(`VACUUM`); err != nil {
+			return int(pruned), err
+		}
+	}
+	return int(pruned), nil
+}
+
+// Close cierra la base
+func (ss *SQLiteStore) Close() error {
+	return ss.db.Close()
+}
+
+// tsRange arma la condición WHERE para un rango de timestamps
+func tsRange(column string, from, to time.Time) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if !from.IsZero() {
+		conditions = append(conditions, column+" >= ?")
+		args = append(args, from.UnixMilli())
+	}
+	if !to.IsZero() {
+		conditions = append(conditions, column+" <= ?")
+		args = append(args, to.UnixMilli())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// nonNilInts evita serializar null en las listas de salidas
+func nonNilInts(values []int) []int {
+	if values == nil {
+		return []int{}
+	}
+	return values
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestSQLiteStoreCapacityQuality verifica que la capacidad y la reconciliación
+// guardadas vuelvan tal cual al reconstruir el snapshot
+func TestSQLiteStoreCapacityQuality(t *testing.T) {
+	store, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "monitor.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	at := time.Date(2026, 3, 10, 9, 30, 0, 0, time.Local)
+	withReports := testSnapshot(at, 1, map[string]float64{"4J": 60, "3J": 30})
+	withReports.Assignments = []Assignment{{Salida: 1, SKU: "4J", SorterID: 1}}
+	withReports.Capacity = &CapacityReport{
+		Unit: "cajas/min",
+		Sorters: []SorterCapacity{
+			{SorterID: 1, Throughput: 120, ThroughputSource: "config", Capacity: 100, Utilization: 1.2, Unallocated: 36},
+		},
+		Lines: []LineLoad{{SorterID: 1, Salida: 1, SKUs: []string{"4J"}, Load: 72}},
+	}
+	// Calculada con una tolerancia distinta a la por defecto
+	withReports.Quality = &QualityReport{
+		OK:          true,
+		SumBySorter: map[int]float64{1: 90},
+	}
+
+	plain := testSnapshot(at.Add(time.Minute), 1, map[string]float64{"4J": 100})
+	plain.Assignments = []Assignment{{Salida: 1, SKU: "4J", SorterID: 1}}
+
+	for _, snapshot := range []DataSnapshot{withReports, plain} {
+		if err := store.SaveSnapshot(snapshot); err != nil {
+			t.Fatalf("SaveSnapshot: %v", err)
+		}
+	}
+
+	snapshots, err := store.Snapshots(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("%d snapshots, esperaba 2", len(snapshots))
+	}
+
+	if !reflect.DeepEqual(snapshots[0].Capacity, withReports.Capacity) {
+		t.Errorf("capacidad = %+v, esperaba %+v", snapshots[0].Capacity, withReports.Capacity)
+	}
+	if !reflect.DeepEqual(snapshots[0].Quality, withReports.Quality) {
+		t.Errorf("reconciliación = %+v, esperaba %+v", snapshots[0].Quality, withReports.Quality)
+	}
+
+	if snapshots[1].Capacity != nil {
+		t.Errorf("capacidad = %+v, esperaba nil", snapshots[1].Capacity)
+	}
+	if snapshots[1].Quality == nil {
+		t.Error("sin reconciliación guardada debería recalcularse")
+	}
+}
+
+// TestSQLiteStoreMigratesV2 abre una base creada con el esquema anterior
+func TestSQLiteStoreMigratesV2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.db")
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range sqliteMigrations[:2] {
+		if _, err := db.Exec(migration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`PRAGMA user_version = 2;
+		INSERT INTO snapshots (ts, total_count, partial, sources_json) VALUES (?, 0, 0, '')`,
+		time.Date(2026, 3, 10, 8, 0, 0, 0, time.Local).UnixMilli()); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	store, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	defer store.Close()
+
+	var version int
+	if err := store.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Errorf("user_version = %d, esperaba %d", version, len(sqliteMigrations))
+	}
+
+	snapshots, err := store.Snapshots(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Capacity != nil {
+		t.Errorf("snapshots migrados = %+v", snapshots)
+	}
+}
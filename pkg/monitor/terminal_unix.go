@@ -0,0 +1,23 @@
+//go:build !windows
+
+package monitor
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalSize retorna las columnas y filas de la terminal
+func terminalSize(f *os.File) (width, height int, ok bool) {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 {
+		return 0, 0, false
+	}
+	return int(ws.Col), int(ws.Row), true
+}
+
+// enableANSI habilita las secuencias de escape; las terminales Unix ya las interpretan
+func enableANSI(f *os.File) error {
+	return nil
+}
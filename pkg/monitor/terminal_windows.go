@@ -0,0 +1,31 @@
+//go:build windows
+
+package monitor
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// terminalSize retorna las columnas y filas visibles de la consola
+func terminalSize(f *os.File) (width, height int, ok bool) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(f.Fd()), &info); err != nil {
+		return 0, 0, false
+	}
+	width = int(info.Window.Right-info.Window.Left) + 1
+	height = int(info.Window.Bottom-info.Window.Top) + 1
+	return width, height, width > 0 && height > 0
+}
+
+// enableANSI activa el procesamiento de secuencias de escape en la consola de
+// Windows (cmd y PowerShell no lo activan por defecto)
+func enableANSI(f *os.File) error {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return err
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+}
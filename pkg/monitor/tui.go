@@ -0,0 +1,797 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"danich/pkg/advisor"
+
+	"github.com/mattn/go-isatty"
+)
+
+// Modos de la consola
+const (
+	DisplayAuto = "auto"
+	DisplayTUI  = "tui"
+	DisplayText = "texto"
+)
+
+// Secuencias ANSI usadas por la TUI
+const (
+	ansiAltScreen  = "\x1b[?1049h"
+	ansiMainScreen = "\x1b[?1049l"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiHome       = "\x1b[H"
+	ansiClearLine  = "\x1b[K"
+	ansiClearBelow = "\x1b[J"
+
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiInverse = "\x1b[7m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// Umbrales de color de las barras (los mismos que el dashboard web)
+const (
+	tuiHighLoad     = 40.0
+	tuiCriticalLoad = 55.0
+)
+
+const (
+	tuiMaxEvents     = 200         // Mensajes de consola que se conservan
+	tuiRefresh       = time.Second // Redibujo periódico (cuenta regresiva y tamaño)
+	tuiDefaultWidth  = 120         // Si no se puede consultar la terminal
+	tuiDefaultHeight = 40
+	tuiSideBySide    = 100 // Ancho mínimo para paneles lado a lado
+)
+
+// UseTUI indica si la consola usa la TUI: siempre con "tui", nunca con
+// "texto" y con "auto" solo si out es una terminal
+func UseTUI(mode string, out *os.File) bool {
+	switch mode {
+	case DisplayTUI:
+		return true
+	case DisplayText:
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isatty.IsTerminal(out.Fd()) || isatty.IsCygwinTerminal(out.Fd())
+}
+
+// TUI dibuja el estado del monitor a pantalla completa: barras por sorter,
+// mapa de salidas coloreado por carga, cambios recientes, la última
+// sugerencia, la salud de las fuentes y un panel con los mensajes de la
+// consola. Implementa io.Writer para recibir esos mensajes.
+type TUI struct {
+	out    *os.File
+	config *SystemConfig
+
+	mu        sync.Mutex
+	snapshot  *DataSnapshot
+	state     *DashboardState
+	sources   []SourceStatus // Fuentes del último ciclo, aunque haya fallado
+	failures  map[string]int // Fuente -> ciclos fallidos consecutivos
+	advice    *advisor.Advice
+	ack       *AdviceAck // Reconocimiento de la sugerencia mostrada
+	total     int        // Snapshots guardados
+	startTime time.Time
+	next      time.Time
+	nextInfo  string
+	events    []string
+	partial   string // Línea recibida sin salto de línea
+
+	dirty chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+}
+
+// NewTUI crea la TUI sobre una terminal; no la dibuja hasta Start
+func NewTUI(out *os.File, config *SystemConfig) *TUI {
+	return &TUI{
+		out:    out,
+		config: config,
+		dirty:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start pasa a la pantalla alternativa y redibuja ante cada actualización.
+// Ctrl+C lo maneja el monitor: termina el ciclo, cierra sus componentes y
+// recién entonces Close restaura la terminal.
+func (t *TUI) Start() {
+	enableANSI(t.out)
+	t.out.WriteString(ansiAltScreen + ansiHideCursor)
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		ticker := time.NewTicker(tuiRefresh)
+		defer ticker.Stop()
+
+		t.render()
+		for {
+			select {
+			case <-t.done:
+				return
+			case <-t.dirty:
+				t.render()
+			case <-ticker.C:
+				t.render()
+			}
+		}
+	}()
+}
+
+// Close restaura la terminal y deja a la vista los últimos mensajes
+func (t *TUI) Close() {
+	t.once.Do(func() {
+		close(t.done)
+		t.wg.Wait()
+		t.out.WriteString(ansiReset + ansiMainScreen + ansiShowCursor)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		events := t.events
+		if len(events) > 10 {
+			events = events[len(events)-10:]
+		}
+		for _, event := range events {
+			fmt.Fprintln(t.out, event)
+		}
+	})
+}
+
+// Write agrega los mensajes de la consola al panel de eventos
+func (t *TUI) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	text := t.partial + string(p)
+	lines := strings.Split(text, "\n")
+	t.partial = lines[len(lines)-1]
+
+	stamp := time.Now().Format("15:04:05")
+	for _, line := range lines[:len(lines)-1] {
+		line = consoleText(line)
+		if line == "" || strings.Trim(line, "═─-=") == "" {
+			continue
+		}
+		t.events = append(t.events, stamp+" "+line)
+	}
+	if len(t.events) > tuiMaxEvents {
+		t.events = t.events[len(t.events)-tuiMaxEvents:]
+	}
+	t.mu.Unlock()
+
+	t.invalidate()
+	return len(p), nil
+}
+
+// Update reemplaza el estado dibujado por el del último ciclo
+func (t *TUI) Update(snapshot DataSnapshot, state DashboardState) {
+	t.mu.Lock()
+	t.snapshot = &snapshot
+	t.state = &state
+	if state.Advice != nil {
+		advice := state.Advice.Advice
+		t.advice = &advice
+		t.ack = state.Advice.Ack
+	}
+	t.mu.Unlock()
+	t.invalidate()
+}
+
+// SetSources actualiza el resultado de las fuentes de datos del ciclo
+func (t *TUI) SetSources(sources []SourceStatus, failures map[string]int) {
+	t.mu.Lock()
+	t.sources = append([]SourceStatus(nil), sources...)
+	sort.Slice(t.sources, func(i, j int) bool { return t.sources[i].Source < t.sources[j].Source })
+	t.failures = make(map[string]int, len(failures))
+	for source, count := range failures {
+		t.failures[source] = count
+	}
+	t.mu.Unlock()
+	t.invalidate()
+}
+
+// SetAdvice muestra una sugerencia nueva sin esperar al próximo ciclo
+func (t *TUI) SetAdvice(advice advisor.Advice) {
+	t.mu.Lock()
+	t.advice = &advice
+	t.ack = nil
+	t.mu.Unlock()
+	t.invalidate()
+}
+
+// SetAck marca la sugerencia mostrada como reconocida
+func (t *TUI) SetAck(ack AdviceAck) {
+	t.mu.Lock()
+	t.ack = &ack
+	t.mu.Unlock()
+	t.invalidate()
+}
+
+// SetStats actualiza la cantidad de snapshots guardados y el inicio de la ejecución
+func (t *TUI) SetStats(totalSnapshots int, startTime time.Time) {
+	t.mu.Lock()
+	t.total = totalSnapshots
+	t.startTime = startTime
+	t.mu.Unlock()
+}
+
+// SetNext indica cuándo será la próxima verificación
+func (t *TUI) SetNext(next time.Time, interval time.Duration, fast bool, stats SchedulerStats) {
+	info := fmt.Sprintf("cada %v", interval)
+	if fast {
+		info += ", polling rápido"
+	}
+	if stats.Overruns > 0 {
+		info += fmt.Sprintf(", %d ciclo(s) excedidos", stats.Overruns)
+	}
+
+	t.mu.Lock()
+	t.next = next
+	t.nextInfo = info
+	t.mu.Unlock()
+	t.invalidate()
+}
+
+// invalidate pide un redibujo; varios pedidos seguidos se agrupan en uno
+func (t *TUI) invalidate() {
+	select {
+	case t.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// render dibuja la pantalla completa sin borrarla antes, para evitar parpadeos
+func (t *TUI) render() {
+	width, height, ok := terminalSize(t.out)
+	if !ok {
+		width, height = tuiDefaultWidth, tuiDefaultHeight
+	}
+
+	t.mu.Lock()
+	lines := t.layout(width, height)
+	t.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString(ansiHome)
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line)
+		b.WriteString(ansiReset + ansiClearLine)
+	}
+	b.WriteString(ansiClearBelow)
+	t.out.WriteString(b.String())
+}
+
+// layout arma las líneas de la pantalla; las secciones se achican para
+// caber en height y el panel de eventos usa el espacio restante
+func (t *TUI) layout(width, height int) []string {
+	var top []string
+	top = append(top, t.headerLine(width), t.healthLine(width))
+	top = append(top, t.alertLines(width)...)
+
+	grid := t.salidaLines(width)
+	bottom := 6 // Título + 5 líneas de sugerencia y cambios
+	if width < tuiSideBySide {
+		bottom = 12
+	}
+	const minEvents = 3
+
+	// Las barras tienen prioridad: al menos un tercio de la pantalla
+	bars := t.sorterLines(width)
+	maxBars := max(height-len(top)-len(grid)-bottom-minEvents, height/3, 2)
+	if len(bars) > maxBars {
+		hidden := len(bars) - maxBars + 1
+		bars = append(bars[:maxBars-1], fit(ansiDim, fmt.Sprintf("  … %d línea(s) más", hidden), width))
+	}
+	bottom = min(bottom, max(height-len(top)-len(bars)-len(grid)-minEvents, 4))
+
+	lines := append(top, bars...)
+	lines = append(lines, grid...)
+	lines = append(lines, t.bottomLines(width, bottom)...)
+
+	events := height - len(lines) - 1
+	if events < 1 {
+		events = 1
+	}
+	lines = append(lines, sectionTitle("Eventos", width))
+	lines = append(lines, t.eventLines(width, events)...)
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lines
+}
+
+// headerLine es la barra de título
+func (t *TUI) headerLine(width int) string {
+	title := " Monitor de Asignaciones"
+	if t.config.PackingName != "" {
+		title += " · " + t.config.PackingName
+	}
+	if t.state != nil {
+		title += fmt.Sprintf(" · %s · verificación #%d", t.state.Timestamp, t.state.CheckCount)
+		if t.state.Shift != "" {
+			title += fmt.Sprintf(" · turno %s (%s)", t.state.Shift, t.state.ProductionDay)
+		}
+		if t.snapshot != nil && t.snapshot.InBreak {
+			title += " en pausa"
+		}
+	} else {
+		title += " · esperando la primera verificación"
+	}
+	if !t.startTime.IsZero() {
+		uptime := strings.TrimSuffix(time.Since(t.startTime).Round(time.Minute).String(), "0s")
+		title += fmt.Sprintf(" · %d snapshots · activo %s", t.total, uptime)
+	}
+	return fit(ansiInverse+ansiBold, title, width)
+}
+
+// healthLine resume las fuentes de datos, Ollama y la próxima verificación
+func (t *TUI) healthLine(width int) string {
+	var parts []string
+	for _, source := range t.sources {
+		if source.OK {
+			parts = append(parts, colored(ansiGreen, "●")+fmt.Sprintf(" %s %s", source.Source, formatMs(source.DurationMs)))
+		} else {
+			parts = append(parts, colored(ansiRed, "✗")+fmt.Sprintf(" %s (%d fallo(s))", source.Source, t.failures[source.Source]))
+		}
+	}
+
+	if t.state != nil && t.state.Ollama != nil {
+		health := t.state.Ollama
+		switch {
+		case health.Healthy() && health.Fallback:
+			parts = append(parts, colored(ansiYellow, "●")+" LLM "+health.Model+" (respaldo)")
+		case health.Healthy():
+			parts = append(parts, colored(ansiGreen, "●")+" LLM "+health.Model)
+		default:
+			parts = append(parts, colored(ansiRed, "✗")+" LLM no disponible, solo reglas")
+		}
+	}
+
+	if !t.next.IsZero() {
+		wait := time.Until(t.next).Round(time.Second)
+		if wait < 0 {
+			wait = 0
+		}
+		parts = append(parts, fmt.Sprintf("próxima %s (%v, %s)", t.next.Format("15:04:05"), wait, t.nextInfo))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return fitColored(" "+strings.Join(parts, "  "), width)
+}
+
+// alertLines muestra hasta dos alertas activas
+func (t *TUI) alertLines(width int) []string {
+	if t.state == nil {
+		return nil
+	}
+	var lines []string
+	for i, alert := range t.state.Alerts {
+		if i == 2 {
+			lines = append(lines, fit(ansiRed, fmt.Sprintf(" … %d alerta(s) más", len(t.state.Alerts)-2), width))
+			break
+		}
+		lines = append(lines, fit(ansiRed+ansiBold, fmt.Sprintf(" ALERTA [%s] %s", alert.Severity, alert.Message), width))
+	}
+	return lines
+}
+
+// sorterLines dibuja una barra por SKU, con los sorters en columnas si caben
+func (t *TUI) sorterLines(width int) []string {
+	lines := []string{sectionTitle("Carga por sorter", width)}
+	if t.state == nil || len(t.state.Sorters) == 0 {
+		return append(lines, fit(ansiDim, "  Sin gráficos capturados", width))
+	}
+
+	sorters := t.state.Sorters
+	columns := len(sorters)
+	colWidth := (width - 1 - 3*(columns-1)) / columns
+	if colWidth < 40 {
+		columns, colWidth = 1, width-1
+	}
+
+	for start := 0; start < len(sorters); start += columns {
+		end := min(start+columns, len(sorters))
+		group := sorters[start:end]
+
+		rows := 0
+		for _, sorter := range group {
+			rows = max(rows, len(sorter.SKUs))
+		}
+
+		header := " "
+		for i, sorter := range group {
+			if i > 0 {
+				header += "   "
+			}
+			header += fit(ansiBold, fmt.Sprintf("Sorter %d", sorter.SorterID), colWidth)
+		}
+		lines = append(lines, header)
+
+		for row := 0; row < rows; row++ {
+			line := " "
+			for i, sorter := range group {
+				if i > 0 {
+					line += ansiDim + " │ " + ansiReset
+				}
+				if row < len(sorter.SKUs) {
+					line += skuBar(sorter.SKUs[row], colWidth)
+				} else {
+					line += strings.Repeat(" ", colWidth)
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// skuBar es la barra de un SKU: nombre, salidas, barra y porcentaje
+func skuBar(sku DashboardSKU, width int) string {
+	const labelWidth, linesWidth, valueWidth = 16, 10, 7
+
+	salidas := "sin salida"
+	if len(sku.Salidas) > 0 {
+		parts := make([]string, len(sku.Salidas))
+		for i, salida := range sku.Salidas {
+			parts[i] = fmt.Sprint(salida)
+		}
+		salidas = "[" + strings.Join(parts, ",") + "]"
+	}
+
+	barWidth := width - labelWidth - linesWidth - valueWidth - 2
+	if barWidth < 5 {
+		barWidth = 5
+	}
+	filled := int(sku.Percentage/100*float64(barWidth) + 0.5)
+	filled = min(max(filled, 0), barWidth)
+
+	return fit("", sku.SKU, labelWidth) + " " +
+		fit(ansiDim, salidas, linesWidth) + " " +
+		colored(loadColor(sku.Percentage), strings.Repeat("█", filled)) +
+		colored(ansiDim, strings.Repeat("░", barWidth-filled)) +
+		fit("", fmt.Sprintf("%6.1f%%", sku.Percentage), valueWidth)
+}
+
+// salidaLines dibuja el mapa de salidas de cada sorter. Cada celda muestra la
+// participación de la salida en el sorter; el color es la utilización si hay
+// modelo de capacidad o, si no, la participación comparada con el promedio
+// de las salidas con SKU.
+func (t *TUI) salidaLines(width int) []string {
+	lines := []string{sectionTitle("Salidas (participación del sorter)", width)}
+	if t.snapshot == nil {
+		return append(lines, fit(ansiDim, "  Sin datos", width))
+	}
+
+	shares := lineShares(*t.snapshot)
+	bySorter := make(map[int][]WhatIfLine)
+	var sorterIDs []int
+	for _, line := range shares {
+		if _, exists := bySorter[line.SorterID]; !exists {
+			sorterIDs = append(sorterIDs, line.SorterID)
+		}
+		bySorter[line.SorterID] = append(bySorter[line.SorterID], line)
+	}
+	sort.Ints(sorterIDs)
+	if len(sorterIDs) == 0 {
+		return append(lines, fit(ansiDim, "  Sin salidas con porcentaje", width))
+	}
+
+	const cellWidth = 9 // " 12  34% "
+	perLine := max((width-5)/cellWidth, 1)
+	for _, sorterID := range sorterIDs {
+		cells := bySorter[sorterID]
+		average := 0.0
+		for _, cell := range cells {
+			average += cell.Percentage
+		}
+		average /= float64(len(cells))
+
+		for start := 0; start < len(cells); start += perLine {
+			line := "    "
+			if start == 0 {
+				line = fmt.Sprintf(" S%-2d", sorterID)
+			}
+			for _, cell := range cells[start:min(start+perLine, len(cells))] {
+				color := shareColor(cell.Percentage, average)
+				if load, ok := lineLoad(t.snapshot.Capacity, sorterID, cell.Salida); ok && load.Capacity > 0 {
+					color = utilizationColor(load.Utilization)
+				}
+				line += " " + colored(ansiInverse+color, fmt.Sprintf("%2d %4.0f%%", cell.Salida, cell.Percentage))
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// bottomLines dibuja la sugerencia y los cambios recientes, lado a lado si caben
+func (t *TUI) bottomLines(width, height int) []string {
+	if width < tuiSideBySide {
+		half := height / 2
+		lines := append([]string{sectionTitle("Sugerencia", width)}, t.adviceLines(width, half-1)...)
+		lines = append(lines, sectionTitle("Cambios recientes", width))
+		return append(lines, t.changeLines(width, height-len(lines))...)
+	}
+
+	left := width / 2
+	right := width - left - 3
+	advice := t.adviceLines(left, height-1)
+	changes := t.changeLines(right, height-1)
+
+	lines := []string{sectionTitle("Sugerencia", left) + "   " + sectionTitle("Cambios recientes", right)}
+	for i := 0; i < height-1; i++ {
+		a, c := strings.Repeat(" ", left), ""
+		if i < len(advice) {
+			a = advice[i]
+		}
+		if i < len(changes) {
+			c = changes[i]
+		}
+		lines = append(lines, a+ansiDim+" │ "+ansiReset+c)
+	}
+	return lines
+}
+
+// adviceLines es el panel de la última sugerencia
+func (t *TUI) adviceLines(width, height int) []string {
+	if t.advice == nil {
+		return []string{fit(ansiDim, "  Sin sugerencias todavía", width)}
+	}
+	advice := t.advice
+
+	var headline, color string
+	switch advice.Accion {
+	case "mover":
+		headline, color = fmt.Sprintf("MOVER %s  S%d → S%d", advice.SKU, advice.DeSorter, advice.ASorter), ansiYellow
+	case "mantener":
+		headline, color = "MANTENER", ansiGreen
+	case "prevenir":
+		headline, color = fmt.Sprintf("PREVENIR %s en S%d", advice.SKU, advice.DeSorter), ansiCyan
+	default:
+		headline, color = strings.ToUpper(advice.Accion), ""
+	}
+	if len(advice.Timestamp) >= 19 {
+		headline += "  (" + advice.Timestamp[11:19] + ")"
+	}
+
+	lines := []string{fit(color+ansiBold, " "+headline, width)}
+	if t.ack != nil {
+		note := fmt.Sprintf("  ✓ Reconocida por %s (%s)", t.ack.Usuario, t.ack.DateTime.Format("15:04"))
+		if t.ack.Nota != "" {
+			note += ": " + t.ack.Nota
+		}
+		lines = append(lines, fit(ansiGreen, note, width))
+	}
+	for _, line := range wrapText(advice.Razon, width-2) {
+		lines = append(lines, fit("", "  "+line, width))
+	}
+	if trace := advice.Trace; trace != nil && trace.LLM != nil {
+		note := "  LLM " + trace.LLM.Model + ": "
+		switch {
+		case !trace.LLM.Used:
+			note += "sin respuesta, se usa la regla"
+		case trace.LLM.Overridden():
+			note += "cambió la regla (" + trace.Rule.Summary() + ")"
+		default:
+			note += "confirmó la regla"
+		}
+		lines = append(lines, fit(ansiDim, note, width))
+	}
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for i := range lines {
+		lines[i] = lines[i] + ansiReset
+	}
+	return lines
+}
+
+// changeLines es el panel de cambios recientes, el más nuevo primero
+func (t *TUI) changeLines(width, height int) []string {
+	if t.state == nil || len(t.state.Changes) == 0 {
+		return []string{fit(ansiDim, " Sin cambios registrados", width)}
+	}
+	var lines []string
+	for _, change := range t.state.Changes {
+		if len(lines) == height {
+			break
+		}
+		stamp := change.Timestamp
+		if len(stamp) >= 19 {
+			stamp = stamp[11:19]
+		}
+		lines = append(lines, colored(ansiCyan, " "+stamp)+" "+fit("", change.Description, width-10))
+	}
+	return lines
+}
+
+// eventLines son los últimos mensajes de la consola
+func (t *TUI) eventLines(width, height int) []string {
+	events := t.events
+	if len(events) > height {
+		events = events[len(events)-height:]
+	}
+	lines := make([]string, len(events))
+	for i, event := range events {
+		color := ""
+		switch {
+		case strings.Contains(event, "ALERTA"), strings.Contains(event, "Error"), strings.Contains(event, "error"):
+			color = ansiRed
+		case strings.Contains(event, "CAMBIOS"), strings.Contains(event, "DESPLAZAMIENTO"), strings.Contains(event, "parcial"):
+			color = ansiYellow
+		}
+		lines[i] = fit(color, " "+event, width)
+	}
+	return lines
+}
+
+// sectionTitle es el título de una sección con una línea divisoria
+func sectionTitle(title string, width int) string {
+	text := "── " + title + " "
+	if pad := width - utf8.RuneCountInString(text); pad > 0 {
+		text += strings.Repeat("─", pad)
+	}
+	return fit(ansiDim, text, width)
+}
+
+// fit ajusta text a width columnas (recortando con "…" o completando con
+// espacios) y lo envuelve en color
+func fit(color, text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) > width {
+		runes = append(runes[:width-1], '…')
+	} else if len(runes) < width {
+		runes = append(runes, []rune(strings.Repeat(" ", width-len(runes)))...)
+	}
+	return colored(color, string(runes))
+}
+
+// fitColored recorta una línea que ya tiene secuencias de color a width columnas visibles
+func fitColored(text string, width int) string {
+	var b strings.Builder
+	visible := 0
+	inEscape := false
+	for _, r := range text {
+		switch {
+		case inEscape:
+			b.WriteRune(r)
+			if r == 'm' {
+				inEscape = false
+			}
+			continue
+		case r == '\x1b':
+			inEscape = true
+			b.WriteRune(r)
+			continue
+		}
+		if visible == width {
+			break
+		}
+		b.WriteRune(r)
+		visible++
+	}
+	return b.String() + ansiReset
+}
+
+// colored envuelve text en un color ANSI
+func colored(color, text string) string {
+	if color == "" || text == "" {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// loadColor es el color de una barra según su porcentaje
+func loadColor(percentage float64) string {
+	switch {
+	case percentage >= tuiCriticalLoad:
+		return ansiRed
+	case percentage >= tuiHighLoad:
+		return ansiYellow
+	default:
+		return ansiGreen
+	}
+}
+
+// utilizationColor es el color de una salida según su utilización
+func utilizationColor(utilization float64) string {
+	switch {
+	case utilization >= 1:
+		return ansiRed
+	case utilization >= 0.8:
+		return ansiYellow
+	default:
+		return ansiGreen
+	}
+}
+
+// shareColor es el color de una salida sin modelo de capacidad: roja con el
+// doble del promedio de las salidas del sorter, amarilla con 1,5 veces
+func shareColor(share, average float64) string {
+	switch {
+	case average <= 0:
+		return ansiGreen
+	case share >= 2*average:
+		return ansiRed
+	case share >= 1.5*average:
+		return ansiYellow
+	default:
+		return ansiGreen
+	}
+}
+
+// wrapText divide text en líneas de hasta width columnas
+func wrapText(text string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatMs muestra una duración en milisegundos
+func formatMs(ms float64) string {
+	if ms >= 1000 {
+		return fmt.Sprintf("%.1fs", ms/1000)
+	}
+	return fmt.Sprintf("%.0fms", ms)
+}
+
+// consoleText quita de un mensaje de consola los emoji y los tabs: su ancho
+// en la terminal varía y desalinearía los paneles
+func consoleText(line string) string {
+	var b strings.Builder
+	for _, r := range line {
+		switch {
+		case r == '\t':
+			b.WriteString("  ")
+		case r == '\r', r == 0xFE0F, r == 0x200D:
+		case r >= 0x1F000, r >= 0x2600 && r <= 0x26FF, r >= 0x23E9 && r <= 0x23FA:
+		case r == 0x2705, r == 0x274C, r == 0x2753, r == 0x2757, r == 0x2B50:
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
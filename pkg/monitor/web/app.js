@@ -0,0 +1,231 @@
+"use strict";
+
+const HIGH_LOAD = 40;
+const CRITICAL_LOAD = 55;
+
+const $ = (id) => document.getElementById(id);
+
+// Quien mira el dashboard (GET api/me); los supervisores pueden reconocer sugerencias
+let viewer = { rol: "lector" };
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs || {})) {
+    if (key === "class") node.className = value;
+    else node.setAttribute(key, value);
+  }
+  for (const child of children) {
+    if (child === null || child === undefined) continue;
+    node.append(child instanceof Node ? child : document.createTextNode(child));
+  }
+  return node;
+}
+
+function renderSorters(sorters) {
+  const container = $("sorters");
+  container.replaceChildren();
+
+  for (const sorter of sorters) {
+    const box = el("div", { class: "sorter" }, el("h3", {}, `Sorter ${sorter.sorter_id}`));
+    for (const sku of sorter.skus) {
+      const level = sku.percentage >= CRITICAL_LOAD ? "critical" : sku.percentage >= HIGH_LOAD ? "high" : "";
+      const fill = el("span", { class: level });
+      fill.style.width = `${Math.min(sku.percentage, 100)}%`;
+      const lines = sku.salidas && sku.salidas.length ? ` [${sku.salidas.join(", ")}]` : " [sin salida]";
+      box.append(el("div", { class: "bar-row" },
+        el("div", { class: "bar-label", title: sku.sku }, sku.sku, el("small", {}, lines)),
+        el("div", { class: "bar" }, fill),
+        el("div", { class: "bar-value" }, `${sku.percentage.toFixed(1)}%`)));
+    }
+    container.append(box);
+  }
+}
+
+function renderSalidas(salidas) {
+  const container = $("salidas");
+  container.replaceChildren();
+
+  const bySorter = new Map();
+  for (const cell of salidas) {
+    if (!bySorter.has(cell.sorter_id)) bySorter.set(cell.sorter_id, []);
+    bySorter.get(cell.sorter_id).push(cell);
+  }
+
+  for (const [sorterID, cells] of bySorter) {
+    const grid = el("div", { class: "salida-grid" }, el("div", { class: "label" }, `Sorter ${sorterID}`));
+    for (const cell of cells) {
+      grid.append(el("div", { class: "salida" }, el("b", {}, `Salida ${cell.salida}`), cell.skus.join(", ")));
+    }
+    container.append(grid);
+  }
+}
+
+function renderAdvice(record) {
+  const box = $("advice");
+  if (!record) {
+    box.className = "advice";
+    box.textContent = "Sin sugerencias todavía";
+    return;
+  }
+
+  const advice = record.advice;
+  const traceOpen = box.querySelector("details.trace")?.open;
+  box.className = `advice ${advice.accion}`;
+  box.replaceChildren(
+    el("div", {}, el("b", {}, advice.accion.toUpperCase()),
+      advice.sku ? ` ${advice.sku} (S${advice.de_sorter}${advice.a_sorter ? ` → S${advice.a_sorter}` : ""})` : ""),
+    el("div", {}, advice.razon),
+    el("small", {}, new Date(record.datetime).toLocaleTimeString()));
+  if (record.ack) {
+    const ack = record.ack;
+    box.append(el("div", { class: "ack" },
+      `✓ Reconocida por ${ack.usuario} a las ${new Date(ack.datetime).toLocaleTimeString()}${ack.nota ? `: ${ack.nota}` : ""}`));
+  } else if (viewer.rol === "supervisor" || viewer.rol === "admin") {
+    const button = el("button", { class: "ack-button" }, "Reconocer");
+    button.onclick = () => acknowledge(record, button);
+    box.append(button);
+  }
+  if (advice.trace) {
+    const trace = renderTrace(advice.trace);
+    trace.open = Boolean(traceOpen); // Mantener abierto entre actualizaciones
+    box.append(trace);
+  }
+}
+
+async function acknowledge(record, button) {
+  const nota = window.prompt("Nota (opcional)", "");
+  if (nota === null) return;
+  button.disabled = true;
+  const response = await fetch("api/advice/ack", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ advice_datetime: record.datetime, nota }),
+  });
+  if (response.ok) {
+    renderAdvice(await response.json());
+  } else {
+    const body = await response.json().catch(() => ({}));
+    window.alert(`No se pudo reconocer: ${body.error || response.status}`);
+    button.disabled = false;
+  }
+}
+
+function renderTrace(trace) {
+  const thresholds = [];
+  if (trace.thresholds.min_difference) thresholds.push(`diferencia > ${trace.thresholds.min_difference}%`);
+  if (trace.thresholds.forecast) thresholds.push(`pronóstico ≥ ${trace.thresholds.forecast}%`);
+
+  const details = el("details", { class: "trace" },
+    el("summary", {}, `Por qué (${(trace.imbalances || []).length} evaluados, ${(trace.rejected || []).length} descartados)`),
+    el("div", {}, `Umbrales: ${thresholds.join(", ")}`));
+
+  const imbalances = el("ul");
+  for (const imb of trace.imbalances || []) {
+    imbalances.append(el("li", { class: imb.selected ? "selected" : "" },
+      `${imb.sku}: S1 ${imb.sorter1_pct.toFixed(1)}% / S2 ${imb.sorter2_pct.toFixed(1)}% · ` +
+      `dif ${imb.difference.toFixed(1)} · carga ${imb.total_load.toFixed(1)} · ` +
+      `relativo ${imb.relative_imbalance.toFixed(2)} → prioridad ${imb.priority.toFixed(1)}`));
+  }
+  if (imbalances.childElementCount) details.append(el("div", {}, "Desbalances:"), imbalances);
+
+  const rejected = el("ul");
+  for (const move of trace.rejected || []) {
+    rejected.append(el("li", {}, `${move.sku}: ${move.reason}`));
+  }
+  if (rejected.childElementCount) details.append(el("div", {}, "Descartados:"), rejected);
+
+  const llm = trace.llm;
+  if (llm) {
+    let note = `LLM ${llm.model}: `;
+    if (!llm.used) note += `sin respuesta (${llm.error}), se usa la regla`;
+    else if ((llm.changed || []).some((field) => field !== "razon")) note += `cambió ${llm.changed.join(", ")} (regla: ${trace.rule.accion} ${trace.rule.sku || ""})`;
+    else note += "confirmó la regla";
+    details.append(el("div", {}, note));
+  }
+  return details;
+}
+
+function renderChanges(changes) {
+  const list = $("changes");
+  list.replaceChildren();
+
+  for (const change of changes) {
+    const item = el("li", {}, el("time", {}, change.timestamp), change.description);
+    const details = el("ul");
+    for (const event of change.events || []) {
+      details.append(el("li", {}, describeEvent(event)));
+    }
+    for (const shift of change.load_shifts || []) {
+      details.append(el("li", {}, `S${shift.sorter_id} ${shift.sku}: ${shift.from.toFixed(1)}% → ${shift.to.toFixed(1)}%`));
+    }
+    if (details.childElementCount) item.append(details);
+    list.append(item);
+  }
+}
+
+function describeEvent(event) {
+  switch (event.type) {
+    case "line_added": return `S${event.sorter_id} ${event.sku}: + salida ${event.salida}`;
+    case "line_removed": return `S${event.sorter_id} ${event.sku}: − salida ${event.salida}`;
+    case "sku_moved": return `${event.sku}: S${event.from_sorter} → S${event.sorter_id}`;
+    case "sku_introduced": return `S${event.sorter_id} ${event.sku}: nuevo en [${(event.salidas || []).join(", ")}]`;
+    case "sku_retired": return `S${event.sorter_id} ${event.sku}: retirado`;
+    default: return `${event.type} ${event.sku}`;
+  }
+}
+
+function renderAlerts(alerts) {
+  const box = $("alerts");
+  box.replaceChildren();
+  box.hidden = !alerts || alerts.length === 0;
+  for (const alert of alerts || []) {
+    box.append(el("div", { class: `alert ${alert.severity}` }, `🚨 ${alert.message}`));
+  }
+}
+
+function render(state) {
+  $("timestamp").textContent = `${state.timestamp} · verificación #${state.check_count}`;
+  $("shift").textContent = state.shift ? `Turno ${state.shift} (${state.production_day})` : "";
+  renderAlerts(state.alerts);
+  renderSorters(state.sorters);
+  renderSalidas(state.salidas);
+  renderAdvice(state.advice);
+  renderChanges(state.changes);
+  renderOllama(state.ollama);
+}
+
+function renderOllama(health) {
+  const badge = $("ollama");
+  if (!health) {
+    badge.textContent = "";
+    return;
+  }
+  if (health.model) {
+    badge.className = health.fallback ? "degraded" : "online";
+    badge.textContent = `LLM ${health.model}${health.fallback ? " (respaldo)" : ""}`;
+  } else {
+    badge.className = "offline";
+    badge.textContent = "LLM no disponible · solo reglas";
+  }
+  badge.title = health.error || health.details || "";
+}
+
+function connect() {
+  const source = new EventSource("api/events");
+  const status = $("status");
+
+  source.onopen = () => {
+    status.textContent = "En vivo";
+    status.className = "online";
+  };
+  source.addEventListener("state", (e) => render(JSON.parse(e.data)));
+  source.onerror = () => {
+    status.textContent = "Reconectando…";
+    status.className = "offline";
+  };
+}
+
+fetch("api/me")
+  .then((response) => (response.ok ? response.json() : viewer))
+  .then((me) => { viewer = me; })
+  .finally(connect);
@@ -0,0 +1,407 @@
+package monitor
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+
+	"danich/pkg/advisor"
+	"danich/pkg/scraper"
+)
+
+// Ediciones hipotéticas de assignments
+const (
+	WhatIfAddSalida    = "agregar_salida"
+	WhatIfRemoveSalida = "quitar_salida"
+	WhatIfMoveSKU      = "mover_sku"
+)
+
+// WhatIfEdit es un cambio hipotético de assignments
+type WhatIfEdit struct {
+	Accion   string `json:"accion"`
+	SKU      string `json:"sku"`
+	SorterID int    `json:"sorter_id"`
+	Salida   int    `json:"salida,omitempty"`   // agregar_salida, quitar_salida
+	ASorter  int    `json:"a_sorter,omitempty"` // mover_sku: sorter de destino
+	Salidas  []int  `json:"salidas,omitempty"`  // mover_sku: salidas en el sorter de destino
+}
+
+// String describe la edición en una línea
+func (e WhatIfEdit) String() string {
+	switch e.Accion {
+	case WhatIfAddSalida:
+		return fmt.Sprintf("agregar salida %d a %s en S%d", e.Salida, e.SKU, e.SorterID)
+	case WhatIfRemoveSalida:
+		return fmt.Sprintf("quitar salida %d de %s en S%d", e.Salida, e.SKU, e.SorterID)
+	case WhatIfMoveSKU:
+		return fmt.Sprintf("mover %s de S%d a S%d (salidas %v)", e.SKU, e.SorterID, e.ASorter, e.Salidas)
+	default:
+		return e.Accion
+	}
+}
+
+// WhatIfLine es la carga de una salida expresada como participación del sorter
+type WhatIfLine struct {
+	SorterID   int      `json:"sorter_id"`
+	Salida     int      `json:"salida"`
+	SKUs       []string `json:"skus"`
+	Percentage float64  `json:"percentage"`
+}
+
+// WhatIfState es la carga de las líneas y los desbalances de un escenario
+type WhatIfState struct {
+	Lines       []WhatIfLine        `json:"lines"`
+	Imbalances  []advisor.Imbalance `json:"imbalances"`
+	MaxPriority float64             `json:"max_priority"`
+	Capacity    *CapacityReport     `json:"capacity,omitempty"`
+	Quality     *QualityReport      `json:"quality,omitempty"`
+}
+
+// WhatIfLineChange es una salida cuya carga cambia en el escenario simulado
+type WhatIfLineChange struct {
+	SorterID      int     `json:"sorter_id"`
+	Salida        int     `json:"salida"`
+	Before        float64 `json:"before"` // Participación del sorter
+	After         float64 `json:"after"`
+	LoadBefore    float64 `json:"load_before,omitempty"` // Unidades por minuto (con modelo de capacidad)
+	LoadAfter     float64 `json:"load_after,omitempty"`
+	OverloadAfter bool    `json:"overload_after,omitempty"`
+}
+
+// WhatIfResult compara el estado actual con el escenario simulado
+type WhatIfResult struct {
+	Timestamp string             `json:"timestamp"` // Snapshot de partida
+	Edits     []WhatIfEdit       `json:"edits"`
+	Before    WhatIfState        `json:"before"`
+	After     WhatIfState        `json:"after"`
+	Changes   []WhatIfLineChange `json:"changes"`
+}
+
+// WhatIfSimulator aplica ediciones hipotéticas sobre una copia de un snapshot
+// y recalcula la carga por línea, los desbalances entre sorters y la
+// utilización. Nunca modifica el snapshot recibido ni persiste nada.
+type WhatIfSimulator struct {
+	builder  *SnapshotBuilder
+	capacity *CapacityModel
+	advisor  *advisor.Advisor
+}
+
+// NewWhatIfSimulator crea un simulador con la configuración del packing
+func NewWhatIfSimulator(config *SystemConfig) *WhatIfSimulator {
+	discard := slog.New(slog.DiscardHandler)
+	return &WhatIfSimulator{
+		builder:  NewSnapshotBuilder(nil, config.QualityOptions(), discard),
+		capacity: NewCapacityModel(config),
+		advisor:  advisor.NewAdvisor(config.Advisor, discard),
+	}
+}
+
+// Simulate aplica las ediciones en orden y retorna la comparación. manual es
+// el throughput ingresado a mano, igual que en el monitor.
+func (ws *WhatIfSimulator) Simulate(snapshot DataSnapshot, manual map[int]ThroughputEntry, edits []WhatIfEdit) (*WhatIfResult, error) {
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no hay ediciones para simular")
+	}
+
+	assignments := slices.Clone(snapshot.Assignments)
+	charts := cloneCharts(snapshot.ChartData)
+
+	before := ws.evaluate(snapshot, assignments, charts, manual)
+
+	// Throughput de cada sorter para trasladar volumen al mover un SKU. Sin
+	// modelo de capacidad se asume la misma entrada en todos los sorters.
+	throughput := make(map[int]float64)
+	if before.Capacity != nil {
+		for _, sorter := range before.Capacity.Sorters {
+			throughput[sorter.SorterID] = sorter.Throughput
+		}
+	}
+
+	for i, edit := range edits {
+		var err error
+		assignments, err = applyWhatIfEdit(edit, assignments, charts, throughput)
+		if err != nil {
+			return nil, fmt.Errorf("edición %d (%s): %w", i+1, edit.Accion, err)
+		}
+	}
+
+	// El throughput resultante reemplaza al configurado en el escenario
+	simulated := make(map[int]ThroughputEntry, len(manual))
+	for sorterID, entry := range manual {
+		simulated[sorterID] = entry
+	}
+	for sorterID, perMinute := range throughput {
+		simulated[sorterID] = ThroughputEntry{SorterID: sorterID, PerMinute: perMinute}
+	}
+	after := ws.evaluate(snapshot, assignments, charts, simulated)
+
+	return &WhatIfResult{
+		Timestamp: snapshot.Timestamp,
+		Edits:     edits,
+		Before:    before,
+		After:     after,
+		Changes:   compareLines(before, after),
+	}, nil
+}
+
+// evaluate reconstruye un snapshot con los assignments y gráficos dados y
+// calcula su estado
+func (ws *WhatIfSimulator) evaluate(base DataSnapshot, assignments []Assignment, charts map[int]*scraper.ChartData, manual map[int]ThroughputEntry) WhatIfState {
+	chartList := make([]*scraper.ChartData, 0, len(charts))
+	for _, chartData := range charts {
+		chartList = append(chartList, cloneChart(chartData))
+	}
+	sort.Slice(chartList, func(i, j int) bool { return chartList[i].SorterID < chartList[j].SorterID })
+
+	snapshot := ws.builder.CreateSnapshot(base.DateTime, slices.Clone(assignments), chartList)
+	snapshot.Capacity = ws.capacity.Compute(snapshot, manual)
+
+	imbalances := ws.advisor.Imbalances(convertToAdvisorState(snapshot))
+	state := WhatIfState{
+		Lines:      lineShares(snapshot),
+		Imbalances: imbalances,
+		Capacity:   snapshot.Capacity,
+		Quality:    snapshot.Quality,
+	}
+	if len(imbalances) > 0 {
+		state.MaxPriority = imbalances[0].Priority
+	}
+	if state.Imbalances == nil {
+		state.Imbalances = []advisor.Imbalance{}
+	}
+	return state
+}
+
+// applyWhatIfEdit aplica una edición sobre las copias de assignments y gráficos
+func applyWhatIfEdit(edit WhatIfEdit, assignments []Assignment, charts map[int]*scraper.ChartData, throughput map[int]float64) ([]Assignment, error) {
+	if edit.SKU == "" || edit.SorterID < 1 {
+		return nil, fmt.Errorf("falta sku o sorter_id")
+	}
+	sku := normalizeSKU(edit.SKU)
+
+	switch edit.Accion {
+	case WhatIfAddSalida:
+		if edit.Salida < 1 {
+			return nil, fmt.Errorf("falta salida")
+		}
+		for _, a := range assignments {
+			if a.SorterID == edit.SorterID && a.Salida == edit.Salida && normalizeSKU(a.SKU) == sku {
+				return nil, fmt.Errorf("%s ya tiene la salida %d en S%d", edit.SKU, edit.Salida, edit.SorterID)
+			}
+		}
+		return append(assignments, Assignment{SorterID: edit.SorterID, Salida: edit.Salida, SKU: chartSKU(charts[edit.SorterID], edit.SKU)}), nil
+
+	case WhatIfRemoveSalida:
+		for i, a := range assignments {
+			if a.SorterID == edit.SorterID && a.Salida == edit.Salida && normalizeSKU(a.SKU) == sku {
+				return append(assignments[:i:i], assignments[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("%s no tiene la salida %d en S%d", edit.SKU, edit.Salida, edit.SorterID)
+
+	case WhatIfMoveSKU:
+		if edit.ASorter < 1 || edit.ASorter == edit.SorterID {
+			return nil, fmt.Errorf("a_sorter inválido %d", edit.ASorter)
+		}
+		name := chartSKU(charts[edit.SorterID], edit.SKU)
+
+		var kept []Assignment
+		found := false
+		for _, a := range assignments {
+			if a.SorterID == edit.SorterID && normalizeSKU(a.SKU) == sku {
+				found = true
+				continue
+			}
+			kept = append(kept, a)
+		}
+		moved := moveChartShare(charts, edit.SorterID, edit.ASorter, name, throughput)
+		if !found && !moved {
+			return nil, fmt.Errorf("%s no está en S%d", edit.SKU, edit.SorterID)
+		}
+		for _, salida := range edit.Salidas {
+			kept = append(kept, Assignment{SorterID: edit.ASorter, Salida: salida, SKU: name})
+		}
+		return kept, nil
+
+	default:
+		return nil, fmt.Errorf("acción desconocida %q (opciones: %s, %s, %s)", edit.Accion, WhatIfAddSalida, WhatIfRemoveSalida, WhatIfMoveSKU)
+	}
+}
+
+// moveChartShare traslada el volumen de un SKU de un sorter a otro y
+// renormaliza ambos gráficos. Retorna false si el SKU no tenía porcentaje.
+func moveChartShare(charts map[int]*scraper.ChartData, from, to int, sku string, throughput map[int]float64) bool {
+	source := charts[from]
+	if source == nil {
+		return false
+	}
+	percentage, ok := source.Percentages[sku]
+	if !ok {
+		return false
+	}
+
+	fromRate, toRate := throughput[from], throughput[to]
+	known := fromRate > 0 && toRate > 0
+	if !known {
+		fromRate, toRate = 1, 1
+	}
+	volume := fromRate * percentage / 100
+
+	// Origen: el resto de los SKUs se reparte el 100%
+	delete(source.Percentages, sku)
+	source.OrderedSKUs = slices.DeleteFunc(source.OrderedSKUs, func(s string) bool { return s == sku })
+	if percentage < 100 {
+		for other, p := range source.Percentages {
+			source.Percentages[other] = p * 100 / (100 - percentage)
+		}
+	}
+	source.TotalSKUs = len(source.Percentages)
+
+	// Destino: el volumen del SKU se suma a la entrada del sorter
+	target := charts[to]
+	if target == nil {
+		target = &scraper.ChartData{SorterID: to, Percentages: make(map[string]float64)}
+		charts[to] = target
+	}
+	existing := ""
+	for other := range target.Percentages {
+		if normalizeSKU(other) == normalizeSKU(sku) {
+			existing = other
+		}
+	}
+	total := toRate + volume
+	for other, p := range target.Percentages {
+		target.Percentages[other] = p * toRate / total
+	}
+	if existing == "" {
+		existing = sku
+		target.OrderedSKUs = append(target.OrderedSKUs, sku)
+	}
+	target.Percentages[existing] += volume / total * 100
+	target.TotalSKUs = len(target.Percentages)
+
+	if known {
+		throughput[from] = fromRate - volume
+		throughput[to] = total
+	}
+	return true
+}
+
+// lineShares reparte el porcentaje de cada SKU en partes iguales entre sus salidas
+func lineShares(snapshot DataSnapshot) []WhatIfLine {
+	type key struct{ sorterID, salida int }
+	lines := make(map[key]*WhatIfLine)
+
+	for sorterID, chartData := range snapshot.ChartData {
+		if chartData == nil {
+			continue
+		}
+		for _, sku := range orderedChartSKUs(chartData) {
+			salidas := getLinesForSKU(snapshot.Assignments, sorterID, sku)
+			for _, salida := range salidas {
+				k := key{sorterID, salida}
+				if lines[k] == nil {
+					lines[k] = &WhatIfLine{SorterID: sorterID, Salida: salida}
+				}
+				lines[k].SKUs = append(lines[k].SKUs, sku)
+				lines[k].Percentage += chartData.Percentages[sku] / float64(len(salidas))
+			}
+		}
+	}
+
+	result := make([]WhatIfLine, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, *line)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SorterID != result[j].SorterID {
+			return result[i].SorterID < result[j].SorterID
+		}
+		return result[i].Salida < result[j].Salida
+	})
+	return result
+}
+
+// compareLines lista las salidas cuya carga cambia entre ambos escenarios
+func compareLines(before, after WhatIfState) []WhatIfLineChange {
+	type key struct{ sorterID, salida int }
+	changes := make(map[key]*WhatIfLineChange)
+	get := func(sorterID, salida int) *WhatIfLineChange {
+		k := key{sorterID, salida}
+		if changes[k] == nil {
+			changes[k] = &WhatIfLineChange{SorterID: sorterID, Salida: salida}
+		}
+		return changes[k]
+	}
+
+	for _, line := range before.Lines {
+		get(line.SorterID, line.Salida).Before = line.Percentage
+	}
+	for _, line := range after.Lines {
+		get(line.SorterID, line.Salida).After = line.Percentage
+	}
+	if before.Capacity != nil {
+		for _, line := range before.Capacity.Lines {
+			get(line.SorterID, line.Salida).LoadBefore = line.Load
+		}
+	}
+	if after.Capacity != nil {
+		for _, line := range after.Capacity.Lines {
+			change := get(line.SorterID, line.Salida)
+			change.LoadAfter = line.Load
+			change.OverloadAfter = line.Overloaded()
+		}
+	}
+
+	result := make([]WhatIfLineChange, 0, len(changes))
+	for _, change := range changes {
+		if math.Abs(change.After-change.Before) >= 0.05 || math.Abs(change.LoadAfter-change.LoadBefore) >= 0.05 {
+			result = append(result, *change)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SorterID != result[j].SorterID {
+			return result[i].SorterID < result[j].SorterID
+		}
+		return result[i].Salida < result[j].Salida
+	})
+	return result
+}
+
+// chartSKU retorna el nombre del SKU tal como aparece en el gráfico, o el
+// recibido si no está
+func chartSKU(chartData *scraper.ChartData, sku string) string {
+	if chartData != nil {
+		for name := range chartData.Percentages {
+			if normalizeSKU(name) == normalizeSKU(sku) {
+				return name
+			}
+		}
+	}
+	return sku
+}
+
+// cloneCharts copia los gráficos de un snapshot
+func cloneCharts(charts map[int]*scraper.ChartData) map[int]*scraper.ChartData {
+	clone := make(map[int]*scraper.ChartData, len(charts))
+	for sorterID, chartData := range charts {
+		if chartData != nil {
+			clone[sorterID] = cloneChart(chartData)
+			clone[sorterID].SorterID = sorterID
+		}
+	}
+	return clone
+}
+
+// cloneChart copia un gráfico sin compartir mapas ni slices
+func cloneChart(chartData *scraper.ChartData) *scraper.ChartData {
+	clone := *chartData
+	clone.Percentages = make(map[string]float64, len(chartData.Percentages))
+	for sku, percentage := range chartData.Percentages {
+		clone.Percentages[sku] = percentage
+	}
+	clone.OrderedSKUs = slices.Clone(chartData.OrderedSKUs)
+	return &clone
+}
@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"danich/pkg/scraper"
+)
+
+// whatIfSnapshot arma dos sorters: S1 con 4J (salidas 1 y 2) y 3J (salida 3),
+// S2 solo con XL en la salida 1
+func whatIfSnapshot() DataSnapshot {
+	at := time.Date(2026, 3, 10, 10, 0, 0, 0, time.Local)
+	snapshot := testSnapshot(at, 1, map[string]float64{"4J": 60, "3J": 40})
+	snapshot.ChartData[2] = &scraper.ChartData{SorterID: 2, Timestamp: at, Percentages: map[string]float64{"XL": 100}}
+	snapshot.Assignments = []Assignment{
+		{SorterID: 1, Salida: 1, SKU: "4J"},
+		{SorterID: 1, Salida: 2, SKU: "4J"},
+		{SorterID: 1, Salida: 3, SKU: "3J"},
+		{SorterID: 2, Salida: 1, SKU: "XL"},
+	}
+	return snapshot
+}
+
+func TestSimulateWhatIf(t *testing.T) {
+	type share struct{ before, after float64 }
+	tests := []struct {
+		name  string
+		edits []WhatIfEdit
+		want  map[[2]int]share // [sorter, salida] -> participación antes y después
+	}{
+		{
+			name:  "agregar salida",
+			edits: []WhatIfEdit{{Accion: WhatIfAddSalida, SKU: "4j", SorterID: 1, Salida: 3}},
+			want: map[[2]int]share{
+				{1, 1}: {30, 20},
+				{1, 2}: {30, 20},
+				{1, 3}: {40, 60},
+			},
+		},
+		{
+			name:  "quitar salida",
+			edits: []WhatIfEdit{{Accion: WhatIfRemoveSalida, SKU: "4J", SorterID: 1, Salida: 2}},
+			want: map[[2]int]share{
+				{1, 1}: {30, 60},
+				{1, 2}: {30, 0},
+			},
+		},
+		{
+			name:  "mover SKU con la misma entrada en ambos sorters",
+			edits: []WhatIfEdit{{Accion: WhatIfMoveSKU, SKU: "3J", SorterID: 1, ASorter: 2, Salidas: []int{2}}},
+			want: map[[2]int]share{
+				{1, 1}: {30, 50},
+				{1, 2}: {30, 50},
+				{1, 3}: {40, 0},
+				{2, 1}: {100, 100 / 1.4},
+				{2, 2}: {0, 40 / 1.4},
+			},
+		},
+		{
+			name: "ediciones encadenadas",
+			edits: []WhatIfEdit{
+				{Accion: WhatIfRemoveSalida, SKU: "4J", SorterID: 1, Salida: 2},
+				{Accion: WhatIfAddSalida, SKU: "3J", SorterID: 1, Salida: 2},
+			},
+			want: map[[2]int]share{
+				{1, 1}: {30, 60},
+				{1, 2}: {30, 20},
+				{1, 3}: {40, 20},
+			},
+		},
+	}
+
+	simulator := NewWhatIfSimulator(testConfig(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := whatIfSnapshot()
+			result, err := simulator.Simulate(snapshot, nil, tt.edits)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[[2]int]share)
+			for _, change := range result.Changes {
+				got[[2]int{change.SorterID, change.Salida}] = share{change.Before, change.After}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("salidas que cambian = %+v, esperaba %+v", got, tt.want)
+			}
+			for line, want := range tt.want {
+				g, ok := got[line]
+				if !ok || math.Abs(g.before-want.before) > 0.01 || math.Abs(g.after-want.after) > 0.01 {
+					t.Errorf("S%d salida %d = %+v, esperaba %+v", line[0], line[1], g, want)
+				}
+			}
+
+			// El snapshot recibido no se modifica
+			if original := whatIfSnapshot(); !reflect.DeepEqual(snapshot.Assignments, original.Assignments) ||
+				!reflect.DeepEqual(snapshot.ChartData[1].Percentages, original.ChartData[1].Percentages) ||
+				!reflect.DeepEqual(snapshot.ChartData[2].Percentages, original.ChartData[2].Percentages) {
+				t.Error("Simulate modificó el snapshot de partida")
+			}
+		})
+	}
+}
+
+func TestSimulateWhatIfErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		edits   []WhatIfEdit
+		wantErr string
+	}{
+		{"sin ediciones", nil, "no hay ediciones"},
+		{"acción desconocida", []WhatIfEdit{{Accion: "duplicar", SKU: "4J", SorterID: 1}}, "acción desconocida"},
+		{"falta sku", []WhatIfEdit{{Accion: WhatIfAddSalida, SorterID: 1, Salida: 4}}, "falta sku"},
+		{"falta salida", []WhatIfEdit{{Accion: WhatIfAddSalida, SKU: "4J", SorterID: 1}}, "falta salida"},
+		{"salida repetida", []WhatIfEdit{{Accion: WhatIfAddSalida, SKU: "4J", SorterID: 1, Salida: 2}}, "ya tiene la salida 2"},
+		{"quitar salida ajena", []WhatIfEdit{{Accion: WhatIfRemoveSalida, SKU: "3J", SorterID: 1, Salida: 1}}, "no tiene la salida 1"},
+		{"mover al mismo sorter", []WhatIfEdit{{Accion: WhatIfMoveSKU, SKU: "3J", SorterID: 1, ASorter: 1}}, "a_sorter inválido"},
+		{"mover SKU ausente", []WhatIfEdit{{Accion: WhatIfMoveSKU, SKU: "XL", SorterID: 1, ASorter: 2}}, "no está en S1"},
+		{
+			"error en la segunda edición",
+			[]WhatIfEdit{
+				{Accion: WhatIfRemoveSalida, SKU: "4J", SorterID: 1, Salida: 2},
+				{Accion: WhatIfRemoveSalida, SKU: "4J", SorterID: 1, Salida: 2},
+			},
+			"edición 2",
+		},
+	}
+
+	simulator := NewWhatIfSimulator(testConfig(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := simulator.Simulate(whatIfSnapshot(), nil, tt.edits)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, esperaba %q", err, tt.wantErr)
+			}
+		})
+	}
+}
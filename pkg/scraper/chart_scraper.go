@@ -3,7 +3,7 @@ package scraper
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
 	"time"
@@ -24,22 +24,37 @@ type ChartData struct {
 type ChartScraper struct {
 	baseURL string
 	timeout time.Duration
+	logger  *slog.Logger
 }
 
-// NewChartScraper crea un nuevo scraper de gráficos
-func NewChartScraper(baseURL string) *ChartScraper {
+// NewChartScraper crea un nuevo scraper de gráficos. Si logger es nil se usa
+// el logger por defecto.
+func NewChartScraper(baseURL string, logger *slog.Logger) *ChartScraper {
+	if logger == nil {
+		logger = slog.Default()
+	}
 	return &ChartScraper{
 		baseURL: baseURL,
 		timeout: 30 * time.Second,
+		logger:  logger,
 	}
 }
 
 // ScrapeAssignment obtiene los porcentajes de un assignment específico
 func (cs *ChartScraper) ScrapeAssignment(sorterID int) (*ChartData, error) {
+	return cs.ScrapeAssignmentContext(context.Background(), sorterID)
+}
+
+// ScrapeAssignmentContext obtiene los porcentajes de un assignment y se
+// cancela con ctx. Si ctx no tiene plazo se usa el timeout del scraper.
+func (cs *ChartScraper) ScrapeAssignmentContext(ctx context.Context, sorterID int) (*ChartData, error) {
 	url := fmt.Sprintf("%s/assignment/%d", cs.baseURL, sorterID)
 
 	// Crear contexto con timeout
-	ctx, cancel := context.WithTimeout(context.Background(), cs.timeout)
+	cancel := context.CancelFunc(func() {})
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		ctx, cancel = context.WithTimeout(ctx, cs.timeout)
+	}
 	defer cancel()
 
 	// Crear contexto de Chrome
@@ -96,7 +111,8 @@ func (cs *ChartScraper) ScrapeAssignment(sorterID int) (*ChartData, error) {
 
 		percentage, err := strconv.ParseFloat(percentageStr, 64)
 		if err != nil {
-			log.Printf("Warning: no se pudo parsear porcentaje '%s': %v", item[1], err)
+			cs.logger.Warn("No se pudo parsear porcentaje",
+				"sorter", sorterID, "sku", sku, "valor", item[1], "error", err)
 			continue
 		}
 
@@ -115,7 +131,7 @@ func (cs *ChartScraper) ScrapeBothSorters() ([]*ChartData, error) {
 	for sorterID := 1; sorterID <= 2; sorterID++ {
 		data, err := cs.ScrapeAssignment(sorterID)
 		if err != nil {
-			log.Printf("Error al obtener datos del sorter %d: %v", sorterID, err)
+			cs.logger.Warn("Error al obtener datos del sorter", "sorter", sorterID, "error", err)
 			continue
 		}
 		results = append(results, data)